@@ -13,7 +13,7 @@ _fabric() {
   _get_comp_words_by_ref -n : cur prev words cword
 
   # Define all possible options/flags
-  local opts="--pattern -p --variable -v --context -C --session --attachment -a --setup -S --temperature -t --topp -T --stream -s --presencepenalty -P --raw -r --frequencypenalty -F --listpatterns -l --listmodels -L --listcontexts -x --listsessions -X --updatepatterns -U --copy -c --model -m --modelContextLength --output -o --output-session --latest -n --changeDefaultModel -d --youtube -y --playlist --transcript --transcript-with-timestamps --comments --metadata --language -g --scrape_url -u --scrape_question -q --seed -e --wipecontext -w --wipesession -W --printcontext --printsession --readability --input-has-vars --dry-run --serve --serveOllama --address --api-key --config --search --search-location --image-file --image-size --image-quality --image-compression --image-background --suppress-think --think-start-tag --think-end-tag --disable-responses-api --voice --list-gemini-voices --version --listextensions --addextension --rmextension --strategy --liststrategies --listvendors --shell-complete-list --help -h"
+  local opts="--pattern -p --variable -v --context -C --session --attachment -a --setup -S --temperature -t --topp -T --stream -s --presencepenalty -P --raw -r --frequencypenalty -F --listpatterns -l --listmodels -L --listcontexts -x --listsessions -X --updatepatterns -U --copy -c --model -m --modelContextLength --output -o --output-session --latest -n --changeDefaultModel -d --youtube -y --playlist --transcript --transcript-with-timestamps --comments --metadata --language -g --scrape_url -u --scrape_question -q --seed -e --wipecontext -w --wipesession -W --printcontext --printsession --readability --input-has-vars --dry-run --serve --serveOllama --address --api-key --config --search --search-location --image-file --image-size --image-quality --image-compression --image-background --suppress-think --think-start-tag --think-end-tag --disable-responses-api --voice --list-gemini-voices --version --listextensions --addextension --rmextension --strategy --liststrategies --listvendors --shell-complete-list --tool --listtools --list-template-plugins --timeout --show-usage --context-strategy --context-turns --context-summarizer --pipeline --compare-format --cache --no-cache --cache-ttl --cache-max-size --cache-clear --json-schema --json-schema-retries --interactive -i --session-tag --sessions-sort --sessions-filter --fork-session --fork-at --rewind-session --truncate-session --edit-message --regenerate --export-session --export-format --import-session --migrate-storage --search-db --search-type --search-since --search-until --search-limit --patterns-tag --patterns-details --pattern-versions --pattern-diff --diff-from --diff-to --pin-pattern --unpin-pattern --rollback-pattern --pattern-version --test-patterns --test-junit --test-mock --pattern-info --help -h"
 
   # Helper function for dynamic completions
   _fabric_get_list() {
@@ -50,7 +50,7 @@ _fabric() {
     COMPREPLY=($(compgen -W "$(_fabric_get_list --listcontexts)" -- "${cur}"))
     return 0
     ;;
-  --printsession)
+  --printsession | --export-session)
     COMPREPLY=($(compgen -W "$(_fabric_get_list --listsessions)" -- "${cur}"))
     return 0
     ;;
@@ -66,8 +66,16 @@ _fabric() {
     COMPREPLY=($(compgen -W "$(_fabric_get_list --list-gemini-voices)" -- "${cur}"))
     return 0
     ;;
+  --tool)
+    COMPREPLY=($(compgen -W "$(_fabric_get_list --listtools)" -- "${cur}"))
+    return 0
+    ;;
+  --context-summarizer | --pattern-versions | --pattern-diff | --pin-pattern | --unpin-pattern | --rollback-pattern | --pattern-info)
+    COMPREPLY=($(compgen -W "$(_fabric_get_list --listpatterns)" -- "${cur}"))
+    return 0
+    ;;
   # Options requiring file/directory paths
-  -a | --attachment | -o | --output | --config | --addextension | --image-file)
+  -a | --attachment | -o | --output | --config | --addextension | --image-file | --pipeline | --json-schema | --import-session | --test-junit)
     _filedir
     return 0
     ;;
@@ -80,12 +88,36 @@ _fabric() {
     COMPREPLY=($(compgen -W "low medium high auto" -- "$cur"))
     return 0
     ;;
+  --context-strategy)
+    COMPREPLY=($(compgen -W "truncate summarize" -- "$cur"))
+    return 0
+    ;;
+  --compare-format)
+    COMPREPLY=($(compgen -W "markdown json" -- "$cur"))
+    return 0
+    ;;
+  --sessions-sort)
+    COMPREPLY=($(compgen -W "name created updated messages size tokens" -- "$cur"))
+    return 0
+    ;;
+  --search-type)
+    COMPREPLY=($(compgen -W "session context pattern" -- "$cur"))
+    return 0
+    ;;
+  --export-format)
+    COMPREPLY=($(compgen -W "markdown html jsonl openai" -- "$cur"))
+    return 0
+    ;;
+  --migrate-storage)
+    COMPREPLY=($(compgen -W "files sqlite" -- "$cur"))
+    return 0
+    ;;
   --image-background)
     COMPREPLY=($(compgen -W "opaque transparent" -- "$cur"))
     return 0
     ;;
   # Options requiring simple arguments (no specific completion logic here)
-  -v | --variable | -t | --temperature | -T | --topp | -P | --presencepenalty | -F | --frequencypenalty | --modelContextLength | -n | --latest | -y | --youtube | -g | --language | -u | --scrape_url | -q | --scrape_question | -e | --seed | --address | --api-key | --search-location | --image-compression | --think-start-tag | --think-end-tag)
+  -v | --variable | -t | --temperature | -T | --topp | -P | --presencepenalty | -F | --frequencypenalty | --modelContextLength | -n | --latest | -y | --youtube | -g | --language | -u | --scrape_url | -q | --scrape_question | -e | --seed | --address | --api-key | --search-location | --image-compression | --think-start-tag | --think-end-tag | --timeout | --context-strategy | --context-turns | --cache-ttl | --cache-max-size | --json-schema-retries | --session-tag | --sessions-filter | --fork-session | --fork-at | --rewind-session | --truncate-session | --edit-message | --regenerate | --search-db | --search-since | --search-until | --search-limit | --patterns-tag | --diff-from | --diff-to | --pattern-version)
     # No specific completion suggestions, user types the value
     return 0
     ;;
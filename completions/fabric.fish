@@ -35,21 +35,25 @@ function __fabric_get_gemini_voices
 	fabric --list-gemini-voices --shell-complete-list 2>/dev/null
 end
 
+function __fabric_get_tools
+	fabric --listtools --shell-complete-list 2>/dev/null
+end
+
 # Main completion function
 complete -c fabric -f
 
 # Flag completions with arguments
 complete -c fabric -s p -l pattern -d "Choose a pattern from the available patterns" -a "(__fabric_get_patterns)"
 complete -c fabric -s v -l variable -d "Values for pattern variables, e.g. -v=#role:expert -v=#points:30"
-complete -c fabric -s C -l context -d "Choose a context from the available contexts" -a "(__fabric_get_contexts)"
+complete -c fabric -s C -l context -d "Choose a context from the available contexts, can be repeated" -a "(__fabric_get_contexts)"
 complete -c fabric -l session -d "Choose a session from the available sessions" -a "(__fabric_get_sessions)"
 complete -c fabric -s a -l attachment -d "Attachment path or URL (e.g. for OpenAI image recognition messages)" -r
 complete -c fabric -s t -l temperature -d "Set temperature (default: 0.7)"
 complete -c fabric -s T -l topp -d "Set top P (default: 0.9)"
 complete -c fabric -s P -l presencepenalty -d "Set presence penalty (default: 0.0)"
 complete -c fabric -s F -l frequencypenalty -d "Set frequency penalty (default: 0.0)"
-complete -c fabric -s m -l model -d "Choose model" -a "(__fabric_get_models)"
-complete -c fabric -l modelContextLength -d "Model context length (only affects ollama)"
+complete -c fabric -s m -l model -d "Choose model, or several comma separated models to compare" -a "(__fabric_get_models)"
+complete -c fabric -l modelContextLength -d "Model context length, used by --context-strategy and as num_ctx for ollama"
 complete -c fabric -s o -l output -d "Output to file" -r
 complete -c fabric -s n -l latest -d "Number of latest patterns to list (default: 0)"
 complete -c fabric -s y -l youtube -d "YouTube video or play list URL to grab transcript, comments from it"
@@ -76,6 +80,46 @@ complete -c fabric -l strategy -d "Choose a strategy from the available strategi
 complete -c fabric -l think-start-tag -d "Start tag for thinking sections (default: <think>)"
 complete -c fabric -l think-end-tag -d "End tag for thinking sections (default: </think>)"
 complete -c fabric -l voice -d "TTS voice name for supported models (e.g., Kore, Charon, Puck)" -a "(__fabric_get_gemini_voices)"
+complete -c fabric -l tool -d "Offer a tool to the model for native tool calling" -a "(__fabric_get_tools)"
+complete -c fabric -l timeout -d "Cancel the request to the model after the given duration (e.g. 30s, 5m)"
+complete -c fabric -l context-strategy -d "Fit long sessions into the model context window" -a "truncate summarize"
+complete -c fabric -l context-turns -d "Maximum number of recent turns sent with --context-strategy"
+complete -c fabric -l context-summarizer -d "Pattern used to summarize older turns" -a "(__fabric_get_patterns)"
+complete -c fabric -l pipeline -d "Run a YAML pipeline of patterns" -r
+complete -c fabric -l compare-format -d "Report format when comparing several models" -a "markdown json"
+complete -c fabric -l cache-ttl -d "How long cached answers are reused (e.g. 24h)"
+complete -c fabric -l cache-max-size -d "Size of the response cache in MB"
+complete -c fabric -l json-schema -d "Answer with JSON that matches a JSON schema" -r
+complete -c fabric -l json-schema-retries -d "How often an invalid JSON answer is sent back"
+complete -c fabric -l session-tag -d "Add a tag to the session of --session"
+complete -c fabric -l sessions-sort -d "Sort --listsessions by a field" -a "name created updated messages size tokens"
+complete -c fabric -l sessions-filter -d "Only list sessions with field=value"
+complete -c fabric -l fork-session -d "Copy --session into a new session with this name"
+complete -c fabric -l fork-at -d "Number of messages that --fork-session copies"
+complete -c fabric -l rewind-session -d "Remove the last N answers of --session"
+complete -c fabric -l truncate-session -d "Keep only the first N messages of --session"
+complete -c fabric -l edit-message -d "Replace answer N of --session by the given message"
+complete -c fabric -l regenerate -d "Ask the model again for answer N of --session"
+complete -c fabric -l export-session -d "Export a session, to the file of --output or to stdout" -a "(__fabric_get_sessions)"
+complete -c fabric -l export-format -d "Format of --export-session: markdown, html, jsonl or openai" -a "markdown html jsonl openai"
+complete -c fabric -l import-session -d "Import a ChatGPT export, OpenAI or Anthropic messages or jsonl as sessions" -r
+complete -c fabric -l migrate-storage -d "Copy patterns, contexts and sessions to the storage files or sqlite" -a "files sqlite"
+complete -c fabric -l search-db -d "Search sessions, contexts and patterns for all words of a query"
+complete -c fabric -l search-type -d "Only search items of this type with --search-db" -a "session context pattern"
+complete -c fabric -l search-since -d "Only find items changed since this date with --search-db"
+complete -c fabric -l search-until -d "Only find items changed until this date with --search-db"
+complete -c fabric -l search-limit -d "Maximum number of results of --search-db"
+complete -c fabric -l patterns-tag -d "Only list patterns with this tag with --listpatterns"
+complete -c fabric -l pattern-versions -d "List the versions of a pattern" -a "(__fabric_get_patterns)"
+complete -c fabric -l pattern-diff -d "Show the changes of a pattern between the versions --diff-from and --diff-to" -a "(__fabric_get_patterns)"
+complete -c fabric -l diff-from -d "Version that --pattern-diff compares (default: the version before the current one)"
+complete -c fabric -l diff-to -d "Version that --pattern-diff compares to (default: the current one)"
+complete -c fabric -l pin-pattern -d "Hold a pattern at --pattern-version (default: its current content), --updatepatterns leaves it as it is" -a "(__fabric_get_patterns)"
+complete -c fabric -l unpin-pattern -d "Let --updatepatterns update a pinned pattern again" -a "(__fabric_get_patterns)"
+complete -c fabric -l rollback-pattern -d "Make --pattern-version (default: the version before the current one) the content of a pattern again" -a "(__fabric_get_patterns)"
+complete -c fabric -l pattern-version -d "Version of a pattern for --pin-pattern and --rollback-pattern"
+complete -c fabric -l test-junit -d "Also write the report of --test-patterns as JUnit XML to this file" -r
+complete -c fabric -l pattern-info -d "Print the variables, plugins and extensions a pattern uses" -a "(__fabric_get_patterns)"
 
 # Boolean flags (no arguments)
 complete -c fabric -s S -l setup -d "Run setup for all reconfigurable parts of fabric"
@@ -104,8 +148,18 @@ complete -c fabric -l version -d "Print current version"
 complete -c fabric -l listextensions -d "List all registered extensions"
 complete -c fabric -l liststrategies -d "List all strategies"
 complete -c fabric -l listvendors -d "List all vendors"
+complete -c fabric -l list-template-plugins -d "List the template plugins and their operations"
 complete -c fabric -l list-gemini-voices -d "List all available Gemini TTS voices"
 complete -c fabric -l shell-complete-list -d "Output raw list without headers/formatting (for shell completion)"
 complete -c fabric -l suppress-think -d "Suppress text enclosed in thinking tags"
 complete -c fabric -l disable-responses-api -d "Disable OpenAI Responses API (default: false)"
+complete -c fabric -l listtools -d "List all tools"
+complete -c fabric -l show-usage -d "Print token usage, finish reason and cost to stderr"
+complete -c fabric -l cache -d "Reuse cached answers to identical requests"
+complete -c fabric -l no-cache -d "Do not use the response cache"
+complete -c fabric -l cache-clear -d "Remove all cached answers"
+complete -c fabric -s i -l interactive -d "Chat with the model in a conversation"
+complete -c fabric -l patterns-details -d "List patterns with their descriptions and tags with --listpatterns"
+complete -c fabric -l test-patterns -d "Run the tests in the tests.yaml of the pattern of -p, or of all patterns with tests"
+complete -c fabric -l test-mock -d "Answer the tests of --test-patterns with their scripted responses instead of a model"
 complete -c fabric -s h -l help -d "Show this help message"
@@ -3,6 +3,7 @@ package chat
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 const (
@@ -51,6 +52,58 @@ type ToolCall struct {
 	Function FunctionCall `json:"function"`
 }
 
+// Tool describes a tool the model may call. Only function tools are supported.
+type Tool struct {
+	Type     ToolType            `json:"type"`
+	Function *FunctionDefinition `json:"function,omitempty"`
+}
+
+// FunctionDefinition describes a callable function; Parameters is a JSON schema object.
+type FunctionDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// Usage reports the tokens used by a model response
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Add adds the token counts of other to the usage
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// MessageMeta holds fabric's own data about a message. It is saved with the session
+// but never sent to the vendors.
+type MessageMeta struct {
+	// Vendor and Model that answered, which differ from the requested ones after a fallback
+	Vendor string `json:"vendor,omitempty"`
+	Model  string `json:"model,omitempty"`
+	// Usage of the model calls that produced the message, including any tool rounds
+	Usage        *Usage `json:"usage,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	// Cached marks an answer that came from the response cache instead of the vendor
+	Cached bool `json:"cached,omitempty"`
+	// Summary marks a meta message that stands in for the earlier turns of the session
+	Summary bool `json:"summary,omitempty"`
+	// Timestamp is when the message was added to the session
+	Timestamp time.Time `json:"timestamp,omitzero"`
+	// Pattern and Strategy that the answer was requested with
+	Pattern  string `json:"pattern,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+	// Edited marks an answer that was changed after the model wrote it
+	Edited bool `json:"edited,omitempty"`
+}
+
 type ChatCompletionMessage struct {
 	Role             string            `json:"role"`
 	Content          string            `json:"content,omitempty"`
@@ -61,6 +114,7 @@ type ChatCompletionMessage struct {
 	FunctionCall     *FunctionCall     `json:"function_call,omitempty"`
 	ToolCalls        []ToolCall        `json:"tool_calls,omitempty"`
 	ToolCallID       string            `json:"tool_call_id,omitempty"`
+	Meta             *MessageMeta      `json:"meta,omitempty"`
 }
 
 func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
@@ -78,6 +132,7 @@ func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
 			FunctionCall     *FunctionCall     `json:"function_call,omitempty"`
 			ToolCalls        []ToolCall        `json:"tool_calls,omitempty"`
 			ToolCallID       string            `json:"tool_call_id,omitempty"`
+			Meta             *MessageMeta      `json:"meta,omitempty"`
 		}(m)
 		return json.Marshal(msg)
 	}
@@ -92,6 +147,7 @@ func (m ChatCompletionMessage) MarshalJSON() ([]byte, error) {
 		FunctionCall     *FunctionCall     `json:"function_call,omitempty"`
 		ToolCalls        []ToolCall        `json:"tool_calls,omitempty"`
 		ToolCallID       string            `json:"tool_call_id,omitempty"`
+		Meta             *MessageMeta      `json:"meta,omitempty"`
 	}(m)
 	return json.Marshal(msg)
 }
@@ -107,6 +163,7 @@ func (m *ChatCompletionMessage) UnmarshalJSON(bs []byte) error {
 		FunctionCall     *FunctionCall `json:"function_call,omitempty"`
 		ToolCalls        []ToolCall    `json:"tool_calls,omitempty"`
 		ToolCallID       string        `json:"tool_call_id,omitempty"`
+		Meta             *MessageMeta  `json:"meta,omitempty"`
 	}{}
 
 	if err := json.Unmarshal(bs, &msg); err == nil {
@@ -123,6 +180,7 @@ func (m *ChatCompletionMessage) UnmarshalJSON(bs []byte) error {
 		FunctionCall     *FunctionCall     `json:"function_call,omitempty"`
 		ToolCalls        []ToolCall        `json:"tool_calls,omitempty"`
 		ToolCallID       string            `json:"tool_call_id,omitempty"`
+		Meta             *MessageMeta      `json:"meta,omitempty"`
 	}{}
 	if err := json.Unmarshal(bs, &multiMsg); err != nil {
 		return err
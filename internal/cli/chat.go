@@ -1,13 +1,19 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/core"
 	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 )
 
@@ -17,23 +23,23 @@ func handleChatProcessing(currentFlags *Flags, registry *core.PluginRegistry, me
 		currentFlags.AppendMessage(messageTools)
 	}
 
+	if models := splitModels(currentFlags.Model); len(models) > 1 {
+		return handleCompare(currentFlags, registry, models)
+	}
+
+	ctx, cancel := newRequestContext(currentFlags.Timeout)
+	defer cancel()
+
 	var chatter *core.Chatter
-	if chatter, err = registry.GetChatter(currentFlags.Model, currentFlags.ModelContextLength,
+	if chatter, err = registry.GetChatter(ctx, currentFlags.Model, currentFlags.ModelContextLength,
 		currentFlags.Strategy, currentFlags.Stream, currentFlags.DryRun); err != nil {
 		return
 	}
 
 	var session *fsdb.Session
 	var chatReq *domain.ChatRequest
-	if chatReq, err = currentFlags.BuildChatRequest(strings.Join(os.Args[1:], " ")); err != nil {
-		return
-	}
-
-	if chatReq.Language == "" {
-		chatReq.Language = registry.Language.DefaultLanguage.Value
-	}
 	var chatOptions *domain.ChatOptions
-	if chatOptions, err = currentFlags.BuildChatOptions(); err != nil {
+	if chatReq, chatOptions, err = buildChatRequestAndOptions(currentFlags, registry); err != nil {
 		return
 	}
 
@@ -70,11 +76,24 @@ func handleChatProcessing(currentFlags *Flags, registry *core.PluginRegistry, me
 		chatOptions.AudioFormat = "wav" // Default to WAV format
 	}
 
-	if session, err = chatter.Send(chatReq, chatOptions); err != nil {
+	requestedModel := chatOptions.Model
+	if requestedModel == "" {
+		requestedModel = registry.Defaults.Model.Value
+	}
+	if session, err = chatter.Send(ctx, chatReq, chatOptions); err != nil {
 		return
 	}
 
 	result := session.GetLastMessage().Content
+	if meta := session.GetLastMessage().Meta; meta != nil && meta.Model != requestedModel {
+		fmt.Fprintf(os.Stderr, "[Answered by %s|%s]\n", meta.Vendor, meta.Model)
+	}
+
+	if currentFlags.ShowUsage {
+		if err = printUsage(session.GetLastMessage(), chatOptions.Model, filepath.Join(registry.Db.Dir, "prices.yaml")); err != nil {
+			return
+		}
+	}
 
 	if !currentFlags.Stream || currentFlags.SuppressThink {
 		// For TTS models with audio output, show a user-friendly message instead of raw data
@@ -118,6 +137,89 @@ func handleChatProcessing(currentFlags *Flags, registry *core.PluginRegistry, me
 	return
 }
 
+// buildChatRequestAndOptions builds the chat request and options from the flags,
+// including the default language and the chosen tools
+func buildChatRequestAndOptions(currentFlags *Flags, registry *core.PluginRegistry) (
+	chatReq *domain.ChatRequest, chatOptions *domain.ChatOptions, err error) {
+
+	if chatReq, err = currentFlags.BuildChatRequest(strings.Join(os.Args[1:], " ")); err != nil {
+		return
+	}
+	if chatReq.Language == "" {
+		chatReq.Language = registry.Language.DefaultLanguage.Value
+	}
+	if chatOptions, err = currentFlags.BuildChatOptions(); err != nil {
+		return
+	}
+
+	if len(currentFlags.Tools) > 0 {
+		// The vendors answer tool calls in one piece, so the answer is printed once it is complete
+		if currentFlags.Stream {
+			fmt.Fprintln(os.Stderr, "Warning: --stream is ignored with --tool, the answer is printed when it is complete")
+		}
+		if err = registry.Tools.Load(); err != nil {
+			return
+		}
+		if chatOptions.Tools, err = registry.Tools.GetChatTools(currentFlags.Tools); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// printUsage writes the token usage, the finish reason and, if the model has a price
+// in the price table, the cost of the answer to stderr
+func printUsage(message *chat.ChatCompletionMessage, model string, pricesFile string) (err error) {
+	if message.Meta == nil || message.Meta.Usage == nil {
+		fmt.Fprintln(os.Stderr, "Usage: not reported by the vendor")
+		return
+	}
+
+	usage := message.Meta.Usage
+	line := fmt.Sprintf("Usage: %d prompt + %d completion = %d tokens",
+		usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	if message.Meta.Model != "" {
+		line += fmt.Sprintf(", model: %s|%s", message.Meta.Vendor, message.Meta.Model)
+	}
+	if message.Meta.FinishReason != "" {
+		line += fmt.Sprintf(", finish reason: %s", message.Meta.FinishReason)
+	}
+
+	var prices ai.PriceTable
+	if prices, err = ai.LoadPriceTable(pricesFile); err != nil {
+		return
+	}
+	if message.Meta.Cached {
+		line += ", from cache"
+	} else if cost, ok := prices.Cost(model, usage); ok {
+		line += fmt.Sprintf(", cost: $%.6f", cost)
+	}
+	fmt.Fprintln(os.Stderr, line)
+	return
+}
+
+// newRequestContext returns a context that is cancelled on Ctrl-C, SIGTERM or after the
+// optional timeout, so the running request to the model is stopped.
+// A second Ctrl-C terminates the process as usual.
+func newRequestContext(timeout time.Duration) (ctx context.Context, cancel context.CancelFunc) {
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signalCtx.Done()
+		stop()
+	}()
+
+	ctx, cancel = signalCtx, stop
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancel = func() {
+			cancelTimeout()
+			stop()
+		}
+	}
+	return
+}
+
 // isTTSModel checks if the model is a text-to-speech model
 func isTTSModel(modelName string) bool {
 	lowerModel := strings.ToLower(modelName)
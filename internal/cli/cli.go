@@ -7,7 +7,9 @@ import (
 	"strings"
 
 	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 	"github.com/danielmiessler/fabric/internal/plugins/ai/openai"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 	"github.com/danielmiessler/fabric/internal/tools/converter"
 	"github.com/danielmiessler/fabric/internal/tools/youtube"
 )
@@ -42,6 +44,7 @@ func Cli(version string) (err error) {
 			return err2
 		}
 	}
+	defer registry.Db.Close()
 
 	// Configure OpenAI Responses API setting based on CLI flag
 	if registry != nil {
@@ -54,6 +57,9 @@ func Cli(version string) (err error) {
 		return
 	}
 
+	// Contexts can include files, directories and URLs on the command line, not in the server
+	registry.ContextIncludes = true
+
 	// Handle configuration commands
 	if handled, err = handleConfigurationCommands(currentFlags, registry); err != nil || handled {
 		return
@@ -69,11 +75,23 @@ func Cli(version string) (err error) {
 		return
 	}
 
+	// Handle session commands
+	if handled, err = handleSessionCommands(currentFlags, registry); err != nil || handled {
+		return
+	}
+
 	// Handle extension commands
 	if handled, err = handleExtensionCommands(currentFlags, registry); err != nil || handled {
 		return
 	}
 
+	// Report all missing pattern variables before any input is fetched
+	if currentFlags.Pattern != "" && currentFlags.Pipeline == "" && !currentFlags.TestPatterns {
+		if err = registry.Db.Patterns.CheckVariables(currentFlags.Pattern, currentFlags.PatternVariables); err != nil {
+			return
+		}
+	}
+
 	// Process HTML readability if needed
 	if currentFlags.HtmlReadability {
 		if msg, cleanErr := converter.HtmlReadability(currentFlags.Message); cleanErr != nil {
@@ -94,6 +112,35 @@ func Cli(version string) (err error) {
 		return nil
 	}
 
+	if currentFlags.Cache && !currentFlags.NoCache {
+		registry.ResponseCache = ai.NewResponseCache(registry.Db.FilePath(ai.ResponseCacheDir),
+			currentFlags.CacheTTL, int64(currentFlags.CacheMaxSize)*1024*1024)
+	}
+
+	if currentFlags.TestPatterns {
+		err = handlePatternTests(currentFlags, registry)
+		return
+	}
+
+	// The pattern may prefer a model, temperature and strategy
+	if currentFlags.Pattern != "" && currentFlags.Pipeline == "" {
+		var metadata *fsdb.PatternMetadata
+		if metadata, err = registry.Db.Patterns.GetMetadata(currentFlags.Pattern); err != nil {
+			return
+		}
+		currentFlags.ApplyPatternDefaults(metadata)
+	}
+
+	if currentFlags.Interactive {
+		err = handleInteractive(currentFlags, registry, messageTools)
+		return
+	}
+
+	if currentFlags.Pipeline != "" {
+		err = handlePipeline(currentFlags, registry, messageTools)
+		return
+	}
+
 	// Handle chat processing
 	err = handleChatProcessing(currentFlags, registry, messageTools)
 	return
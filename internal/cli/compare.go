@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+const (
+	compareFormatMarkdown = "markdown"
+	compareFormatJSON     = "json"
+)
+
+// splitModels splits a comma separated list of models, as used for comparisons with -m
+func splitModels(model string) (ret []string) {
+	for _, name := range strings.Split(model, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	return
+}
+
+// handleCompare sends the request to all models at the same time and prints a report
+// with the answer, the timing and the usage of every model
+func handleCompare(currentFlags *Flags, registry *core.PluginRegistry, models []string) (err error) {
+	if currentFlags.CompareFormat != compareFormatMarkdown && currentFlags.CompareFormat != compareFormatJSON {
+		return fmt.Errorf("invalid compare format %s, expected %s or %s",
+			currentFlags.CompareFormat, compareFormatMarkdown, compareFormatJSON)
+	}
+	if currentFlags.Session != "" {
+		return fmt.Errorf("sessions can not be used when comparing several models")
+	}
+
+	var chatReq *domain.ChatRequest
+	var chatOptions *domain.ChatOptions
+	if chatReq, chatOptions, err = buildChatRequestAndOptions(currentFlags, registry); err != nil {
+		return
+	}
+
+	ctx, cancel := newRequestContext(currentFlags.Timeout)
+	defer cancel()
+
+	results := registry.Compare(ctx, models, currentFlags.ModelContextLength, currentFlags.Strategy,
+		currentFlags.DryRun, chatReq, chatOptions)
+
+	var report string
+	if currentFlags.CompareFormat == compareFormatJSON {
+		var data []byte
+		if data, err = json.MarshalIndent(results, "", "  "); err != nil {
+			return
+		}
+		report = string(data)
+	} else {
+		report = formatCompareMarkdown(results)
+	}
+
+	fmt.Println(report)
+
+	if currentFlags.Copy {
+		if err = CopyToClipboard(report); err != nil {
+			return
+		}
+	}
+	if currentFlags.Output != "" {
+		err = CreateOutputFile(report, currentFlags.Output)
+	}
+	return
+}
+
+// formatCompareMarkdown builds a Markdown report with one section per model
+func formatCompareMarkdown(results []*core.CompareResult) string {
+	var builder strings.Builder
+	builder.WriteString("# Model Comparison\n")
+	for _, result := range results {
+		builder.WriteString("\n## " + result.Model)
+		if result.Vendor != "" {
+			builder.WriteString(" (" + result.Vendor + ")")
+		}
+		builder.WriteString("\n\n")
+
+		stats := []string{(time.Duration(result.DurationMs) * time.Millisecond).String()}
+		if result.Usage != nil {
+			stats = append(stats, fmt.Sprintf("%d prompt + %d completion = %d tokens",
+				result.Usage.PromptTokens, result.Usage.CompletionTokens, result.Usage.TotalTokens))
+		}
+		if result.FinishReason != "" {
+			stats = append(stats, "finish reason: "+result.FinishReason)
+		}
+		if result.AnsweredBy != "" {
+			stats = append(stats, "answered by "+result.AnsweredBy)
+		}
+		builder.WriteString("_" + strings.Join(stats, ", ") + "_\n\n")
+
+		if result.Error != "" {
+			builder.WriteString("**Error:** " + result.Error + "\n")
+		} else {
+			builder.WriteString(strings.TrimSpace(result.Output) + "\n")
+		}
+	}
+	return builder.String()
+}
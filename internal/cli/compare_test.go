@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/core"
+)
+
+func TestSplitModels(t *testing.T) {
+	if got := splitModels("gpt-4o, claude-sonnet-4,,llama3 "); !reflect.DeepEqual(got, []string{"gpt-4o", "claude-sonnet-4", "llama3"}) {
+		t.Errorf("splitModels() = %v", got)
+	}
+	if got := splitModels("gpt-4o"); len(got) != 1 {
+		t.Errorf("expected a single model, got %v", got)
+	}
+}
+
+func TestFormatCompareMarkdown(t *testing.T) {
+	report := formatCompareMarkdown([]*core.CompareResult{
+		{Model: "gpt-4o", Vendor: "OpenAI", Output: "answer\n", DurationMs: 1500,
+			Usage: &chat.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, FinishReason: "stop"},
+		{Model: "llama3", Vendor: "Ollama", Error: "connection refused", DurationMs: 20},
+	})
+
+	for _, want := range []string{
+		"## gpt-4o (OpenAI)\n\n_1.5s, 10 prompt + 5 completion = 15 tokens, finish reason: stop_\n\nanswer\n",
+		"## llama3 (Ollama)\n\n_20ms_\n\n**Error:** connection refused\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -10,9 +11,11 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 	"github.com/danielmiessler/fabric/internal/util"
 	"github.com/jessevdk/go-flags"
 	"golang.org/x/text/language"
@@ -23,7 +26,7 @@ import (
 type Flags struct {
 	Pattern                         string            `short:"p" long:"pattern" yaml:"pattern" description:"Choose a pattern from the available patterns" default:""`
 	PatternVariables                map[string]string `short:"v" long:"variable" description:"Values for pattern variables, e.g. -v=#role:expert -v=#points:30"`
-	Context                         string            `short:"C" long:"context" description:"Choose a context from the available contexts" default:""`
+	Context                         []string          `short:"C" long:"context" description:"Choose a context from the available contexts, can be used multiple times to merge contexts in order"`
 	Session                         string            `long:"session" description:"Choose a session from the available sessions"`
 	Attachments                     []string          `short:"a" long:"attachment" description:"Attachment path or URL (e.g. for OpenAI image recognition messages)"`
 	Setup                           bool              `short:"S" long:"setup" description:"Run setup for all reconfigurable parts of fabric"`
@@ -40,8 +43,8 @@ type Flags struct {
 	UpdatePatterns                  bool              `short:"U" long:"updatepatterns" description:"Update patterns"`
 	Message                         string            `hidden:"true" description:"Messages to send to chat"`
 	Copy                            bool              `short:"c" long:"copy" description:"Copy to clipboard"`
-	Model                           string            `short:"m" long:"model" yaml:"model" description:"Choose model"`
-	ModelContextLength              int               `long:"modelContextLength" yaml:"modelContextLength" description:"Model context length (only affects ollama)"`
+	Model                           string            `short:"m" long:"model" yaml:"model" description:"Choose model, or several comma separated models to compare their answers"`
+	ModelContextLength              int               `long:"modelContextLength" yaml:"modelContextLength" description:"Model context length, used by --context-strategy and as num_ctx for ollama"`
 	Output                          string            `short:"o" long:"output" description:"Output to file" default:""`
 	OutputSession                   bool              `long:"output-session" description:"Output the entire session (also a temporary one) to the output file"`
 	LatestPatterns                  string            `short:"n" long:"latest" description:"Number of latest patterns to list" default:"0"`
@@ -89,6 +92,59 @@ type Flags struct {
 	DisableResponsesAPI             bool              `long:"disable-responses-api" yaml:"disableResponsesAPI" description:"Disable OpenAI Responses API (default: false)"`
 	Voice                           string            `long:"voice" yaml:"voice" description:"TTS voice name for supported models (e.g., Kore, Charon, Puck)" default:"Kore"`
 	ListGeminiVoices                bool              `long:"list-gemini-voices" description:"List all available Gemini TTS voices"`
+	Tools                           []string          `long:"tool" yaml:"tools" description:"Offer a tool to the model for native tool calling, repeat for more tools (see --listtools); answers with tools are not streamed"`
+	ListTools                       bool              `long:"listtools" description:"List all tools"`
+	ListTemplatePlugins             bool              `long:"list-template-plugins" description:"List the template plugins and their operations"`
+	Timeout                         time.Duration     `long:"timeout" yaml:"timeout" description:"Cancel the request to the model after the given duration (e.g. 30s, 5m)"`
+	ShowUsage                       bool              `long:"show-usage" yaml:"showUsage" description:"Print token usage, finish reason and cost (see prices.yaml) to stderr"`
+	ContextStrategy                 string            `long:"context-strategy" yaml:"contextStrategy" description:"Fit long sessions into the model context window: truncate (drop older turns) or summarize (replace older turns with a summary)"`
+	ContextTurns                    int               `long:"context-turns" yaml:"contextTurns" description:"Maximum number of recent turns sent with --context-strategy (default: as many as fit)"`
+	ContextSummarizer               string            `long:"context-summarizer" yaml:"contextSummarizer" description:"Pattern used to summarize older turns with --context-strategy=summarize"`
+	Pipeline                        string            `long:"pipeline" description:"Run a YAML pipeline of patterns, given as a file or by name from ~/.config/fabric/pipelines"`
+	CompareFormat                   string            `long:"compare-format" yaml:"compareFormat" description:"Report format when -m lists several models to compare: markdown or json" default:"markdown"`
+	Cache                           bool              `long:"cache" yaml:"cache" description:"Reuse cached answers to identical requests and cache new ones"`
+	NoCache                         bool              `long:"no-cache" description:"Do not use the response cache, even if enabled in the config"`
+	CacheTTL                        time.Duration     `long:"cache-ttl" yaml:"cacheTTL" description:"How long cached answers are reused (0 for no expiry)" default:"24h"`
+	CacheMaxSize                    int               `long:"cache-max-size" yaml:"cacheMaxSize" description:"Size of the response cache in MB above which the oldest answers are removed (0 for no limit)" default:"100"`
+	CacheClear                      bool              `long:"cache-clear" description:"Remove all cached answers"`
+	JSONSchema                      string            `long:"json-schema" description:"Answer with JSON that matches a JSON schema, given as a file or inline (default: the schema.json of the pattern)"`
+	JSONSchemaRetries               int               `long:"json-schema-retries" yaml:"jsonSchemaRetries" description:"How often the model is asked to fix an answer that does not match the JSON schema" default:"2"`
+	Interactive                     bool              `short:"i" long:"interactive" description:"Chat with the model in a conversation, with slash commands to change the pattern, model, context or strategy"`
+	SessionTags                     []string          `long:"session-tag" description:"Add a tag to the session of --session, can be used multiple times"`
+	SessionsSort                    string            `long:"sessions-sort" description:"Sort --listsessions by name, created, updated, messages, size or tokens" default:"name"`
+	SessionsFilter                  []string          `long:"sessions-filter" description:"Only list sessions with field=value, for the fields tag, model, vendor, pattern, strategy, since and until, can be used multiple times"`
+	ForkSession                     string            `long:"fork-session" description:"Copy --session into a new session with this name, linked to its parent"`
+	ForkAt                          int               `long:"fork-at" description:"Number of messages of --session that --fork-session copies (default: all)"`
+	RewindSession                   int               `long:"rewind-session" description:"Remove the last N answers of --session with the messages they answered"`
+	TruncateSession                 int               `long:"truncate-session" description:"Keep only the first N messages of --session"`
+	EditMessage                     int               `long:"edit-message" description:"Replace the answer with number N in --session by the given message"`
+	Regenerate                      int               `long:"regenerate" description:"Ask the model again for the answer with number N in --session (-1 for the last message), replacing it and the messages after it"`
+	ExportSession                   string            `long:"export-session" description:"Export a session, to the file of --output or to stdout"`
+	ExportFormat                    string            `long:"export-format" description:"Format of --export-session: markdown, html, jsonl or openai" default:"markdown"`
+	ImportSession                   string            `long:"import-session" description:"Import the conversations of a ChatGPT conversations.json export, an OpenAI or Anthropic message array or jsonl messages as sessions, named by --session or their titles"`
+	MigrateStorage                  string            `long:"migrate-storage" description:"Copy patterns, contexts and sessions to the storage files or sqlite from the other one"`
+	SearchDb                        string            `long:"search-db" description:"Search the messages of sessions, the contexts and the system prompts of patterns for all words of a query, a word ending with * matches as a prefix"`
+	SearchTypes                     []string          `long:"search-type" description:"Only search items of this type with --search-db: session, context or pattern, can be used multiple times"`
+	SearchSince                     string            `long:"search-since" description:"Only find items changed since this date with --search-db, like 2025-01-31"`
+	SearchUntil                     string            `long:"search-until" description:"Only find items changed until this date with --search-db, like 2025-01-31"`
+	SearchLimit                     int               `long:"search-limit" description:"Maximum number of results of --search-db (0 for all)" default:"20"`
+	PatternsTags                    []string          `long:"patterns-tag" description:"Only list patterns with this tag with --listpatterns, can be used multiple times"`
+	PatternsDetails                 bool              `long:"patterns-details" description:"List patterns with their descriptions and tags with --listpatterns"`
+	PatternVersions                 string            `long:"pattern-versions" description:"List the versions of a pattern"`
+	PatternDiff                     string            `long:"pattern-diff" description:"Show the changes of a pattern between the versions --diff-from and --diff-to"`
+	DiffFrom                        int               `long:"diff-from" description:"Version that --pattern-diff compares (default: the version before the current one)"`
+	DiffTo                          int               `long:"diff-to" description:"Version that --pattern-diff compares to (default: the current one)"`
+	PinPattern                      string            `long:"pin-pattern" description:"Hold a pattern at --pattern-version (default: its current content), --updatepatterns leaves it as it is"`
+	UnpinPattern                    string            `long:"unpin-pattern" description:"Let --updatepatterns update a pinned pattern again"`
+	RollbackPattern                 string            `long:"rollback-pattern" description:"Make --pattern-version (default: the version before the current one) the content of a pattern again"`
+	PatternVersion                  int               `long:"pattern-version" description:"Version of a pattern for --pin-pattern and --rollback-pattern"`
+	TestPatterns                    bool              `long:"test-patterns" description:"Run the tests in the tests.yaml of the pattern of -p, or of all patterns with tests"`
+	TestJUnit                       string            `long:"test-junit" description:"Also write the report of --test-patterns as JUnit XML to this file"`
+	TestMock                        bool              `long:"test-mock" description:"Answer the tests of --test-patterns with their scripted responses instead of a model"`
+	PatternInfo                     string            `long:"pattern-info" description:"Print the variables, plugins and extensions a pattern uses"`
+
+	// cliFlags holds the long names of the flags given on the command line
+	cliFlags map[string]bool
 }
 
 var debug = false
@@ -144,6 +200,7 @@ func Init() (ret *Flags, err error) {
 	if args, err = parser.Parse(); err != nil {
 		return
 	}
+	ret.cliFlags = givenFlags(yamlArgsScan)
 
 	// Check to see if a ~/.config/fabric/config.yaml config file exists (only when user didn't specify a config)
 	if ret.Config == "" {
@@ -198,7 +255,8 @@ func Init() (ret *Flags, err error) {
 		ret.Message = AppendMessage(ret.Message, args[len(args)-1])
 	}
 
-	if pipedToStdin {
+	// In interactive mode piped input is read line by line as the conversation
+	if pipedToStdin && !ret.Interactive {
 		var pipedMessage string
 		if pipedMessage, err = readStdin(); err != nil {
 			return
@@ -208,6 +266,43 @@ func Init() (ret *Flags, err error) {
 	return
 }
 
+// givenFlags returns the long names of the flags in args
+func givenFlags(args []string) (ret map[string]bool) {
+	longNames := make(map[string]string)
+	t := reflect.TypeOf(Flags{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if longTag := field.Tag.Get("long"); longTag != "" {
+			longNames[longTag] = longTag
+			if shortTag := field.Tag.Get("short"); shortTag != "" {
+				longNames[shortTag] = longTag
+			}
+		}
+	}
+
+	ret = make(map[string]bool)
+	for _, arg := range args {
+		if longName, exists := longNames[extractFlag(arg)]; exists {
+			ret[longName] = true
+		}
+	}
+	return
+}
+
+// ApplyPatternDefaults uses the model, temperature and strategy that a pattern prefers unless
+// the user gave them on the command line
+func (o *Flags) ApplyPatternDefaults(metadata *fsdb.PatternMetadata) {
+	if metadata.Model != "" && !o.cliFlags["model"] {
+		o.Model = metadata.Model
+	}
+	if metadata.Temperature != nil && !o.cliFlags["temperature"] {
+		o.Temperature = *metadata.Temperature
+	}
+	if metadata.Strategy != "" && !o.cliFlags["strategy"] {
+		o.Strategy = metadata.Strategy
+	}
+}
+
 func extractFlag(arg string) string {
 	var flag string
 	if strings.HasPrefix(arg, "--") {
@@ -415,6 +510,18 @@ func (o *Flags) BuildChatOptions() (ret *domain.ChatOptions, err error) {
 		return nil, err
 	}
 
+	switch o.ContextStrategy {
+	case "", domain.ContextStrategyTruncate, domain.ContextStrategySummarize:
+	default:
+		return nil, fmt.Errorf("invalid context strategy %s, expected %s or %s", o.ContextStrategy,
+			domain.ContextStrategyTruncate, domain.ContextStrategySummarize)
+	}
+
+	var jsonSchema json.RawMessage
+	if jsonSchema, err = loadJSONSchema(o.JSONSchema); err != nil {
+		return nil, err
+	}
+
 	startTag := o.ThinkStartTag
 	if startTag == "" {
 		startTag = "<think>"
@@ -444,16 +551,47 @@ func (o *Flags) BuildChatOptions() (ret *domain.ChatOptions, err error) {
 		ThinkStartTag:      startTag,
 		ThinkEndTag:        endTag,
 		Voice:              o.Voice,
+		ContextStrategy:    o.ContextStrategy,
+		ContextTurns:       o.ContextTurns,
+		ContextSummarizer:  o.ContextSummarizer,
+		JSONSchema:         jsonSchema,
+		JSONSchemaRetries:  o.JSONSchemaRetries,
+	}
+	return
+}
+
+// loadJSONSchema reads the JSON schema of --json-schema, which is inline JSON or a file path
+func loadJSONSchema(value string) (ret json.RawMessage, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	data := []byte(value)
+	if !strings.HasPrefix(value, "{") {
+		var path string
+		if path, err = util.GetAbsolutePath(value); err != nil {
+			return
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			err = fmt.Errorf("could not read JSON schema %s: %v", value, err)
+			return
+		}
+	}
+	if _, err = util.ParseJSONSchema(data); err != nil {
+		return
 	}
+	ret = data
 	return
 }
 
 func (o *Flags) BuildChatRequest(Meta string) (ret *domain.ChatRequest, err error) {
 	ret = &domain.ChatRequest{
-		ContextName:      o.Context,
+		ContextNames:     o.Context,
 		SessionName:      o.Session,
 		PatternName:      o.Pattern,
 		StrategyName:     o.Strategy,
+		SessionTags:      o.SessionTags,
 		PatternVariables: o.PatternVariables,
 		InputHasVars:     o.InputHasVars,
 		Meta:             Meta,
@@ -519,7 +657,7 @@ func (o *Flags) AppendMessage(message string) {
 }
 
 func (o *Flags) IsChatRequest() (ret bool) {
-	ret = o.Message != "" || len(o.Attachments) > 0 || o.Context != "" || o.Session != "" || o.Pattern != ""
+	ret = o.Message != "" || len(o.Attachments) > 0 || len(o.Context) > 0 || o.Session != "" || o.Pattern != "" || o.Pipeline != "" || o.Interactive
 	return
 }
 
@@ -9,7 +9,9 @@ import (
 	"testing"
 
 	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInit(t *testing.T) {
@@ -24,6 +26,21 @@ func TestInit(t *testing.T) {
 	assert.Equal(t, expectedFlags.Copy, flags.Copy)
 }
 
+func TestApplyPatternDefaults(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "-t", "0.9", "--pattern", "translate"}
+
+	flags, err := Init()
+	require.NoError(t, err)
+
+	temperature := 0.2
+	flags.ApplyPatternDefaults(&fsdb.PatternMetadata{Model: "gpt-4o", Temperature: &temperature, Strategy: "cot"})
+	assert.Equal(t, "gpt-4o", flags.Model)
+	assert.Equal(t, "cot", flags.Strategy)
+	assert.Equal(t, 0.9, flags.Temperature, "the temperature given on the command line wins")
+}
+
 func TestReadStdin(t *testing.T) {
 	input := "test input"
 	stdin := io.NopCloser(strings.NewReader(input))
@@ -97,6 +114,25 @@ func TestBuildChatOptionsDefaultSeed(t *testing.T) {
 	assert.Equal(t, expectedOptions, options)
 }
 
+func TestBuildChatOptionsJSONSchema(t *testing.T) {
+	schema := `{"type": "object", "required": ["score"]}`
+	schemaFile := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(schema), 0644))
+
+	for _, value := range []string{schema, schemaFile} {
+		flags := &Flags{JSONSchema: value, JSONSchemaRetries: 3}
+		options, err := flags.BuildChatOptions()
+		require.NoError(t, err)
+		assert.JSONEq(t, schema, string(options.JSONSchema))
+		assert.Equal(t, 3, options.JSONSchemaRetries)
+	}
+
+	_, err := (&Flags{JSONSchema: `{"type": `}).BuildChatOptions()
+	assert.Error(t, err)
+	_, err = (&Flags{JSONSchema: filepath.Join(t.TempDir(), "missing.json")}).BuildChatOptions()
+	assert.Error(t, err)
+}
+
 func TestBuildChatOptionsSuppressThink(t *testing.T) {
 	flags := &Flags{
 		SuppressThink: true,
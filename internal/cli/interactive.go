@@ -0,0 +1,458 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+	"github.com/danielmiessler/fabric/internal/plugins/strategy"
+)
+
+// InteractiveHistoryFile keeps the inputs of the interactive mode between runs
+const InteractiveHistoryFile = "interactive_history"
+
+// maxInteractiveHistory limits how many inputs are kept in the history file
+const maxInteractiveHistory = 500
+
+const interactiveHelp = `Commands:
+  /pattern [name]    use a pattern for the following messages, without a name stop using one
+  /context [name]    use a context for the following messages, without a name stop using one
+  /strategy [name]   use a strategy for the following messages, without a name stop using one
+  /model [name]      switch the model, the conversation is kept; without a name show the model
+  /attach [path]     attach a file or URL to the next message; without a path list the attachments
+  /undo              remove the last message and its answer
+  /save [name]       save the session, optionally under a new name
+  /usage             show the token usage of the last answer and of the session
+  /history           list the previous inputs; !n sends input n again and !! the last one
+  /help              show this help
+  /exit              leave, like Ctrl-D
+
+End a line with \ to continue on the next line, or enclose several lines in """ lines.
+Start a message with // to send a message that begins with /.`
+
+// handleInteractive runs the interactive mode: a conversation that keeps one chatter and
+// one session open, reads messages and commands from stdin and streams the answers
+func handleInteractive(currentFlags *Flags, registry *core.PluginRegistry, messageTools string) (err error) {
+	if messageTools != "" {
+		currentFlags.AppendMessage(messageTools)
+	}
+	if len(splitModels(currentFlags.Model)) > 1 {
+		err = errors.New("the interactive mode uses one model, choose a single model with -m")
+		return
+	}
+
+	var conversation *interactiveChat
+	if conversation, err = newInteractiveChat(currentFlags, registry, os.Stdin, os.Stdout); err != nil {
+		return
+	}
+	err = conversation.run(currentFlags.Message)
+	return
+}
+
+// interactiveChat is the state of the interactive mode. The flags hold the current pattern,
+// context, strategy and model, which apply to every message until they are changed.
+type interactiveChat struct {
+	flags       Flags
+	registry    *core.PluginRegistry
+	chatter     *core.Chatter
+	session     *fsdb.Session
+	attachments []string
+	history     []string
+	historyFile string
+	in          *bufio.Reader
+	out         io.Writer
+}
+
+func newInteractiveChat(currentFlags *Flags, registry *core.PluginRegistry, in io.Reader, out io.Writer) (
+	ret *interactiveChat, err error) {
+
+	ret = &interactiveChat{
+		flags:       *currentFlags,
+		registry:    registry,
+		attachments: currentFlags.Attachments,
+		historyFile: registry.Db.FilePath(InteractiveHistoryFile),
+		in:          bufio.NewReader(in),
+		out:         out,
+	}
+	ret.flags.Stream = true
+	ret.flags.Message = ""
+	ret.flags.Attachments = nil
+
+	// The session is kept open by the interactive mode instead of being loaded for every message
+	if ret.flags.Session != "" {
+		if ret.session, err = registry.Db.Sessions.Get(ret.flags.Session); err != nil {
+			return
+		}
+		ret.flags.Session = ""
+	} else {
+		ret.session = &fsdb.Session{}
+	}
+
+	if err = ret.setModel(ret.flags.Model); err != nil {
+		return
+	}
+	ret.loadHistory()
+	return
+}
+
+// run sends the initial message, if any, and then reads inputs until /exit or the end of the input
+func (o *interactiveChat) run(initialMessage string) (err error) {
+	fmt.Fprintln(o.out, "Interactive mode, /help lists the commands, /exit or Ctrl-D leaves.")
+	if initialMessage != "" {
+		o.reportError(o.send(initialMessage))
+	}
+
+	for {
+		var input string
+		var readErr error
+		input, readErr = o.readInput()
+		if input != "" && o.handleInput(input) {
+			return
+		}
+		if readErr != nil {
+			if readErr != io.EOF {
+				err = readErr
+			}
+			fmt.Fprintln(o.out)
+			return
+		}
+	}
+}
+
+// handleInput runs a command or sends a message and reports whether the user wants to leave
+func (o *interactiveChat) handleInput(input string) (exit bool) {
+	if strings.HasPrefix(input, "!") {
+		if resolved, ok, err := o.resolveHistory(input); err != nil {
+			o.reportError(err)
+			return
+		} else if ok {
+			input = resolved
+			fmt.Fprintln(o.out, input)
+		}
+	}
+	o.addHistory(input)
+
+	var err error
+	switch {
+	case strings.HasPrefix(input, "//"):
+		err = o.send(input[1:])
+	case strings.HasPrefix(input, "/"):
+		exit, err = o.runCommand(input)
+	default:
+		err = o.send(input)
+	}
+	o.reportError(err)
+	return
+}
+
+func (o *interactiveChat) runCommand(input string) (exit bool, err error) {
+	command, arg, _ := strings.Cut(input, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "/exit", "/quit":
+		exit = true
+	case "/help":
+		fmt.Fprintln(o.out, interactiveHelp)
+	case "/pattern":
+		if arg != "" {
+			if _, err = o.registry.Db.Patterns.Get(arg); err != nil {
+				err = fmt.Errorf("could not get pattern %s: %v", arg, err)
+				return
+			}
+		}
+		o.flags.Pattern = arg
+		o.printSetting("Pattern", arg)
+	case "/context":
+		if arg != "" {
+			if _, err = o.registry.Db.Contexts.Get(arg); err != nil {
+				return
+			}
+		}
+		o.flags.Context = nil
+		if arg != "" {
+			o.flags.Context = []string{arg}
+		}
+		o.printSetting("Context", arg)
+	case "/strategy":
+		if arg != "" {
+			if _, err = strategy.LoadStrategy(arg); err != nil {
+				return
+			}
+		}
+		o.flags.Strategy = arg
+		o.printSetting("Strategy", arg)
+	case "/model":
+		if arg != "" {
+			if err = o.setModel(arg); err != nil {
+				return
+			}
+		}
+		fmt.Fprintf(o.out, "Model: %s\n", o.currentModel())
+	case "/attach":
+		if arg != "" {
+			if !strings.Contains(arg, "://") {
+				if _, err = os.Stat(arg); err != nil {
+					return
+				}
+			}
+			o.attachments = append(o.attachments, arg)
+		}
+		if len(o.attachments) == 0 {
+			fmt.Fprintln(o.out, "No attachments")
+		} else {
+			fmt.Fprintf(o.out, "Attached to the next message: %s\n", strings.Join(o.attachments, ", "))
+		}
+	case "/undo":
+		err = o.undo()
+	case "/save":
+		err = o.save(arg)
+	case "/usage":
+		err = o.printUsage()
+	case "/history":
+		for i, entry := range o.history {
+			fmt.Fprintf(o.out, "%4d  %s\n", i+1, strings.ReplaceAll(entry, "\n", "\n      "))
+		}
+	default:
+		err = fmt.Errorf("unknown command %s, /help lists the commands", command)
+	}
+	return
+}
+
+// send adds the message to the session with the current pattern, context and strategy
+// and streams the answer. A failed message is removed from the session again.
+func (o *interactiveChat) send(message string) (err error) {
+	flags := o.flags
+	flags.Message = message
+	flags.Attachments = o.attachments
+
+	var request *domain.ChatRequest
+	var opts *domain.ChatOptions
+	if request, opts, err = buildChatRequestAndOptions(&flags, o.registry); err != nil {
+		return
+	}
+	// The command line is already in the session from the start of the conversation
+	request.Meta = ""
+
+	ctx, cancel := newRequestContext(flags.Timeout)
+	defer cancel()
+
+	length := len(o.session.Messages)
+	if err = o.chatter.SendInSession(ctx, o.session, request, opts); err != nil {
+		o.session.Truncate(length)
+		if ctx.Err() != nil {
+			err = fmt.Errorf("the answer was interrupted: %w", err)
+		}
+		return
+	}
+	o.attachments = nil
+
+	answer := o.session.GetLastMessage()
+	if flags.SuppressThink {
+		fmt.Fprintln(o.out, answer.Content)
+	} else {
+		fmt.Fprintln(o.out)
+	}
+	if meta := answer.Meta; meta != nil && meta.Model != o.currentModel() {
+		fmt.Fprintf(os.Stderr, "[Answered by %s|%s]\n", meta.Vendor, meta.Model)
+	}
+	if flags.ShowUsage {
+		err = printUsage(answer, opts.Model, filepath.Join(o.registry.Db.Dir, "prices.yaml"))
+	}
+	return
+}
+
+// undo removes the last answer and everything that was sent with it since the previous answer,
+// that is the user message and the system prompt of the pattern, context or strategy
+func (o *interactiveChat) undo() (err error) {
+	if o.session.RemoveLastTurns(1) == 0 {
+		err = errors.New("there is nothing to undo")
+		return
+	}
+	fmt.Fprintln(o.out, "Removed the last message and its answer")
+	if o.session.Name != "" {
+		err = o.registry.Db.Sessions.SaveSession(o.session)
+	}
+	return
+}
+
+func (o *interactiveChat) save(name string) (err error) {
+	if name != "" {
+		o.session.Name = name
+	}
+	if o.session.Name == "" {
+		err = errors.New("the session has no name, use /save name")
+		return
+	}
+	if err = o.registry.Db.Sessions.SaveSession(o.session); err != nil {
+		return
+	}
+	fmt.Fprintf(o.out, "Saved session %s, the following messages are saved automatically\n", o.session.Name)
+	return
+}
+
+// printUsage shows the usage of the last answer and the sum over all answers of the session
+func (o *interactiveChat) printUsage() (err error) {
+	total := &chat.Usage{}
+	var last *chat.ChatCompletionMessage
+	for _, message := range o.session.Messages {
+		if message.Role == chat.ChatMessageRoleAssistant && message.Meta != nil && message.Meta.Usage != nil {
+			total.Add(message.Meta.Usage)
+			last = message
+		}
+	}
+	if last == nil {
+		fmt.Fprintln(o.out, "No usage reported yet")
+		return
+	}
+	if err = printUsage(last, last.Meta.Model, filepath.Join(o.registry.Db.Dir, "prices.yaml")); err != nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Session: %d prompt + %d completion = %d tokens\n",
+		total.PromptTokens, total.CompletionTokens, total.TotalTokens)
+	return
+}
+
+// setModel gets a chatter for the model, the session is kept
+func (o *interactiveChat) setModel(model string) (err error) {
+	ctx, cancel := newRequestContext(o.flags.Timeout)
+	defer cancel()
+
+	var chatter *core.Chatter
+	if chatter, err = o.registry.GetChatter(ctx, model, o.flags.ModelContextLength, o.flags.Strategy,
+		true, o.flags.DryRun); err != nil {
+		return
+	}
+	o.chatter = chatter
+	o.flags.Model = model
+	return
+}
+
+func (o *interactiveChat) currentModel() string {
+	if o.flags.Model != "" {
+		return o.flags.Model
+	}
+	return o.registry.Defaults.Model.Value
+}
+
+func (o *interactiveChat) printSetting(name string, value string) {
+	if value == "" {
+		fmt.Fprintf(o.out, "%s: none\n", name)
+	} else {
+		fmt.Fprintf(o.out, "%s: %s\n", name, value)
+	}
+}
+
+func (o *interactiveChat) reportError(err error) {
+	if err != nil {
+		fmt.Fprintf(o.out, "Error: %v\n", err)
+	}
+}
+
+// readInput reads one input. A line ending with \ continues on the next line and
+// lines between two """ lines are read as one input.
+func (o *interactiveChat) readInput() (ret string, err error) {
+	fmt.Fprint(o.out, o.prompt())
+
+	var line string
+	if line, err = o.readLine(); err != nil && line == "" {
+		return
+	}
+
+	if strings.TrimSpace(line) == `"""` {
+		var lines []string
+		for err == nil {
+			fmt.Fprint(o.out, "... ")
+			if line, err = o.readLine(); strings.TrimSpace(line) == `"""` {
+				break
+			}
+			lines = append(lines, line)
+		}
+		ret = strings.TrimSpace(strings.Join(lines, "\n"))
+		return
+	}
+
+	for strings.HasSuffix(line, `\`) && err == nil {
+		ret += strings.TrimSuffix(line, `\`) + "\n"
+		fmt.Fprint(o.out, "... ")
+		line, err = o.readLine()
+	}
+	ret = strings.TrimSpace(ret + line)
+	return
+}
+
+func (o *interactiveChat) readLine() (ret string, err error) {
+	ret, err = o.in.ReadString('\n')
+	ret = strings.TrimRight(ret, "\r\n")
+	return
+}
+
+func (o *interactiveChat) prompt() string {
+	if o.flags.Pattern != "" {
+		return o.flags.Pattern + "> "
+	}
+	return "> "
+}
+
+// resolveHistory replaces !! by the last input and !n by input n of the history.
+// Other inputs that start with ! are messages.
+func (o *interactiveChat) resolveHistory(input string) (ret string, ok bool, err error) {
+	index := len(o.history)
+	if input != "!!" {
+		if index, err = strconv.Atoi(input[1:]); err != nil {
+			err = nil
+			return
+		}
+	}
+	if index < 1 || index > len(o.history) {
+		err = fmt.Errorf("there is no input %s in the history", input)
+		return
+	}
+	ret, ok = o.history[index-1], true
+	return
+}
+
+// loadHistory reads the history file, which holds one JSON string per input
+func (o *interactiveChat) loadHistory() {
+	data, err := os.ReadFile(o.historyFile)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		var entry string
+		if json.Unmarshal([]byte(line), &entry) == nil && entry != "" {
+			o.history = append(o.history, entry)
+		}
+	}
+	if len(o.history) > maxInteractiveHistory {
+		o.history = o.history[len(o.history)-maxInteractiveHistory:]
+	}
+}
+
+// addHistory adds the input to the history and the history file. Failing to write the
+// history file does not interrupt the conversation.
+func (o *interactiveChat) addHistory(input string) {
+	if len(o.history) > 0 && o.history[len(o.history)-1] == input {
+		return
+	}
+	o.history = append(o.history, input)
+
+	var lines []string
+	for _, entry := range o.history[max(0, len(o.history)-maxInteractiveHistory):] {
+		data, _ := json.Marshal(entry)
+		lines = append(lines, string(data))
+	}
+	if err := os.WriteFile(o.historyFile, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		Debugf("could not write the history file: %v\n", err)
+	}
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+// newInteractiveTestChat returns an interactive chat with the dry-run vendor that reads the given input
+func newInteractiveTestChat(t *testing.T, input string) (*interactiveChat, *bytes.Buffer) {
+	t.Helper()
+	db := fsdb.NewDb(t.TempDir())
+	patternDir := filepath.Join(db.Patterns.Dir, "summarize")
+	if err := os.MkdirAll(patternDir, 0755); err != nil {
+		t.Fatalf("failed to create pattern: %v", err)
+	}
+	if err := os.MkdirAll(db.Sessions.Dir, 0755); err != nil {
+		t.Fatalf("failed to create the sessions directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(patternDir, db.Patterns.SystemPatternFile), []byte("Summarize"), 0644); err != nil {
+		t.Fatalf("failed to write pattern: %v", err)
+	}
+
+	registry, err := core.NewPluginRegistry(db)
+	if err != nil {
+		t.Fatalf("NewPluginRegistry() error = %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	conversation, err := newInteractiveChat(&Flags{DryRun: true, Model: "test-model"}, registry, strings.NewReader(input), out)
+	if err != nil {
+		t.Fatalf("newInteractiveChat() error = %v", err)
+	}
+	return conversation, out
+}
+
+func TestInteractiveChat_ReadInput(t *testing.T) {
+	conversation, _ := newInteractiveTestChat(t, "first line \\\nsecond line\n\"\"\"\nblock one\n\nblock two\n\"\"\"\nlast")
+
+	for _, want := range []string{"first line \nsecond line", "block one\n\nblock two", "last"} {
+		got, _ := conversation.readInput()
+		if got != want {
+			t.Errorf("readInput() = %q, want %q", got, want)
+		}
+	}
+	if _, err := conversation.readInput(); err == nil {
+		t.Error("expected the end of the input")
+	}
+}
+
+func TestInteractiveChat_Run(t *testing.T) {
+	conversation, out := newInteractiveTestChat(t, strings.Join([]string{
+		"/pattern summarize",
+		"first message",
+		"/pattern",
+		"second message",
+		"/undo",
+		"!2",
+		"/save chat",
+		"/unknown",
+		"/exit",
+		"never sent",
+	}, "\n"))
+
+	if err := conversation.run(""); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	var roles []string
+	for _, message := range conversation.session.Messages {
+		roles = append(roles, message.Role)
+	}
+	if got := strings.Join(roles, ","); got != "system,assistant,user,assistant" {
+		t.Fatalf("unexpected messages %s", got)
+	}
+	if conversation.session.Messages[2].Content != "first message" {
+		t.Errorf("expected !2 to send the first message again, got %q", conversation.session.Messages[2].Content)
+	}
+
+	if !conversation.registry.Db.Sessions.Exists("chat") {
+		t.Error("expected the session to be saved")
+	}
+	if !strings.Contains(out.String(), "Error: unknown command /unknown") {
+		t.Errorf("expected an error for the unknown command, got:\n%s", out.String())
+	}
+	if history, _ := os.ReadFile(conversation.historyFile); strings.Contains(string(history), "never sent") ||
+		!strings.Contains(string(history), `"second message"`) {
+		t.Errorf("unexpected history file:\n%s", history)
+	}
+}
+
+func TestInteractiveChat_UndoRemovesTheWholeTurn(t *testing.T) {
+	conversation, _ := newInteractiveTestChat(t, "")
+	conversation.session.Messages = []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleSystem, Content: "first prompt"},
+		{Role: chat.ChatMessageRoleUser, Content: "first"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "answer"},
+		{Role: chat.ChatMessageRoleSystem, Content: "second prompt"},
+		{Role: chat.ChatMessageRoleUser, Content: "second"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "answer"},
+	}
+
+	if err := conversation.undo(); err != nil {
+		t.Fatalf("undo() error = %v", err)
+	}
+	if len(conversation.session.Messages) != 3 {
+		t.Errorf("expected the second turn with its system prompt to be removed, got %d messages", len(conversation.session.Messages))
+	}
+	if err := conversation.undo(); err != nil || len(conversation.session.Messages) != 0 {
+		t.Errorf("expected the first turn to be removed, got %d messages and %v", len(conversation.session.Messages), err)
+	}
+	if err := conversation.undo(); err == nil {
+		t.Error("expected an error when there is nothing to undo")
+	}
+}
+
+func TestInteractiveChat_ResolveHistory(t *testing.T) {
+	conversation, _ := newInteractiveTestChat(t, "")
+	conversation.history = []string{"one", "two"}
+
+	if got, ok, err := conversation.resolveHistory("!!"); err != nil || !ok || got != "two" {
+		t.Errorf("resolveHistory(!!) = %q, %v, %v", got, ok, err)
+	}
+	if got, ok, err := conversation.resolveHistory("!1"); err != nil || !ok || got != "one" {
+		t.Errorf("resolveHistory(!1) = %q, %v, %v", got, ok, err)
+	}
+	if _, _, err := conversation.resolveHistory("!3"); err == nil {
+		t.Error("expected an error for an input that is not in the history")
+	}
+	if _, ok, err := conversation.resolveHistory("!important"); err != nil || ok {
+		t.Error("expected other inputs that start with ! to be messages")
+	}
+}
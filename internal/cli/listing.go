@@ -27,13 +27,22 @@ func handleListingCommands(currentFlags *Flags, fabricDb *fsdb.Db, registry *cor
 	}
 
 	if currentFlags.ListPatterns {
-		err = fabricDb.Patterns.ListNames(currentFlags.ShellCompleteOutput)
+		err = fabricDb.Patterns.ListPatterns(currentFlags.PatternsTags, currentFlags.PatternsDetails,
+			currentFlags.ShellCompleteOutput)
+		return true, err
+	}
+
+	if currentFlags.PatternInfo != "" {
+		err = fabricDb.Patterns.PrintInfo(currentFlags.PatternInfo)
 		return true, err
 	}
 
 	if currentFlags.ListAllModels {
+		ctx, cancel := newRequestContext(currentFlags.Timeout)
+		defer cancel()
+
 		var models *ai.VendorsModels
-		if models, err = registry.VendorManager.GetModels(); err != nil {
+		if models, err = registry.VendorManager.GetModels(ctx); err != nil {
 			return true, err
 		}
 		models.Print(currentFlags.ShellCompleteOutput)
@@ -46,15 +55,50 @@ func handleListingCommands(currentFlags *Flags, fabricDb *fsdb.Db, registry *cor
 	}
 
 	if currentFlags.ListAllSessions {
-		err = fabricDb.Sessions.ListNames(currentFlags.ShellCompleteOutput)
+		if currentFlags.ShellCompleteOutput {
+			err = fabricDb.Sessions.ListNames(true)
+			return true, err
+		}
+		var filter *fsdb.SessionFilter
+		if filter, err = fsdb.ParseSessionFilter(currentFlags.SessionsFilter); err != nil {
+			return true, err
+		}
+		err = fabricDb.Sessions.ListSessions(filter, currentFlags.SessionsSort)
 		return true, err
 	}
 
+	if currentFlags.SearchDb != "" {
+		var options *fsdb.SearchOptions
+		if options, err = fsdb.NewSearchOptions(currentFlags.SearchTypes, currentFlags.SearchSince,
+			currentFlags.SearchUntil, currentFlags.SearchLimit); err != nil {
+			return true, err
+		}
+		var results []*fsdb.SearchResult
+		if results, err = fabricDb.Search.Search(currentFlags.SearchDb, options); err != nil {
+			return true, err
+		}
+		fsdb.PrintSearchResults(results)
+		return true, nil
+	}
+
 	if currentFlags.ListStrategies {
 		err = registry.Strategies.ListStrategies(currentFlags.ShellCompleteOutput)
 		return true, err
 	}
 
+	if currentFlags.ListTools {
+		if err = registry.Tools.Load(); err != nil {
+			return true, err
+		}
+		err = registry.Tools.ListTools(currentFlags.ShellCompleteOutput)
+		return true, err
+	}
+
+	if currentFlags.ListTemplatePlugins {
+		err = registry.TemplatePlugins.PrintPlugins(os.Stdout)
+		return true, err
+	}
+
 	if currentFlags.ListVendors {
 		err = registry.ListVendors(os.Stdout)
 		return true, err
@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 )
 
@@ -17,6 +21,20 @@ func handleManagementCommands(currentFlags *Flags, fabricDb *fsdb.Db) (handled b
 		return true, err
 	}
 
+	if currentFlags.CacheClear {
+		err = ai.NewResponseCache(fabricDb.FilePath(ai.ResponseCacheDir), 0, 0).Clear()
+		return true, err
+	}
+
+	if currentFlags.MigrateStorage != "" {
+		err = migrateStorage(fabricDb, currentFlags.MigrateStorage)
+		return true, err
+	}
+
+	if handled, err = handlePatternVersionCommands(currentFlags, fabricDb.Patterns); handled || err != nil {
+		return
+	}
+
 	if currentFlags.PrintSession != "" {
 		err = fabricDb.Sessions.PrintSession(currentFlags.PrintSession)
 		return true, err
@@ -29,3 +47,60 @@ func handleManagementCommands(currentFlags *Flags, fabricDb *fsdb.Db) (handled b
 
 	return false, nil
 }
+
+// migrateStorage copies the data to the SQLite database or back to the files
+func migrateStorage(fabricDb *fsdb.Db, to string) (err error) {
+	var migration *fsdb.StorageMigration
+	if migration, err = fabricDb.MigrateStorage(to); err != nil {
+		return
+	}
+	target := fabricDb.Dir
+	if to == fsdb.StorageSQLite {
+		target = fabricDb.SQLitePath()
+	}
+	fmt.Printf("Copied %d patterns, %d contexts and %d sessions to %s\n",
+		migration.Patterns, migration.Contexts, migration.Sessions, target)
+	if current := os.Getenv(fsdb.StorageBackendEnv); current != to && (current != "" || to != fsdb.StorageFiles) {
+		fmt.Printf("Choose the %s storage with fabric --setup (Storage) to use them\n", to)
+	}
+	return
+}
+
+// handlePatternVersionCommands lists, compares, pins and rolls back the versions of patterns
+func handlePatternVersionCommands(currentFlags *Flags, patterns *fsdb.PatternsEntity) (handled bool, err error) {
+	switch {
+	case currentFlags.PatternVersions != "":
+		err = patterns.PrintHistory(currentFlags.PatternVersions)
+	case currentFlags.PatternDiff != "":
+		var diff string
+		if diff, err = patterns.Diff(currentFlags.PatternDiff, currentFlags.DiffFrom, currentFlags.DiffTo); err != nil {
+			return true, err
+		}
+		if diff == "" {
+			fmt.Println("The versions are the same")
+		} else {
+			fmt.Print(diff)
+		}
+	case currentFlags.PinPattern != "":
+		var version *fsdb.PatternVersion
+		if version, err = patterns.Pin(currentFlags.PinPattern, currentFlags.PatternVersion); err != nil {
+			return true, err
+		}
+		fmt.Printf("Pinned pattern %s at version %d\n", currentFlags.PinPattern, version.Number)
+	case currentFlags.UnpinPattern != "":
+		if err = patterns.Unpin(currentFlags.UnpinPattern); err != nil {
+			return true, err
+		}
+		fmt.Printf("Unpinned pattern %s\n", currentFlags.UnpinPattern)
+	case currentFlags.RollbackPattern != "":
+		var version *fsdb.PatternVersion
+		if version, err = patterns.Rollback(currentFlags.RollbackPattern, currentFlags.PatternVersion); err != nil {
+			return true, err
+		}
+		fmt.Printf("Rolled back pattern %s, its content is version %d (%s) now\n",
+			currentFlags.RollbackPattern, version.Number, version.Source)
+	default:
+		return false, nil
+	}
+	return true, err
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// handlePatternTests runs the tests of the pattern of -p, or of all patterns with tests, and
+// prints a report. It fails when a test failed, so that it can guard changes in scripts and CI.
+func handlePatternTests(currentFlags *Flags, registry *core.PluginRegistry) (err error) {
+	if currentFlags.DryRun && currentFlags.TestMock {
+		return fmt.Errorf("--dry-run and --test-mock can not be used together")
+	}
+	var patterns []string
+	if currentFlags.Pattern != "" {
+		patterns = []string{currentFlags.Pattern}
+	}
+
+	var chatOptions *domain.ChatOptions
+	if chatOptions, err = currentFlags.BuildChatOptions(); err != nil {
+		return
+	}
+
+	ctx, cancel := newRequestContext(currentFlags.Timeout)
+	defer cancel()
+
+	var results []*core.PatternTestResult
+	if results, err = registry.RunPatternTests(ctx, patterns, chatOptions, currentFlags.DryRun, currentFlags.TestMock); err != nil {
+		return
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no pattern has tests, add a tests.yaml next to the system.md of a pattern")
+	}
+
+	fmt.Print(formatPatternTestsText(results))
+
+	if currentFlags.TestJUnit != "" {
+		var report []byte
+		if report, err = formatPatternTestsJUnit(results); err != nil {
+			return
+		}
+		// The report is replaced on every run, as CI systems pick it up from the same path
+		if err = os.WriteFile(currentFlags.TestJUnit, report, 0644); err != nil {
+			err = fmt.Errorf("could not write the JUnit report: %v", err)
+			return
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Passed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		err = fmt.Errorf("%d of %d pattern tests failed", failed, len(results))
+	}
+	return
+}
+
+// formatPatternTestsText builds a report with one line per case and the reasons of its failures
+func formatPatternTestsText(results []*core.PatternTestResult) string {
+	var builder strings.Builder
+	passed, failed, errored, skipped := 0, 0, 0, 0
+	for _, result := range results {
+		status := "PASS"
+		switch {
+		case result.Error != "":
+			status = "ERROR"
+			errored++
+		case !result.Passed():
+			status = "FAIL"
+			failed++
+		case result.WasSkipped():
+			status = "SKIP"
+			skipped++
+		default:
+			passed++
+		}
+		fmt.Fprintf(&builder, "%-5s %s/%s", status, result.Pattern, result.Case)
+		if result.Model != "" {
+			fmt.Fprintf(&builder, " [%s]", result.Model)
+		}
+		fmt.Fprintf(&builder, " (%s)\n", result.Duration.Round(time.Millisecond))
+
+		if result.Error != "" {
+			fmt.Fprintf(&builder, "      %s\n", result.Error)
+		}
+		for _, failure := range result.Failures {
+			fmt.Fprintf(&builder, "      - the answer %s\n", failure)
+		}
+		for _, reason := range result.Skipped {
+			fmt.Fprintf(&builder, "      skipped %s\n", reason)
+		}
+	}
+	fmt.Fprintf(&builder, "\n%d tests: %d passed, %d failed, %d errors, %d skipped\n",
+		len(results), passed, failed, errored, skipped)
+	return builder.String()
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Skipped   int              `xml:"skipped,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// formatPatternTestsJUnit builds a JUnit XML report with a test suite per pattern, for CI systems
+func formatPatternTestsJUnit(results []*core.PatternTestResult) (ret []byte, err error) {
+	report := &junitTestSuites{Name: "fabric pattern tests"}
+	suites := map[string]*junitTestSuite{}
+	durations := map[*junitTestSuite]time.Duration{}
+	var total time.Duration
+	for _, result := range results {
+		suite := suites[result.Pattern]
+		if suite == nil {
+			suite = &junitTestSuite{Name: result.Pattern}
+			suites[result.Pattern] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		testCase := &junitTestCase{Name: result.Case, ClassName: result.Pattern,
+			Time: junitTime(result.Duration), SystemOut: result.Output}
+		switch {
+		case result.Error != "":
+			testCase.Error = &junitMessage{Message: result.Error}
+			suite.Errors++
+		case !result.Passed():
+			testCase.Failure = &junitMessage{
+				Message: fmt.Sprintf("%d of %d assertions failed", len(result.Failures), result.Assertions),
+				Text:    "the answer " + strings.Join(result.Failures, "\nthe answer "),
+			}
+			suite.Failures++
+		case result.WasSkipped():
+			testCase.Skipped = &junitMessage{Message: strings.Join(result.Skipped, "; ")}
+			suite.Skipped++
+		}
+		suite.Tests++
+		durations[suite] += result.Duration
+		total += result.Duration
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	for _, suite := range report.Suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		suite.Time = junitTime(durations[suite])
+	}
+	report.Time = junitTime(total)
+
+	if ret, err = xml.MarshalIndent(report, "", "  "); err != nil {
+		return
+	}
+	ret = append([]byte(xml.Header), ret...)
+	ret = append(ret, '\n')
+	return
+}
+
+// junitTime formats a duration in seconds, as JUnit reports have it
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
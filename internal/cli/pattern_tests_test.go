@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/core"
+)
+
+var testPatternTestResults = []*core.PatternTestResult{
+	{Pattern: "translate", Case: "greeting", Model: "gpt-4o", Output: "Bonjour", Duration: 1500 * time.Millisecond, Assertions: 2},
+	{Pattern: "translate", Case: "json", Output: "{}", Assertions: 2,
+		Failures: []string{`does not contain "text"`, "is 2 characters long, more than 1"}},
+	{Pattern: "summarize", Case: "judged", Assertions: 1, Skipped: []string{"judge: no scripted verdict in an offline run"}},
+	{Pattern: "summarize", Case: "tests.yaml", Error: "invalid tests.yaml"},
+}
+
+func TestFormatPatternTestsText(t *testing.T) {
+	report := formatPatternTestsText(testPatternTestResults)
+
+	for _, want := range []string{
+		"PASS  translate/greeting [gpt-4o] (1.5s)\n",
+		"FAIL  translate/json (0s)\n      - the answer does not contain \"text\"\n      - the answer is 2 characters long, more than 1\n",
+		"SKIP  summarize/judged (0s)\n      skipped judge: no scripted verdict in an offline run\n",
+		"ERROR summarize/tests.yaml (0s)\n      invalid tests.yaml\n",
+		"4 tests: 1 passed, 1 failed, 1 errors, 1 skipped\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, report)
+		}
+	}
+}
+
+func TestFormatPatternTestsJUnit(t *testing.T) {
+	data, err := formatPatternTestsJUnit(testPatternTestResults)
+	if err != nil {
+		t.Fatalf("formatPatternTestsJUnit() error = %v", err)
+	}
+
+	var report junitTestSuites
+	if err = xml.Unmarshal(data, &report); err != nil {
+		t.Fatalf("the report is not valid XML: %v", err)
+	}
+	if report.Tests != 4 || report.Failures != 1 || report.Errors != 1 || report.Skipped != 1 || report.Time != "1.500" {
+		t.Errorf("unexpected totals %+v", report)
+	}
+	if len(report.Suites) != 2 || report.Suites[0].Name != "translate" || report.Suites[1].Tests != 2 {
+		t.Fatalf("expected a suite per pattern, got %+v", report.Suites)
+	}
+	failure := report.Suites[0].TestCases[1].Failure
+	if failure == nil || failure.Message != "2 of 2 assertions failed" || !strings.Contains(failure.Text, "the answer is 2 characters long") {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+	if report.Suites[1].TestCases[0].Skipped == nil || report.Suites[1].TestCases[1].Error == nil {
+		t.Errorf("expected a skipped and an errored case, got %+v", report.Suites[1].TestCases)
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// handlePipeline runs the pipeline of the --pipeline flag with the message as input
+func handlePipeline(currentFlags *Flags, registry *core.PluginRegistry, messageTools string) (err error) {
+	if messageTools != "" {
+		currentFlags.AppendMessage(messageTools)
+	}
+
+	var pipeline *core.Pipeline
+	if pipeline, err = registry.LoadPipeline(currentFlags.Pipeline); err != nil {
+		return
+	}
+
+	var chatOptions *domain.ChatOptions
+	if chatOptions, err = currentFlags.BuildChatOptions(); err != nil {
+		return
+	}
+
+	ctx, cancel := newRequestContext(currentFlags.Timeout)
+	defer cancel()
+
+	var results []*core.PipelineStepResult
+	if results, err = registry.RunPipeline(ctx, pipeline, currentFlags.Message, chatOptions, currentFlags.DryRun,
+		func(index int, result *core.PipelineStepResult) (stepErr error) {
+			step := pipeline.Steps[index]
+			if currentFlags.DryRun {
+				fmt.Printf("=== Step %d/%d: %s ===\n%s\n", index+1, len(pipeline.Steps), step.Name, result.Output)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "[Step %d/%d: %s done]\n", index+1, len(pipeline.Steps), step.Name)
+			if step.Output != "" {
+				stepErr = CreateOutputFile(result.Output, step.Output)
+			}
+			return
+		}); err != nil {
+		return
+	}
+
+	if currentFlags.DryRun {
+		return
+	}
+
+	result := results[len(results)-1].Output
+	fmt.Println(result)
+
+	if currentFlags.Copy {
+		if err = CopyToClipboard(result); err != nil {
+			return
+		}
+	}
+	if currentFlags.Output != "" {
+		err = CreateOutputFile(result, currentFlags.Output)
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+// handleSessionCommands exports and imports sessions, forks, rewinds, truncates and edits
+// the session of --session or regenerates one of its answers.
+// Returns (handled, error) where handled indicates if a command was processed and should exit
+func handleSessionCommands(currentFlags *Flags, registry *core.PluginRegistry) (handled bool, err error) {
+	sessions := registry.Db.Sessions
+	name := currentFlags.Session
+	if currentFlags.ExportSession != "" {
+		return true, exportSession(currentFlags, sessions)
+	}
+	if currentFlags.ImportSession != "" {
+		return true, importSessions(currentFlags.ImportSession, name, sessions)
+	}
+	if currentFlags.ForkSession == "" && currentFlags.RewindSession == 0 && currentFlags.TruncateSession == 0 &&
+		currentFlags.EditMessage == 0 && currentFlags.Regenerate == 0 {
+		return false, nil
+	}
+	if name == "" {
+		return true, errors.New("choose the session to change with --session")
+	}
+
+	var session *fsdb.Session
+	switch {
+	case currentFlags.ForkSession != "":
+		if session, err = sessions.ForkSession(name, currentFlags.ForkAt, currentFlags.ForkSession); err == nil {
+			fmt.Printf("Forked session %s at message %d into %s\n", name, session.Parent.Message, session.Name)
+		}
+	case currentFlags.RewindSession != 0:
+		if session, err = sessions.RewindSession(name, currentFlags.RewindSession); err == nil {
+			fmt.Printf("Rewound session %s to %d messages\n", name, len(session.Messages))
+		}
+	case currentFlags.TruncateSession != 0:
+		if session, err = sessions.TruncateSession(name, currentFlags.TruncateSession); err == nil {
+			fmt.Printf("Truncated session %s to %d messages\n", name, len(session.Messages))
+		}
+	case currentFlags.EditMessage != 0:
+		if currentFlags.Message == "" {
+			return true, errors.New("give the new content of the answer as the message")
+		}
+		if _, err = sessions.EditMessage(name, currentFlags.EditMessage, currentFlags.Message); err == nil {
+			fmt.Printf("Edited message %d of session %s\n", currentFlags.EditMessage, name)
+		}
+	default:
+		err = regenerateAnswer(currentFlags, registry)
+	}
+	return true, err
+}
+
+// regenerateAnswer asks the model again for an answer of the session and prints the new answer
+func regenerateAnswer(currentFlags *Flags, registry *core.PluginRegistry) (err error) {
+	if !registry.Db.Sessions.Exists(currentFlags.Session) {
+		return fmt.Errorf("session %s does not exist", currentFlags.Session)
+	}
+
+	ctx, cancel := newRequestContext(currentFlags.Timeout)
+	defer cancel()
+
+	var chatter *core.Chatter
+	if chatter, err = registry.GetChatter(ctx, currentFlags.Model, currentFlags.ModelContextLength,
+		currentFlags.Strategy, currentFlags.Stream, currentFlags.DryRun); err != nil {
+		return
+	}
+	var opts *domain.ChatOptions
+	if opts, err = currentFlags.BuildChatOptions(); err != nil {
+		return
+	}
+
+	var session *fsdb.Session
+	if session, err = registry.Db.Sessions.Get(currentFlags.Session); err != nil {
+		return
+	}
+	if err = chatter.Regenerate(ctx, session, currentFlags.Regenerate, opts); err != nil {
+		return
+	}
+
+	answer := session.GetLastMessage()
+	if currentFlags.ShowUsage {
+		if err = printUsage(answer, opts.Model, filepath.Join(registry.Db.Dir, "prices.yaml")); err != nil {
+			return
+		}
+	}
+	if !currentFlags.Stream || currentFlags.SuppressThink {
+		fmt.Println(answer.Content)
+	}
+	return
+}
+
+// exportSession writes a session in the format of --export-format to the output file or stdout
+func exportSession(currentFlags *Flags, sessions *fsdb.SessionsEntity) (err error) {
+	name := currentFlags.ExportSession
+	if !sessions.Exists(name) {
+		return fmt.Errorf("session %s does not exist", name)
+	}
+	var session *fsdb.Session
+	if session, err = sessions.Get(name); err != nil {
+		return
+	}
+	var exported []byte
+	if exported, err = fsdb.ExportSession(session, currentFlags.ExportFormat); err != nil {
+		return
+	}
+	if currentFlags.Output != "" {
+		return CreateOutputFile(string(exported), currentFlags.Output)
+	}
+	_, err = os.Stdout.Write(exported)
+	return
+}
+
+// importSessions saves the conversations of an export file as sessions
+func importSessions(fileName string, name string, sessions *fsdb.SessionsEntity) (err error) {
+	var content []byte
+	if content, err = os.ReadFile(fileName); err != nil {
+		return fmt.Errorf("could not read %s: %w", fileName, err)
+	}
+	var imported []*fsdb.Session
+	if imported, err = sessions.ImportSessions(content, name); err != nil {
+		return fmt.Errorf("could not import %s: %w", fileName, err)
+	}
+	for _, session := range imported {
+		fmt.Printf("Imported session %s (%d messages)\n", session.Name, len(session.Messages))
+	}
+	return
+}
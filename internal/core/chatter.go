@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/danielmiessler/fabric/internal/chat"
@@ -18,6 +19,14 @@ import (
 
 const NoSessionPatternUserMessages = "no session, pattern or user messages provided"
 
+// MaxToolRounds limits how many times the model may request tools before giving a final answer
+const MaxToolRounds = 10
+
+// ToolExecutor runs the tool calls requested by a model
+type ToolExecutor interface {
+	Execute(ctx context.Context, call chat.ToolCall) (string, error)
+}
+
 type Chatter struct {
 	db *fsdb.Db
 
@@ -26,12 +35,79 @@ type Chatter struct {
 
 	model              string
 	modelContextLength int
-	vendor             ai.Vendor
-	strategy           string
+	// modelContextLengthGiven tells whether modelContextLength was given or is the one of the defaults
+	modelContextLengthGiven bool
+	vendor                  ai.Vendor
+	strategy                string
+	tools                   ToolExecutor
+	contextLimits           ai.ContextLimits
+	retry                   *ai.RetryConfig
+	fallbacks               []string
+	vendorManager           *ai.VendorsManager
+	cache                   *ai.ResponseCache
+	contextIncludes         bool
+}
+
+// Send processes a chat request and applies file changes for create_coding_feature pattern.
+// Cancelling ctx stops the upstream vendor call. Retryable errors are retried and then the
+// fallback models are tried; opts.Model is set to the model that answered.
+func (o *Chatter) Send(ctx context.Context, request *domain.ChatRequest, opts *domain.ChatOptions) (session *fsdb.Session, err error) {
+	o.applyRawMode(opts)
+	if session, err = o.BuildSession(request, opts.Raw); err != nil {
+		return
+	}
+	if err = o.complete(ctx, session, request, opts); errors.Is(err, errEmptyResponse) {
+		session = nil
+	}
+	return
+}
+
+// SendInSession adds the request to a session that the caller keeps open between requests,
+// like the interactive mode does, and sends it. The session is saved if it has a name.
+func (o *Chatter) SendInSession(ctx context.Context, session *fsdb.Session, request *domain.ChatRequest, opts *domain.ChatOptions) (err error) {
+	o.applyRawMode(opts)
+	if err = o.appendRequest(session, request, opts.Raw); err != nil {
+		return
+	}
+	err = o.complete(ctx, session, request, opts)
+	return
+}
+
+// Regenerate asks the model again for answer number of the session, with the pattern and strategy
+// of the original answer. The answer and the messages after it are replaced by the new answer,
+// fork the session first to keep them. The session is saved if it has a name, and keeps its
+// messages when no new answer could be made.
+func (o *Chatter) Regenerate(ctx context.Context, session *fsdb.Session, number int, opts *domain.ChatOptions) (err error) {
+	var answer *chat.ChatCompletionMessage
+	if answer, err = session.GetAssistantMessage(number); err != nil {
+		return
+	}
+	index := slices.Index(session.Messages, answer)
+	if index == 0 {
+		err = fmt.Errorf("message %d of session %s does not answer a message", number, session.Name)
+		return
+	}
+
+	request := &domain.ChatRequest{}
+	if answer.Meta != nil {
+		request.PatternName, request.StrategyName = answer.Meta.Pattern, answer.Meta.Strategy
+	}
+	messages := slices.Clone(session.Messages)
+	session.Truncate(index)
+	o.applyRawMode(opts)
+	if err = o.complete(ctx, session, request, opts); err != nil {
+		session.Truncate(0)
+		session.Append(messages...)
+		if session.Name != "" {
+			if saveErr := o.db.Sessions.SaveSession(session); saveErr != nil {
+				err = fmt.Errorf("%w, and the session could not be restored: %v", err, saveErr)
+			}
+		}
+	}
+	return
 }
 
-// Send processes a chat request and applies file changes for create_coding_feature pattern
-func (o *Chatter) Send(request *domain.ChatRequest, opts *domain.ChatOptions) (session *fsdb.Session, err error) {
+func (o *Chatter) applyRawMode(opts *domain.ChatOptions) {
 	modelToUse := opts.Model
 	if modelToUse == "" {
 		modelToUse = o.model
@@ -39,10 +115,12 @@ func (o *Chatter) Send(request *domain.ChatRequest, opts *domain.ChatOptions) (s
 	if o.vendor.NeedsRawMode(modelToUse) {
 		opts.Raw = true
 	}
-	if session, err = o.BuildSession(request, opts.Raw); err != nil {
-		return
-	}
+}
+
+var errEmptyResponse = errors.New("empty response")
 
+// complete sends the messages of the session to the vendor and appends the answer
+func (o *Chatter) complete(ctx context.Context, session *fsdb.Session, request *domain.ChatRequest, opts *domain.ChatOptions) (err error) {
 	vendorMessages := session.GetVendorMessages()
 	if len(vendorMessages) == 0 {
 		if session.Name != "" {
@@ -63,16 +141,113 @@ func (o *Chatter) Send(request *domain.ChatRequest, opts *domain.ChatOptions) (s
 		opts.ModelContextLength = o.modelContextLength
 	}
 
-	message := ""
+	if opts.JSONSchema == nil && request.PatternName != "" {
+		if opts.JSONSchema, err = o.db.Patterns.GetSchema(request.PatternName); err != nil {
+			return
+		}
+	}
+
+	var message string
+	var result *domain.ChatResult
+	var vendor ai.Vendor
+	if message, result, vendor, err = o.sendWithFallback(ctx, session, request.PatternName, opts); err != nil {
+		return
+	}
+
+	if opts.SuppressThink && !o.DryRun {
+		message = domain.StripThinkBlocks(message, opts.ThinkStartTag, opts.ThinkEndTag)
+	}
+
+	if message == "" {
+		err = errEmptyResponse
+		return
+	}
+
+	// Process file changes for create_coding_feature pattern
+	if request.PatternName == "create_coding_feature" {
+		summary, fileChanges, parseErr := domain.ParseFileChanges(message)
+		if parseErr != nil {
+			fmt.Printf("Warning: Failed to parse file changes: %v\n", parseErr)
+		} else if len(fileChanges) > 0 {
+			projectRoot, err := os.Getwd()
+			if err != nil {
+				fmt.Printf("Warning: Failed to get current directory: %v\n", err)
+			} else {
+				if applyErr := domain.ApplyFileChanges(projectRoot, fileChanges); applyErr != nil {
+					fmt.Printf("Warning: Failed to apply file changes: %v\n", applyErr)
+				} else {
+					fmt.Println("Successfully applied file changes.")
+					fmt.Printf("You can review the changes with 'git diff' if you're using git.\n\n")
+				}
+			}
+		}
+		message = summary
+	}
+
+	meta := messageMeta(result, vendor, opts.Model)
+	meta.Pattern, meta.Strategy = request.PatternName, request.StrategyName
+	session.Append(&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: message, Meta: meta})
+
+	if session.Name != "" {
+		err = o.db.Sessions.SaveSession(session)
+	}
+	return
+}
+
+// sendOnce sends the session to the vendor, streaming the answer if enabled. With a response
+// cache a cached answer is returned instead, or replayed through the stream.
+// The returned message holds what was received, also when streaming failed midway.
+func (o *Chatter) sendOnce(ctx context.Context, vendor ai.Vendor, session *fsdb.Session, opts *domain.ChatOptions) (
+	message string, result *domain.ChatResult, err error) {
+
+	// Tool calls are answered in one piece, so an answer with tools is printed once it is complete
+	if len(opts.Tools) > 0 {
+		if result, err = o.sendWithTools(ctx, vendor, session, opts); err != nil {
+			return
+		}
+		message = result.Content
+		if o.Stream && !opts.SuppressThink {
+			fmt.Println(message)
+		}
+		return
+	}
 
-	if o.Stream {
+	var vendorMessages []*chat.ChatCompletionMessage
+	if vendorMessages, err = o.contextMessages(ctx, vendor, session, opts); err != nil {
+		return
+	}
+
+	var cacheKey string
+	var cached *ai.CachedResponse
+	if o.cache != nil && !o.DryRun && ai.IsCacheable(opts) {
+		if cacheKey, err = ai.CacheKey(vendor.GetName(), vendorMessages, opts); err != nil {
+			return
+		}
+		cached = o.cache.Get(cacheKey)
+	}
+
+	if opts.JSONSchema != nil && cached == nil {
+		// A structured answer is validated as a whole before it is printed
+		if result, err = o.sendStructured(ctx, vendor, vendorMessages, opts); err != nil {
+			return
+		}
+		message = result.Content
+		if o.Stream && !opts.SuppressThink {
+			fmt.Println(message)
+		}
+	} else if o.Stream {
 		responseChan := make(chan string)
 		errChan := make(chan error, 1)
 		done := make(chan struct{})
 
 		go func() {
 			defer close(done)
-			if streamErr := o.vendor.SendStream(session.GetVendorMessages(), opts, responseChan); streamErr != nil {
+			if cached != nil {
+				result = cached.SendStream(responseChan)
+				return
+			}
+			var streamErr error
+			if result, streamErr = vendor.SendStream(ctx, vendorMessages, opts, responseChan); streamErr != nil {
 				errChan <- streamErr
 			}
 		}()
@@ -97,47 +272,25 @@ func (o *Chatter) Send(request *domain.ChatRequest, opts *domain.ChatOptions) (s
 		default:
 			// No errors, continue
 		}
+	} else if cached != nil {
+		result = cached.Send()
+		message = result.Content
 	} else {
-		if message, err = o.vendor.Send(context.Background(), session.GetVendorMessages(), opts); err != nil {
+		if result, err = vendor.Send(ctx, vendorMessages, opts); err != nil {
 			return
 		}
+		message = result.Content
 	}
 
-	if opts.SuppressThink && !o.DryRun {
-		message = domain.StripThinkBlocks(message, opts.ThinkStartTag, opts.ThinkEndTag)
-	}
-
-	if message == "" {
-		session = nil
-		err = fmt.Errorf("empty response")
-		return
-	}
-
-	// Process file changes for create_coding_feature pattern
-	if request.PatternName == "create_coding_feature" {
-		summary, fileChanges, parseErr := domain.ParseFileChanges(message)
-		if parseErr != nil {
-			fmt.Printf("Warning: Failed to parse file changes: %v\n", parseErr)
-		} else if len(fileChanges) > 0 {
-			projectRoot, err := os.Getwd()
-			if err != nil {
-				fmt.Printf("Warning: Failed to get current directory: %v\n", err)
-			} else {
-				if applyErr := domain.ApplyFileChanges(projectRoot, fileChanges); applyErr != nil {
-					fmt.Printf("Warning: Failed to apply file changes: %v\n", applyErr)
-				} else {
-					fmt.Println("Successfully applied file changes.")
-					fmt.Printf("You can review the changes with 'git diff' if you're using git.\n\n")
-				}
-			}
+	if cacheKey != "" && cached == nil && message != "" {
+		response := &ai.CachedResponse{Vendor: vendor.GetName(), Model: opts.Model, Content: message}
+		if result != nil {
+			response.Usage = result.Usage
+			response.FinishReason = result.FinishReason
+		}
+		if cacheErr := o.cache.Put(cacheKey, response); cacheErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not cache the response: %v\n", cacheErr)
 		}
-		message = summary
-	}
-
-	session.Append(&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: message})
-
-	if session.Name != "" {
-		err = o.db.Sessions.SaveSession(session)
 	}
 	return
 }
@@ -154,19 +307,24 @@ func (o *Chatter) BuildSession(request *domain.ChatRequest, raw bool) (session *
 		session = &fsdb.Session{}
 	}
 
+	if err = o.appendRequest(session, request, raw); err != nil {
+		session = nil
+	}
+	return
+}
+
+// appendRequest adds the system prompt built from the context, pattern and strategy of the
+// request and the user message to the session
+func (o *Chatter) appendRequest(session *fsdb.Session, request *domain.ChatRequest, raw bool) (err error) {
+	session.AddTags(request.SessionTags...)
 	if request.Meta != "" {
 		session.Append(&chat.ChatCompletionMessage{Role: domain.ChatMessageRoleMeta, Content: request.Meta})
 	}
 
-	// if a context name is provided, retrieve it from the database
+	// the contexts are merged in order, with their includes expanded
 	var contextContent string
-	if request.ContextName != "" {
-		var ctx *fsdb.Context
-		if ctx, err = o.db.Contexts.Get(request.ContextName); err != nil {
-			err = fmt.Errorf("could not find context %s: %v", request.ContextName, err)
-			return
-		}
-		contextContent = ctx.Content
+	if contextContent, err = LoadContexts(o.db, request.ContextNames, o.contextIncludes); err != nil {
+		return
 	}
 
 	// Process template variables in message content
@@ -183,7 +341,7 @@ func (o *Chatter) BuildSession(request *domain.ChatRequest, raw bool) (session *
 	if request.InputHasVars {
 		request.Message.Content, err = template.ApplyTemplate(request.Message.Content, request.PatternVariables, "")
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
@@ -193,7 +351,7 @@ func (o *Chatter) BuildSession(request *domain.ChatRequest, raw bool) (session *
 		pattern, err := o.db.Patterns.GetApplyVariables(request.PatternName, request.PatternVariables, request.Message.Content)
 
 		if err != nil {
-			return nil, fmt.Errorf("could not get pattern %s: %v", request.PatternName, err)
+			return fmt.Errorf("could not get pattern %s: %v", request.PatternName, err)
 		}
 		patternContent = pattern.Pattern
 		inputUsed = true
@@ -204,7 +362,7 @@ func (o *Chatter) BuildSession(request *domain.ChatRequest, raw bool) (session *
 	if request.StrategyName != "" {
 		strategy, err := strategy.LoadStrategy(request.StrategyName)
 		if err != nil {
-			return nil, fmt.Errorf("could not load strategy %s: %v", request.StrategyName, err)
+			return fmt.Errorf("could not load strategy %s: %v", request.StrategyName, err)
 		}
 		if strategy != nil && strategy.Prompt != "" {
 			// prepend the strategy prompt to the system message
@@ -269,8 +427,87 @@ func (o *Chatter) BuildSession(request *domain.ChatRequest, raw bool) (session *
 	}
 
 	if session.IsEmpty() {
-		session = nil
 		err = errors.New(NoSessionPatternUserMessages)
 	}
 	return
 }
+
+// sendWithTools runs the tool calling loop: it sends the conversation with the tool
+// definitions, executes the tools requested by the model and feeds their results back
+// until the model gives a final answer. Every step is appended to the session.
+func (o *Chatter) sendWithTools(ctx context.Context, vendor ai.Vendor, session *fsdb.Session, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
+	toolCaller, ok := vendor.(ai.ToolCaller)
+	if !ok {
+		err = fmt.Errorf("vendor %s does not support tool calling", vendor.GetName())
+		return
+	}
+	if o.tools == nil {
+		err = fmt.Errorf("no tool executor configured")
+		return
+	}
+
+	// The tool calls and outputs of a request that fails are removed again, so that retries,
+	// fallback models and the next turn start from the session as it was
+	length := len(session.Messages)
+	defer func() {
+		if err != nil {
+			session.Truncate(length)
+		}
+	}()
+
+	// The usage of all rounds is reported with the final answer
+	usage := &chat.Usage{}
+	for round := 0; round < MaxToolRounds; round++ {
+		var messages []*chat.ChatCompletionMessage
+		if messages, err = o.contextMessages(ctx, vendor, session, opts); err != nil {
+			return
+		}
+		var reply *domain.ChatResult
+		if reply, err = toolCaller.SendWithTools(ctx, messages, opts); err != nil {
+			return
+		}
+		usage.Add(reply.Usage)
+
+		if len(reply.ToolCalls) == 0 {
+			ret = reply
+			if usage.TotalTokens > 0 {
+				ret.Usage = usage
+			}
+			return
+		}
+
+		session.Append(&chat.ChatCompletionMessage{
+			Role:      chat.ChatMessageRoleAssistant,
+			Content:   reply.Content,
+			ToolCalls: reply.ToolCalls,
+		})
+
+		for _, call := range reply.ToolCalls {
+			output, toolErr := o.tools.Execute(ctx, call)
+			if toolErr != nil {
+				// Report the failure to the model, it may recover with different arguments
+				output = fmt.Sprintf("error: %v", toolErr)
+			}
+			session.Append(&chat.ChatCompletionMessage{
+				Role:       chat.ChatMessageRoleTool,
+				Content:    output,
+				Name:       call.Function.Name,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+
+	err = fmt.Errorf("no final answer after %d tool rounds", MaxToolRounds)
+	return
+}
+
+// messageMeta builds the metadata saved with the assistant message of a vendor result
+func messageMeta(result *domain.ChatResult, vendor ai.Vendor, model string) (ret *chat.MessageMeta) {
+	ret = &chat.MessageMeta{Vendor: vendor.GetName(), Model: model}
+	if result != nil {
+		ret.Usage = result.Usage
+		ret.FinishReason = result.FinishReason
+		ret.Cached = result.Cached
+	}
+	return
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 )
 
@@ -16,6 +21,7 @@ type mockVendor struct {
 	sendStreamError error
 	streamChunks    []string
 	sendFunc        func(context.Context, []*chat.ChatCompletionMessage, *domain.ChatOptions) (string, error)
+	usage           *chat.Usage
 }
 
 func (m *mockVendor) GetName() string {
@@ -41,11 +47,11 @@ func (m *mockVendor) Setup() error {
 func (m *mockVendor) SetupFillEnvFileContent(*bytes.Buffer) {
 }
 
-func (m *mockVendor) ListModels() ([]string, error) {
+func (m *mockVendor) ListModels(ctx context.Context) ([]string, error) {
 	return []string{"test-model"}, nil
 }
 
-func (m *mockVendor) SendStream(messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions, responseChan chan string) error {
+func (m *mockVendor) SendStream(ctx context.Context, messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions, responseChan chan string) (*domain.ChatResult, error) {
 	// Send chunks if provided (for successful streaming test)
 	if m.streamChunks != nil {
 		for _, chunk := range m.streamChunks {
@@ -54,14 +60,18 @@ func (m *mockVendor) SendStream(messages []*chat.ChatCompletionMessage, opts *do
 	}
 	// Close the channel like real vendors do
 	close(responseChan)
-	return m.sendStreamError
+	return &domain.ChatResult{Usage: m.usage}, m.sendStreamError
 }
 
-func (m *mockVendor) Send(ctx context.Context, messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (string, error) {
+func (m *mockVendor) Send(ctx context.Context, messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (*domain.ChatResult, error) {
+	content := "test response"
 	if m.sendFunc != nil {
-		return m.sendFunc(ctx, messages, opts)
+		var err error
+		if content, err = m.sendFunc(ctx, messages, opts); err != nil {
+			return nil, err
+		}
 	}
-	return "test response", nil
+	return &domain.ChatResult{Content: content, Usage: m.usage, FinishReason: "stop"}, nil
 }
 
 func (m *mockVendor) NeedsRawMode(modelName string) bool {
@@ -100,7 +110,7 @@ func TestChatter_Send_SuppressThink(t *testing.T) {
 		return "<think>hidden</think> visible", nil
 	}
 
-	session, err := chatter.Send(request, opts)
+	session, err := chatter.Send(context.Background(), request, opts)
 	if err != nil {
 		t.Fatalf("Send returned error: %v", err)
 	}
@@ -146,7 +156,7 @@ func TestChatter_Send_StreamingErrorPropagation(t *testing.T) {
 	}
 
 	// Call Send and expect it to return the streaming error
-	session, err := chatter.Send(request, opts)
+	session, err := chatter.Send(context.Background(), request, opts)
 
 	// Verify that the error from SendStream is propagated
 	if err == nil {
@@ -200,7 +210,7 @@ func TestChatter_Send_StreamingSuccessfulAggregation(t *testing.T) {
 	}
 
 	// Call Send and expect successful aggregation
-	session, err := chatter.Send(request, opts)
+	session, err := chatter.Send(context.Background(), request, opts)
 
 	// Verify no error occurred
 	if err != nil {
@@ -228,3 +238,395 @@ func TestChatter_Send_StreamingSuccessfulAggregation(t *testing.T) {
 		t.Errorf("Expected aggregated message %q, got %q", expectedMessage, assistantMessage.Content)
 	}
 }
+
+// mockToolVendor adds scripted tool calling replies to mockVendor
+type mockToolVendor struct {
+	mockVendor
+	replies []*domain.ChatResult
+	calls   int
+}
+
+func (m *mockToolVendor) SendWithTools(ctx context.Context, messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (*domain.ChatResult, error) {
+	if m.calls == len(m.replies) {
+		return nil, errors.New("no more replies")
+	}
+	reply := m.replies[m.calls]
+	m.calls++
+	return reply, nil
+}
+
+type mockToolExecutor struct {
+	calls []chat.ToolCall
+}
+
+func (m *mockToolExecutor) Execute(ctx context.Context, call chat.ToolCall) (string, error) {
+	m.calls = append(m.calls, call)
+	return "42", nil
+}
+
+func TestChatter_Send_ToolLoop(t *testing.T) {
+	tempDir := t.TempDir()
+	db := fsdb.NewDb(tempDir)
+
+	vendor := &mockToolVendor{
+		replies: []*domain.ChatResult{
+			{
+				ToolCalls: []chat.ToolCall{{
+					ID:       "call_1",
+					Type:     chat.ToolTypeFunction,
+					Function: chat.FunctionCall{Name: "answer", Arguments: `{"question":"life"}`},
+				}},
+				Usage: &chat.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			},
+			{
+				Content: "The answer is 42",
+				Usage:   &chat.Usage{PromptTokens: 20, CompletionTokens: 6, TotalTokens: 26},
+			},
+		},
+	}
+	executor := &mockToolExecutor{}
+
+	chatter := &Chatter{
+		db:     db,
+		vendor: vendor,
+		model:  "test-model",
+		tools:  executor,
+	}
+
+	request := &domain.ChatRequest{
+		Message: &chat.ChatCompletionMessage{
+			Role:    chat.ChatMessageRoleUser,
+			Content: "What is the answer?",
+		},
+	}
+	opts := &domain.ChatOptions{
+		Model: "test-model",
+		Tools: []chat.Tool{{Type: chat.ToolTypeFunction, Function: &chat.FunctionDefinition{Name: "answer"}}},
+	}
+
+	session, err := chatter.Send(context.Background(), request, opts)
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if len(executor.calls) != 1 || executor.calls[0].Function.Name != "answer" {
+		t.Fatalf("expected one call of tool 'answer', got %v", executor.calls)
+	}
+
+	messages := session.GetVendorMessages()
+	if len(messages) != 4 { // user, assistant tool call, tool result, final answer
+		t.Fatalf("expected 4 messages, got %d", len(messages))
+	}
+	if messages[2].Role != chat.ChatMessageRoleTool || messages[2].ToolCallID != "call_1" || messages[2].Content != "42" {
+		t.Errorf("unexpected tool result message: %+v", messages[2])
+	}
+	last := session.GetLastMessage()
+	if last.Content != "The answer is 42" {
+		t.Errorf("expected final answer, got %q", last.Content)
+	}
+	if last.Meta == nil || last.Meta.Usage == nil || last.Meta.Usage.TotalTokens != 41 {
+		t.Errorf("expected the usage of both rounds on the final answer, got %+v", last.Meta)
+	}
+}
+
+func TestChatter_SendWithTools_RemovesRoundsOnError(t *testing.T) {
+	toolCall := &domain.ChatResult{ToolCalls: []chat.ToolCall{{
+		ID:       "call_1",
+		Type:     chat.ToolTypeFunction,
+		Function: chat.FunctionCall{Name: "answer", Arguments: `{}`},
+	}}}
+	endless := make([]*domain.ChatResult, MaxToolRounds)
+	for i := range endless {
+		endless[i] = toolCall
+	}
+
+	tests := []struct {
+		name    string
+		replies []*domain.ChatResult
+		want    string
+	}{
+		{"later round fails", []*domain.ChatResult{toolCall}, "no more replies"},
+		{"too many rounds", endless, "no final answer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatter := &Chatter{db: fsdb.NewDb(t.TempDir()), model: "test-model", tools: &mockToolExecutor{}}
+			session := &fsdb.Session{}
+			session.Append(&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "What is the answer?"})
+
+			_, err := chatter.sendWithTools(context.Background(), &mockToolVendor{replies: tt.replies}, session,
+				&domain.ChatOptions{Model: "test-model"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error with %q, got %v", tt.want, err)
+			}
+			if len(session.Messages) != 1 {
+				t.Errorf("expected only the user message to be left, got %d messages", len(session.Messages))
+			}
+		})
+	}
+}
+
+func TestChatter_Send_ToolsUnsupportedVendor(t *testing.T) {
+	chatter := &Chatter{
+		db:     fsdb.NewDb(t.TempDir()),
+		vendor: &mockVendor{},
+		model:  "test-model",
+		tools:  &mockToolExecutor{},
+	}
+
+	request := &domain.ChatRequest{
+		Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "test"},
+	}
+	opts := &domain.ChatOptions{
+		Model: "test-model",
+		Tools: []chat.Tool{{Type: chat.ToolTypeFunction, Function: &chat.FunctionDefinition{Name: "answer"}}},
+	}
+
+	if _, err := chatter.Send(context.Background(), request, opts); err == nil {
+		t.Fatal("expected an error for a vendor without tool calling support")
+	}
+}
+
+func TestChatter_Send_PassesContextToVendor(t *testing.T) {
+	tempDir := t.TempDir()
+	db := fsdb.NewDb(tempDir)
+
+	mockVendor := &mockVendor{}
+	mockVendor.sendFunc = func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		return "", ctx.Err()
+	}
+
+	chatter := &Chatter{
+		db:     db,
+		Stream: false,
+		vendor: mockVendor,
+		model:  "test-model",
+	}
+
+	request := &domain.ChatRequest{
+		Message: &chat.ChatCompletionMessage{
+			Role:    chat.ChatMessageRoleUser,
+			Content: "test",
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := chatter.Send(ctx, request, &domain.ChatOptions{Model: "test-model"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestChatter_Send_SavesUsage(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	if err := db.Sessions.Configure(); err != nil {
+		t.Fatalf("failed to configure sessions: %v", err)
+	}
+	usage := &chat.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+
+	for _, stream := range []bool{false, true} {
+		mockVendor := &mockVendor{usage: usage, streamChunks: []string{"streamed"}}
+		chatter := &Chatter{
+			db:     db,
+			Stream: stream,
+			vendor: mockVendor,
+			model:  "test-model",
+		}
+
+		request := &domain.ChatRequest{
+			SessionName: "usage",
+			Message: &chat.ChatCompletionMessage{
+				Role:    chat.ChatMessageRoleUser,
+				Content: "test",
+			},
+		}
+		if _, err := chatter.Send(context.Background(), request, &domain.ChatOptions{Model: "test-model"}); err != nil {
+			t.Fatalf("Send returned error: %v", err)
+		}
+
+		saved, err := db.Sessions.Get("usage")
+		if err != nil {
+			t.Fatalf("failed to load session: %v", err)
+		}
+		last := saved.GetLastMessage()
+		if last.Meta == nil || last.Meta.Usage == nil || *last.Meta.Usage != *usage {
+			t.Errorf("stream=%v: expected usage %+v to be saved, got %+v", stream, usage, last.Meta)
+		}
+	}
+}
+
+func TestChatter_Send_UsesResponseCache(t *testing.T) {
+	cache := ai.NewResponseCache(t.TempDir(), time.Hour, 0)
+
+	for _, stream := range []bool{false, true} {
+		var calls int
+		mockVendor := &mockVendor{streamChunks: []string{"first line\n", "second line"}, sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+			calls++
+			return "first line\nsecond line", nil
+		}}
+		chatter := &Chatter{
+			db:     fsdb.NewDb(t.TempDir()),
+			Stream: stream,
+			vendor: mockVendor,
+			model:  "test-model",
+			cache:  cache,
+		}
+
+		var sessions []*fsdb.Session
+		for range 2 {
+			request := &domain.ChatRequest{Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "cached question"}}
+			session, err := chatter.Send(context.Background(), request, &domain.ChatOptions{Model: "test-model"})
+			if err != nil {
+				t.Fatalf("stream=%v: Send returned error: %v", stream, err)
+			}
+			sessions = append(sessions, session)
+		}
+
+		if !stream && calls != 1 {
+			t.Errorf("expected the vendor to be called once, got %d calls", calls)
+		}
+		first, second := sessions[0].GetLastMessage(), sessions[1].GetLastMessage()
+		if second.Content != "first line\nsecond line" || second.Content != first.Content {
+			t.Errorf("stream=%v: expected the cached answer, got %q", stream, second.Content)
+		}
+		if first.Meta.Cached || !second.Meta.Cached {
+			t.Errorf("stream=%v: expected only the second answer to be marked as cached", stream)
+		}
+
+		if err := cache.Clear(); err != nil {
+			t.Fatalf("failed to clear the cache: %v", err)
+		}
+	}
+}
+
+func TestChatter_Send_DoesNotCacheImageRequests(t *testing.T) {
+	cache := ai.NewResponseCache(t.TempDir(), time.Hour, 0)
+	chatter := &Chatter{db: fsdb.NewDb(t.TempDir()), vendor: &mockVendor{}, model: "test-model", cache: cache}
+
+	request := &domain.ChatRequest{Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "question"}}
+	if _, err := chatter.Send(context.Background(), request, &domain.ChatOptions{ImageFile: filepath.Join(t.TempDir(), "out.png")}); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if entries, _ := os.ReadDir(cache.Dir); len(entries) != 0 {
+		t.Errorf("expected no cached answers for an image request, got %d", len(entries))
+	}
+}
+
+func TestChatter_SendInSession(t *testing.T) {
+	var calls [][]*chat.ChatCompletionMessage
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		calls = append(calls, msgs)
+		return "answer", nil
+	}}
+	chatter := &Chatter{db: fsdb.NewDb(t.TempDir()), vendor: mockVendor, model: "test-model"}
+
+	session := &fsdb.Session{}
+	for _, question := range []string{"first question", "second question"} {
+		request := &domain.ChatRequest{Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: question}}
+		if err := chatter.SendInSession(context.Background(), session, request, &domain.ChatOptions{}); err != nil {
+			t.Fatalf("SendInSession() error = %v", err)
+		}
+	}
+
+	if len(session.Messages) != 4 {
+		t.Fatalf("expected both questions and answers in the session, got %d messages", len(session.Messages))
+	}
+	if len(calls) != 2 || len(calls[1]) != 3 || calls[1][0].Content != "first question" {
+		t.Error("expected the second request to include the conversation so far")
+	}
+}
+
+func TestChatter_Send_RecordsSessionMetadata(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	if err := os.MkdirAll(db.Sessions.Dir, 0755); err != nil {
+		t.Fatalf("failed to create the sessions directory: %v", err)
+	}
+	chatter := &Chatter{db: db, vendor: &mockVendor{}, model: "test-model"}
+
+	request := &domain.ChatRequest{SessionName: "tagged", SessionTags: []string{"work"},
+		Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "question"}}
+	if _, err := chatter.Send(context.Background(), request, &domain.ChatOptions{Model: "test-model"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	session, err := db.Sessions.Get("tagged")
+	if err != nil {
+		t.Fatalf("failed to load the session: %v", err)
+	}
+	if len(session.Tags) != 1 || session.Tags[0] != "work" {
+		t.Errorf("expected the tag to be saved, got %v", session.Tags)
+	}
+	if meta := session.GetLastMessage().Meta; meta == nil || meta.Model != "test-model" || meta.Timestamp.IsZero() {
+		t.Errorf("expected the model and time of the answer, got %+v", meta)
+	}
+}
+
+func TestChatter_Regenerate(t *testing.T) {
+	var calls [][]*chat.ChatCompletionMessage
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		calls = append(calls, msgs)
+		return "new answer", nil
+	}}
+	chatter := &Chatter{db: fsdb.NewDb(t.TempDir()), vendor: mockVendor, model: "test-model"}
+
+	session := &fsdb.Session{Messages: []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleUser, Content: "first question"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "first answer", Meta: &chat.MessageMeta{Strategy: "cot"}},
+		{Role: chat.ChatMessageRoleUser, Content: "second question"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "second answer"},
+	}}
+	if err := chatter.Regenerate(context.Background(), session, 2, &domain.ChatOptions{}); err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+
+	if len(calls) != 1 || len(calls[0]) != 1 || calls[0][0].Content != "first question" {
+		t.Errorf("expected only the messages before the answer to be sent, got %v", calls)
+	}
+	if len(session.Messages) != 2 || session.Messages[1].Content != "new answer" {
+		t.Fatalf("expected the answer and the messages after it to be replaced, got %d messages", len(session.Messages))
+	}
+	if session.Messages[1].Meta.Strategy != "cot" {
+		t.Error("expected the strategy of the original answer to be recorded")
+	}
+
+	if err := chatter.Regenerate(context.Background(), session, 1, &domain.ChatOptions{}); err == nil {
+		t.Error("expected an error for a message that is not an answer")
+	}
+}
+
+func TestChatter_Regenerate_KeepsAnswerOnError(t *testing.T) {
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		return "", errors.New("vendor unavailable")
+	}}
+	db := fsdb.NewDb(t.TempDir())
+	if err := db.Sessions.Configure(); err != nil {
+		t.Fatalf("failed to configure sessions: %v", err)
+	}
+	chatter := &Chatter{db: db, vendor: mockVendor, model: "test-model"}
+
+	session := &fsdb.Session{Name: "regenerate", Messages: []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleUser, Content: "first question"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "first answer"},
+		{Role: chat.ChatMessageRoleUser, Content: "second question"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "second answer"},
+	}}
+	if err := db.Sessions.SaveSession(session); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	if err := chatter.Regenerate(context.Background(), session, 2, &domain.ChatOptions{}); err == nil {
+		t.Fatal("expected the error of the vendor")
+	}
+	if len(session.Messages) != 4 || session.Messages[1].Content != "first answer" || session.Messages[3].Content != "second answer" {
+		t.Errorf("expected the session to keep its messages, got %d messages", len(session.Messages))
+	}
+	saved, err := db.Sessions.Get("regenerate")
+	if err != nil {
+		t.Fatalf("failed to load the saved session: %v", err)
+	}
+	if len(saved.Messages) != 4 || saved.Messages[1].Content != "first answer" {
+		t.Errorf("expected the saved session to keep its messages, got %d messages", len(saved.Messages))
+	}
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// CompareResult is the answer of one model in a comparison
+type CompareResult struct {
+	Model        string      `json:"model"`
+	Vendor       string      `json:"vendor,omitempty"`
+	Output       string      `json:"output,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	DurationMs   int64       `json:"duration_ms"`
+	Usage        *chat.Usage `json:"usage,omitempty"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	// AnsweredBy is the vendor|model that answered when the model fell back to another one
+	AnsweredBy string `json:"answered_by,omitempty"`
+}
+
+// Compare sends the same request to all models at the same time and returns their answers
+// in the order of models. A model that fails is reported in its result and does not stop the others.
+func (o *PluginRegistry) Compare(ctx context.Context, models []string, modelContextLength int, strategy string, dryRun bool,
+	request *domain.ChatRequest, opts *domain.ChatOptions) (ret []*CompareResult) {
+
+	ret = make([]*CompareResult, len(models))
+	chatters := make([]*Chatter, len(models))
+	for i, model := range models {
+		ret[i] = &CompareResult{Model: model}
+		chatter, err := o.GetChatter(ctx, model, modelContextLength, strategy, false, dryRun)
+		if err != nil {
+			ret[i].Error = err.Error()
+			continue
+		}
+		chatters[i] = chatter
+	}
+
+	compareChatters(ctx, chatters, ret, request, opts)
+	return
+}
+
+// compareChatters runs the request with every chatter in parallel and fills in the results.
+// Chatters that are nil are skipped.
+func compareChatters(ctx context.Context, chatters []*Chatter, results []*CompareResult,
+	request *domain.ChatRequest, opts *domain.ChatOptions) {
+
+	var wg sync.WaitGroup
+	for i, chatter := range chatters {
+		if chatter == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(chatter *Chatter, result *CompareResult) {
+			defer wg.Done()
+			result.Vendor = chatter.vendor.GetName()
+
+			// Every model gets its own copy, as building the session changes the request
+			modelRequest := *request
+			if request.Message != nil {
+				message := *request.Message
+				modelRequest.Message = &message
+			}
+			modelOpts := *opts
+			modelOpts.Model = result.Model
+
+			start := time.Now()
+			session, err := chatter.Send(ctx, &modelRequest, &modelOpts)
+			result.DurationMs = time.Since(start).Milliseconds()
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+
+			last := session.GetLastMessage()
+			result.Output = last.Content
+			if last.Meta != nil {
+				result.Usage = last.Meta.Usage
+				result.FinishReason = last.Meta.FinishReason
+				if last.Meta.Model != result.Model {
+					result.AnsweredBy = last.Meta.Vendor + "|" + last.Meta.Model
+				}
+			}
+		}(chatter, results[i])
+	}
+	wg.Wait()
+}
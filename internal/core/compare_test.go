@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+func TestCompareChatters(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	usage := &chat.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}
+
+	working := &mockVendor{usage: usage, sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		return "answer from " + o.Model, nil
+	}}
+	failing := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		return "", errors.New("model is down")
+	}}
+
+	chatters := []*Chatter{
+		{db: db, vendor: working, model: "model-a"},
+		{db: db, vendor: failing, model: "model-b"},
+		nil,
+		{db: db, vendor: working, model: "model-d"},
+	}
+	results := []*CompareResult{{Model: "model-a"}, {Model: "model-b"}, {Model: "model-c", Error: "could not find vendor"}, {Model: "model-d"}}
+
+	request := &domain.ChatRequest{Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "question"}}
+	compareChatters(context.Background(), chatters, results, request, &domain.ChatOptions{})
+
+	if results[0].Output != "answer from model-a" || results[3].Output != "answer from model-d" {
+		t.Errorf("expected an answer from every working model, got %q and %q", results[0].Output, results[3].Output)
+	}
+	if results[0].Vendor != "mock" || results[0].Usage == nil || *results[0].Usage != *usage || results[0].FinishReason != "stop" {
+		t.Errorf("expected vendor, usage and finish reason in the result, got %+v", results[0])
+	}
+	if results[1].Error != "model is down" || results[1].Output != "" {
+		t.Errorf("expected the failure of model-b in its result, got %+v", results[1])
+	}
+	if results[2].Error != "could not find vendor" {
+		t.Errorf("expected the result of a skipped model to be kept, got %+v", results[2])
+	}
+	if request.Message.Content != "question" {
+		t.Errorf("expected the shared request to be unchanged, got %q", request.Message.Content)
+	}
+}
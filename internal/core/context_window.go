@@ -0,0 +1,252 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+const (
+	// charsPerToken is the rough average used to estimate token counts without a tokenizer
+	charsPerToken = 4
+	// messageOverheadTokens covers the role and the formatting of each message
+	messageOverheadTokens = 4
+	// imageTokens is the estimate for each image attachment
+	imageTokens = 1000
+)
+
+// defaultSummarizerPrompt is used when no summarizer pattern is chosen
+const defaultSummarizerPrompt = `You summarize conversations between a user and an AI assistant so they can be continued with less context.
+
+Write a concise summary of the conversation you are given. Keep every fact, decision, name, number, piece of code and open question that later messages may refer to. Leave out greetings and repetition.
+
+Output only the summary in plain text.`
+
+// turn is a user message together with the system messages before it and everything that answers it
+type turn []*chat.ChatCompletionMessage
+
+// contextMessages returns the session messages to send to the vendor. With a context strategy
+// only the system prompt and the most recent turns that fit into the context window are sent.
+// The summarize strategy replaces the older turns with a summary that is saved in the session.
+func (o *Chatter) contextMessages(ctx context.Context, vendor ai.Vendor, session *fsdb.Session, opts *domain.ChatOptions) (ret []*chat.ChatCompletionMessage, err error) {
+	if opts.ContextStrategy == "" {
+		ret = session.GetVendorMessages()
+		return
+	}
+
+	prompt, summary, turns := splitSession(session)
+	budget := o.contextBudget(opts)
+
+	keep := keepTurns(turns, estimateTokens(prompt...)+estimateTokens(summary), opts.ContextTurns, budget)
+	if keep < len(turns) && opts.ContextStrategy == domain.ContextStrategySummarize && !o.DryRun {
+		if summary, err = o.summarize(ctx, vendor, summary, turns[:len(turns)-keep], opts); err != nil {
+			return
+		}
+		session.InsertBefore(turns[len(turns)-keep][0], summary)
+		turns = turns[len(turns)-keep:]
+		// The new summary may push more turns out of the budget, those are dropped
+		keep = keepTurns(turns, estimateTokens(prompt...)+estimateTokens(summary), 0, budget)
+	}
+
+	ret = append(ret, prompt...)
+	if summary != nil {
+		role := chat.ChatMessageRoleSystem
+		if opts.Raw {
+			role = chat.ChatMessageRoleUser
+		}
+		ret = append(ret, &chat.ChatCompletionMessage{
+			Role:    role,
+			Content: "Summary of the earlier conversation:\n\n" + summary.Content,
+		})
+	}
+	for _, t := range turns[len(turns)-keep:] {
+		ret = append(ret, t...)
+	}
+	return
+}
+
+// contextBudget returns the number of tokens available for the messages, or 0 when
+// the context limit of the model is unknown
+func (o *Chatter) contextBudget(opts *domain.ChatOptions) (ret int) {
+	limit := opts.ModelContextLength
+	// The context length of the defaults only applies to models without a known limit
+	if !o.modelContextLengthGiven {
+		if known := o.contextLimits.Get(opts.Model); known != 0 {
+			limit = known
+		}
+	}
+	if limit == 0 {
+		return
+	}
+
+	// Leave room for the answer
+	reserve := opts.MaxTokens
+	if reserve == 0 {
+		reserve = limit / 4
+	}
+	ret = max(limit-reserve, 1)
+	return
+}
+
+// summarize asks the model for a summary of the previous summary and the given turns
+func (o *Chatter) summarize(ctx context.Context, vendor ai.Vendor, previous *chat.ChatCompletionMessage, turns []turn, opts *domain.ChatOptions) (ret *chat.ChatCompletionMessage, err error) {
+	prompt := defaultSummarizerPrompt
+	if opts.ContextSummarizer != "" {
+		var pattern *fsdb.Pattern
+		if pattern, err = o.db.Patterns.GetApplyVariables(opts.ContextSummarizer, nil, ""); err != nil {
+			err = fmt.Errorf("could not get summarizer pattern %s: %v", opts.ContextSummarizer, err)
+			return
+		}
+		prompt = pattern.Pattern
+	}
+
+	var transcript strings.Builder
+	if previous != nil {
+		fmt.Fprintf(&transcript, "[summary of the earlier conversation]\n%s\n\n", previous.Content)
+	}
+	for _, t := range turns {
+		for _, message := range t {
+			fmt.Fprintf(&transcript, "[%s]\n%s\n\n", message.Role, messageText(message))
+		}
+	}
+
+	messages := []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleSystem, Content: prompt},
+		{Role: chat.ChatMessageRoleUser, Content: transcript.String()},
+	}
+	if opts.Raw {
+		messages = []*chat.ChatCompletionMessage{
+			{Role: chat.ChatMessageRoleUser, Content: prompt + "\n\n" + transcript.String()},
+		}
+	}
+
+	summaryOpts := *opts
+	summaryOpts.Tools = nil
+
+	var result *domain.ChatResult
+	if result, err = vendor.Send(ctx, messages, &summaryOpts); err != nil {
+		err = fmt.Errorf("could not summarize the session: %w", err)
+		return
+	}
+	content := strings.TrimSpace(result.Content)
+	if summaryOpts.SuppressThink {
+		content = strings.TrimSpace(domain.StripThinkBlocks(content, summaryOpts.ThinkStartTag, summaryOpts.ThinkEndTag))
+	}
+	if content == "" {
+		err = fmt.Errorf("could not summarize the session: empty response")
+		return
+	}
+
+	ret = &chat.ChatCompletionMessage{
+		Role:    domain.ChatMessageRoleMeta,
+		Content: content,
+		Meta:    &chat.MessageMeta{Summary: true, Usage: result.Usage},
+	}
+	return
+}
+
+// splitSession splits the vendor messages of the session into the leading system prompt,
+// the latest summary and the turns after that summary
+func splitSession(session *fsdb.Session) (prompt []*chat.ChatCompletionMessage, summary *chat.ChatCompletionMessage, turns []turn) {
+	start := 0
+	for i, message := range session.Messages {
+		if message.Role == domain.ChatMessageRoleMeta && message.Meta != nil && message.Meta.Summary {
+			summary = message
+			start = i + 1
+		}
+	}
+
+	inPrompt := true
+	for i, message := range session.Messages {
+		if message.Role == domain.ChatMessageRoleMeta {
+			continue
+		}
+		if inPrompt && message.Role == chat.ChatMessageRoleSystem {
+			prompt = append(prompt, message)
+			continue
+		}
+		inPrompt = false
+		if i < start {
+			continue
+		}
+
+		if len(turns) == 0 || (isTurnStart(message) && !isTurnStart(lastMessage(turns))) {
+			turns = append(turns, turn{})
+		}
+		turns[len(turns)-1] = append(turns[len(turns)-1], message)
+	}
+	return
+}
+
+// keepTurns returns how many of the most recent turns fit into the budget next to the
+// fixed tokens. The last turn is always kept.
+func keepTurns(turns []turn, fixed int, maxTurns int, budget int) (ret int) {
+	keep := len(turns)
+	if maxTurns > 0 && keep > maxTurns {
+		keep = maxTurns
+	}
+	if budget == 0 {
+		return keep
+	}
+
+	used := fixed
+	for ret < keep {
+		tokens := estimateTokens(turns[len(turns)-1-ret]...)
+		if ret > 0 && used+tokens > budget {
+			break
+		}
+		used += tokens
+		ret++
+	}
+	return
+}
+
+func isTurnStart(message *chat.ChatCompletionMessage) bool {
+	return message.Role == chat.ChatMessageRoleUser || message.Role == chat.ChatMessageRoleSystem
+}
+
+func lastMessage(turns []turn) *chat.ChatCompletionMessage {
+	last := turns[len(turns)-1]
+	return last[len(last)-1]
+}
+
+// estimateTokens roughly estimates the number of tokens of the messages
+func estimateTokens(messages ...*chat.ChatCompletionMessage) (ret int) {
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		chars := len(message.Content)
+		for _, part := range message.MultiContent {
+			if part.Type == chat.ChatMessagePartTypeText {
+				chars += len(part.Text)
+			} else {
+				ret += imageTokens
+			}
+		}
+		for _, call := range message.ToolCalls {
+			chars += len(call.Function.Name) + len(call.Function.Arguments)
+		}
+		ret += chars/charsPerToken + messageOverheadTokens
+	}
+	return
+}
+
+// messageText returns the text of a message, including the text parts of multi part messages
+func messageText(message *chat.ChatCompletionMessage) string {
+	text := message.Content
+	for _, part := range message.MultiContent {
+		if part.Type == chat.ChatMessagePartTypeText {
+			text = strings.TrimSpace(text + "\n" + part.Text)
+		}
+	}
+	for _, call := range message.ToolCalls {
+		text = strings.TrimSpace(fmt.Sprintf("%s\ncalled %s(%s)", text, call.Function.Name, call.Function.Arguments))
+	}
+	return text
+}
@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+// newLongSession saves a session with a system prompt and three answered turns
+func newLongSession(t *testing.T, db *fsdb.Db) {
+	t.Helper()
+	if err := db.Sessions.Configure(); err != nil {
+		t.Fatalf("failed to configure sessions: %v", err)
+	}
+	session := &fsdb.Session{Name: "long", Messages: []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleSystem, Content: "system prompt"},
+		{Role: chat.ChatMessageRoleUser, Content: "question 1 " + strings.Repeat("a", 400)},
+		{Role: chat.ChatMessageRoleAssistant, Content: "answer 1"},
+		{Role: chat.ChatMessageRoleUser, Content: "question 2 " + strings.Repeat("b", 400)},
+		{Role: chat.ChatMessageRoleAssistant, Content: "answer 2"},
+		{Role: domain.ChatMessageRoleMeta, Content: "meta"},
+		{Role: chat.ChatMessageRoleUser, Content: "question 3"},
+		{Role: chat.ChatMessageRoleAssistant, Content: "answer 3"},
+	}}
+	if err := db.Sessions.SaveSession(session); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+}
+
+func newLongSessionRequest() *domain.ChatRequest {
+	return &domain.ChatRequest{
+		SessionName: "long",
+		Message:     &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "question 4"},
+	}
+}
+
+func contents(msgs []*chat.ChatCompletionMessage) (ret []string) {
+	for _, msg := range msgs {
+		ret = append(ret, msg.Content)
+	}
+	return
+}
+
+func TestChatter_Send_ContextTruncateTurns(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	newLongSession(t, db)
+
+	var sent []*chat.ChatCompletionMessage
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		sent = msgs
+		return "answer 4", nil
+	}}
+	chatter := &Chatter{db: db, vendor: mockVendor, model: "test-model"}
+
+	opts := &domain.ChatOptions{ContextStrategy: domain.ContextStrategyTruncate, ContextTurns: 2}
+	session, err := chatter.Send(context.Background(), newLongSessionRequest(), opts)
+	if err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	want := []string{"system prompt", "question 3", "answer 3", "question 4"}
+	if got := contents(sent); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+	if len(session.Messages) != 10 {
+		t.Errorf("expected the session history to be kept, got %d messages", len(session.Messages))
+	}
+}
+
+func TestChatter_Send_ContextTruncateBudget(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	newLongSession(t, db)
+
+	var sent []*chat.ChatCompletionMessage
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		sent = msgs
+		return "answer 4", nil
+	}}
+	chatter := &Chatter{db: db, vendor: mockVendor, model: "test-model"}
+
+	// 160 tokens minus a quarter for the answer leaves room for the last two turns only
+	opts := &domain.ChatOptions{ContextStrategy: domain.ContextStrategyTruncate, ModelContextLength: 160}
+	if _, err := chatter.Send(context.Background(), newLongSessionRequest(), opts); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	want := []string{"system prompt", "question 3", "answer 3", "question 4"}
+	if got := contents(sent); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+}
+
+func TestChatter_Send_ContextSummarize(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	newLongSession(t, db)
+
+	var transcript string
+	var sent []*chat.ChatCompletionMessage
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		if msgs[0].Content == defaultSummarizerPrompt {
+			transcript = msgs[1].Content
+			return "summary of 1 and 2", nil
+		}
+		sent = msgs
+		return "answer 4", nil
+	}}
+	chatter := &Chatter{db: db, vendor: mockVendor, model: "test-model"}
+
+	opts := &domain.ChatOptions{ContextStrategy: domain.ContextStrategySummarize, ContextTurns: 2}
+	if _, err := chatter.Send(context.Background(), newLongSessionRequest(), opts); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if !strings.Contains(transcript, "question 1") || !strings.Contains(transcript, "answer 2") || strings.Contains(transcript, "question 3") {
+		t.Errorf("expected the first two turns to be summarized, got %q", transcript)
+	}
+	want := []string{"system prompt", "Summary of the earlier conversation:\n\nsummary of 1 and 2", "question 3", "answer 3", "question 4"}
+	if got := contents(sent); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+
+	saved, err := db.Sessions.Get("long")
+	if err != nil {
+		t.Fatalf("failed to load session: %v", err)
+	}
+	summary := saved.Messages[6]
+	if summary.Role != domain.ChatMessageRoleMeta || summary.Meta == nil || !summary.Meta.Summary {
+		t.Fatalf("expected a summary before the kept turns, got %+v", summary)
+	}
+
+	// The next request builds on the saved summary without summarizing again
+	transcript = ""
+	opts = &domain.ChatOptions{ContextStrategy: domain.ContextStrategySummarize, ContextTurns: 3}
+	if _, err = chatter.Send(context.Background(), newLongSessionRequest(), opts); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if transcript != "" {
+		t.Errorf("expected no new summary, got a request for %q", transcript)
+	}
+	if sent[1].Content != "Summary of the earlier conversation:\n\nsummary of 1 and 2" || len(sent) != 7 {
+		t.Errorf("expected the saved summary and the three turns after it, got %q", contents(sent))
+	}
+}
+
+func TestChatter_Send_ContextUnknownModel(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	newLongSession(t, db)
+
+	var sent []*chat.ChatCompletionMessage
+	mockVendor := &mockVendor{sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+		sent = msgs
+		return "answer 4", nil
+	}}
+	chatter := &Chatter{db: db, vendor: mockVendor, model: "test-model"}
+
+	opts := &domain.ChatOptions{ContextStrategy: domain.ContextStrategyTruncate}
+	if _, err := chatter.Send(context.Background(), newLongSessionRequest(), opts); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if len(sent) != 8 {
+		t.Errorf("expected all messages without a known context limit, got %q", contents(sent))
+	}
+}
+
+func TestChatter_ContextBudget_PrefersKnownLimits(t *testing.T) {
+	chatter := &Chatter{contextLimits: ai.ContextLimits{"gpt-4o": 1000}}
+
+	if got := chatter.contextBudget(&domain.ChatOptions{Model: "gpt-4o", ModelContextLength: 400}); got != 750 {
+		t.Errorf("expected the limit of the model to win over the default, got a budget of %d", got)
+	}
+	if got := chatter.contextBudget(&domain.ChatOptions{Model: "unknown-model", ModelContextLength: 400}); got != 300 {
+		t.Errorf("expected the default for an unknown model, got a budget of %d", got)
+	}
+
+	chatter.modelContextLengthGiven = true
+	if got := chatter.contextBudget(&domain.ChatOptions{Model: "gpt-4o", ModelContextLength: 400}); got != 300 {
+		t.Errorf("expected a given context length to win over the limit of the model, got a budget of %d", got)
+	}
+}
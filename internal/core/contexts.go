@@ -0,0 +1,277 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+	"github.com/danielmiessler/fabric/internal/tools/converter"
+	"github.com/danielmiessler/fabric/internal/util"
+)
+
+// A line "@include kind:target" of a context is replaced by what it names:
+//
+//	@include context:coding-style
+//	@include file:~/notes/project.md
+//	@include dir:./src/**/*.go
+//	@include url:https://go.dev/doc/effective_go
+const contextIncludePrefix = "@include "
+
+// The kinds of includes, file, dir and url read outside of the contexts
+const (
+	ContextIncludeContext = "context"
+	ContextIncludeFile    = "file"
+	ContextIncludeDir     = "dir"
+	ContextIncludeURL     = "url"
+)
+
+const (
+	// maxContextIncludeSize is the largest file or URL a context can include, larger files in
+	// included directories are skipped
+	maxContextIncludeSize = 1 << 20
+	// maxContextSize is the largest size of all contexts of a request with their includes
+	maxContextSize = 8 << 20
+	// maxContextDirFiles is the most files an included directory can have
+	maxContextDirFiles = 1000
+)
+
+// contextResolver expands the includes of contexts
+type contextResolver struct {
+	db *fsdb.Db
+	// local allows includes of files, directories and URLs, not only of other contexts
+	local bool
+	// stack are the contexts being expanded, to detect contexts that include themselves
+	stack []string
+	size  int
+}
+
+// LoadContexts expands the contexts with their includes and merges them in order. local allows
+// includes of files, directories and URLs, which must not be allowed to clients that can write
+// contexts but not read the files of the host, like those of the REST API.
+func LoadContexts(db *fsdb.Db, names []string, local bool) (ret string, err error) {
+	resolver := &contextResolver{db: db, local: local}
+	var contents []string
+	for _, name := range names {
+		var content string
+		if content, err = resolver.resolveContext(name); err != nil {
+			return
+		}
+		contents = append(contents, strings.TrimSpace(content))
+	}
+	ret = strings.Join(contents, "\n\n")
+	return
+}
+
+func (o *contextResolver) resolveContext(name string) (ret string, err error) {
+	for i, parent := range o.stack {
+		if parent == name {
+			return "", fmt.Errorf("the contexts include each other: %s -> %s", strings.Join(o.stack[i:], " -> "), name)
+		}
+	}
+
+	var context *fsdb.Context
+	if context, err = o.db.Contexts.Get(name); err != nil {
+		return "", fmt.Errorf("could not find context %s: %v", name, err)
+	}
+	o.stack = append(o.stack, name)
+	defer func() { o.stack = o.stack[:len(o.stack)-1] }()
+	return o.expand(context.Content, name)
+}
+
+// expand replaces the include lines of the content of a context
+func (o *contextResolver) expand(content string, name string) (ret string, err error) {
+	var builder strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxContextSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		directive, found := strings.CutPrefix(strings.TrimSpace(line), contextIncludePrefix)
+		if !found {
+			if err = o.add(&builder, line+"\n", name); err != nil {
+				return
+			}
+			continue
+		}
+
+		var included string
+		if included, err = o.include(strings.TrimSpace(directive)); err != nil {
+			return "", fmt.Errorf("context %s: %v", name, err)
+		}
+		if err = o.add(&builder, strings.TrimRight(included, "\n")+"\n", name); err != nil {
+			return
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", fmt.Errorf("could not read context %s: %v", name, err)
+	}
+	ret = builder.String()
+	return
+}
+
+// add appends content and checks the size of all contexts
+func (o *contextResolver) add(builder *strings.Builder, content string, name string) (err error) {
+	if o.size += len(content); o.size > maxContextSize {
+		return fmt.Errorf("the contexts are larger than %d MiB with the includes of context %s",
+			maxContextSize>>20, name)
+	}
+	builder.WriteString(content)
+	return
+}
+
+func (o *contextResolver) include(directive string) (ret string, err error) {
+	kind, target, found := strings.Cut(directive, ":")
+	if target = strings.TrimSpace(target); !found || target == "" {
+		return "", fmt.Errorf("invalid include %q, expected kind:target", directive)
+	}
+	if kind != ContextIncludeContext && !o.local {
+		return "", fmt.Errorf("%s includes are not allowed here, only context includes", kind)
+	}
+
+	switch kind {
+	case ContextIncludeContext:
+		// The size of an included context is counted with its lines
+		var content string
+		if content, err = o.resolveContext(target); err == nil {
+			o.size -= len(content)
+		}
+		return content, err
+	case ContextIncludeFile:
+		return includeFile(target)
+	case ContextIncludeDir:
+		return includeDir(target)
+	case ContextIncludeURL:
+		return includeURL(target)
+	default:
+		return "", fmt.Errorf("unknown include kind %s, expected %s, %s, %s or %s", kind,
+			ContextIncludeContext, ContextIncludeFile, ContextIncludeDir, ContextIncludeURL)
+	}
+}
+
+// expandHome resolves a path that starts with ~/ in the home directory
+func expandHome(path string) (ret string, err error) {
+	ret = path
+	if rest, found := strings.CutPrefix(path, "~/"); found {
+		var home string
+		if home, err = os.UserHomeDir(); err != nil {
+			return
+		}
+		ret = filepath.Join(home, rest)
+	}
+	return
+}
+
+// isText tells whether data looks like text rather than a binary file
+func isText(data []byte) bool {
+	return bytes.IndexByte(data, 0) < 0 && utf8.Valid(data)
+}
+
+func includeFile(target string) (ret string, err error) {
+	var path string
+	if path, err = expandHome(target); err != nil {
+		return
+	}
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return "", fmt.Errorf("could not include file %s: %v", target, err)
+	}
+	if info.Size() > maxContextIncludeSize {
+		return "", fmt.Errorf("could not include file %s: it is larger than %d MiB", target, maxContextIncludeSize>>20)
+	}
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return "", fmt.Errorf("could not include file %s: %v", target, err)
+	}
+	if !isText(data) {
+		return "", fmt.Errorf("could not include file %s: it is not a text file", target)
+	}
+	ret = string(data)
+	return
+}
+
+// includeDir includes the text files of a directory that match a glob, where ** matches any
+// number of directories. Files ignored by the .gitignore files of the directory are left out.
+func includeDir(target string) (ret string, err error) {
+	base, pattern := util.SplitGlob(target)
+	var root string
+	if root, err = expandHome(base); err != nil {
+		return
+	}
+
+	var builder strings.Builder
+	files := 0
+	err = util.WalkFiles(root, func(path string, segments []string, entry os.DirEntry) error {
+		if !entry.Type().IsRegular() || !util.MatchGlob(pattern, segments) {
+			return nil
+		}
+
+		info, infoErr := entry.Info()
+		if infoErr != nil || info.Size() > maxContextIncludeSize {
+			return nil
+		}
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return readErr
+		}
+		if !isText(data) {
+			return nil
+		}
+		if files++; files > maxContextDirFiles {
+			return fmt.Errorf("it has more than %d files", maxContextDirFiles)
+		}
+		rel := strings.Join(segments, "/")
+		fmt.Fprintf(&builder, "=== %s ===\n%s\n", filepath.ToSlash(filepath.Join(base, rel)), strings.TrimRight(string(data), "\n"))
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not include directory %s: %v", target, err)
+	}
+	ret = builder.String()
+	return
+}
+
+// contextHTTPClient fetches the URLs included by contexts
+var contextHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// includeURL fetches a URL, HTML pages are reduced to their readable text by the converter
+func includeURL(target string) (ret string, err error) {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return "", fmt.Errorf("could not include URL %s: only http and https URLs can be included", target)
+	}
+	var response *http.Response
+	if response, err = contextHTTPClient.Get(target); err != nil {
+		return "", fmt.Errorf("could not include URL %s: %v", target, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not include URL %s: %s", target, response.Status)
+	}
+
+	var data []byte
+	if data, err = io.ReadAll(io.LimitReader(response.Body, maxContextIncludeSize+1)); err != nil {
+		return "", fmt.Errorf("could not include URL %s: %v", target, err)
+	}
+	if len(data) > maxContextIncludeSize {
+		return "", fmt.Errorf("could not include URL %s: it is larger than %d MiB", target, maxContextIncludeSize>>20)
+	}
+	if !isText(data) {
+		return "", fmt.Errorf("could not include URL %s: it is not text", target)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type")); mediaType == "text/html" {
+		if ret, err = converter.HtmlReadability(string(data)); err != nil {
+			return "", fmt.Errorf("could not include URL %s: %v", target, err)
+		}
+		return
+	}
+	ret = string(data)
+	return
+}
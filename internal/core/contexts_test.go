@@ -0,0 +1,218 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+func newContextsDb(t *testing.T, contexts map[string]string) *fsdb.Db {
+	t.Helper()
+	db := fsdb.NewDb(t.TempDir())
+	if err := os.MkdirAll(db.Contexts.Dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range contexts {
+		if err := db.Contexts.Save(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestLoadContexts_MergesInOrder(t *testing.T) {
+	db := newContextsDb(t, map[string]string{
+		"first":  "First context\n",
+		"second": "\nSecond context\n\n",
+	})
+
+	got, err := LoadContexts(db, []string{"second", "first"}, false)
+	if err != nil {
+		t.Fatalf("LoadContexts returned error: %v", err)
+	}
+	if want := "Second context\n\nFirst context"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadContexts_IncludesContexts(t *testing.T) {
+	db := newContextsDb(t, map[string]string{
+		"project": "Project rules\n  @include context:style\nEnd",
+		"style":   "@include context:base\nUse tabs",
+		"base":    "Be concise",
+	})
+
+	got, err := LoadContexts(db, []string{"project"}, false)
+	if err != nil {
+		t.Fatalf("LoadContexts returned error: %v", err)
+	}
+	if want := "Project rules\nBe concise\nUse tabs\nEnd"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadContexts_DetectsCycles(t *testing.T) {
+	db := newContextsDb(t, map[string]string{
+		"a": "@include context:b",
+		"b": "@include context:c",
+		"c": "@include context:a",
+	})
+
+	_, err := LoadContexts(db, []string{"a"}, false)
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Fatalf("expected a cycle error with the chain, got %v", err)
+	}
+}
+
+func TestLoadContexts_SameContextTwice(t *testing.T) {
+	db := newContextsDb(t, map[string]string{
+		"a":    "@include context:base\n@include context:base",
+		"base": "Base",
+	})
+
+	got, err := LoadContexts(db, []string{"a", "base"}, false)
+	if err != nil {
+		t.Fatalf("a context included twice is not a cycle, got %v", err)
+	}
+	if want := "Base\nBase\n\nBase"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadContexts_LocalIncludesNeedPermission(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.md")
+	writeTestFile(t, file, "secret")
+	db := newContextsDb(t, map[string]string{"notes": "@include file:" + file})
+
+	if _, err := LoadContexts(db, []string{"notes"}, false); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected file includes to be refused, got %v", err)
+	}
+
+	got, err := LoadContexts(db, []string{"notes"}, true)
+	if err != nil {
+		t.Fatalf("LoadContexts returned error: %v", err)
+	}
+	if got != "secret" {
+		t.Errorf("got %q, want %q", got, "secret")
+	}
+}
+
+func TestLoadContexts_InvalidIncludes(t *testing.T) {
+	for _, directive := range []string{"@include nothing", "@include file:", "@include disk:/tmp"} {
+		db := newContextsDb(t, map[string]string{"bad": directive})
+		if _, err := LoadContexts(db, []string{"bad"}, true); err == nil {
+			t.Errorf("expected an error for %q", directive)
+		}
+	}
+}
+
+func TestLoadContexts_FileLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "large.txt"), strings.Repeat("a", maxContextIncludeSize+1))
+	writeTestFile(t, filepath.Join(dir, "binary.bin"), "a\x00b")
+
+	for _, name := range []string{"large.txt", "binary.bin", "missing.txt"} {
+		db := newContextsDb(t, map[string]string{"ctx": "@include file:" + filepath.Join(dir, name)})
+		if _, err := LoadContexts(db, []string{"ctx"}, true); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
+
+func TestLoadContexts_TotalSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "part.txt")
+	writeTestFile(t, file, strings.Repeat("a", maxContextIncludeSize-10))
+
+	content := strings.Repeat("@include file:"+file+"\n", maxContextSize/maxContextIncludeSize+1)
+	db := newContextsDb(t, map[string]string{"ctx": content})
+	if _, err := LoadContexts(db, []string{"ctx"}, true); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("expected a size error, got %v", err)
+	}
+}
+
+func TestLoadContexts_IncludesDirectories(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ".gitignore"), "vendor/\n*.gen.go\n")
+	writeTestFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	writeTestFile(t, filepath.Join(dir, "main.gen.go"), "package generated\n")
+	writeTestFile(t, filepath.Join(dir, "README.md"), "readme\n")
+	writeTestFile(t, filepath.Join(dir, "pkg", "util.go"), "package pkg\n")
+	writeTestFile(t, filepath.Join(dir, "pkg", ".gitignore"), "skip.go\n")
+	writeTestFile(t, filepath.Join(dir, "pkg", "skip.go"), "package skip\n")
+	writeTestFile(t, filepath.Join(dir, "vendor", "lib.go"), "package lib\n")
+	writeTestFile(t, filepath.Join(dir, ".git", "config.go"), "package git\n")
+	writeTestFile(t, filepath.Join(dir, "image.go"), "\x00\x01")
+
+	db := newContextsDb(t, map[string]string{"code": "@include dir:" + dir + "/**/*.go"})
+	got, err := LoadContexts(db, []string{"code"}, true)
+	if err != nil {
+		t.Fatalf("LoadContexts returned error: %v", err)
+	}
+
+	base := filepath.ToSlash(dir)
+	want := "=== " + base + "/main.go ===\npackage main\n=== " + base + "/pkg/util.go ===\npackage pkg"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLoadContexts_IncludesDirectoriesWithRepositoryGitignore(t *testing.T) {
+	repo := t.TempDir()
+	writeTestFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeTestFile(t, filepath.Join(repo, ".gitignore"), "*.gen.go\n/internal/tmp/\n")
+	writeTestFile(t, filepath.Join(repo, "internal", "core", "core.go"), "package core\n")
+	writeTestFile(t, filepath.Join(repo, "internal", "core", "core.gen.go"), "package generated\n")
+	writeTestFile(t, filepath.Join(repo, "internal", "tmp", "scratch.go"), "package tmp\n")
+
+	dir := filepath.Join(repo, "internal")
+	db := newContextsDb(t, map[string]string{"code": "@include dir:" + dir + "/**/*.go"})
+	got, err := LoadContexts(db, []string{"code"}, true)
+	if err != nil {
+		t.Fatalf("LoadContexts returned error: %v", err)
+	}
+
+	want := "=== " + filepath.ToSlash(dir) + "/core/core.go ===\npackage core"
+	if got != want {
+		t.Errorf("expected the .gitignore of the repository to apply, got %q, want %q", got, want)
+	}
+}
+
+func TestLoadContexts_IncludesURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain notes"))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><head><title>Page</title></head><body><article><p>The readable text of the page, long enough to be kept by readability.</p></article></body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	db := newContextsDb(t, map[string]string{
+		"text":    "@include url:" + server.URL + "/notes.txt",
+		"page":    "@include url:" + server.URL + "/page",
+		"missing": "@include url:" + server.URL + "/missing",
+	})
+
+	got, err := LoadContexts(db, []string{"text"}, true)
+	if err != nil || got != "plain notes" {
+		t.Errorf("got %q, %v, want the plain text", got, err)
+	}
+	got, err = LoadContexts(db, []string{"page"}, true)
+	if err != nil || !strings.Contains(got, "readable text of the page") || strings.Contains(got, "<p>") {
+		t.Errorf("got %q, %v, want the readable text of the page", got, err)
+	}
+	if _, err = LoadContexts(db, []string{"missing"}, true); err == nil {
+		t.Error("expected an error for a missing page")
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+// fallbackTarget is a model that is tried when the requested one keeps failing
+type fallbackTarget struct {
+	vendor ai.Vendor
+	model  string
+}
+
+// sendWithFallback sends the session to the model of the chatter and, when that fails, to the
+// fallback models in order. It returns the vendor that answered and sets opts.Model to its model.
+func (o *Chatter) sendWithFallback(ctx context.Context, session *fsdb.Session, patternName string, opts *domain.ChatOptions) (
+	message string, result *domain.ChatResult, vendor ai.Vendor, err error) {
+
+	vendor = o.vendor
+	var kind ai.ErrorKind
+	if message, result, kind, err = o.sendWithRetry(ctx, vendor, session, opts); !canFallBack(ctx, message, kind, err) {
+		return
+	}
+
+	var targets []*fallbackTarget
+	var resolveErr error
+	if targets, resolveErr = o.resolveFallbacks(ctx, patternName); resolveErr != nil {
+		err = fmt.Errorf("%w (%v)", err, resolveErr)
+		return
+	}
+	for _, target := range targets {
+		fmt.Fprintf(os.Stderr, "%s|%s failed: %v\nFalling back to %s|%s\n",
+			vendor.GetName(), opts.Model, err, target.vendor.GetName(), target.model)
+
+		vendor = target.vendor
+		opts.Model = target.model
+		if message, result, kind, err = o.sendWithRetry(ctx, vendor, session, opts); !canFallBack(ctx, message, kind, err) {
+			return
+		}
+	}
+	return
+}
+
+// sendWithRetry sends the session to the vendor and retries the errors the vendor reports
+// as retryable, with exponential backoff and jitter. It returns the kind of the last error,
+// empty for errors that are not retryable.
+func (o *Chatter) sendWithRetry(ctx context.Context, vendor ai.Vendor, session *fsdb.Session, opts *domain.ChatOptions) (
+	message string, result *domain.ChatResult, kind ai.ErrorKind, err error) {
+
+	for attempt := 1; ; attempt++ {
+		if message, result, err = o.sendOnce(ctx, vendor, session, opts); err == nil || !canResend(ctx, message) {
+			kind = ""
+			return
+		}
+
+		if kind = ai.ClassifyError(vendor, err); kind == "" {
+			return
+		}
+		rule := o.retry.Rule(vendor.GetName(), kind)
+		if attempt >= rule.MaxAttempts {
+			return
+		}
+
+		delay := rule.Delay(attempt)
+		fmt.Fprintf(os.Stderr, "%s|%s: %v\nRetrying in %s (attempt %d of %d)\n",
+			vendor.GetName(), opts.Model, err, delay.Round(time.Millisecond), attempt+1, rule.MaxAttempts)
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// canResend tells whether a failed request can be sent again. It can not when it was
+// cancelled or when part of a streamed answer was already printed.
+func canResend(ctx context.Context, message string) bool {
+	return ctx.Err() == nil && message == ""
+}
+
+// canFallBack tells whether a failed request goes to the next model: only retryable errors
+// whose retries ran out do, errors like invalid requests would fail with any model
+func canFallBack(ctx context.Context, message string, kind ai.ErrorKind, err error) bool {
+	return err != nil && kind != "" && canResend(ctx, message)
+}
+
+// resolveFallbacks finds the vendors of the fallback models of the pattern, or of the default
+// fallback models if the pattern has none
+func (o *Chatter) resolveFallbacks(ctx context.Context, patternName string) (ret []*fallbackTarget, err error) {
+	names := o.fallbacks
+	if o.retry != nil && patternName != "" {
+		if patternFallbacks, ok := o.retry.Fallbacks[patternName]; ok {
+			names = patternFallbacks
+		}
+	}
+	if len(names) == 0 || o.vendorManager == nil {
+		return
+	}
+
+	var models *ai.VendorsModels
+	for _, name := range names {
+		vendorName, model, found := strings.Cut(strings.TrimSpace(name), "|")
+		if !found {
+			model = vendorName
+			if models == nil {
+				if models, err = o.vendorManager.GetModels(ctx); err != nil {
+					err = fmt.Errorf("could not list the fallback models: %w", err)
+					return
+				}
+			}
+			vendorName = models.FindGroupsByItemFirst(model)
+		}
+
+		vendor := o.vendorManager.FindByName(vendorName)
+		if vendor == nil {
+			err = fmt.Errorf("could not find the vendor of fallback model %s", name)
+			return
+		}
+		ret = append(ret, &fallbackTarget{vendor: vendor, model: model})
+	}
+	return
+}
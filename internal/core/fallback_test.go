@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+var errOverloaded = errors.New("overloaded")
+
+// classifyingMockVendor is a mock vendor with its own name that reports errOverloaded as retryable
+type classifyingMockVendor struct {
+	*mockVendor
+	name string
+}
+
+func (m *classifyingMockVendor) GetName() string {
+	return m.name
+}
+
+func (m *classifyingMockVendor) ClassifyError(err error) ai.ErrorKind {
+	if errors.Is(err, errOverloaded) {
+		return ai.ErrorKindOverloaded
+	}
+	return ""
+}
+
+func newFallbackTestVendor(name string, failures int, calls *int) *classifyingMockVendor {
+	return &classifyingMockVendor{name: name, mockVendor: &mockVendor{
+		sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+			*calls++
+			if *calls <= failures {
+				return "", errOverloaded
+			}
+			return "answer from " + o.Model, nil
+		},
+	}}
+}
+
+func newFallbackTestChatter(t *testing.T, vendor ai.Vendor, fallbacks ...ai.Vendor) *Chatter {
+	db := fsdb.NewDb(t.TempDir())
+	if err := db.Sessions.Configure(); err != nil {
+		t.Fatalf("failed to configure sessions: %v", err)
+	}
+	manager := ai.NewVendorsManager()
+	manager.AddVendors(append([]ai.Vendor{vendor}, fallbacks...)...)
+	return &Chatter{
+		db:            db,
+		vendor:        vendor,
+		model:         "primary-model",
+		retry:         &ai.RetryConfig{RetryRule: ai.RetryRule{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}},
+		vendorManager: manager,
+	}
+}
+
+func newFallbackTestRequest() *domain.ChatRequest {
+	return &domain.ChatRequest{
+		SessionName: "fallback",
+		Message:     &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "question"},
+	}
+}
+
+func TestChatter_Send_RetriesRetryableErrors(t *testing.T) {
+	var calls int
+	chatter := newFallbackTestChatter(t, newFallbackTestVendor("Primary", 2, &calls))
+
+	session, err := chatter.Send(context.Background(), newFallbackTestRequest(), &domain.ChatOptions{})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	if got := session.GetLastMessage().Content; got != "answer from primary-model" {
+		t.Errorf("unexpected answer %q", got)
+	}
+}
+
+func TestChatter_Send_FallsBackWhenRetriesRunOut(t *testing.T) {
+	var primaryCalls, fallbackCalls int
+	primary := newFallbackTestVendor("Primary", 10, &primaryCalls)
+	fallback := newFallbackTestVendor("Backup", 0, &fallbackCalls)
+	chatter := newFallbackTestChatter(t, primary, fallback)
+	chatter.fallbacks = []string{"Backup|backup-model"}
+
+	opts := &domain.ChatOptions{}
+	session, err := chatter.Send(context.Background(), newFallbackTestRequest(), opts)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if primaryCalls != 3 || fallbackCalls != 1 {
+		t.Errorf("expected 3 attempts on the primary and 1 on the fallback, got %d and %d", primaryCalls, fallbackCalls)
+	}
+	if opts.Model != "backup-model" {
+		t.Errorf("expected opts.Model to be the model that answered, got %q", opts.Model)
+	}
+
+	meta := session.GetLastMessage().Meta
+	if meta == nil || meta.Vendor != "Backup" || meta.Model != "backup-model" {
+		t.Fatalf("expected the fallback in the message meta, got %+v", meta)
+	}
+	saved, err := chatter.db.Sessions.Get("fallback")
+	if err != nil {
+		t.Fatalf("failed to load the saved session: %v", err)
+	}
+	if meta := saved.GetLastMessage().Meta; meta == nil || meta.Model != "backup-model" {
+		t.Errorf("expected the fallback in the saved session, got %+v", meta)
+	}
+}
+
+func TestChatter_ResolveFallbacks(t *testing.T) {
+	var calls int
+	chatter := newFallbackTestChatter(t, newFallbackTestVendor("Primary", 0, &calls),
+		newFallbackTestVendor("Default", 0, &calls), newFallbackTestVendor("Pattern", 0, &calls))
+	chatter.fallbacks = []string{"Default|default-model"}
+	chatter.retry.Fallbacks = map[string][]string{"summarize": {"Pattern|pattern-model"}}
+
+	targets, err := chatter.resolveFallbacks(context.Background(), "summarize")
+	if err != nil {
+		t.Fatalf("resolveFallbacks() error = %v", err)
+	}
+	if len(targets) != 1 || targets[0].vendor.GetName() != "Pattern" || targets[0].model != "pattern-model" {
+		t.Errorf("expected the pattern fallback to replace the defaults, got %+v", targets)
+	}
+
+	if targets, err = chatter.resolveFallbacks(context.Background(), "other"); err != nil {
+		t.Fatalf("resolveFallbacks() error = %v", err)
+	}
+	if len(targets) != 1 || targets[0].vendor.GetName() != "Default" || targets[0].model != "default-model" {
+		t.Errorf("expected the default fallback for other patterns, got %+v", targets)
+	}
+
+	chatter.fallbacks = []string{"Missing|model"}
+	if _, err = chatter.resolveFallbacks(context.Background(), "other"); err == nil {
+		t.Error("expected an error for a fallback of an unknown vendor")
+	}
+}
+
+func TestChatter_Send_DoesNotRetryOrFallBackOnOtherErrors(t *testing.T) {
+	var fallbackCalls, calls int
+	primary := &classifyingMockVendor{name: "Primary", mockVendor: &mockVendor{
+		sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+			calls++
+			return "", errors.New("400 Bad Request: invalid request")
+		},
+	}}
+	chatter := newFallbackTestChatter(t, primary, newFallbackTestVendor("Backup", 0, &fallbackCalls))
+	chatter.fallbacks = []string{"Backup|backup-model"}
+
+	opts := &domain.ChatOptions{}
+	if _, err := chatter.Send(context.Background(), newFallbackTestRequest(), opts); err == nil {
+		t.Fatal("expected the error of the primary model")
+	}
+	if calls != 1 || fallbackCalls != 0 {
+		t.Errorf("expected one attempt and no fallback, got %d attempts and %d fallback calls", calls, fallbackCalls)
+	}
+	if opts.Model == "backup-model" {
+		t.Error("expected opts.Model not to switch to the fallback")
+	}
+}
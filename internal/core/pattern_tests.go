@@ -0,0 +1,422 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai/mock"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+	"github.com/danielmiessler/fabric/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// judgePrompt is the system prompt of the model that grades answers against a rubric
+const judgePrompt = `You judge the answer of an AI system against a rubric.
+
+You are given the rubric, the input the system was given and its answer. Decide whether the answer meets every point of the rubric.
+
+Reply with PASS or FAIL on the first line, followed by one or two sentences with the reason.`
+
+// PatternTests are the regression tests of a pattern, read from the tests.yaml next to its system.md.
+//
+// Example:
+//
+//	model: gpt-4o
+//	cases:
+//	  - name: greeting
+//	    input: Good morning
+//	    variables:
+//	      lang: French
+//	    response: Bonjour
+//	    assert:
+//	      - contains: Bonjour
+//	      - max_length: 200
+//	      - judge:
+//	          rubric: The answer is a polite greeting in French
+type PatternTests struct {
+	// Model is the model of the cases that do not set one
+	Model string             `yaml:"model"`
+	Cases []*PatternTestCase `yaml:"cases"`
+}
+
+// PatternTestCase runs the pattern once and checks the answer with its assertions
+type PatternTestCase struct {
+	Name string `yaml:"name"`
+	// Input is the message sent to the pattern, InputFile names a file next to the pattern instead
+	Input     string            `yaml:"input"`
+	InputFile string            `yaml:"input_file"`
+	Variables map[string]string `yaml:"variables"`
+	Model     string            `yaml:"model"`
+	Strategy  string            `yaml:"strategy"`
+	// Response is the scripted answer of the mock vendor
+	Response string              `yaml:"response"`
+	Assert   []*PatternAssertion `yaml:"assert"`
+}
+
+// PatternAssertion checks one thing about an answer, exactly one of its fields is set
+type PatternAssertion struct {
+	Contains    string `yaml:"contains"`
+	NotContains string `yaml:"not_contains"`
+	Regex       string `yaml:"regex"`
+	// MaxLength is the maximum number of characters of the answer
+	MaxLength int `yaml:"max_length"`
+	// JSONSchema is a file next to the pattern or an inline schema the answer must match
+	JSONSchema any           `yaml:"json_schema"`
+	Judge      *PatternJudge `yaml:"judge"`
+}
+
+// PatternJudge lets a model grade the answer against a rubric
+type PatternJudge struct {
+	Rubric string `yaml:"rubric"`
+	// Model is the judging model, the model of the case by default
+	Model string `yaml:"model"`
+	// Response is the scripted verdict in offline runs, without it the judge is skipped there
+	Response string `yaml:"response"`
+}
+
+// PatternTestResult is the outcome of one case
+type PatternTestResult struct {
+	Pattern  string        `json:"pattern"`
+	Case     string        `json:"case"`
+	Model    string        `json:"model,omitempty"`
+	Output   string        `json:"output,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Assertions is the number of assertions of the case
+	Assertions int `json:"assertions"`
+	// Failures describe the assertions the answer did not pass
+	Failures []string `json:"failures,omitempty"`
+	// Skipped describe the assertions that could not be checked
+	Skipped []string `json:"skipped,omitempty"`
+	// Error is set when the case could not run
+	Error string `json:"error,omitempty"`
+}
+
+// Passed tells whether the case ran and passed all assertions that were checked
+func (o *PatternTestResult) Passed() bool {
+	return o.Error == "" && len(o.Failures) == 0
+}
+
+// WasSkipped tells whether none of the assertions of a passed case could be checked
+func (o *PatternTestResult) WasSkipped() bool {
+	return o.Passed() && len(o.Skipped) > 0 && len(o.Skipped) == o.Assertions
+}
+
+// describe tells what the assertion checks
+func (o *PatternAssertion) describe() string {
+	switch {
+	case o.Contains != "":
+		return fmt.Sprintf("contains %q", o.Contains)
+	case o.NotContains != "":
+		return fmt.Sprintf("does not contain %q", o.NotContains)
+	case o.Regex != "":
+		return fmt.Sprintf("matches %q", o.Regex)
+	case o.MaxLength != 0:
+		return fmt.Sprintf("is at most %d characters long", o.MaxLength)
+	case o.JSONSchema != nil:
+		return "matches the JSON schema"
+	default:
+		return "meets the rubric of the judge"
+	}
+}
+
+func (o *PatternAssertion) validate() (err error) {
+	kinds := 0
+	for _, set := range []bool{o.Contains != "", o.NotContains != "", o.Regex != "", o.MaxLength != 0,
+		o.JSONSchema != nil, o.Judge != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("an assertion needs exactly one of contains, not_contains, regex, max_length, json_schema or judge")
+	}
+	if o.Regex != "" {
+		if _, err = regexp.Compile(o.Regex); err != nil {
+			return fmt.Errorf("invalid regex %q: %v", o.Regex, err)
+		}
+	}
+	if o.MaxLength < 0 {
+		return fmt.Errorf("max_length must be positive, not %d", o.MaxLength)
+	}
+	if o.Judge != nil && strings.TrimSpace(o.Judge.Rubric) == "" {
+		return fmt.Errorf("a judge needs a rubric")
+	}
+	return
+}
+
+// Validate checks the cases and names the cases without a name by their number
+func (o *PatternTests) Validate() (err error) {
+	if len(o.Cases) == 0 {
+		return fmt.Errorf("no cases")
+	}
+	for i, testCase := range o.Cases {
+		if testCase == nil {
+			return fmt.Errorf("case %d is empty", i+1)
+		}
+		if testCase.Name == "" {
+			testCase.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if testCase.Input != "" && testCase.InputFile != "" {
+			return fmt.Errorf("case %s has both input and input_file", testCase.Name)
+		}
+		if len(testCase.Assert) == 0 {
+			return fmt.Errorf("case %s has no assertions", testCase.Name)
+		}
+		for j, assertion := range testCase.Assert {
+			if assertion == nil {
+				return fmt.Errorf("assertion %d of case %s is empty", j+1, testCase.Name)
+			}
+			if err = assertion.validate(); err != nil {
+				return fmt.Errorf("assertion %d of case %s: %v", j+1, testCase.Name, err)
+			}
+		}
+	}
+	return
+}
+
+// LoadPatternTests reads the tests of a pattern, ret is nil for a pattern without tests
+func (o *PluginRegistry) LoadPatternTests(pattern string) (ret *PatternTests, err error) {
+	var content []byte
+	if content, err = o.Db.Patterns.LoadFile(pattern, fsdb.PatternTestsFile); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	ret = &PatternTests{}
+	if err = yaml.Unmarshal(content, ret); err == nil {
+		err = ret.Validate()
+	}
+	if err != nil {
+		ret = nil
+		err = fmt.Errorf("invalid %s of pattern %s: %v", fsdb.PatternTestsFile, pattern, err)
+	}
+	return
+}
+
+// RunPatternTests runs the tests of the patterns, or of all patterns that have tests without
+// patterns. opts are the options of every case, opts.Model is the model of the cases that do
+// not name one. A dry run answers with the requests of the dryrun vendor, a mock run with the
+// scripted responses of the cases, neither calls a model. A case that cannot run is reported
+// in its result and does not stop the others.
+func (o *PluginRegistry) RunPatternTests(ctx context.Context, patterns []string, opts *domain.ChatOptions,
+	dryRun bool, mockRun bool) (ret []*PatternTestResult, err error) {
+
+	explicit := len(patterns) > 0
+	if !explicit {
+		if patterns, err = o.Db.Patterns.GetNames(); err != nil {
+			return
+		}
+	}
+
+	for _, pattern := range patterns {
+		tests, loadErr := o.LoadPatternTests(pattern)
+		if loadErr != nil {
+			ret = append(ret, &PatternTestResult{Pattern: pattern, Case: fsdb.PatternTestsFile, Error: loadErr.Error()})
+			continue
+		}
+		if tests == nil {
+			if explicit {
+				ret = append(ret, &PatternTestResult{Pattern: pattern, Case: fsdb.PatternTestsFile,
+					Error: fmt.Sprintf("pattern %s has no %s", pattern, fsdb.PatternTestsFile)})
+			}
+			continue
+		}
+		for _, testCase := range tests.Cases {
+			ret = append(ret, o.runPatternTest(ctx, pattern, tests, testCase, opts, dryRun, mockRun))
+		}
+	}
+	return
+}
+
+func (o *PluginRegistry) runPatternTest(ctx context.Context, pattern string, tests *PatternTests, testCase *PatternTestCase,
+	opts *domain.ChatOptions, dryRun bool, mockRun bool) (ret *PatternTestResult) {
+
+	ret = &PatternTestResult{Pattern: pattern, Case: testCase.Name, Model: testCase.Model, Assertions: len(testCase.Assert)}
+	for _, model := range []string{tests.Model, opts.Model} {
+		if ret.Model == "" {
+			ret.Model = model
+		}
+	}
+	start := time.Now()
+	defer func() { ret.Duration = time.Since(start) }()
+
+	input := testCase.Input
+	if testCase.InputFile != "" {
+		content, err := o.Db.Patterns.LoadFile(pattern, testCase.InputFile)
+		if err != nil {
+			ret.Error = fmt.Sprintf("could not read the input file %s: %v", testCase.InputFile, err)
+			return
+		}
+		input = string(content)
+	}
+
+	var responses []string
+	if mockRun {
+		if testCase.Response == "" {
+			ret.Error = "the case has no scripted response for a mock run"
+			return
+		}
+		responses = []string{testCase.Response}
+	}
+	chatter, err := o.getTestChatter(ctx, ret.Model, opts, testCase.Strategy, dryRun, mockRun, responses)
+	if err != nil {
+		ret.Error = err.Error()
+		return
+	}
+
+	request := &domain.ChatRequest{
+		PatternName:      pattern,
+		PatternVariables: testCase.Variables,
+		StrategyName:     testCase.Strategy,
+		Language:         o.Language.DefaultLanguage.Value,
+		Message:          &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: input},
+	}
+	caseOpts := *opts
+	caseOpts.Model = ret.Model
+
+	var session *fsdb.Session
+	if session, err = chatter.Send(ctx, request, &caseOpts); err != nil {
+		ret.Error = err.Error()
+		return
+	}
+	ret.Model = caseOpts.Model
+	ret.Output = session.GetLastMessage().Content
+
+	for _, assertion := range testCase.Assert {
+		failure, skipped := o.checkAssertion(ctx, pattern, assertion, input, ret, opts, dryRun || mockRun)
+		if skipped != "" {
+			ret.Skipped = append(ret.Skipped, skipped)
+		} else if failure != "" {
+			ret.Failures = append(ret.Failures, failure)
+		}
+	}
+	return
+}
+
+// getTestChatter returns the chatter of a case. Offline runs never reach a model, neither
+// through the vendor nor through fallback models.
+func (o *PluginRegistry) getTestChatter(ctx context.Context, model string, opts *domain.ChatOptions, strategy string,
+	dryRun bool, mockRun bool, responses []string) (ret *Chatter, err error) {
+
+	if ret, err = o.GetChatter(ctx, model, opts.ModelContextLength, strategy, false, dryRun || mockRun); err != nil {
+		return
+	}
+	if dryRun || mockRun {
+		ret.fallbacks = nil
+		ret.cache = nil
+	}
+	if mockRun {
+		ret.vendor = mock.NewClient(responses...)
+	}
+	return
+}
+
+// checkAssertion checks an answer, it returns why it failed or why it was skipped
+func (o *PluginRegistry) checkAssertion(ctx context.Context, pattern string, assertion *PatternAssertion, input string,
+	result *PatternTestResult, opts *domain.ChatOptions, offline bool) (failure string, skipped string) {
+
+	output := result.Output
+	switch {
+	case assertion.Contains != "":
+		if !strings.Contains(output, assertion.Contains) {
+			failure = fmt.Sprintf("does not contain %q", assertion.Contains)
+		}
+	case assertion.NotContains != "":
+		if strings.Contains(output, assertion.NotContains) {
+			failure = fmt.Sprintf("contains %q", assertion.NotContains)
+		}
+	case assertion.Regex != "":
+		if !regexp.MustCompile(assertion.Regex).MatchString(output) {
+			failure = fmt.Sprintf("does not match %q", assertion.Regex)
+		}
+	case assertion.MaxLength != 0:
+		if length := utf8.RuneCountInString(output); length > assertion.MaxLength {
+			failure = fmt.Sprintf("is %d characters long, more than %d", length, assertion.MaxLength)
+		}
+	case assertion.JSONSchema != nil:
+		failure = o.checkJSONSchema(pattern, assertion.JSONSchema, output, opts)
+	case assertion.Judge != nil:
+		if offline && assertion.Judge.Response == "" {
+			skipped = "judge: no scripted verdict in an offline run"
+			return
+		}
+		failure = o.judge(ctx, assertion.Judge, input, result, opts, offline)
+	}
+	return
+}
+
+func (o *PluginRegistry) checkJSONSchema(pattern string, schemaSource any, output string, opts *domain.ChatOptions) (failure string) {
+	var data []byte
+	var err error
+	if file, ok := schemaSource.(string); ok {
+		data, err = o.Db.Patterns.LoadFile(pattern, file)
+	} else {
+		data, err = json.Marshal(schemaSource)
+	}
+	var schema *util.JSONSchema
+	if err == nil {
+		schema, err = util.ParseJSONSchema(data)
+	}
+	if err != nil {
+		return fmt.Sprintf("invalid JSON schema: %v", err)
+	}
+	if err = schema.Validate([]byte(extractJSON(output, opts))); err != nil {
+		return fmt.Sprintf("does not match the JSON schema: %v", err)
+	}
+	return
+}
+
+// judge asks the judging model whether the answer meets the rubric
+func (o *PluginRegistry) judge(ctx context.Context, judge *PatternJudge, input string, result *PatternTestResult,
+	opts *domain.ChatOptions, offline bool) (failure string) {
+
+	model := judge.Model
+	if model == "" {
+		model = result.Model
+	}
+	var responses []string
+	if offline {
+		responses = []string{judge.Response}
+	}
+	chatter, err := o.getTestChatter(ctx, model, opts, "", false, offline, responses)
+	if err != nil {
+		return fmt.Sprintf("judge: %v", err)
+	}
+
+	messages := []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleSystem, Content: judgePrompt},
+		{Role: chat.ChatMessageRoleUser, Content: fmt.Sprintf("# RUBRIC\n\n%s\n\n# INPUT\n\n%s\n\n# ANSWER\n\n%s",
+			judge.Rubric, input, result.Output)},
+	}
+	judgeOpts := *opts
+	judgeOpts.Model = chatter.model
+	judgeOpts.Temperature = 0
+	judgeOpts.Tools = nil
+	judgeOpts.JSONSchema = nil
+
+	var verdict *domain.ChatResult
+	if verdict, err = chatter.vendor.Send(ctx, messages, &judgeOpts); err != nil {
+		return fmt.Sprintf("judge: %v", err)
+	}
+	content := strings.TrimSpace(domain.StripThinkBlocks(verdict.Content, opts.ThinkStartTag, opts.ThinkEndTag))
+	first, reason, _ := strings.Cut(content, "\n")
+	first = strings.ToUpper(strings.Trim(strings.TrimSpace(first), "*#. "))
+	switch {
+	case strings.HasPrefix(first, "PASS"):
+		return
+	case strings.HasPrefix(first, "FAIL"):
+		return fmt.Sprintf("the judge failed the answer: %s", strings.TrimSpace(reason))
+	default:
+		return fmt.Sprintf("the judge gave no verdict: %s", content)
+	}
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+const testPatternTests = `model: case-model
+cases:
+  - name: greeting
+    input: Good morning
+    variables:
+      lang: French
+    response: Bonjour
+    assert:
+      - contains: Bonjour
+      - max_length: 10
+      - judge:
+          rubric: A greeting in French
+          response: "PASS\nIt is French"
+  - name: json
+    input_file: article.txt
+    variables:
+      lang: German
+    response: '{"text": 3}'
+    assert:
+      - regex: '^\{'
+      - json_schema:
+          type: object
+          properties:
+            text: {type: string}
+      - judge:
+          rubric: A greeting in German
+          response: "FAIL\nIt is a number"
+  - name: unjudged
+    input: Hi
+    variables:
+      lang: English
+    response: Hello
+    assert:
+      - judge:
+          rubric: A greeting in English
+`
+
+func newPatternTestsRegistry(t *testing.T) *PluginRegistry {
+	t.Helper()
+	db := fsdb.NewDb(t.TempDir())
+	patternDir := filepath.Join(db.Patterns.Dir, "translate")
+	writeTestFile(t, filepath.Join(patternDir, db.Patterns.SystemPatternFile), "Translate into {{lang}}.")
+	writeTestFile(t, filepath.Join(patternDir, fsdb.PatternTestsFile), testPatternTests)
+	writeTestFile(t, filepath.Join(patternDir, "article.txt"), "Guten Morgen")
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "untested", db.Patterns.SystemPatternFile), "No tests")
+
+	registry, err := NewPluginRegistry(db)
+	if err != nil {
+		t.Fatalf("NewPluginRegistry() error = %v", err)
+	}
+	return registry
+}
+
+func TestPluginRegistry_RunPatternTests_Mock(t *testing.T) {
+	registry := newPatternTestsRegistry(t)
+
+	results, err := registry.RunPatternTests(context.Background(), nil, &domain.ChatOptions{}, false, true)
+	if err != nil {
+		t.Fatalf("RunPatternTests() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected the cases of the only pattern with tests, got %d results", len(results))
+	}
+
+	greeting, schema, unjudged := results[0], results[1], results[2]
+	if !greeting.Passed() || greeting.Output != "Bonjour" || greeting.Model != "case-model" {
+		t.Errorf("expected the greeting to pass with the model of the tests, got %+v", greeting)
+	}
+	if schema.Passed() || len(schema.Failures) != 2 {
+		t.Fatalf("expected the schema and the judge to fail, got %+v", schema)
+	}
+	if !strings.Contains(schema.Failures[0], "$.text: expected string") || schema.Failures[1] != "the judge failed the answer: It is a number" {
+		t.Errorf("unexpected failures %q", schema.Failures)
+	}
+	if !unjudged.Passed() || !unjudged.WasSkipped() {
+		t.Errorf("expected a judge without a scripted verdict to be skipped, got %+v", unjudged)
+	}
+}
+
+func TestPluginRegistry_RunPatternTests_DryRun(t *testing.T) {
+	registry := newPatternTestsRegistry(t)
+
+	results, err := registry.RunPatternTests(context.Background(), []string{"translate"}, &domain.ChatOptions{}, true, false)
+	if err != nil {
+		t.Fatalf("RunPatternTests() error = %v", err)
+	}
+	// The dryrun vendor answers with the request, so assertions see the prompt
+	if !strings.Contains(results[1].Output, "Translate into German.") || !strings.Contains(results[1].Output, "Guten Morgen") {
+		t.Errorf("expected the prompt with the variables and the input file, got %q", results[1].Output)
+	}
+
+	results, _ = registry.RunPatternTests(context.Background(), []string{"untested"}, &domain.ChatOptions{}, true, false)
+	if len(results) != 1 || !strings.Contains(results[0].Error, "has no tests.yaml") {
+		t.Errorf("expected an error for a named pattern without tests, got %+v", results)
+	}
+}
+
+func TestPatternTests_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no cases", "cases: []", "no cases"},
+		{"no assertions", "cases:\n  - input: hi", "case case 1 has no assertions"},
+		{"two kinds", "cases:\n  - assert:\n      - contains: a\n        regex: b", "exactly one of"},
+		{"invalid regex", "cases:\n  - assert:\n      - regex: '('", "invalid regex"},
+		{"judge without rubric", "cases:\n  - assert:\n      - judge:\n          model: gpt-4o", "needs a rubric"},
+		{"two inputs", "cases:\n  - input: a\n    input_file: b.txt\n    assert:\n      - contains: a", "both input and input_file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := fsdb.NewDb(t.TempDir())
+			writeTestFile(t, filepath.Join(db.Patterns.Dir, "broken", db.Patterns.SystemPatternFile), "Prompt")
+			writeTestFile(t, filepath.Join(db.Patterns.Dir, "broken", fsdb.PatternTestsFile), tt.content)
+			registry := &PluginRegistry{Db: db}
+
+			if _, err := registry.LoadPatternTests("broken"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error with %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+	"gopkg.in/yaml.v3"
+)
+
+// Pipeline is an ordered list of pattern steps. The output of a step is the input of
+// the next one unless the step sets feed to false.
+//
+// Example:
+//
+//	name: wisdom_to_tweet
+//	steps:
+//	  - pattern: extract_wisdom
+//	    model: gpt-4o
+//	    output: wisdom.md
+//	  - pattern: create_tweet
+//	    variables:
+//	      tone: friendly
+type Pipeline struct {
+	Name  string          `yaml:"name" json:"name"`
+	Steps []*PipelineStep `yaml:"steps" json:"steps"`
+}
+
+// PipelineStep runs one pattern. Empty fields use the defaults of the run.
+type PipelineStep struct {
+	Name      string            `yaml:"name" json:"name,omitempty"`
+	Pattern   string            `yaml:"pattern" json:"pattern"`
+	Model     string            `yaml:"model" json:"model,omitempty"`
+	Context   string            `yaml:"context" json:"context,omitempty"`
+	Strategy  string            `yaml:"strategy" json:"strategy,omitempty"`
+	Variables map[string]string `yaml:"variables" json:"variables,omitempty"`
+	// Feed sets whether the output is the input of the next step, default true.
+	// When false the next step gets the same input as this step.
+	Feed *bool `yaml:"feed" json:"feed,omitempty"`
+	// Output is an optional file the output of the step is saved to
+	Output string `yaml:"output" json:"output,omitempty"`
+}
+
+// PipelineStepResult is the outcome of one step of a pipeline run
+type PipelineStepResult struct {
+	Step    string      `json:"step"`
+	Pattern string      `json:"pattern"`
+	Model   string      `json:"model"`
+	Output  string      `json:"output"`
+	Usage   *chat.Usage `json:"usage,omitempty"`
+}
+
+// FeedsNext tells whether the output of the step is the input of the next step
+func (o *PipelineStep) FeedsNext() bool {
+	return o.Feed == nil || *o.Feed
+}
+
+// Validate checks the pipeline and fills in the default step names
+func (o *Pipeline) Validate() (err error) {
+	if len(o.Steps) == 0 {
+		return fmt.Errorf("pipeline %s has no steps", o.Name)
+	}
+	for i, step := range o.Steps {
+		if step == nil || step.Pattern == "" {
+			return fmt.Errorf("step %d of pipeline %s has no pattern", i+1, o.Name)
+		}
+		if step.Name == "" {
+			step.Name = step.Pattern
+		}
+	}
+	return
+}
+
+// LoadPipeline reads and validates a pipeline file
+func LoadPipeline(path string) (ret *Pipeline, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+	ret = &Pipeline{}
+	if err = yaml.Unmarshal(data, ret); err != nil {
+		err = fmt.Errorf("failed to parse pipeline %s: %w", path, err)
+		return
+	}
+	if ret.Name == "" {
+		ret.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	err = ret.Validate()
+	return
+}
+
+// GetPipelinesDir returns the directory of the named pipelines
+func (o *PluginRegistry) GetPipelinesDir() string {
+	return filepath.Join(o.Db.Dir, "pipelines")
+}
+
+// LoadPipeline loads a pipeline from a file path or by name from the pipelines directory
+func (o *PluginRegistry) LoadPipeline(nameOrPath string) (ret *Pipeline, err error) {
+	if _, statErr := os.Stat(nameOrPath); statErr == nil {
+		return LoadPipeline(nameOrPath)
+	}
+	return o.LoadNamedPipeline(nameOrPath)
+}
+
+// LoadNamedPipeline loads a pipeline by name from the pipelines directory only
+func (o *PluginRegistry) LoadNamedPipeline(name string) (ret *Pipeline, err error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		err = fmt.Errorf("invalid pipeline name: %s", name)
+		return
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(o.GetPipelinesDir(), name+ext)
+		if _, statErr := os.Stat(path); statErr == nil {
+			return LoadPipeline(path)
+		}
+	}
+	err = fmt.Errorf("pipeline %s not found in %s", name, o.GetPipelinesDir())
+	return
+}
+
+// RunPipeline runs the steps of the pipeline in order, starting with input. opts are the
+// options of every step, a step model overrides opts.Model. In a dry run every step shows
+// its prompt and the next step gets a placeholder instead of a model answer.
+// onStep, if set, is called with the index and the result of each step.
+func (o *PluginRegistry) RunPipeline(ctx context.Context, pipeline *Pipeline, input string, opts *domain.ChatOptions,
+	dryRun bool, onStep func(int, *PipelineStepResult) error) (ret []*PipelineStepResult, err error) {
+
+	if err = pipeline.Validate(); err != nil {
+		return
+	}
+
+	for i, step := range pipeline.Steps {
+		model := step.Model
+		if model == "" {
+			model = opts.Model
+		}
+
+		var chatter *Chatter
+		if chatter, err = o.GetChatter(ctx, model, opts.ModelContextLength, step.Strategy, false, dryRun); err != nil {
+			return
+		}
+
+		var contextNames []string
+		if step.Context != "" {
+			contextNames = []string{step.Context}
+		}
+		request := &domain.ChatRequest{
+			ContextNames:     contextNames,
+			PatternName:      step.Pattern,
+			PatternVariables: step.Variables,
+			StrategyName:     step.Strategy,
+			Language:         o.Language.DefaultLanguage.Value,
+			Message:          &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: input},
+		}
+		stepOpts := *opts
+		stepOpts.Model = model
+
+		var session *fsdb.Session
+		if session, err = chatter.Send(ctx, request, &stepOpts); err != nil {
+			err = fmt.Errorf("step %d (%s) of pipeline %s failed: %w", i+1, step.Name, pipeline.Name, err)
+			return
+		}
+
+		last := session.GetLastMessage()
+		result := &PipelineStepResult{
+			Step:    step.Name,
+			Pattern: step.Pattern,
+			Model:   stepOpts.Model,
+			Output:  last.Content,
+		}
+		if last.Meta != nil {
+			result.Usage = last.Meta.Usage
+		}
+		ret = append(ret, result)
+
+		if onStep != nil {
+			if err = onStep(i, result); err != nil {
+				return
+			}
+		}
+
+		if step.FeedsNext() {
+			if dryRun {
+				input = fmt.Sprintf("[output of step %d (%s)]", i+1, step.Name)
+			} else {
+				input = result.Output
+			}
+		}
+	}
+	return
+}
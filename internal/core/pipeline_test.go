@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadPipeline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "review.yaml")
+	writeTestFile(t, path, "steps:\n  - pattern: summarize\n  - name: tweet\n    pattern: create_tweet\n    feed: false\n")
+
+	pipeline, err := LoadPipeline(path)
+	if err != nil {
+		t.Fatalf("LoadPipeline() error = %v", err)
+	}
+	if pipeline.Name != "review" {
+		t.Errorf("expected the file name as pipeline name, got %s", pipeline.Name)
+	}
+	if pipeline.Steps[0].Name != "summarize" || !pipeline.Steps[0].FeedsNext() {
+		t.Errorf("expected the pattern as step name and feeding by default, got %+v", pipeline.Steps[0])
+	}
+	if pipeline.Steps[1].Name != "tweet" || pipeline.Steps[1].FeedsNext() {
+		t.Errorf("expected a named step that does not feed the next one, got %+v", pipeline.Steps[1])
+	}
+}
+
+func TestLoadPipeline_StepWithoutPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.yaml")
+	writeTestFile(t, path, "steps:\n  - model: gpt-4o\n")
+
+	if _, err := LoadPipeline(path); err == nil {
+		t.Fatal("expected an error for a step without a pattern")
+	}
+}
+
+func TestPluginRegistry_LoadNamedPipeline_RejectsPaths(t *testing.T) {
+	registry := &PluginRegistry{Db: fsdb.NewDb(t.TempDir())}
+	for _, name := range []string{"../secret", "a/b", ".hidden", ""} {
+		if _, err := registry.LoadNamedPipeline(name); err == nil {
+			t.Errorf("expected an error for pipeline name %q", name)
+		}
+	}
+}
+
+func TestPluginRegistry_RunPipeline_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	db := fsdb.NewDb(dir)
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "first", db.Patterns.SystemPatternFile), "FIRST PATTERN")
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "second", db.Patterns.SystemPatternFile), "SECOND PATTERN")
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "third", db.Patterns.SystemPatternFile), "THIRD PATTERN")
+
+	registry, err := NewPluginRegistry(db)
+	if err != nil {
+		t.Fatalf("NewPluginRegistry() error = %v", err)
+	}
+
+	noFeed := false
+	pipeline := &Pipeline{Name: "test", Steps: []*PipelineStep{
+		{Pattern: "first"},
+		{Pattern: "second", Feed: &noFeed},
+		{Pattern: "third", Model: "step-model"},
+	}}
+
+	var seen []string
+	results, err := registry.RunPipeline(context.Background(), pipeline, "original input",
+		&domain.ChatOptions{Model: "default-model"}, true, func(_ int, result *PipelineStepResult) error {
+			seen = append(seen, result.Step)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("RunPipeline() error = %v", err)
+	}
+
+	if strings.Join(seen, ",") != "first,second,third" {
+		t.Errorf("expected a callback for every step, got %v", seen)
+	}
+	if !strings.Contains(results[0].Output, "FIRST PATTERN") || !strings.Contains(results[0].Output, "original input") {
+		t.Errorf("expected the first prompt to hold the pattern and the input, got %q", results[0].Output)
+	}
+	if !strings.Contains(results[1].Output, "[output of step 1 (first)]") {
+		t.Errorf("expected the second step to get the first output, got %q", results[1].Output)
+	}
+	if !strings.Contains(results[2].Output, "[output of step 1 (first)]") {
+		t.Errorf("expected the third step to get the input of the non feeding second step, got %q", results[2].Output)
+	}
+	if results[0].Model != "default-model" || results[2].Model != "step-model" {
+		t.Errorf("expected the step model to override the default, got %s and %s", results[0].Model, results[2].Model)
+	}
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -22,6 +23,7 @@ import (
 	"github.com/danielmiessler/fabric/internal/plugins/ai/openai_compatible"
 	"github.com/danielmiessler/fabric/internal/plugins/ai/perplexity" // Added Perplexity plugin
 	"github.com/danielmiessler/fabric/internal/plugins/strategy"
+	"github.com/danielmiessler/fabric/internal/plugins/toolcall"
 
 	"github.com/samber/lo"
 
@@ -33,6 +35,7 @@ import (
 	"github.com/danielmiessler/fabric/internal/tools/custom_patterns"
 	"github.com/danielmiessler/fabric/internal/tools/jina"
 	"github.com/danielmiessler/fabric/internal/tools/lang"
+	"github.com/danielmiessler/fabric/internal/tools/storage"
 	"github.com/danielmiessler/fabric/internal/tools/youtube"
 	"github.com/danielmiessler/fabric/internal/util"
 )
@@ -75,6 +78,7 @@ func NewPluginRegistry(db *fsdb.Db) (ret *PluginRegistry, err error) {
 		VendorsAll:     ai.NewVendorsManager(),
 		PatternsLoader: tools.NewPatternsLoader(db.Patterns),
 		CustomPatterns: custom_patterns.NewCustomPatterns(),
+		Storage:        storage.NewStorage(),
 		YouTube:        youtube.NewYouTube(),
 		Language:       lang.NewLanguage(),
 		Jina:           jina.NewClient(),
@@ -86,6 +90,11 @@ func NewPluginRegistry(db *fsdb.Db) (ret *PluginRegistry, err error) {
 		return
 	}
 	ret.TemplateExtensions = template.NewExtensionManager(filepath.Join(homedir, ".config/fabric"))
+	ret.TemplatePlugins = template.Plugins
+	if err = ret.TemplatePlugins.LoadConfig(filepath.Join(homedir, ".config/fabric", template.PluginsConfigFile)); err != nil {
+		return
+	}
+	ret.Tools = toolcall.NewToolsManager(filepath.Join(homedir, ".config/fabric/tools"), ret.TemplateExtensions)
 
 	ret.Defaults = tools.NeeDefaults(ret.GetModels)
 
@@ -145,11 +154,19 @@ type PluginRegistry struct {
 	Defaults           *tools.Defaults
 	PatternsLoader     *tools.PatternsLoader
 	CustomPatterns     *custom_patterns.CustomPatterns
+	Storage            *storage.Storage
 	YouTube            *youtube.YouTube
 	Language           *lang.Language
 	Jina               *jina.Client
 	TemplateExtensions *template.ExtensionManager
+	TemplatePlugins    *template.TemplatePluginRegistry
 	Strategies         *strategy.StrategiesManager
+	Tools              *toolcall.ToolsManager
+	// ResponseCache is used by all chatters when set, caching is off otherwise
+	ResponseCache *ai.ResponseCache
+	// ContextIncludes allows contexts to include files, directories and URLs. It is only set for
+	// the command line, as clients of the REST API can write contexts but not read the host.
+	ContextIncludes bool
 }
 
 func (o *PluginRegistry) SaveEnvFile() (err error) {
@@ -159,6 +176,7 @@ func (o *PluginRegistry) SaveEnvFile() (err error) {
 	o.Defaults.Settings.FillEnvFileContent(&envFileContent)
 	o.PatternsLoader.SetupFillEnvFileContent(&envFileContent)
 	o.CustomPatterns.SetupFillEnvFileContent(&envFileContent)
+	o.Storage.SetupFillEnvFileContent(&envFileContent)
 	o.Strategies.SetupFillEnvFileContent(&envFileContent)
 
 	for _, vendor := range o.VendorManager.Vendors {
@@ -191,7 +209,7 @@ func (o *PluginRegistry) Setup() (err error) {
 			return vendor
 		})...)
 
-	groupsPlugins.AddGroupItems("Tools", o.CustomPatterns, o.Defaults, o.Jina, o.Language, o.PatternsLoader, o.Strategies, o.YouTube)
+	groupsPlugins.AddGroupItems("Tools", o.CustomPatterns, o.Defaults, o.Jina, o.Language, o.PatternsLoader, o.Storage, o.Strategies, o.YouTube)
 
 	for {
 		groupsPlugins.Print(false)
@@ -253,9 +271,9 @@ func (o *PluginRegistry) ConfigureVendors() {
 	}
 }
 
-func (o *PluginRegistry) GetModels() (ret *ai.VendorsModels, err error) {
+func (o *PluginRegistry) GetModels(ctx context.Context) (ret *ai.VendorsModels, err error) {
 	o.ConfigureVendors()
-	ret, err = o.VendorManager.GetModels()
+	ret, err = o.VendorManager.GetModels(ctx)
 	return
 }
 
@@ -267,6 +285,7 @@ func (o *PluginRegistry) Configure() (err error) {
 		return fmt.Errorf("error configuring CustomPatterns: %w", err)
 	}
 	_ = o.PatternsLoader.Configure()
+	_ = o.Storage.Configure()
 
 	// Refresh the database custom patterns directory after custom patterns plugin is configured
 	customPatternsDir := os.Getenv("CUSTOM_PATTERNS_DIRECTORY")
@@ -288,11 +307,15 @@ func (o *PluginRegistry) Configure() (err error) {
 	return
 }
 
-func (o *PluginRegistry) GetChatter(model string, modelContextLength int, strategy string, stream bool, dryRun bool) (ret *Chatter, err error) {
+func (o *PluginRegistry) GetChatter(ctx context.Context, model string, modelContextLength int, strategy string, stream bool, dryRun bool) (ret *Chatter, err error) {
 	ret = &Chatter{
 		db:     o.Db,
 		Stream: stream,
 		DryRun: dryRun,
+		tools:  o.Tools,
+		cache:  o.ResponseCache,
+
+		contextIncludes: o.ContextIncludes,
 	}
 
 	defaultModel := o.Defaults.Model.Value
@@ -306,10 +329,24 @@ func (o *PluginRegistry) GetChatter(model string, modelContextLength int, strate
 	}
 
 	ret.modelContextLength = modelContextLength
+	ret.modelContextLengthGiven = modelContextLength != 0
 	if ret.modelContextLength == 0 {
 		ret.modelContextLength = defaultModelContextLength
 	}
 
+	if ret.contextLimits, err = ai.LoadContextLimits(filepath.Join(o.Db.Dir, "context_limits.yaml")); err != nil {
+		return
+	}
+	if ret.retry, err = ai.LoadRetryConfig(filepath.Join(o.Db.Dir, "retry.yaml")); err != nil {
+		return
+	}
+	ret.vendorManager = vendorManager
+	for _, name := range strings.Split(o.Defaults.FallbackModels.Value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ret.fallbacks = append(ret.fallbacks, name)
+		}
+	}
+
 	if dryRun {
 		ret.vendor = dryrun.NewClient()
 		ret.model = model
@@ -321,7 +358,7 @@ func (o *PluginRegistry) GetChatter(model string, modelContextLength int, strate
 		ret.model = defaultModel
 	} else {
 		var models *ai.VendorsModels
-		if models, err = vendorManager.GetModels(); err != nil {
+		if models, err = vendorManager.GetModels(ctx); err != nil {
 			return
 		}
 		ret.vendor = vendorManager.FindByName(models.FindGroupsByItemFirst(model))
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -19,3 +20,27 @@ func TestSaveEnvFile(t *testing.T) {
 		t.Fatalf("SaveEnvFile() error = %v", err)
 	}
 }
+
+func TestGetChatter_ModelContextLength(t *testing.T) {
+	registry, err := NewPluginRegistry(fsdb.NewDb(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewPluginRegistry() error = %v", err)
+	}
+	registry.Defaults.ModelContextLength.Value = "4096"
+
+	chatter, err := registry.GetChatter(context.Background(), "gpt-4o", 0, "", false, true)
+	if err != nil {
+		t.Fatalf("GetChatter() error = %v", err)
+	}
+	if chatter.modelContextLength != 4096 || chatter.modelContextLengthGiven {
+		t.Errorf("expected the default context length, got %d (given %v)", chatter.modelContextLength, chatter.modelContextLengthGiven)
+	}
+
+	// A given length that equals the default is still given
+	if chatter, err = registry.GetChatter(context.Background(), "gpt-4o", 4096, "", false, true); err != nil {
+		t.Fatalf("GetChatter() error = %v", err)
+	}
+	if !chatter.modelContextLengthGiven {
+		t.Error("expected the context length to be given")
+	}
+}
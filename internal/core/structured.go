@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
+	"github.com/danielmiessler/fabric/internal/util"
+)
+
+// DefaultJSONSchemaRetries is how often the model is asked to fix an invalid structured answer
+// when the caller does not choose
+const DefaultJSONSchemaRetries = 2
+
+// sendStructured asks the vendor for an answer that matches opts.JSONSchema. Vendors with
+// structured output constrain the answer natively, all answers are validated locally and an
+// invalid answer is sent back with the validation error up to opts.JSONSchemaRetries times.
+func (o *Chatter) sendStructured(ctx context.Context, vendor ai.Vendor, messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (
+	ret *domain.ChatResult, err error) {
+
+	var schema *util.JSONSchema
+	if schema, err = util.ParseJSONSchema(opts.JSONSchema); err != nil {
+		return
+	}
+
+	messages = withSchemaInstruction(messages, string(opts.JSONSchema))
+	usage := &chat.Usage{}
+	for attempt := 0; ; attempt++ {
+		if ret, err = vendor.Send(ctx, messages, opts); err != nil {
+			return
+		}
+		usage.Add(ret.Usage)
+
+		answer := extractJSON(ret.Content, opts)
+		validationErr := schema.Validate([]byte(answer))
+		if validationErr == nil {
+			ret.Content = answer
+			if usage.TotalTokens > 0 {
+				ret.Usage = usage
+			}
+			return
+		}
+		if attempt >= opts.JSONSchemaRetries {
+			err = fmt.Errorf("the answer of %s is not valid after %d attempts: %v", opts.Model, attempt+1, validationErr)
+			return
+		}
+
+		messages = append(messages,
+			&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: ret.Content},
+			&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: fmt.Sprintf(
+				"Your answer is not valid: %v\n\nReply again with only the corrected JSON.", validationErr)},
+		)
+	}
+}
+
+// withSchemaInstruction asks for JSON that matches the schema at the end of the last user message,
+// which also guides vendors without native structured output
+func withSchemaInstruction(messages []*chat.ChatCompletionMessage, schema string) (ret []*chat.ChatCompletionMessage) {
+	instruction := "Reply only with JSON, without any other text or code fences, that matches this JSON schema:\n" + schema
+
+	ret = append(ret, messages...)
+	for i := len(ret) - 1; i >= 0; i-- {
+		if ret[i].Role != chat.ChatMessageRoleUser {
+			continue
+		}
+		message := *ret[i]
+		if len(message.MultiContent) > 0 {
+			message.MultiContent = append(append([]chat.ChatMessagePart{}, message.MultiContent...),
+				chat.ChatMessagePart{Type: chat.ChatMessagePartTypeText, Text: instruction})
+		} else {
+			message.Content = strings.TrimSpace(message.Content + "\n\n" + instruction)
+		}
+		ret[i] = &message
+		return
+	}
+	return append(ret, &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: instruction})
+}
+
+// extractJSON removes think blocks and Markdown code fences around the JSON of an answer
+func extractJSON(answer string, opts *domain.ChatOptions) string {
+	answer = strings.TrimSpace(domain.StripThinkBlocks(answer, opts.ThinkStartTag, opts.ThinkEndTag))
+	if strings.HasPrefix(answer, "```") && strings.HasSuffix(answer, "```") {
+		answer = strings.TrimSuffix(answer, "```")
+		if _, body, found := strings.Cut(answer, "\n"); found {
+			answer = body
+		}
+	}
+	return strings.TrimSpace(answer)
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+const testScoreSchema = `{"type": "object", "required": ["score"], "properties": {"score": {"type": "integer"}}}`
+
+// newStructuredTestVendor answers with the given answers in order and records the messages of every call
+func newStructuredTestVendor(answers []string, calls *[][]*chat.ChatCompletionMessage) *mockVendor {
+	return &mockVendor{
+		usage: &chat.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		sendFunc: func(ctx context.Context, msgs []*chat.ChatCompletionMessage, o *domain.ChatOptions) (string, error) {
+			*calls = append(*calls, msgs)
+			return answers[min(len(*calls), len(answers))-1], nil
+		},
+	}
+}
+
+func TestChatter_Send_RepairsInvalidJSON(t *testing.T) {
+	var calls [][]*chat.ChatCompletionMessage
+	vendor := newStructuredTestVendor([]string{`{"score": "high"}`, "```json\n{\"score\": 8}\n```"}, &calls)
+	chatter := &Chatter{db: fsdb.NewDb(t.TempDir()), vendor: vendor, model: "test-model"}
+
+	request := &domain.ChatRequest{Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "Rate this"}}
+	session, err := chatter.Send(context.Background(), request, &domain.ChatOptions{JSONSchema: []byte(testScoreSchema), JSONSchemaRetries: 2})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	last := session.GetLastMessage()
+	if last.Content != `{"score": 8}` {
+		t.Errorf("expected the repaired JSON without code fences, got %q", last.Content)
+	}
+	if last.Meta.Usage == nil || last.Meta.Usage.TotalTokens != 30 {
+		t.Errorf("expected the usage of both attempts, got %+v", last.Meta.Usage)
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if !strings.Contains(calls[0][len(calls[0])-1].Content, testScoreSchema) {
+		t.Error("expected the schema in the request")
+	}
+	repair := calls[1][len(calls[1])-1]
+	if repair.Role != chat.ChatMessageRoleUser || !strings.Contains(repair.Content, "$.score: expected integer, got string") {
+		t.Errorf("expected the validation error to be sent back, got %q", repair.Content)
+	}
+	if len(session.Messages) != 2 || session.Messages[0].Content != "Rate this" {
+		t.Error("expected the schema instruction and the repair not to be saved in the session")
+	}
+}
+
+func TestChatter_Send_FailsAfterJSONRetries(t *testing.T) {
+	var calls [][]*chat.ChatCompletionMessage
+	vendor := newStructuredTestVendor([]string{"not json"}, &calls)
+	chatter := &Chatter{db: fsdb.NewDb(t.TempDir()), vendor: vendor, model: "test-model"}
+
+	request := &domain.ChatRequest{Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "Rate this"}}
+	_, err := chatter.Send(context.Background(), request, &domain.ChatOptions{JSONSchema: []byte(testScoreSchema), JSONSchemaRetries: 1})
+	if err == nil || !strings.Contains(err.Error(), "not valid after 2 attempts") {
+		t.Errorf("expected an error after 2 attempts, got %v", err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("expected 2 calls, got %d", len(calls))
+	}
+}
+
+func TestChatter_Send_UsesPatternSchema(t *testing.T) {
+	db := fsdb.NewDb(t.TempDir())
+	patternDir := filepath.Join(db.Patterns.Dir, "rate")
+	if err := os.MkdirAll(patternDir, 0755); err != nil {
+		t.Fatalf("failed to create pattern: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(patternDir, "system.md"), []byte("Rate {{input}}"), 0644); err != nil {
+		t.Fatalf("failed to write pattern: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(patternDir, fsdb.PatternSchemaFile), []byte(testScoreSchema), 0644); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+
+	var calls [][]*chat.ChatCompletionMessage
+	chatter := &Chatter{db: db, vendor: newStructuredTestVendor([]string{"oops", `{"score": 3}`}, &calls), model: "test-model"}
+
+	request := &domain.ChatRequest{PatternName: "rate", Message: &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "this"}}
+	opts := &domain.ChatOptions{JSONSchemaRetries: 1}
+	session, err := chatter.Send(context.Background(), request, opts)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := session.GetLastMessage().Content; got != `{"score": 3}` {
+		t.Errorf("unexpected answer %q", got)
+	}
+	if string(opts.JSONSchema) != testScoreSchema {
+		t.Errorf("expected the schema of the pattern in the options, got %s", opts.JSONSchema)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	opts := &domain.ChatOptions{ThinkStartTag: "<think>", ThinkEndTag: "</think>"}
+	tests := map[string]string{
+		`{"a": 1}`:                           `{"a": 1}`,
+		"```json\n{\"a\": 1}\n```":           `{"a": 1}`,
+		"```\n[1, 2]\n```":                   `[1, 2]`,
+		"<think>hmm</think>\n  {\"a\": 1}  ": `{"a": 1}`,
+	}
+	for answer, want := range tests {
+		if got := extractJSON(answer, opts); got != want {
+			t.Errorf("extractJSON(%q) = %q, want %q", answer, got, want)
+		}
+	}
+}
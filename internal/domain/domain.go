@@ -1,11 +1,22 @@
 package domain
 
-import "github.com/danielmiessler/fabric/internal/chat"
+import (
+	"encoding/json"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+)
 
 const ChatMessageRoleMeta = "meta"
 
+// Strategies to fit long sessions into the context window of the model
+const (
+	ContextStrategyTruncate  = "truncate"
+	ContextStrategySummarize = "summarize"
+)
+
 type ChatRequest struct {
-	ContextName      string
+	// ContextNames are merged in order into the system message
+	ContextNames     []string
 	SessionName      string
 	PatternName      string
 	PatternVariables map[string]string
@@ -14,6 +25,7 @@ type ChatRequest struct {
 	Meta             string
 	InputHasVars     bool
 	StrategyName     string
+	SessionTags      []string
 }
 
 type ChatOptions struct {
@@ -39,6 +51,25 @@ type ChatOptions struct {
 	AudioOutput        bool
 	AudioFormat        string
 	Voice              string
+	Tools              []chat.Tool
+	ContextStrategy    string
+	ContextTurns       int
+	ContextSummarizer  string
+	// JSONSchema asks for an answer in JSON that matches the schema
+	JSONSchema json.RawMessage
+	// JSONSchemaRetries is how often the model is asked to fix an answer that does not match JSONSchema
+	JSONSchemaRetries int
+}
+
+// ChatResult is the structured result of a vendor call.
+// For streamed responses the text is delivered through the stream channel and Content is empty.
+type ChatResult struct {
+	Content      string
+	ToolCalls    []chat.ToolCall
+	Usage        *chat.Usage
+	FinishReason string
+	// Cached is set when the result came from the response cache
+	Cached bool
 }
 
 // NormalizeMessages remove empty messages and ensure messages order user-assist-user
@@ -2,6 +2,7 @@ package anthropic
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/domain"
 	"github.com/danielmiessler/fabric/internal/plugins"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 	"github.com/danielmiessler/fabric/internal/util"
 )
 
@@ -143,37 +145,46 @@ func (an *Client) configure() (err error) {
 	return
 }
 
-func (an *Client) ListModels() (ret []string, err error) {
+func (an *Client) ListModels(_ context.Context) (ret []string, err error) {
 	return an.models, nil
 }
 
 func (an *Client) SendStream(
-	msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
-) (err error) {
+	ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
+) (ret *domain.ChatResult, err error) {
+	defer close(channel)
+
+	ret = &domain.ChatResult{}
 	messages := an.toMessages(msgs)
 	if len(messages) == 0 {
-		close(channel)
 		// No messages to send after normalization, consider this a non-error condition for streaming.
 		return
 	}
 
-	ctx := context.Background()
-
 	stream := an.client.Messages.NewStreaming(ctx, an.buildMessageParams(messages, opts))
 
+	var usage anthropic.Usage
 	for stream.Next() {
 		event := stream.Current()
 
+		switch event.Type {
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+		case "message_delta":
+			usage.OutputTokens = event.Usage.OutputTokens
+			ret.FinishReason = string(event.Delta.StopReason)
+		}
+
 		// directly send any non-empty delta text
 		if event.Delta.Text != "" {
 			channel <- event.Delta.Text
 		}
 	}
 
-	if stream.Err() != nil {
-		fmt.Printf("Messages stream error: %v\n", stream.Err())
+	if err = stream.Err(); err != nil {
+		return
 	}
-	close(channel)
+	ret.Usage = toUsage(usage)
 	return
 }
 
@@ -217,15 +228,19 @@ func (an *Client) buildMessageParams(msgs []anthropic.MessageParam, opts *domain
 			{OfWebSearchTool20250305: &webTool},
 		}
 	}
+
+	// Add function tools declared for the request
+	params.Tools = append(params.Tools, buildTools(opts.Tools)...)
 	return
 }
 
 func (an *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (
-	ret string, err error) {
+	ret *domain.ChatResult, err error) {
 
+	ret = &domain.ChatResult{}
 	messages := an.toMessages(msgs)
 	if len(messages) == 0 {
-		// No messages to send after normalization, return empty string and no error.
+		// No messages to send after normalization, return an empty result and no error.
 		return
 	}
 
@@ -234,6 +249,28 @@ func (an *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage,
 		return
 	}
 
+	ret.Content = an.extractText(message)
+	ret.Usage = toUsage(message.Usage)
+	ret.FinishReason = string(message.StopReason)
+	return
+}
+
+// toUsage converts Anthropic token usage; cache reads and writes count as prompt tokens
+func toUsage(usage anthropic.Usage) *chat.Usage {
+	prompt := int(usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens)
+	completion := int(usage.OutputTokens)
+	if prompt == 0 && completion == 0 {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+	}
+}
+
+// extractText joins the text blocks of a message and appends web search citations
+func (an *Client) extractText(message *anthropic.Message) (ret string) {
 	var textParts []string
 	var citations []string
 	citationMap := make(map[string]bool) // To avoid duplicate citations
@@ -270,7 +307,6 @@ func (an *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage,
 		resultBuilder.WriteString(strings.Join(citations, "\n"))
 	}
 	ret = resultBuilder.String()
-
 	return
 }
 
@@ -287,9 +323,10 @@ func (an *Client) toMessages(msgs []*chat.ChatCompletionMessage) (ret []anthropi
 
 	isFirstUserMessage := true
 	lastRoleWasUser := false
+	lastWasToolResult := false
 
 	for _, msg := range msgs {
-		if msg.Content == "" {
+		if msg.Content == "" && len(msg.ToolCalls) == 0 && msg.Role != chat.ChatMessageRoleTool {
 			continue // Skip empty messages
 		}
 
@@ -314,6 +351,19 @@ func (an *Client) toMessages(msgs []*chat.ChatCompletionMessage) (ret []anthropi
 			}
 			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(anthropic.NewTextBlock(userContent)))
 			lastRoleWasUser = true
+			lastWasToolResult = false
+		case chat.ChatMessageRoleTool:
+			// Anthropic expects tool_result blocks in the user message right after the tool_use
+			// blocks, so the results of parallel calls are added to the same message
+			block := anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)
+			if lastWasToolResult {
+				last := &anthropicMessages[len(anthropicMessages)-1]
+				last.Content = append(last.Content, block)
+			} else {
+				anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(block))
+			}
+			lastRoleWasUser = true
+			lastWasToolResult = true
 		case chat.ChatMessageRoleAssistant:
 			// If the first message is an assistant message, and we have system content,
 			// prepend a user message with the system content.
@@ -327,8 +377,9 @@ func (an *Client) toMessages(msgs []*chat.ChatCompletionMessage) (ret []anthropi
 				anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(anthropic.NewTextBlock(an.defaultRequiredUserMessage)))
 				lastRoleWasUser = true
 			}
-			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content)))
+			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(toAssistantBlocks(msg)...))
 			lastRoleWasUser = false
+			lastWasToolResult = false
 		default:
 			// Other roles (like 'meta') are ignored for Anthropic's message structure.
 			continue
@@ -346,3 +397,12 @@ func (an *Client) toMessages(msgs []*chat.ChatCompletionMessage) (ret []anthropi
 func (an *Client) NeedsRawMode(modelName string) bool {
 	return false
 }
+
+// ClassifyError maps the status of an *anthropic.Error, including the 529 of an overloaded API
+func (an *Client) ClassifyError(err error) (ret ai.ErrorKind) {
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		ret = ai.ErrorKindForStatus(apiErr.StatusCode)
+	}
+	return
+}
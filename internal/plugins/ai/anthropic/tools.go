@@ -0,0 +1,105 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// SendWithTools sends the conversation to the Messages API. The tool_use blocks of the answer
+// become tool calls, with their input as the JSON arguments.
+func (an *Client) SendWithTools(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (
+	ret *domain.ChatResult, err error) {
+
+	ret = &domain.ChatResult{}
+
+	messages := an.toMessages(msgs)
+	if len(messages) == 0 {
+		return
+	}
+
+	var message *anthropic.Message
+	if message, err = an.client.Messages.New(ctx, an.buildMessageParams(messages, opts)); err != nil {
+		return
+	}
+
+	ret.Content = an.extractText(message)
+	ret.Usage = toUsage(message.Usage)
+	ret.FinishReason = string(message.StopReason)
+	for _, block := range message.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		ret.ToolCalls = append(ret.ToolCalls, chat.ToolCall{
+			ID:   block.ID,
+			Type: chat.ToolTypeFunction,
+			Function: chat.FunctionCall{
+				Name:      block.Name,
+				Arguments: string(block.Input),
+			},
+		})
+	}
+	return
+}
+
+// buildTools converts fabric tools to Anthropic custom tools
+func buildTools(tools []chat.Tool) (ret []anthropic.ToolUnionParam) {
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		schema := anthropic.ToolInputSchemaParam{ExtraFields: map[string]any{}}
+		for key, value := range tool.Function.Parameters {
+			switch key {
+			case "type":
+			case "properties":
+				schema.Properties = value
+			case "required":
+				schema.Required = toStrings(value)
+			default:
+				schema.ExtraFields[key] = value
+			}
+		}
+		if schema.Properties == nil {
+			schema.Properties = map[string]any{}
+		}
+		param := anthropic.ToolUnionParamOfTool(schema, tool.Function.Name)
+		if tool.Function.Description != "" {
+			param.OfTool.Description = anthropic.String(tool.Function.Description)
+		}
+		ret = append(ret, param)
+	}
+	return
+}
+
+// toAssistantBlocks converts an assistant message, including its tool calls, to content blocks
+func toAssistantBlocks(msg *chat.ChatCompletionMessage) (ret []anthropic.ContentBlockParamUnion) {
+	if msg.Content != "" {
+		ret = append(ret, anthropic.NewTextBlock(msg.Content))
+	}
+	for _, call := range msg.ToolCalls {
+		input := json.RawMessage(call.Function.Arguments)
+		if !json.Valid(input) {
+			input = json.RawMessage("{}")
+		}
+		ret = append(ret, anthropic.NewToolUseBlock(call.ID, input, call.Function.Name))
+	}
+	return
+}
+
+func toStrings(value any) (ret []string) {
+	switch v := value.(type) {
+	case []string:
+		ret = v
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				ret = append(ret, s)
+			}
+		}
+	}
+	return
+}
@@ -1,6 +1,7 @@
 package azure
 
 import (
+	"context"
 	"testing"
 )
 
@@ -58,7 +59,7 @@ func TestListModels(t *testing.T) {
 	client := NewClient()
 	client.apiDeployments = []string{"deployment1", "deployment2"}
 
-	models, err := client.ListModels()
+	models, err := client.ListModels(context.Background())
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -7,6 +7,7 @@ package bedrock
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/danielmiessler/fabric/internal/domain"
@@ -124,9 +125,8 @@ func (c *BedrockClient) configure() error {
 
 // ListModels retrieves all available foundation models and inference profiles
 // from AWS Bedrock that can be used with this plugin.
-func (c *BedrockClient) ListModels() ([]string, error) {
+func (c *BedrockClient) ListModels(ctx context.Context) ([]string, error) {
 	models := []string{}
-	ctx := context.Background()
 
 	foundationModels, err := c.controlPlaneClient.ListFoundationModels(ctx, &bedrock.ListFoundationModelsInput{})
 	if err != nil {
@@ -154,7 +154,7 @@ func (c *BedrockClient) ListModels() ([]string, error) {
 }
 
 // SendStream sends the messages to the the Bedrock ConverseStream API
-func (c *BedrockClient) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (err error) {
+func (c *BedrockClient) SendStream(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (ret *domain.ChatResult, err error) {
 	// Ensure channel is closed on all exit paths to prevent goroutine leaks
 	defer func() {
 		if r := recover(); r != nil {
@@ -163,6 +163,7 @@ func (c *BedrockClient) SendStream(msgs []*chat.ChatCompletionMessage, opts *dom
 		close(channel)
 	}()
 
+	ret = &domain.ChatResult{}
 	messages := c.toMessages(msgs)
 
 	var converseInput = bedrockruntime.ConverseStreamInput{
@@ -173,12 +174,15 @@ func (c *BedrockClient) SendStream(msgs []*chat.ChatCompletionMessage, opts *dom
 			TopP:        aws.Float32(float32(opts.TopP))},
 	}
 
-	response, err := c.runtimeClient.ConverseStream(context.Background(), &converseInput)
+	response, err := c.runtimeClient.ConverseStream(ctx, &converseInput)
 	if err != nil {
-		return fmt.Errorf("bedrock conversestream failed for model %s: %w", opts.Model, err)
+		return nil, fmt.Errorf("bedrock conversestream failed for model %s: %w", opts.Model, err)
 	}
 
-	for event := range response.GetStream().Events() {
+	stream := response.GetStream()
+	defer stream.Close()
+
+	for event := range stream.Events() {
 		// Possible ConverseStream event types
 		// https://docs.aws.amazon.com/bedrock/latest/userguide/conversation-inference-call.html#conversation-inference-call-response-converse-stream
 		switch v := event.(type) {
@@ -191,24 +195,29 @@ func (c *BedrockClient) SendStream(msgs []*chat.ChatCompletionMessage, opts *dom
 
 		case *types.ConverseStreamOutputMemberMessageStop:
 			channel <- "\n"
-			return nil // Let defer handle the close
+			ret.FinishReason = string(v.Value.StopReason)
+
+		// The metadata event follows the message stop and carries the token usage
+		case *types.ConverseStreamOutputMemberMetadata:
+			ret.Usage = toUsage(v.Value.Usage)
 
 		// Unused Events
 		case *types.ConverseStreamOutputMemberMessageStart,
 			*types.ConverseStreamOutputMemberContentBlockStart,
-			*types.ConverseStreamOutputMemberContentBlockStop,
-			*types.ConverseStreamOutputMemberMetadata:
+			*types.ConverseStreamOutputMemberContentBlockStop:
 
 		default:
-			return fmt.Errorf("unknown stream event type: %T", v)
+			return nil, fmt.Errorf("unknown stream event type: %T", v)
 		}
 	}
 
-	return nil
+	// The event channel is closed early when the stream fails or ctx is cancelled
+	err = stream.Err()
+	return
 }
 
 // Send sends the messages the Bedrock Converse API
-func (c *BedrockClient) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+func (c *BedrockClient) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 
 	messages := c.toMessages(msgs)
 
@@ -218,25 +227,42 @@ func (c *BedrockClient) Send(ctx context.Context, msgs []*chat.ChatCompletionMes
 	}
 	response, err := c.runtimeClient.Converse(ctx, &converseInput)
 	if err != nil {
-		return "", fmt.Errorf("bedrock converse failed for model %s: %w", opts.Model, err)
+		return nil, fmt.Errorf("bedrock converse failed for model %s: %w", opts.Model, err)
 	}
 
 	responseText, ok := response.Output.(*types.ConverseOutputMemberMessage)
 	if !ok {
-		return "", fmt.Errorf("unexpected response type: %T", response.Output)
+		return nil, fmt.Errorf("unexpected response type: %T", response.Output)
 	}
 
 	if len(responseText.Value.Content) == 0 {
-		return "", fmt.Errorf("empty response content")
+		return nil, fmt.Errorf("empty response content")
 	}
 
 	responseContentBlock := responseText.Value.Content[0]
 	text, ok := responseContentBlock.(*types.ContentBlockMemberText)
 	if !ok {
-		return "", fmt.Errorf("unexpected content block type: %T", responseContentBlock)
+		return nil, fmt.Errorf("unexpected content block type: %T", responseContentBlock)
 	}
 
-	return text.Value, nil
+	ret = &domain.ChatResult{
+		Content:      text.Value,
+		Usage:        toUsage(response.Usage),
+		FinishReason: string(response.StopReason),
+	}
+	return
+}
+
+// toUsage converts the Bedrock token usage
+func toUsage(usage *types.TokenUsage) *chat.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     int(aws.ToInt32(usage.InputTokens)),
+		CompletionTokens: int(aws.ToInt32(usage.OutputTokens)),
+		TotalTokens:      int(aws.ToInt32(usage.TotalTokens)),
+	}
 }
 
 // NeedsRawMode indicates whether the model requires raw mode processing.
@@ -257,14 +283,32 @@ func (c *BedrockClient) toMessages(inputMessages []*chat.ChatCompletionMessage)
 			chat.ChatMessageRoleSystem:    types.ConversationRoleUser,
 		}
 
+		if msg.Role == chat.ChatMessageRoleTool {
+			// Converse only accepts toolResult blocks with the user role and needs the roles to
+			// alternate, so the results of one assistant turn share a message
+			block := toToolResultBlock(msg)
+			if last := len(messages) - 1; last >= 0 && isToolResultMessage(messages[last]) {
+				messages[last].Content = append(messages[last].Content, block)
+			} else {
+				messages = append(messages, types.Message{Role: types.ConversationRoleUser, Content: []types.ContentBlock{block}})
+			}
+			continue
+		}
+
 		role, ok := roles[msg.Role]
 		if !ok {
 			continue
 		}
 
+		var content []types.ContentBlock
+		if msg.Content != "" || len(msg.ToolCalls) == 0 {
+			content = append(content, &types.ContentBlockMemberText{Value: msg.Content})
+		}
+		content = append(content, toToolUseBlocks(msg)...)
+
 		message := types.Message{
 			Role:    role,
-			Content: []types.ContentBlock{&types.ContentBlockMemberText{Value: msg.Content}},
+			Content: content,
 		}
 		messages = append(messages, message)
 
@@ -272,3 +316,12 @@ func (c *BedrockClient) toMessages(inputMessages []*chat.ChatCompletionMessage)
 
 	return
 }
+
+// ClassifyError maps the HTTP status of the smithy response errors of the AWS SDK, like the 429 of a ThrottlingException
+func (c *BedrockClient) ClassifyError(err error) (ret ai.ErrorKind) {
+	var responseErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &responseErr) {
+		ret = ai.ErrorKindForStatus(responseErr.HTTPStatusCode())
+	}
+	return
+}
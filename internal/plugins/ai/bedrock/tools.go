@@ -0,0 +1,125 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// SendWithTools calls the Converse API. The text blocks of the answer make up its content and
+// the toolUse blocks become tool calls.
+func (c *BedrockClient) SendWithTools(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
+	var converseInput = bedrockruntime.ConverseInput{
+		ModelId:    aws.String(opts.Model),
+		Messages:   c.toMessages(msgs),
+		ToolConfig: buildToolConfiguration(opts.Tools),
+	}
+	response, err := c.runtimeClient.Converse(ctx, &converseInput)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock converse failed for model %s: %w", opts.Model, err)
+	}
+
+	output, ok := response.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected response type: %T", response.Output)
+	}
+
+	ret = &domain.ChatResult{
+		Usage:        toUsage(response.Usage),
+		FinishReason: string(response.StopReason),
+	}
+	for _, block := range output.Value.Content {
+		switch v := block.(type) {
+		case *types.ContentBlockMemberText:
+			ret.Content += v.Value
+		case *types.ContentBlockMemberToolUse:
+			var arguments []byte
+			if arguments, err = toolUseArguments(v.Value.Input); err != nil {
+				return nil, err
+			}
+			ret.ToolCalls = append(ret.ToolCalls, chat.ToolCall{
+				ID:   aws.ToString(v.Value.ToolUseId),
+				Type: chat.ToolTypeFunction,
+				Function: chat.FunctionCall{
+					Name:      aws.ToString(v.Value.Name),
+					Arguments: string(arguments),
+				},
+			})
+		}
+	}
+	return
+}
+
+// buildToolConfiguration converts fabric tools to a Bedrock tool configuration
+func buildToolConfiguration(tools []chat.Tool) (ret *types.ToolConfiguration) {
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		if ret == nil {
+			ret = &types.ToolConfiguration{}
+		}
+		schema := tool.Function.Parameters
+		if len(schema) == 0 {
+			schema = map[string]any{"type": "object", "properties": map[string]any{}}
+		}
+		spec := types.ToolSpecification{
+			Name:        aws.String(tool.Function.Name),
+			InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(schema)},
+		}
+		if tool.Function.Description != "" {
+			spec.Description = aws.String(tool.Function.Description)
+		}
+		ret.Tools = append(ret.Tools, &types.ToolMemberToolSpec{Value: spec})
+	}
+	return
+}
+
+// toToolUseBlocks converts the tool calls of an assistant message to Bedrock tool use blocks
+func toToolUseBlocks(msg *chat.ChatCompletionMessage) (ret []types.ContentBlock) {
+	for _, call := range msg.ToolCalls {
+		input := map[string]any{}
+		if call.Function.Arguments != "" {
+			_ = json.Unmarshal([]byte(call.Function.Arguments), &input)
+		}
+		ret = append(ret, &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+			ToolUseId: aws.String(call.ID),
+			Name:      aws.String(call.Function.Name),
+			Input:     document.NewLazyDocument(input),
+		}})
+	}
+	return
+}
+
+// toToolResultBlock converts a tool message to a Bedrock tool result block
+func toToolResultBlock(msg *chat.ChatCompletionMessage) types.ContentBlock {
+	return &types.ContentBlockMemberToolResult{Value: types.ToolResultBlock{
+		ToolUseId: aws.String(msg.ToolCallID),
+		Content:   []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberText{Value: msg.Content}},
+	}}
+}
+
+func isToolResultMessage(message types.Message) bool {
+	if message.Role != types.ConversationRoleUser || len(message.Content) == 0 {
+		return false
+	}
+	_, ok := message.Content[0].(*types.ContentBlockMemberToolResult)
+	return ok
+}
+
+func toolUseArguments(input document.Interface) (ret []byte, err error) {
+	args := map[string]any{}
+	if input != nil {
+		if err = input.UnmarshalSmithyDocument(&args); err != nil {
+			return nil, fmt.Errorf("failed to decode tool input: %w", err)
+		}
+	}
+	return json.Marshal(args)
+}
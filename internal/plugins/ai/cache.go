@@ -0,0 +1,213 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// ResponseCacheDir is the directory of the response cache in the fabric config directory
+const ResponseCacheDir = "cache"
+
+const cacheFileExtension = ".json"
+
+// ResponseCache stores the answers of vendors on disk, keyed by a fingerprint of the request,
+// so that repeating a request does not call the vendor again
+type ResponseCache struct {
+	Dir string
+	// TTL is how long an answer is reused, 0 keeps answers until they are evicted
+	TTL time.Duration
+	// MaxSize is the size of the cache in bytes above which the oldest answers are evicted, 0 is unlimited
+	MaxSize int64
+}
+
+func NewResponseCache(dir string, ttl time.Duration, maxSize int64) *ResponseCache {
+	return &ResponseCache{Dir: dir, TTL: ttl, MaxSize: maxSize}
+}
+
+// CachedResponse is a cached answer of a vendor
+type CachedResponse struct {
+	Vendor       string      `json:"vendor"`
+	Model        string      `json:"model"`
+	Content      string      `json:"content"`
+	Usage        *chat.Usage `json:"usage,omitempty"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// Send returns the cached answer like Vendor.Send
+func (o *CachedResponse) Send() *domain.ChatResult {
+	return &domain.ChatResult{Content: o.Content, Usage: o.Usage, FinishReason: o.FinishReason, Cached: true}
+}
+
+// SendStream replays the cached answer line by line through the channel like Vendor.SendStream
+func (o *CachedResponse) SendStream(responseChan chan string) *domain.ChatResult {
+	defer close(responseChan)
+	for line := range strings.SplitAfterSeq(o.Content, "\n") {
+		responseChan <- line
+	}
+	return &domain.ChatResult{Usage: o.Usage, FinishReason: o.FinishReason, Cached: true}
+}
+
+// IsCacheable tells whether the answer to a request with the options can be cached.
+// Tool calls, images and audio have side effects or are too large to cache.
+func IsCacheable(opts *domain.ChatOptions) bool {
+	return len(opts.Tools) == 0 && opts.ImageFile == "" && !opts.AudioOutput
+}
+
+// CacheKey returns the fingerprint of a request: a hash of the vendor, the model,
+// the normalized messages and the options
+func CacheKey(vendor string, messages []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+	normalized := make([]*chat.ChatCompletionMessage, 0, len(messages))
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		copied := *message
+		copied.Content = normalizeCacheText(copied.Content)
+		copied.MultiContent = slices.Clone(copied.MultiContent)
+		for i := range copied.MultiContent {
+			copied.MultiContent[i].Text = normalizeCacheText(copied.MultiContent[i].Text)
+		}
+		copied.Meta = nil
+		normalized = append(normalized, &copied)
+	}
+
+	var data []byte
+	if data, err = json.Marshal(struct {
+		Vendor   string
+		Messages []*chat.ChatCompletionMessage
+		Options  *domain.ChatOptions
+	}{vendor, normalized, opts}); err != nil {
+		err = fmt.Errorf("could not build the cache key: %w", err)
+		return
+	}
+	sum := sha256.Sum256(data)
+	ret = hex.EncodeToString(sum[:])
+	return
+}
+
+// normalizeCacheText makes texts that differ only in line endings or surrounding whitespace equal
+func normalizeCacheText(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// Get returns the cached answer for the key, or nil if there is none or it expired
+func (o *ResponseCache) Get(key string) (ret *CachedResponse) {
+	path := o.filePath(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var response CachedResponse
+	if err = json.Unmarshal(data, &response); err != nil {
+		// A damaged entry is treated as missing and replaced by the next answer
+		return
+	}
+	if o.TTL > 0 && time.Since(response.CreatedAt) > o.TTL {
+		_ = os.Remove(path)
+		return
+	}
+	ret = &response
+	return
+}
+
+// Put stores the answer for the key and evicts the oldest answers if the cache is too large
+func (o *ResponseCache) Put(key string, response *CachedResponse) (err error) {
+	if err = os.MkdirAll(o.Dir, os.ModePerm); err != nil {
+		return
+	}
+	if response.CreatedAt.IsZero() {
+		response.CreatedAt = time.Now()
+	}
+
+	var data []byte
+	if data, err = json.Marshal(response); err != nil {
+		return
+	}
+	if err = os.WriteFile(o.filePath(key), data, 0644); err != nil {
+		return
+	}
+	err = o.evict()
+	return
+}
+
+// Clear removes all cached answers
+func (o *ResponseCache) Clear() (err error) {
+	var entries []cacheEntry
+	if entries, err = o.entries(); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if err = os.Remove(entry.path); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// evict removes expired answers and then the oldest answers until the cache fits into MaxSize
+func (o *ResponseCache) evict() (err error) {
+	var entries []cacheEntry
+	if entries, err = o.entries(); err != nil {
+		return
+	}
+
+	slices.SortFunc(entries, func(a, b cacheEntry) int { return a.modTime.Compare(b.modTime) })
+	var total int64
+	for _, entry := range entries {
+		total += entry.size
+	}
+	for _, entry := range entries {
+		expired := o.TTL > 0 && time.Since(entry.modTime) > o.TTL
+		if !expired && (o.MaxSize <= 0 || total <= o.MaxSize) {
+			continue
+		}
+		if err = os.Remove(entry.path); err != nil {
+			return
+		}
+		total -= entry.size
+	}
+	return
+}
+
+func (o *ResponseCache) entries() (ret []cacheEntry, err error) {
+	var dirEntries []os.DirEntry
+	if dirEntries, err = os.ReadDir(o.Dir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || filepath.Ext(dirEntry.Name()) != cacheFileExtension {
+			continue
+		}
+		var info os.FileInfo
+		if info, err = dirEntry.Info(); err != nil {
+			return
+		}
+		ret = append(ret, cacheEntry{path: filepath.Join(o.Dir, dirEntry.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	return
+}
+
+func (o *ResponseCache) filePath(key string) string {
+	return filepath.Join(o.Dir, key+cacheFileExtension)
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+func TestCacheKey(t *testing.T) {
+	messages := []*chat.ChatCompletionMessage{{Role: chat.ChatMessageRoleUser, Content: "line one\r\nline two\n"}}
+	opts := &domain.ChatOptions{Model: "gpt-4o", Temperature: 0.7}
+
+	key, err := CacheKey("OpenAI", messages, opts)
+	if err != nil {
+		t.Fatalf("CacheKey() error = %v", err)
+	}
+
+	same, _ := CacheKey("OpenAI", []*chat.ChatCompletionMessage{{Role: chat.ChatMessageRoleUser, Content: "line one\nline two",
+		Meta: &chat.MessageMeta{Model: "gpt-4o"}}}, &domain.ChatOptions{Model: "gpt-4o", Temperature: 0.7})
+	if same != key {
+		t.Error("expected messages that differ only in line endings, whitespace and meta to have the same key")
+	}
+	if messages[0].Content != "line one\r\nline two\n" {
+		t.Error("expected the messages to be left unchanged")
+	}
+
+	for name, other := range map[string]func() (string, error){
+		"vendor": func() (string, error) { return CacheKey("Azure", messages, opts) },
+		"model": func() (string, error) {
+			return CacheKey("OpenAI", messages, &domain.ChatOptions{Model: "gpt-4o-mini", Temperature: 0.7})
+		},
+		"options": func() (string, error) {
+			return CacheKey("OpenAI", messages, &domain.ChatOptions{Model: "gpt-4o", Temperature: 0.2})
+		},
+		"messages": func() (string, error) {
+			return CacheKey("OpenAI", []*chat.ChatCompletionMessage{{Role: chat.ChatMessageRoleUser, Content: "other"}}, opts)
+		},
+	} {
+		if otherKey, _ := other(); otherKey == key {
+			t.Errorf("expected a different %s to give a different key", name)
+		}
+	}
+}
+
+func TestIsCacheable(t *testing.T) {
+	if !IsCacheable(&domain.ChatOptions{Model: "gpt-4o"}) {
+		t.Error("expected a plain request to be cacheable")
+	}
+	if IsCacheable(&domain.ChatOptions{Tools: []chat.Tool{{}}}) || IsCacheable(&domain.ChatOptions{ImageFile: "out.png"}) ||
+		IsCacheable(&domain.ChatOptions{AudioOutput: true}) {
+		t.Error("expected requests with tools, images or audio not to be cacheable")
+	}
+}
+
+func TestResponseCache_PutGet(t *testing.T) {
+	cache := NewResponseCache(filepath.Join(t.TempDir(), "cache"), time.Hour, 0)
+
+	if cache.Get("missing") != nil {
+		t.Error("expected no answer for a missing key")
+	}
+
+	usage := &chat.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}
+	if err := cache.Put("key", &CachedResponse{Vendor: "OpenAI", Model: "gpt-4o", Content: "answer", Usage: usage, FinishReason: "stop"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got := cache.Get("key")
+	if got == nil || got.Content != "answer" || got.Usage == nil || *got.Usage != *usage || got.FinishReason != "stop" {
+		t.Fatalf("unexpected cached answer %+v", got)
+	}
+	if result := got.Send(); result.Content != "answer" || !result.Cached {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestResponseCache_Expired(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour, 0)
+	if err := cache.Put("key", &CachedResponse{Content: "old", CreatedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if cache.Get("key") != nil {
+		t.Error("expected an expired answer not to be returned")
+	}
+	if _, err := os.Stat(cache.filePath("key")); !os.IsNotExist(err) {
+		t.Error("expected the expired answer to be removed")
+	}
+}
+
+func TestResponseCache_EvictsOldest(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewResponseCache(dir, 0, 0)
+	content := strings.Repeat("x", 100)
+	for i, key := range []string{"first", "second", "third"} {
+		if err := cache.Put(key, &CachedResponse{Content: content}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		modTime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(cache.filePath(key), modTime, modTime); err != nil {
+			t.Fatalf("failed to set the modification time: %v", err)
+		}
+	}
+
+	// Room for exactly the two newer answers, as written
+	cache.MaxSize = 0
+	for _, key := range []string{"second", "third"} {
+		info, err := os.Stat(cache.filePath(key))
+		if err != nil {
+			t.Fatalf("failed to stat the answer: %v", err)
+		}
+		cache.MaxSize += info.Size()
+	}
+	if err := cache.evict(); err != nil {
+		t.Fatalf("evict() error = %v", err)
+	}
+
+	if cache.Get("first") != nil {
+		t.Error("expected the oldest answer to be evicted")
+	}
+	if cache.Get("second") == nil || cache.Get("third") == nil {
+		t.Error("expected the newer answers to be kept")
+	}
+}
+
+func TestResponseCache_Clear(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), 0, 0)
+	if err := cache.Put("key", &CachedResponse{Content: "answer"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if cache.Get("key") != nil {
+		t.Error("expected no answers after Clear()")
+	}
+
+	if err := NewResponseCache(filepath.Join(t.TempDir(), "missing"), 0, 0).Clear(); err != nil {
+		t.Errorf("expected clearing a missing cache to succeed, got %v", err)
+	}
+}
+
+func TestCachedResponse_SendStream(t *testing.T) {
+	cached := &CachedResponse{Content: "line one\nline two", FinishReason: "stop"}
+	responseChan := make(chan string)
+
+	done := make(chan *domain.ChatResult)
+	go func() {
+		done <- cached.SendStream(responseChan)
+	}()
+
+	var chunks []string
+	for chunk := range responseChan {
+		chunks = append(chunks, chunk)
+	}
+	result := <-done
+
+	if len(chunks) != 2 || strings.Join(chunks, "") != cached.Content {
+		t.Errorf("expected the answer to be replayed line by line, got %q", chunks)
+	}
+	if !result.Cached || result.FinishReason != "stop" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ContextLimits maps model names or name prefixes to the size of their context window in tokens.
+// Entries can be added or overridden with an optional YAML file:
+//
+//	gpt-4o: 128000
+//	llama3.1: 131072
+type ContextLimits map[string]int
+
+// KnownContextLimits are the context windows of common models, matched by name prefix
+var KnownContextLimits = ContextLimits{
+	"gpt-3.5-turbo":  16385,
+	"gpt-4":          8192,
+	"gpt-4-turbo":    128000,
+	"gpt-4o":         128000,
+	"gpt-4.1":        1047576,
+	"gpt-5":          400000,
+	"o1":             200000,
+	"o3":             200000,
+	"o4-mini":        200000,
+	"claude":         200000,
+	"gemini":         1048576,
+	"gemini-1.5-pro": 2097152,
+	"llama3":         8192,
+	"llama3.1":       131072,
+	"llama3.2":       131072,
+	"llama3.3":       131072,
+	"mistral":        32768,
+	"qwen2.5":        32768,
+	"deepseek":       65536,
+	"sonar":          127072,
+}
+
+// LoadContextLimits returns the known context limits overlaid with the entries of the
+// limits file; a missing file gives the known limits
+func LoadContextLimits(path string) (ret ContextLimits, err error) {
+	ret = ContextLimits{}
+	for name, limit := range KnownContextLimits {
+		ret[name] = limit
+	}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	var configured ContextLimits
+	if err = yaml.Unmarshal(data, &configured); err != nil {
+		err = fmt.Errorf("failed to parse context limits %s: %w", path, err)
+		return
+	}
+	for name, limit := range configured {
+		ret[strings.ToLower(name)] = limit
+	}
+	return
+}
+
+// Get returns the context limit of the model from the longest matching name prefix,
+// or 0 when the model is unknown. Vendor prefixes like "anthropic/" are ignored.
+func (o ContextLimits) Get(model string) (ret int) {
+	model = strings.ToLower(model)
+	if index := strings.LastIndex(model, "/"); index >= 0 {
+		model = model[index+1:]
+	}
+
+	longest := -1
+	for name, limit := range o {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(model, name) && len(name) > longest {
+			ret, longest = limit, len(name)
+		}
+	}
+	return
+}
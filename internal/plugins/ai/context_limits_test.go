@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextLimits_Get(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4o-mini", 128000},
+		{"gpt-4-turbo-preview", 128000},
+		{"gpt-4", 8192},
+		{"claude-sonnet-4-20250514", 200000},
+		{"anthropic/claude-3.5-sonnet", 200000},
+		{"llama3.1:8b", 131072},
+		{"Gemini-2.5-Pro", 1048576},
+		{"unknown-model", 0},
+	}
+	for _, tt := range tests {
+		if got := KnownContextLimits.Get(tt.model); got != tt.want {
+			t.Errorf("Get(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestLoadContextLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "context_limits.yaml")
+	if err := os.WriteFile(path, []byte("gpt-4o: 64000\nMy-Model: 4096\n"), 0644); err != nil {
+		t.Fatalf("failed to write context limits: %v", err)
+	}
+
+	limits, err := LoadContextLimits(path)
+	if err != nil {
+		t.Fatalf("LoadContextLimits() error = %v", err)
+	}
+	if got := limits.Get("gpt-4o"); got != 64000 {
+		t.Errorf("Get(gpt-4o) = %d, want the configured 64000", got)
+	}
+	if got := limits.Get("my-model-q4"); got != 4096 {
+		t.Errorf("Get(my-model-q4) = %d, want 4096", got)
+	}
+	if got := limits.Get("claude-3-haiku"); got != 200000 {
+		t.Errorf("Get(claude-3-haiku) = %d, want the known 200000", got)
+	}
+	if KnownContextLimits.Get("gpt-4o") != 128000 {
+		t.Errorf("loading a limits file must not change the known limits")
+	}
+}
+
+func TestLoadContextLimits_MissingFile(t *testing.T) {
+	limits, err := LoadContextLimits(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadContextLimits() error = %v", err)
+	}
+	if len(limits) != len(KnownContextLimits) {
+		t.Errorf("expected the known limits, got %d entries", len(limits))
+	}
+}
@@ -22,7 +22,7 @@ func NewClient() *Client {
 	return &Client{PluginBase: &plugins.PluginBase{Name: "DryRun"}}
 }
 
-func (c *Client) ListModels() ([]string, error) {
+func (c *Client) ListModels(_ context.Context) ([]string, error) {
 	return []string{"dry-run-model"}, nil
 }
 
@@ -92,6 +92,11 @@ func (c *Client) formatOptions(opts *domain.ChatOptions) string {
 		builder.WriteString(fmt.Sprintf("Thinking Start Tag: %s\n", opts.ThinkStartTag))
 		builder.WriteString(fmt.Sprintf("Thinking End Tag: %s\n", opts.ThinkEndTag))
 	}
+	for _, tool := range opts.Tools {
+		if tool.Function != nil {
+			builder.WriteString(fmt.Sprintf("Tool: %s\n", tool.Function.Name))
+		}
+	}
 
 	return builder.String()
 }
@@ -105,19 +110,24 @@ func (c *Client) constructRequest(msgs []*chat.ChatCompletionMessage, opts *doma
 	return builder.String()
 }
 
-func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) error {
+func (c *Client) SendStream(_ context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (*domain.ChatResult, error) {
 	defer close(channel)
 	request := c.constructRequest(msgs, opts)
 	channel <- request
 	channel <- "\n"
 	channel <- DryRunResponse
-	return nil
+	return &domain.ChatResult{}, nil
 }
 
-func (c *Client) Send(_ context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (string, error) {
+func (c *Client) Send(_ context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (*domain.ChatResult, error) {
 	request := c.constructRequest(msgs, opts)
 
-	return request + "\n" + DryRunResponse, nil
+	return &domain.ChatResult{Content: request + "\n" + DryRunResponse}, nil
+}
+
+// SendWithTools shows the request including the offered tools; it never requests a tool call.
+func (c *Client) SendWithTools(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (*domain.ChatResult, error) {
+	return c.Send(ctx, msgs, opts)
 }
 
 func (c *Client) Setup() error {
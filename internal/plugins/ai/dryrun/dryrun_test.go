@@ -1,6 +1,7 @@
 package dryrun
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
@@ -11,7 +12,7 @@ import (
 // Test generated using Keploy
 func TestListModels_ReturnsExpectedModel(t *testing.T) {
 	client := NewClient()
-	models, err := client.ListModels()
+	models, err := client.ListModels(context.Background())
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -41,7 +42,7 @@ func TestSendStream_SendsMessages(t *testing.T) {
 	}
 	channel := make(chan string)
 	go func() {
-		err := client.SendStream(msgs, opts, channel)
+		_, err := client.SendStream(context.Background(), msgs, opts, channel)
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/plugins"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 
 	"github.com/danielmiessler/fabric/internal/domain"
 	"google.golang.org/genai"
@@ -45,8 +47,7 @@ type Client struct {
 	ApiKey *plugins.SetupQuestion
 }
 
-func (o *Client) ListModels() (ret []string, err error) {
-	ctx := context.Background()
+func (o *Client) ListModels(ctx context.Context) (ret []string, err error) {
 	var client *genai.Client
 	if client, err = genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey:  o.ApiKey.Value,
@@ -69,7 +70,7 @@ func (o *Client) ListModels() (ret []string, err error) {
 	return
 }
 
-func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 	// Check if this is a TTS model request
 	if o.isTTSModel(opts.Model) {
 		if !opts.AudioOutput {
@@ -78,7 +79,12 @@ func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, o
 		}
 
 		// Handle TTS generation
-		return o.generateTTSAudio(ctx, msgs, opts)
+		var audio string
+		if audio, err = o.generateTTSAudio(ctx, msgs, opts); err != nil {
+			return
+		}
+		ret = &domain.ChatResult{Content: audio}
+		return
 	}
 
 	// Regular text generation
@@ -94,24 +100,24 @@ func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, o
 	contents := o.convertMessages(msgs)
 
 	// Generate content
-	temperature := float32(opts.Temperature)
-	topP := float32(opts.TopP)
-	response, err := client.Models.GenerateContent(ctx, o.buildModelNameFull(opts.Model), contents, &genai.GenerateContentConfig{
-		Temperature:     &temperature,
-		TopP:            &topP,
-		MaxOutputTokens: int32(opts.ModelContextLength),
-	})
+	response, err := client.Models.GenerateContent(ctx, o.buildModelNameFull(opts.Model), contents, o.buildGenerateContentConfig(opts))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Extract text from response
-	ret = o.extractTextFromResponse(response)
+	ret = &domain.ChatResult{
+		Content:      o.extractTextFromResponse(response),
+		Usage:        toUsage(response),
+		FinishReason: finishReason(response),
+	}
 	return
 }
 
-func (o *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (err error) {
-	ctx := context.Background()
+func (o *Client) SendStream(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (ret *domain.ChatResult, err error) {
+	defer close(channel)
+
+	ret = &domain.ChatResult{}
 	var client *genai.Client
 	if client, err = genai.NewClient(ctx, &genai.ClientConfig{
 		APIKey:  o.ApiKey.Value,
@@ -124,31 +130,51 @@ func (o *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.Cha
 	contents := o.convertMessages(msgs)
 
 	// Generate streaming content
-	temperature := float32(opts.Temperature)
-	topP := float32(opts.TopP)
-	stream := client.Models.GenerateContentStream(ctx, o.buildModelNameFull(opts.Model), contents, &genai.GenerateContentConfig{
-		Temperature:     &temperature,
-		TopP:            &topP,
-		MaxOutputTokens: int32(opts.ModelContextLength),
-	})
+	stream := client.Models.GenerateContentStream(ctx, o.buildModelNameFull(opts.Model), contents, o.buildGenerateContentConfig(opts))
 
-	for response, err := range stream {
-		if err != nil {
-			channel <- fmt.Sprintf("Error: %v\n", err)
-			close(channel)
-			break
+	for response, streamErr := range stream {
+		if streamErr != nil {
+			err = streamErr
+			return
 		}
 
 		text := o.extractTextFromResponse(response)
 		if text != "" {
 			channel <- text
 		}
+
+		// The last chunk carries the usage of the whole response
+		if usage := toUsage(response); usage != nil {
+			ret.Usage = usage
+		}
+		if reason := finishReason(response); reason != "" {
+			ret.FinishReason = reason
+		}
 	}
-	close(channel)
 
 	return
 }
 
+// toUsage converts the usage metadata of a response
+func toUsage(response *genai.GenerateContentResponse) *chat.Usage {
+	if response.UsageMetadata == nil || response.UsageMetadata.TotalTokenCount == 0 {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     int(response.UsageMetadata.PromptTokenCount),
+		CompletionTokens: int(response.UsageMetadata.CandidatesTokenCount + response.UsageMetadata.ThoughtsTokenCount),
+		TotalTokens:      int(response.UsageMetadata.TotalTokenCount),
+	}
+}
+
+// finishReason returns the finish reason of the first candidate, e.g. "STOP" or "MAX_TOKENS"
+func finishReason(response *genai.GenerateContentResponse) string {
+	if len(response.Candidates) == 0 {
+		return ""
+	}
+	return string(response.Candidates[0].FinishReason)
+}
+
 func (o *Client) NeedsRawMode(modelName string) bool {
 	return false
 }
@@ -329,11 +355,35 @@ func (o *Client) generateWAVFile(pcmData []byte) ([]byte, error) {
 	return result, nil
 }
 
+// buildGenerateContentConfig builds the generation config shared by all text requests
+func (o *Client) buildGenerateContentConfig(opts *domain.ChatOptions) (ret *genai.GenerateContentConfig) {
+	temperature := float32(opts.Temperature)
+	topP := float32(opts.TopP)
+	ret = &genai.GenerateContentConfig{
+		Temperature:     &temperature,
+		TopP:            &topP,
+		MaxOutputTokens: int32(opts.ModelContextLength),
+	}
+	if tool := buildTool(opts.Tools); tool != nil {
+		ret.Tools = []*genai.Tool{tool}
+	}
+	if len(opts.JSONSchema) > 0 {
+		ret.ResponseMIMEType = "application/json"
+		ret.ResponseJsonSchema = opts.JSONSchema
+	}
+	return
+}
+
 // convertMessages converts fabric chat messages to genai Content format
 func (o *Client) convertMessages(msgs []*chat.ChatCompletionMessage) []*genai.Content {
 	var contents []*genai.Content
 
 	for _, msg := range msgs {
+		if msg.Role == chat.ChatMessageRoleTool || len(msg.ToolCalls) > 0 {
+			contents = append(contents, convertToolMessage(msg))
+			continue
+		}
+
 		content := &genai.Content{Parts: []*genai.Part{}}
 
 		if msg.Content != "" {
@@ -373,3 +423,12 @@ func (o *Client) extractTextFromResponse(response *genai.GenerateContentResponse
 
 	return result.String()
 }
+
+// ClassifyError maps the HTTP code of a genai.APIError, like 429 RESOURCE_EXHAUSTED
+func (o *Client) ClassifyError(err error) (ret ai.ErrorKind) {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		ret = ai.ErrorKindForStatus(apiErr.Code)
+	}
+	return
+}
@@ -3,6 +3,7 @@ package gemini
 import (
 	"testing"
 
+	"github.com/danielmiessler/fabric/internal/domain"
 	"google.golang.org/genai"
 )
 
@@ -97,3 +98,18 @@ func TestGenerateWAVFile(t *testing.T) {
 		t.Error("Generated WAV data doesn't start with RIFF header")
 	}
 }
+
+// Test that a JSON schema asks for a structured answer
+func TestBuildGenerateContentConfig_JSONSchema(t *testing.T) {
+	client := &Client{}
+
+	config := client.buildGenerateContentConfig(&domain.ChatOptions{JSONSchema: []byte(`{"type": "object"}`)})
+	if config.ResponseMIMEType != "application/json" || config.ResponseJsonSchema == nil {
+		t.Errorf("expected a JSON response with the schema, got %q and %v", config.ResponseMIMEType, config.ResponseJsonSchema)
+	}
+
+	config = client.buildGenerateContentConfig(&domain.ChatOptions{})
+	if config.ResponseMIMEType != "" || config.ResponseJsonSchema != nil {
+		t.Error("expected no response schema without a JSON schema")
+	}
+}
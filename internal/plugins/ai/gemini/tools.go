@@ -0,0 +1,97 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"google.golang.org/genai"
+)
+
+// SendWithTools calls GenerateContent. The functionCall parts of the answer become tool calls,
+// whose args are marshalled back to JSON arguments.
+func (o *Client) SendWithTools(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
+	var client *genai.Client
+	if client, err = o.createGenaiClient(ctx); err != nil {
+		return
+	}
+
+	var response *genai.GenerateContentResponse
+	if response, err = client.Models.GenerateContent(ctx, o.buildModelNameFull(opts.Model), o.convertMessages(msgs), o.buildGenerateContentConfig(opts)); err != nil {
+		return
+	}
+
+	ret = &domain.ChatResult{
+		Content:      o.extractTextFromResponse(response),
+		Usage:        toUsage(response),
+		FinishReason: finishReason(response),
+	}
+	for _, call := range response.FunctionCalls() {
+		var arguments []byte
+		if arguments, err = json.Marshal(call.Args); err != nil {
+			return
+		}
+		ret.ToolCalls = append(ret.ToolCalls, chat.ToolCall{
+			ID:   call.ID,
+			Type: chat.ToolTypeFunction,
+			Function: chat.FunctionCall{
+				Name:      call.Name,
+				Arguments: string(arguments),
+			},
+		})
+	}
+	return
+}
+
+// buildTool converts fabric tools to a genai tool with function declarations
+func buildTool(tools []chat.Tool) (ret *genai.Tool) {
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		if ret == nil {
+			ret = &genai.Tool{}
+		}
+		declaration := &genai.FunctionDeclaration{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+		}
+		if len(tool.Function.Parameters) > 0 {
+			declaration.ParametersJsonSchema = tool.Function.Parameters
+		}
+		ret.FunctionDeclarations = append(ret.FunctionDeclarations, declaration)
+	}
+	return
+}
+
+// convertToolMessage converts model function calls and tool results to genai content
+func convertToolMessage(msg *chat.ChatCompletionMessage) (ret *genai.Content) {
+	if msg.Role == chat.ChatMessageRoleTool {
+		return &genai.Content{
+			Role: genai.RoleUser,
+			Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{
+				ID:       msg.ToolCallID,
+				Name:     msg.Name,
+				Response: map[string]any{"output": msg.Content},
+			}}},
+		}
+	}
+
+	ret = &genai.Content{Role: genai.RoleModel}
+	if msg.Content != "" {
+		ret.Parts = append(ret.Parts, &genai.Part{Text: msg.Content})
+	}
+	for _, call := range msg.ToolCalls {
+		args := map[string]any{}
+		if call.Function.Arguments != "" {
+			_ = json.Unmarshal([]byte(call.Function.Arguments), &args)
+		}
+		ret.Parts = append(ret.Parts, &genai.Part{FunctionCall: &genai.FunctionCall{
+			ID:   call.ID,
+			Name: call.Function.Name,
+			Args: args,
+		}})
+	}
+	return
+}
@@ -51,10 +51,10 @@ func (c *Client) configure() error {
 }
 
 // ListModels returns a list of available models.
-func (c *Client) ListModels() ([]string, error) {
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
 	url := fmt.Sprintf("%s/models", c.ApiUrl.Value)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -87,11 +87,14 @@ func (c *Client) ListModels() ([]string, error) {
 	return models, nil
 }
 
-func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (err error) {
+func (c *Client) SendStream(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (ret *domain.ChatResult, err error) {
+	defer close(channel)
+
+	ret = &domain.ChatResult{}
 	url := fmt.Sprintf("%s/chat/completions", c.ApiUrl.Value)
 
 	payload := map[string]interface{}{
-		"messages": msgs,
+		"messages": toPayloadMessages(msgs),
 		"model":    opts.Model,
 		"stream":   true, // Enable streaming
 	}
@@ -103,7 +106,7 @@ func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.Cha
 	}
 
 	var req *http.Request
-	if req, err = http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload)); err != nil {
+	if req, err = http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload)); err != nil {
 		err = fmt.Errorf("failed to create request: %w", err)
 		return
 	}
@@ -122,8 +125,6 @@ func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.Cha
 		return
 	}
 
-	defer close(channel)
-
 	reader := bufio.NewReader(resp.Body)
 	for {
 		var line []byte
@@ -153,12 +154,20 @@ func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.Cha
 			continue
 		}
 
+		if usage := parseUsage(result); usage != nil {
+			ret.Usage = usage
+		}
+
 		var choices []interface{}
 		var ok bool
 		if choices, ok = result["choices"].([]interface{}); !ok || len(choices) == 0 {
 			continue
 		}
 
+		if reason, _ := choices[0].(map[string]interface{})["finish_reason"].(string); reason != "" {
+			ret.FinishReason = reason
+		}
+
 		var delta map[string]interface{}
 		if delta, ok = choices[0].(map[string]interface{})["delta"].(map[string]interface{}); !ok {
 			continue
@@ -173,11 +182,11 @@ func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.Cha
 	return
 }
 
-func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (content string, err error) {
+func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 	url := fmt.Sprintf("%s/chat/completions", c.ApiUrl.Value)
 
 	payload := map[string]interface{}{
-		"messages": msgs,
+		"messages": toPayloadMessages(msgs),
 		"model":    opts.Model,
 		// Add other options from opts if supported by LM Studio
 	}
@@ -227,14 +236,44 @@ func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, o
 		return
 	}
 
+	var content string
 	if content, ok = message["content"].(string); !ok {
 		err = fmt.Errorf("invalid response format: missing or non-string content in message")
 		return
 	}
 
+	ret = &domain.ChatResult{Content: content, Usage: parseUsage(result)}
+	ret.FinishReason, _ = choices[0].(map[string]interface{})["finish_reason"].(string)
 	return
 }
 
+// toPayloadMessages copies the messages without fabric's own metadata
+func toPayloadMessages(msgs []*chat.ChatCompletionMessage) (ret []chat.ChatCompletionMessage) {
+	ret = make([]chat.ChatCompletionMessage, len(msgs))
+	for i, msg := range msgs {
+		ret[i] = *msg
+		ret[i].Meta = nil
+	}
+	return
+}
+
+// parseUsage reads the OpenAI compatible usage object of a response, if present
+func parseUsage(result map[string]interface{}) *chat.Usage {
+	usage, ok := result["usage"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	tokens := func(name string) int {
+		value, _ := usage[name].(float64)
+		return int(value)
+	}
+	return &chat.Usage{
+		PromptTokens:     tokens("prompt_tokens"),
+		CompletionTokens: tokens("completion_tokens"),
+		TotalTokens:      tokens("total_tokens"),
+	}
+}
+
 func (c *Client) Complete(ctx context.Context, prompt string, opts *domain.ChatOptions) (text string, err error) {
 	url := fmt.Sprintf("%s/completions", c.ApiUrl.Value)
 
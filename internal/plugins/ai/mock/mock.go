@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/danielmiessler/fabric/internal/plugins"
+)
+
+// Client is a vendor that answers with scripted responses instead of a model, so that
+// patterns can be run offline and give known answers.
+type Client struct {
+	*plugins.PluginBase
+
+	// Responses are the answers, in the order of the requests
+	Responses []string
+	// Requests are the messages of the requests that were sent
+	Requests [][]*chat.ChatCompletionMessage
+}
+
+func NewClient(responses ...string) *Client {
+	return &Client{PluginBase: &plugins.PluginBase{Name: "Mock"}, Responses: responses}
+}
+
+func (c *Client) ListModels(_ context.Context) ([]string, error) {
+	return []string{"mock-model"}, nil
+}
+
+// next returns the response to the next request
+func (c *Client) next(msgs []*chat.ChatCompletionMessage) (ret string, err error) {
+	c.Requests = append(c.Requests, msgs)
+	if len(c.Requests) > len(c.Responses) {
+		err = fmt.Errorf("the mock vendor has no response for request %d, it has %d scripted responses",
+			len(c.Requests), len(c.Responses))
+		return
+	}
+	ret = c.Responses[len(c.Requests)-1]
+	return
+}
+
+func (c *Client) SendStream(_ context.Context, msgs []*chat.ChatCompletionMessage, _ *domain.ChatOptions, channel chan string) (*domain.ChatResult, error) {
+	defer close(channel)
+	response, err := c.next(msgs)
+	if err != nil {
+		return nil, err
+	}
+	channel <- response
+	return &domain.ChatResult{}, nil
+}
+
+func (c *Client) Send(_ context.Context, msgs []*chat.ChatCompletionMessage, _ *domain.ChatOptions) (*domain.ChatResult, error) {
+	response, err := c.next(msgs)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ChatResult{Content: response}, nil
+}
+
+func (c *Client) Setup() error {
+	return nil
+}
+
+func (c *Client) SetupFillEnvFileContent(_ *bytes.Buffer) {
+	// No environment variables needed for the mock vendor
+}
+
+func (c *Client) NeedsRawMode(_ string) bool {
+	return false
+}
@@ -0,0 +1,46 @@
+package mock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+func TestSend_ReturnsScriptedResponses(t *testing.T) {
+	client := NewClient("first", "second")
+	msgs := []*chat.ChatCompletionMessage{{Role: chat.ChatMessageRoleUser, Content: "Hello"}}
+
+	for _, want := range []string{"first", "second"} {
+		result, err := client.Send(context.Background(), msgs, &domain.ChatOptions{})
+		if err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+		if result.Content != want {
+			t.Errorf("expected %q, got %q", want, result.Content)
+		}
+	}
+	if len(client.Requests) != 2 || client.Requests[0][0].Content != "Hello" {
+		t.Errorf("expected the requests to be recorded, got %v", client.Requests)
+	}
+
+	if _, err := client.Send(context.Background(), msgs, &domain.ChatOptions{}); err == nil {
+		t.Error("expected an error once the scripted responses are used up")
+	}
+}
+
+func TestSendStream_SendsScriptedResponse(t *testing.T) {
+	client := NewClient("streamed")
+	channel := make(chan string, 1)
+
+	if _, err := client.SendStream(context.Background(), nil, &domain.ChatOptions{}, channel); err != nil {
+		t.Fatalf("SendStream() error = %v", err)
+	}
+	if got := <-channel; got != "streamed" {
+		t.Errorf("expected the scripted response, got %q", got)
+	}
+	if _, open := <-channel; open {
+		t.Error("expected the channel to be closed")
+	}
+}
@@ -2,6 +2,7 @@ package ollama
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/danielmiessler/fabric/internal/domain"
 	"github.com/danielmiessler/fabric/internal/plugins"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 )
 
 const defaultBaseUrl = "http://localhost:11434"
@@ -89,9 +91,7 @@ func (o *Client) configure() (err error) {
 	return
 }
 
-func (o *Client) ListModels() (ret []string, err error) {
-	ctx := context.Background()
-
+func (o *Client) ListModels(ctx context.Context) (ret []string, err error) {
 	var listResp *ollamaapi.ListResponse
 	if listResp, err = o.client.List(ctx); err != nil {
 		return
@@ -103,44 +103,58 @@ func (o *Client) ListModels() (ret []string, err error) {
 	return
 }
 
-func (o *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (err error) {
+func (o *Client) SendStream(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (ret *domain.ChatResult, err error) {
+	defer close(channel)
+
+	ret = &domain.ChatResult{}
 	req := o.createChatRequest(msgs, opts)
 
 	respFunc := func(resp ollamaapi.ChatResponse) (streamErr error) {
 		channel <- resp.Message.Content
+		if resp.Done {
+			ret.Usage = toUsage(resp.Metrics)
+			ret.FinishReason = resp.DoneReason
+		}
 		return
 	}
 
-	ctx := context.Background()
-
-	if err = o.client.Chat(ctx, &req, respFunc); err != nil {
-		return
-	}
-
-	close(channel)
+	err = o.client.Chat(ctx, &req, respFunc)
 	return
 }
 
-func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 	bf := false
 
 	req := o.createChatRequest(msgs, opts)
 	req.Stream = &bf
 
+	ret = &domain.ChatResult{}
 	respFunc := func(resp ollamaapi.ChatResponse) (streamErr error) {
-		ret = resp.Message.Content
+		ret.Content = resp.Message.Content
+		ret.Usage = toUsage(resp.Metrics)
+		ret.FinishReason = resp.DoneReason
 		return
 	}
 
-	if err = o.client.Chat(ctx, &req, respFunc); err != nil {
-		fmt.Printf("FRED --> %s\n", err)
-	}
+	err = o.client.Chat(ctx, &req, respFunc)
 	return
 }
 
+// toUsage converts the Ollama eval counts to token usage
+func toUsage(metrics ollamaapi.Metrics) *chat.Usage {
+	if metrics.PromptEvalCount == 0 && metrics.EvalCount == 0 {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     metrics.PromptEvalCount,
+		CompletionTokens: metrics.EvalCount,
+		TotalTokens:      metrics.PromptEvalCount + metrics.EvalCount,
+	}
+}
+
 func (o *Client) createChatRequest(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret ollamaapi.ChatRequest) {
 	messages := lo.Map(msgs, func(message *chat.ChatCompletionMessage, _ int) (ret ollamaapi.Message) {
-		return ollamaapi.Message{Role: message.Role, Content: message.Content}
+		return ollamaapi.Message{Role: message.Role, Content: message.Content, ToolCalls: toToolCalls(message.ToolCalls)}
 	})
 
 	options := map[string]interface{}{
@@ -158,6 +172,8 @@ func (o *Client) createChatRequest(msgs []*chat.ChatCompletionMessage, opts *dom
 		Model:    opts.Model,
 		Messages: messages,
 		Options:  options,
+		Tools:    toTools(opts.Tools),
+		Format:   opts.JSONSchema,
 	}
 	return
 }
@@ -175,3 +191,12 @@ func (o *Client) NeedsRawMode(modelName string) bool {
 	}
 	return false
 }
+
+// ClassifyError maps the status of an ollama StatusError, like the 503 of a server with too many pending requests
+func (o *Client) ClassifyError(err error) (ret ai.ErrorKind) {
+	var statusErr ollamaapi.StatusError
+	if errors.As(err, &statusErr) {
+		ret = ai.ErrorKindForStatus(statusErr.StatusCode)
+	}
+	return
+}
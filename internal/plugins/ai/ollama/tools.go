@@ -0,0 +1,79 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	ollamaapi "github.com/ollama/ollama/api"
+)
+
+// SendWithTools sends the conversation together with the tools from opts and
+// returns the answer, including any tool calls the model requested.
+func (o *Client) SendWithTools(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
+	bf := false
+
+	req := o.createChatRequest(msgs, opts)
+	req.Stream = &bf
+
+	ret = &domain.ChatResult{}
+	respFunc := func(resp ollamaapi.ChatResponse) (streamErr error) {
+		ret.Content += resp.Message.Content
+		if resp.Done {
+			ret.Usage = toUsage(resp.Metrics)
+			ret.FinishReason = resp.DoneReason
+		}
+		for _, call := range resp.Message.ToolCalls {
+			var arguments []byte
+			if arguments, streamErr = json.Marshal(call.Function.Arguments); streamErr != nil {
+				return
+			}
+			ret.ToolCalls = append(ret.ToolCalls, chat.ToolCall{
+				Type: chat.ToolTypeFunction,
+				Function: chat.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: string(arguments),
+				},
+			})
+		}
+		return
+	}
+
+	err = o.client.Chat(ctx, &req, respFunc)
+	return
+}
+
+// toTools converts fabric tools to Ollama tools; the JSON schema is mapped via its JSON form
+func toTools(tools []chat.Tool) (ret ollamaapi.Tools) {
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		var converted ollamaapi.Tool
+		data, err := json.Marshal(tool)
+		if err != nil {
+			continue
+		}
+		if err = json.Unmarshal(data, &converted); err != nil {
+			continue
+		}
+		ret = append(ret, converted)
+	}
+	return
+}
+
+// toToolCalls converts assistant tool calls to Ollama tool calls
+func toToolCalls(calls []chat.ToolCall) (ret []ollamaapi.ToolCall) {
+	for _, call := range calls {
+		args := ollamaapi.ToolCallFunctionArguments{}
+		if call.Function.Arguments != "" {
+			_ = json.Unmarshal([]byte(call.Function.Arguments), &args)
+		}
+		ret = append(ret, ollamaapi.ToolCall{Function: ollamaapi.ToolCallFunction{
+			Name:      call.Function.Name,
+			Arguments: args,
+		}})
+	}
+	return
+}
@@ -15,37 +15,62 @@ import (
 )
 
 // sendChatCompletions sends a request using the Chat Completions API
-func (o *Client) sendChatCompletions(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+func (o *Client) sendChatCompletions(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 	req := o.buildChatCompletionParams(msgs, opts)
 
 	var resp *openai.ChatCompletion
 	if resp, err = o.ApiClient.Chat.Completions.New(ctx, req); err != nil {
 		return
 	}
+	ret = &domain.ChatResult{Usage: completionUsage(resp.Usage)}
 	if len(resp.Choices) > 0 {
-		ret = resp.Choices[0].Message.Content
+		ret.Content = resp.Choices[0].Message.Content
+		ret.FinishReason = resp.Choices[0].FinishReason
 	}
 	return
 }
 
 // sendStreamChatCompletions sends a streaming request using the Chat Completions API
 func (o *Client) sendStreamChatCompletions(
-	msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
-) (err error) {
+	ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
+) (ret *domain.ChatResult, err error) {
 	defer close(channel)
 
+	ret = &domain.ChatResult{}
 	req := o.buildChatCompletionParams(msgs, opts)
-	stream := o.ApiClient.Chat.Completions.NewStreaming(context.Background(), req)
+	// Ask for a final chunk with the token usage of the whole request
+	req.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+	stream := o.ApiClient.Chat.Completions.NewStreaming(ctx, req)
 	for stream.Next() {
 		chunk := stream.Current()
-		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
-			channel <- chunk.Choices[0].Delta.Content
+		if len(chunk.Choices) > 0 {
+			if chunk.Choices[0].Delta.Content != "" {
+				channel <- chunk.Choices[0].Delta.Content
+			}
+			if chunk.Choices[0].FinishReason != "" {
+				ret.FinishReason = chunk.Choices[0].FinishReason
+			}
+		}
+		if usage := completionUsage(chunk.Usage); usage != nil {
+			ret.Usage = usage
 		}
 	}
-	if stream.Err() == nil {
+	if err = stream.Err(); err == nil {
 		channel <- "\n"
 	}
-	return stream.Err()
+	return
+}
+
+// completionUsage converts the token usage of a Chat Completions API response
+func completionUsage(usage openai.CompletionUsage) *chat.Usage {
+	if usage.TotalTokens == 0 {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     int(usage.PromptTokens),
+		CompletionTokens: int(usage.CompletionTokens),
+		TotalTokens:      int(usage.TotalTokens),
+	}
 }
 
 // buildChatCompletionParams builds parameters for the Chat Completions API
@@ -67,6 +92,14 @@ func (o *Client) buildChatCompletionParams(
 		Messages: messages,
 	}
 
+	if len(opts.Tools) > 0 {
+		ret.Tools = buildChatCompletionTools(opts.Tools)
+	}
+
+	if schema := decodeJSONSchema(opts.JSONSchema); schema != nil {
+		ret.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{OfJSONSchema: chatCompletionResponseFormat(schema)}
+	}
+
 	if !opts.Raw {
 		ret.Temperature = openai.Float(opts.Temperature)
 		if opts.TopP != 0 {
@@ -111,7 +144,25 @@ func (o *Client) convertChatMessage(msg chat.ChatCompletionMessage) openai.ChatC
 		}
 		return openai.UserMessage(result.Content)
 	case chat.ChatMessageRoleAssistant:
+		if len(msg.ToolCalls) > 0 {
+			assistant := openai.ChatCompletionAssistantMessageParam{}
+			if result.Content != "" {
+				assistant.Content.OfString = openai.String(result.Content)
+			}
+			for _, call := range msg.ToolCalls {
+				assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+					ID: call.ID,
+					Function: openai.ChatCompletionMessageToolCallFunctionParam{
+						Name:      call.Function.Name,
+						Arguments: call.Function.Arguments,
+					},
+				})
+			}
+			return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+		}
 		return openai.AssistantMessage(result.Content)
+	case chat.ChatMessageRoleTool:
+		return openai.ToolMessage(result.Content, msg.ToolCallID)
 	default:
 		return openai.UserMessage(result.Content)
 	}
@@ -2,6 +2,7 @@ package openai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -9,6 +10,7 @@ import (
 	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/domain"
 	"github.com/danielmiessler/fabric/internal/plugins"
+	"github.com/danielmiessler/fabric/internal/plugins/ai"
 	openai "github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/openai/openai-go/packages/pagination"
@@ -81,9 +83,9 @@ func (o *Client) configure() (ret error) {
 	return
 }
 
-func (o *Client) ListModels() (ret []string, err error) {
+func (o *Client) ListModels(ctx context.Context) (ret []string, err error) {
 	var page *pagination.Page[openai.Model]
-	if page, err = o.ApiClient.Models.List(context.Background()); err != nil {
+	if page, err = o.ApiClient.Models.List(ctx); err != nil {
 		return
 	}
 	for _, mod := range page.Data {
@@ -93,22 +95,23 @@ func (o *Client) ListModels() (ret []string, err error) {
 }
 
 func (o *Client) SendStream(
-	msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
-) (err error) {
+	ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
+) (ret *domain.ChatResult, err error) {
 	// Use Responses API for OpenAI, Chat Completions API for other providers
 	if o.supportsResponsesAPI() {
-		return o.sendStreamResponses(msgs, opts, channel)
+		return o.sendStreamResponses(ctx, msgs, opts, channel)
 	}
-	return o.sendStreamChatCompletions(msgs, opts, channel)
+	return o.sendStreamChatCompletions(ctx, msgs, opts, channel)
 }
 
 func (o *Client) sendStreamResponses(
-	msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
-) (err error) {
+	ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string,
+) (ret *domain.ChatResult, err error) {
 	defer close(channel)
 
+	ret = &domain.ChatResult{}
 	req := o.buildResponseParams(msgs, opts)
-	stream := o.ApiClient.Responses.NewStreaming(context.Background(), req)
+	stream := o.ApiClient.Responses.NewStreaming(ctx, req)
 	for stream.Next() {
 		event := stream.Current()
 		switch event.Type {
@@ -116,15 +119,18 @@ func (o *Client) sendStreamResponses(
 			channel <- event.AsResponseOutputTextDelta().Delta
 		case string(constant.ResponseOutputTextDone("").Default()):
 			channel <- event.AsResponseOutputTextDone().Text
+		case string(constant.ResponseCompleted("").Default()), string(constant.ResponseIncomplete("").Default()):
+			ret.Usage = responseUsage(&event.Response)
+			ret.FinishReason = responseFinishReason(&event.Response)
 		}
 	}
-	if stream.Err() == nil {
+	if err = stream.Err(); err == nil {
 		channel <- "\n"
 	}
-	return stream.Err()
+	return
 }
 
-func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 	// Use Responses API for OpenAI, Chat Completions API for other providers
 	if o.supportsResponsesAPI() {
 		return o.sendResponses(ctx, msgs, opts)
@@ -132,10 +138,10 @@ func (o *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, o
 	return o.sendChatCompletions(ctx, msgs, opts)
 }
 
-func (o *Client) sendResponses(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret string, err error) {
+func (o *Client) sendResponses(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
 	// Validate model supports image generation if image file is specified
 	if opts.ImageFile != "" && !supportsImageGeneration(opts.Model) {
-		return "", fmt.Errorf("model '%s' does not support image generation. Supported models: %s", opts.Model, strings.Join(ImageGenerationSupportedModels, ", "))
+		return nil, fmt.Errorf("model '%s' does not support image generation. Supported models: %s", opts.Model, strings.Join(ImageGenerationSupportedModels, ", "))
 	}
 
 	req := o.buildResponseParams(msgs, opts)
@@ -150,7 +156,11 @@ func (o *Client) sendResponses(ctx context.Context, msgs []*chat.ChatCompletionM
 		return
 	}
 
-	ret = o.extractText(resp)
+	ret = &domain.ChatResult{
+		Content:      o.extractText(resp),
+		Usage:        responseUsage(resp),
+		FinishReason: responseFinishReason(resp),
+	}
 	return
 }
 
@@ -183,13 +193,17 @@ func (o *Client) buildResponseParams(
 	inputMsgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions,
 ) (ret responses.ResponseNewParams) {
 
-	items := make([]responses.ResponseInputItemUnionParam, len(inputMsgs))
-	for i, msgPtr := range inputMsgs {
+	items := make([]responses.ResponseInputItemUnionParam, 0, len(inputMsgs))
+	for _, msgPtr := range inputMsgs {
 		msg := *msgPtr
 		if strings.Contains(opts.Model, "deepseek") && len(inputMsgs) == 1 && msg.Role == chat.ChatMessageRoleSystem {
 			msg.Role = chat.ChatMessageRoleUser
 		}
-		items[i] = convertMessage(msg)
+		if msg.Role == chat.ChatMessageRoleTool || len(msg.ToolCalls) > 0 {
+			items = append(items, convertToolMessage(msg)...)
+			continue
+		}
+		items = append(items, convertMessage(msg))
 	}
 
 	ret = responses.ResponseNewParams{
@@ -220,10 +234,19 @@ func (o *Client) buildResponseParams(
 	// Add image generation tool if needed
 	tools = o.addImageGenerationTool(opts, tools)
 
+	// Add function tools declared for the request
+	tools = append(tools, buildResponseTools(opts.Tools)...)
+
 	if len(tools) > 0 {
 		ret.Tools = tools
 	}
 
+	if schema := decodeJSONSchema(opts.JSONSchema); schema != nil {
+		ret.Text = responses.ResponseTextConfigParam{
+			Format: responses.ResponseFormatTextConfigUnionParam{OfJSONSchema: responseTextFormat(schema)},
+		}
+	}
+
 	if !opts.Raw {
 		ret.Temperature = openai.Float(opts.Temperature)
 		if opts.TopP != 0 {
@@ -314,3 +337,32 @@ func (o *Client) extractText(resp *responses.Response) (ret string) {
 
 	return
 }
+
+// responseUsage converts the token usage of a Responses API response
+func responseUsage(resp *responses.Response) *chat.Usage {
+	if resp.Usage.TotalTokens == 0 {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     int(resp.Usage.InputTokens),
+		CompletionTokens: int(resp.Usage.OutputTokens),
+		TotalTokens:      int(resp.Usage.TotalTokens),
+	}
+}
+
+// responseFinishReason returns why the model stopped, e.g. "completed" or "max_output_tokens"
+func responseFinishReason(resp *responses.Response) string {
+	if resp.IncompleteDetails.Reason != "" {
+		return resp.IncompleteDetails.Reason
+	}
+	return string(resp.Status)
+}
+
+// ClassifyError maps the status of an *openai.Error, which compatible providers return as well
+func (o *Client) ClassifyError(err error) (ret ai.ErrorKind) {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		ret = ai.ErrorKindForStatus(apiErr.StatusCode)
+	}
+	return
+}
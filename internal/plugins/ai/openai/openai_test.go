@@ -82,6 +82,29 @@ func TestBuildResponseParams_WithoutSearch(t *testing.T) {
 	assert.Equal(t, openai.Float(opts.Temperature), params.Temperature)
 }
 
+func TestBuildParams_WithJSONSchema(t *testing.T) {
+	client := NewClient()
+	opts := &domain.ChatOptions{
+		Model:      "gpt-4o",
+		JSONSchema: []byte(`{"type": "object", "properties": {"score": {"type": "integer"}}}`),
+	}
+	msgs := []*chat.ChatCompletionMessage{{Role: "user", Content: "Rate this"}}
+
+	params := client.buildResponseParams(msgs, opts)
+	if assert.NotNil(t, params.Text.Format.OfJSONSchema) {
+		assert.Equal(t, "object", params.Text.Format.OfJSONSchema.Schema["type"])
+	}
+
+	completionParams := client.buildChatCompletionParams(msgs, opts)
+	if assert.NotNil(t, completionParams.ResponseFormat.OfJSONSchema) {
+		assert.Equal(t, structuredOutputName, completionParams.ResponseFormat.OfJSONSchema.JSONSchema.Name)
+	}
+
+	opts.JSONSchema = nil
+	assert.Nil(t, client.buildResponseParams(msgs, opts).Text.Format.OfJSONSchema)
+	assert.Nil(t, client.buildChatCompletionParams(msgs, opts).ResponseFormat.OfJSONSchema)
+}
+
 func TestBuildResponseParams_WithSearch(t *testing.T) {
 	client := NewClient()
 	opts := &domain.ChatOptions{
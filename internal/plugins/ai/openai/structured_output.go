@@ -0,0 +1,32 @@
+package openai
+
+import (
+	"encoding/json"
+
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+)
+
+// structuredOutputName names the JSON schema of the answer in the request
+const structuredOutputName = "answer"
+
+// decodeJSONSchema decodes the schema of a structured answer, nil if there is none or it is not an object
+func decodeJSONSchema(schema json.RawMessage) (ret map[string]any) {
+	if len(schema) > 0 {
+		_ = json.Unmarshal(schema, &ret)
+	}
+	return
+}
+
+// chatCompletionResponseFormat asks the Chat Completions API for an answer that matches the schema.
+// The schema is not strict, strict mode only supports schemas that require all properties.
+func chatCompletionResponseFormat(schema map[string]any) *shared.ResponseFormatJSONSchemaParam {
+	return &shared.ResponseFormatJSONSchemaParam{
+		JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{Name: structuredOutputName, Schema: schema},
+	}
+}
+
+// responseTextFormat asks the Responses API for an answer that matches the schema
+func responseTextFormat(schema map[string]any) *responses.ResponseFormatTextJSONSchemaConfigParam {
+	return &responses.ResponseFormatTextJSONSchemaConfigParam{Name: structuredOutputName, Schema: schema}
+}
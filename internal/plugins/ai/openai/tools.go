@@ -0,0 +1,130 @@
+package openai
+
+// This file contains the native tool/function calling support for both the
+// Responses API and the Chat Completions API.
+
+import (
+	"context"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+	openai "github.com/openai/openai-go"
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+)
+
+// SendWithTools uses the Responses API when the provider supports it, where requested calls
+// are function_call output items, and Chat Completions otherwise, where they are the
+// tool_calls of the first choice.
+func (o *Client) SendWithTools(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (ret *domain.ChatResult, err error) {
+	if o.supportsResponsesAPI() {
+		req := o.buildResponseParams(msgs, opts)
+		var resp *responses.Response
+		if resp, err = o.ApiClient.Responses.New(ctx, req); err != nil {
+			return
+		}
+		ret = &domain.ChatResult{
+			Content:      o.extractText(resp),
+			ToolCalls:    extractResponseToolCalls(resp),
+			Usage:        responseUsage(resp),
+			FinishReason: responseFinishReason(resp),
+		}
+		return
+	}
+
+	req := o.buildChatCompletionParams(msgs, opts)
+	var resp *openai.ChatCompletion
+	if resp, err = o.ApiClient.Chat.Completions.New(ctx, req); err != nil {
+		return
+	}
+	ret = &domain.ChatResult{Usage: completionUsage(resp.Usage)}
+	if len(resp.Choices) > 0 {
+		ret.Content = resp.Choices[0].Message.Content
+		ret.FinishReason = resp.Choices[0].FinishReason
+		for _, call := range resp.Choices[0].Message.ToolCalls {
+			ret.ToolCalls = append(ret.ToolCalls, chat.ToolCall{
+				ID:   call.ID,
+				Type: chat.ToolTypeFunction,
+				Function: chat.FunctionCall{
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				},
+			})
+		}
+	}
+	return
+}
+
+// buildResponseTools converts fabric tools to Responses API function tools
+func buildResponseTools(tools []chat.Tool) (ret []responses.ToolUnionParam) {
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		param := responses.ToolParamOfFunction(tool.Function.Name, toolParameters(tool.Function), false)
+		if tool.Function.Description != "" {
+			param.OfFunction.Description = openai.String(tool.Function.Description)
+		}
+		ret = append(ret, param)
+	}
+	return
+}
+
+// buildChatCompletionTools converts fabric tools to Chat Completions API tools
+func buildChatCompletionTools(tools []chat.Tool) (ret []openai.ChatCompletionToolParam) {
+	for _, tool := range tools {
+		if tool.Function == nil {
+			continue
+		}
+		definition := shared.FunctionDefinitionParam{
+			Name:       tool.Function.Name,
+			Parameters: shared.FunctionParameters(toolParameters(tool.Function)),
+		}
+		if tool.Function.Description != "" {
+			definition.Description = openai.String(tool.Function.Description)
+		}
+		ret = append(ret, openai.ChatCompletionToolParam{Function: definition})
+	}
+	return
+}
+
+// toolParameters returns the parameter schema, defaulting to an empty object schema
+func toolParameters(function *chat.FunctionDefinition) map[string]any {
+	if len(function.Parameters) == 0 {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return function.Parameters
+}
+
+// convertToolMessage converts assistant tool calls and tool results to Responses API input items
+func convertToolMessage(msg chat.ChatCompletionMessage) (ret []responses.ResponseInputItemUnionParam) {
+	if msg.Role == chat.ChatMessageRoleTool {
+		return append(ret, responses.ResponseInputItemParamOfFunctionCallOutput(msg.ToolCallID, msg.Content))
+	}
+	if msg.Content != "" {
+		ret = append(ret, responses.ResponseInputItemParamOfMessage(msg.Content, responses.EasyInputMessageRoleAssistant))
+	}
+	for _, call := range msg.ToolCalls {
+		ret = append(ret, responses.ResponseInputItemParamOfFunctionCall(call.Function.Arguments, call.ID, call.Function.Name))
+	}
+	return
+}
+
+// extractResponseToolCalls collects the function calls from a Responses API response
+func extractResponseToolCalls(resp *responses.Response) (ret []chat.ToolCall) {
+	for _, item := range resp.Output {
+		if item.Type != "function_call" {
+			continue
+		}
+		call := item.AsFunctionCall()
+		ret = append(ret, chat.ToolCall{
+			ID:   call.CallID,
+			Type: chat.ToolTypeFunction,
+			Function: chat.FunctionCall{
+				Name:      call.Name,
+				Arguments: call.Arguments,
+			},
+		})
+	}
+	return
+}
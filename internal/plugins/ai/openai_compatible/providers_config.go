@@ -33,16 +33,14 @@ func NewClient(providerConfig ProviderConfig) *Client {
 }
 
 // ListModels overrides the default ListModels to handle different response formats
-func (c *Client) ListModels() ([]string, error) {
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
 	// First try the standard OpenAI SDK approach
-	models, err := c.Client.ListModels()
+	models, err := c.Client.ListModels(ctx)
 	if err == nil && len(models) > 0 { // only return if OpenAI SDK returns models
 		return models, nil
 	}
 
-	// TODO: Handle context properly in Fabric by accepting and propagating a context.Context
-	// instead of creating a new one here.
-	return c.DirectlyGetModels(context.Background())
+	return c.DirectlyGetModels(ctx)
 }
 
 // ProviderMap is a map of provider name to ProviderConfig for O(1) lookup
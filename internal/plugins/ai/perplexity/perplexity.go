@@ -3,6 +3,7 @@ package perplexity
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"sync" // Added sync package
 
@@ -55,16 +56,16 @@ func (c *Client) Configure() error {
 	return nil
 }
 
-func (c *Client) ListModels() ([]string, error) {
+func (c *Client) ListModels(_ context.Context) ([]string, error) {
 	// Perplexity API does not have a ListModels endpoint.
 	// We return a predefined list.
 	return models, nil
 }
 
-func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (string, error) {
+func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions) (*domain.ChatResult, error) {
 	if c.client == nil {
 		if err := c.Configure(); err != nil {
-			return "", fmt.Errorf("failed to configure Perplexity client: %w", err)
+			return nil, fmt.Errorf("failed to configure Perplexity client: %w", err)
 		}
 	}
 
@@ -101,9 +102,9 @@ func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, o
 	request := perplexity.NewCompletionRequest(requestOptions...)
 
 	// Corrected: Use SendCompletionRequest method from perplexity-go library
-	resp, err := c.client.SendCompletionRequest(request) // Pass request directly
+	resp, err := c.clientWithContext(ctx).SendCompletionRequest(request) // Pass request directly
 	if err != nil {
-		return "", fmt.Errorf("perplexity API request failed: %w", err) // Corrected capitalization
+		return nil, fmt.Errorf("perplexity API request failed: %w", err) // Corrected capitalization
 	}
 
 	content := resp.GetLastContent()
@@ -117,14 +118,18 @@ func (c *Client) Send(ctx context.Context, msgs []*chat.ChatCompletionMessage, o
 		}
 	}
 
-	return content, nil
+	return &domain.ChatResult{
+		Content:      content,
+		Usage:        toUsage(resp),
+		FinishReason: finishReason(resp),
+	}, nil
 }
 
-func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) error {
+func (c *Client) SendStream(ctx context.Context, msgs []*chat.ChatCompletionMessage, opts *domain.ChatOptions, channel chan string) (*domain.ChatResult, error) {
 	if c.client == nil {
 		if err := c.Configure(); err != nil {
 			close(channel) // Ensure channel is closed on error
-			return fmt.Errorf("failed to configure Perplexity client: %w", err)
+			return nil, fmt.Errorf("failed to configure Perplexity client: %w", err)
 		}
 	}
 
@@ -163,54 +168,93 @@ func (c *Client) SendStream(msgs []*chat.ChatCompletionMessage, opts *domain.Cha
 	request := perplexity.NewCompletionRequest(requestOptions...)
 
 	responseChan := make(chan perplexity.CompletionResponse)
+	errChan := make(chan error, 1)
 	var wg sync.WaitGroup // Use sync.WaitGroup
 	wg.Add(1)
 
 	go func() {
-		err := c.client.SendSSEHTTPRequest(&wg, request, responseChan)
-		if err != nil {
-			// Log error, can't send to string channel directly.
-			// Consider a mechanism to propagate this error if needed.
-			fmt.Fprintf(os.Stderr, "perplexity streaming error: %v\\n", err) // Corrected capitalization
-			// If the error occurs during stream setup, the channel might not have been closed by the receiver loop.
-			// However, closing it here might cause a panic if the receiver loop also tries to close it.
-			// close(channel) // Caution: Uncommenting this may cause panic, as channel is closed in the receiver goroutine.
-		}
+		// The response channel is closed by SendSSEHTTPRequest when the request is done
+		errChan <- c.clientWithContext(ctx).SendSSEHTTPRequest(&wg, request, responseChan)
 	}()
 
-	go func() {
-		defer close(channel) // Ensure the output channel is closed when this goroutine finishes
-		var lastResponse *perplexity.CompletionResponse
-		for resp := range responseChan {
-			lastResponse = &resp
-			if len(resp.Choices) > 0 {
-				content := ""
-				// Corrected: Check Delta.Content and Message.Content directly for non-emptiness
-				// as Delta and Message are structs, not pointers, in perplexity.Choice
-				if resp.Choices[0].Delta.Content != "" {
-					content = resp.Choices[0].Delta.Content
-				} else if resp.Choices[0].Message.Content != "" {
-					content = resp.Choices[0].Message.Content
-				}
-				if content != "" {
-					channel <- content
-				}
+	defer close(channel) // Ensure the output channel is closed when streaming finishes
+	var lastResponse *perplexity.CompletionResponse
+	for resp := range responseChan {
+		lastResponse = &resp
+		if len(resp.Choices) > 0 {
+			content := ""
+			// Corrected: Check Delta.Content and Message.Content directly for non-emptiness
+			// as Delta and Message are structs, not pointers, in perplexity.Choice
+			if resp.Choices[0].Delta.Content != "" {
+				content = resp.Choices[0].Delta.Content
+			} else if resp.Choices[0].Message.Content != "" {
+				content = resp.Choices[0].Message.Content
+			}
+			if content != "" {
+				channel <- content
 			}
 		}
+	}
+
+	if err := <-errChan; err != nil {
+		return nil, fmt.Errorf("perplexity streaming error: %w", err)
+	}
 
-		// Send citations at the end if available
-		if lastResponse != nil {
-			citations := lastResponse.GetCitations()
-			if len(citations) > 0 {
-				channel <- "\n\n# CITATIONS\n\n"
-				for i, citation := range citations {
-					channel <- fmt.Sprintf("- [%d] %s\n", i+1, citation)
-				}
+	result := &domain.ChatResult{}
+
+	// Send citations at the end if available
+	if lastResponse != nil {
+		citations := lastResponse.GetCitations()
+		if len(citations) > 0 {
+			channel <- "\n\n# CITATIONS\n\n"
+			for i, citation := range citations {
+				channel <- fmt.Sprintf("- [%d] %s\n", i+1, citation)
 			}
 		}
-	}()
+		// The last event carries the usage of the whole response
+		result.Usage = toUsage(lastResponse)
+		result.FinishReason = finishReason(lastResponse)
+	}
 
-	return nil
+	return result, nil
+}
+
+func toUsage(resp *perplexity.CompletionResponse) *chat.Usage {
+	if resp.Usage.TotalTokens == 0 {
+		return nil
+	}
+	return &chat.Usage{
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+		TotalTokens:      resp.Usage.TotalTokens,
+	}
+}
+
+func finishReason(resp *perplexity.CompletionResponse) string {
+	if len(resp.Choices) == 0 {
+		return ""
+	}
+	return resp.Choices[0].FinishReason
+}
+
+// clientWithContext returns a Perplexity client whose HTTP requests are bound to ctx.
+// The perplexity-go library does not accept a context, so it is attached by the transport.
+func (c *Client) clientWithContext(ctx context.Context) (ret *perplexity.Client) {
+	ret = perplexity.NewClient(c.APIKey.Value)
+	ret.SetHTTPClient(&http.Client{
+		Timeout:   perplexity.DefaultTimeout,
+		Transport: &contextTransport{ctx: ctx, underlyingTransport: http.DefaultTransport},
+	})
+	return
+}
+
+type contextTransport struct {
+	ctx                 context.Context
+	underlyingTransport http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.underlyingTransport.RoundTrip(req.WithContext(t.ctx))
 }
 
 func (c *Client) NeedsRawMode(modelName string) bool {
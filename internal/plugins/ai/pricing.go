@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"gopkg.in/yaml.v3"
+)
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// PriceTable maps model names to their prices. It is read from an optional YAML file:
+//
+//	gpt-4o:
+//	  input: 2.50
+//	  output: 10.00
+type PriceTable map[string]ModelPrice
+
+// LoadPriceTable reads the price table file; a missing file gives an empty table
+func LoadPriceTable(path string) (ret PriceTable, err error) {
+	ret = PriceTable{}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if err = yaml.Unmarshal(data, &ret); err != nil {
+		err = fmt.Errorf("failed to parse price table %s: %w", path, err)
+	}
+	return
+}
+
+// Cost returns the cost in USD of the usage for the model. ok is false when the model has no price.
+func (o PriceTable) Cost(model string, usage *chat.Usage) (ret float64, ok bool) {
+	if usage == nil {
+		return
+	}
+	var price ModelPrice
+	if price, ok = o[model]; !ok {
+		for name, candidate := range o {
+			if strings.EqualFold(name, model) {
+				price, ok = candidate, true
+				break
+			}
+		}
+	}
+	if ok {
+		ret = (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1_000_000
+	}
+	return
+}
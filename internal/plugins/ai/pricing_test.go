@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+)
+
+func TestLoadPriceTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	if err := os.WriteFile(path, []byte("gpt-4o:\n  input: 2.5\n  output: 10\n"), 0644); err != nil {
+		t.Fatalf("failed to write price table: %v", err)
+	}
+
+	prices, err := LoadPriceTable(path)
+	if err != nil {
+		t.Fatalf("LoadPriceTable() error = %v", err)
+	}
+
+	usage := &chat.Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	cost, ok := prices.Cost("GPT-4o", usage)
+	if !ok {
+		t.Fatalf("expected a price for GPT-4o")
+	}
+	if want := 0.0075; math.Abs(cost-want) > 1e-12 {
+		t.Errorf("Cost() = %v, want %v", cost, want)
+	}
+
+	if _, ok = prices.Cost("unknown", usage); ok {
+		t.Errorf("expected no price for an unknown model")
+	}
+}
+
+func TestLoadPriceTable_MissingFile(t *testing.T) {
+	prices, err := LoadPriceTable(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadPriceTable() error = %v", err)
+	}
+	if len(prices) != 0 {
+		t.Errorf("expected an empty price table, got %v", prices)
+	}
+}
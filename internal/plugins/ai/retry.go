@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrorKind groups vendor errors by how they should be retried. The empty kind is not retried.
+type ErrorKind string
+
+const (
+	ErrorKindRateLimit  ErrorKind = "rate_limit"
+	ErrorKindOverloaded ErrorKind = "overloaded"
+	ErrorKindServer     ErrorKind = "server"
+	ErrorKindNetwork    ErrorKind = "network"
+)
+
+// ErrorClassifier is implemented by vendors that can tell which errors of their API are worth retrying
+type ErrorClassifier interface {
+	ClassifyError(err error) ErrorKind
+}
+
+// ClassifyError returns the kind of a vendor error, asking the vendor first and
+// falling back to network errors
+func ClassifyError(vendor Vendor, err error) (ret ErrorKind) {
+	if err == nil {
+		return
+	}
+	if classifier, ok := vendor.(ErrorClassifier); ok {
+		if ret = classifier.ClassifyError(err); ret != "" {
+			return
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		ret = ErrorKindNetwork
+	}
+	return
+}
+
+// ErrorKindForStatus maps an HTTP status code of a vendor response to an error kind
+func ErrorKindForStatus(code int) (ret ErrorKind) {
+	switch {
+	case code == http.StatusTooManyRequests:
+		ret = ErrorKindRateLimit
+	case code == http.StatusServiceUnavailable || code == 529:
+		ret = ErrorKindOverloaded
+	case code == http.StatusRequestTimeout || code >= 500:
+		ret = ErrorKindServer
+	}
+	return
+}
+
+// RetryRule sets how often and how fast a request is retried. Zero fields use the enclosing rule.
+type RetryRule struct {
+	// MaxAttempts counts the first attempt, 1 disables retries
+	MaxAttempts  int           `yaml:"max_attempts"`
+	InitialDelay time.Duration `yaml:"initial_delay"`
+	MaxDelay     time.Duration `yaml:"max_delay"`
+}
+
+// Delay returns the exponential backoff with jitter before the next attempt after attempt
+func (o RetryRule) Delay(attempt int) time.Duration {
+	delay := o.InitialDelay
+	for i := 1; i < attempt && delay < o.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, o.MaxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	// Wait between half and the full delay so parallel clients do not retry in lockstep
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func (o RetryRule) with(override RetryRule) RetryRule {
+	if override.MaxAttempts != 0 {
+		o.MaxAttempts = override.MaxAttempts
+	}
+	if override.InitialDelay != 0 {
+		o.InitialDelay = override.InitialDelay
+	}
+	if override.MaxDelay != 0 {
+		o.MaxDelay = override.MaxDelay
+	}
+	return o
+}
+
+// DefaultRetryRule applies to every retryable error that has no configured rule
+var DefaultRetryRule = RetryRule{MaxAttempts: 3, InitialDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// RetryConfig is read from an optional YAML file:
+//
+//	max_attempts: 3
+//	initial_delay: 1s
+//	max_delay: 30s
+//	errors:
+//	  rate_limit: {max_attempts: 5, initial_delay: 5s}
+//	vendors:
+//	  Anthropic:
+//	    overloaded: {max_attempts: 6}
+//	fallbacks:
+//	  summarize: [Ollama|llama3]
+type RetryConfig struct {
+	RetryRule `yaml:",inline"`
+	// Errors overrides the rule for a kind of error
+	Errors map[ErrorKind]RetryRule `yaml:"errors"`
+	// Vendors overrides the rule for a kind of error of one vendor
+	Vendors map[string]map[ErrorKind]RetryRule `yaml:"vendors"`
+	// Fallbacks are the fallback models of patterns, as model or vendor|model
+	Fallbacks map[string][]string `yaml:"fallbacks"`
+}
+
+// LoadRetryConfig reads the retry config file; a missing file gives the defaults
+func LoadRetryConfig(path string) (ret *RetryConfig, err error) {
+	ret = &RetryConfig{}
+
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	if err = yaml.Unmarshal(data, ret); err != nil {
+		err = fmt.Errorf("failed to parse retry config %s: %w", path, err)
+	}
+	return
+}
+
+// Rule returns the rule for a kind of error of the vendor
+func (o *RetryConfig) Rule(vendor string, kind ErrorKind) (ret RetryRule) {
+	ret = DefaultRetryRule
+	if o == nil {
+		return
+	}
+	ret = ret.with(o.RetryRule).with(o.Errors[kind])
+	if rules, ok := o.Vendors[vendor]; ok {
+		ret = ret.with(rules[kind])
+	}
+	return
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// classifyingVendor satisfies Vendor through a nil embedded interface, only the classifier is called
+type classifyingVendor struct {
+	Vendor
+}
+
+func (o classifyingVendor) ClassifyError(err error) ErrorKind {
+	if err.Error() == "slow down" {
+		return ErrorKindRateLimit
+	}
+	return ""
+}
+
+func TestClassifyError(t *testing.T) {
+	vendor := classifyingVendor{}
+
+	if got := ClassifyError(vendor, errors.New("slow down")); got != ErrorKindRateLimit {
+		t.Errorf("expected the vendor classification, got %q", got)
+	}
+	if got := ClassifyError(vendor, &net.OpError{Op: "dial", Err: errors.New("connection reset")}); got != ErrorKindNetwork {
+		t.Errorf("expected a network error, got %q", got)
+	}
+	if got := ClassifyError(vendor, errors.New("invalid api key")); got != "" {
+		t.Errorf("expected no retry for other errors, got %q", got)
+	}
+}
+
+func TestErrorKindForStatus(t *testing.T) {
+	tests := map[int]ErrorKind{
+		429: ErrorKindRateLimit,
+		529: ErrorKindOverloaded,
+		503: ErrorKindOverloaded,
+		500: ErrorKindServer,
+		408: ErrorKindServer,
+		400: "",
+		401: "",
+	}
+	for code, want := range tests {
+		if got := ErrorKindForStatus(code); got != want {
+			t.Errorf("ErrorKindForStatus(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestRetryRule_Delay(t *testing.T) {
+	rule := RetryRule{MaxAttempts: 5, InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		for range 20 {
+			if got := rule.Delay(attempt); got < want/2 || got > want {
+				t.Fatalf("Delay(%d) = %v, want between %v and %v", attempt, got, want/2, want)
+			}
+		}
+	}
+}
+
+func TestLoadRetryConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retry.yaml")
+	content := `max_attempts: 4
+initial_delay: 2s
+errors:
+  rate_limit: {max_attempts: 6}
+vendors:
+  Anthropic:
+    overloaded: {initial_delay: 10s}
+fallbacks:
+  summarize: [Ollama|llama3]
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write retry config: %v", err)
+	}
+
+	config, err := LoadRetryConfig(path)
+	if err != nil {
+		t.Fatalf("LoadRetryConfig() error = %v", err)
+	}
+
+	if got := config.Rule("OpenAI", ErrorKindServer); got != (RetryRule{MaxAttempts: 4, InitialDelay: 2 * time.Second, MaxDelay: 30 * time.Second}) {
+		t.Errorf("unexpected base rule %+v", got)
+	}
+	if got := config.Rule("OpenAI", ErrorKindRateLimit); got.MaxAttempts != 6 || got.InitialDelay != 2*time.Second {
+		t.Errorf("unexpected rate limit rule %+v", got)
+	}
+	if got := config.Rule("Anthropic", ErrorKindOverloaded); got.MaxAttempts != 4 || got.InitialDelay != 10*time.Second {
+		t.Errorf("unexpected vendor rule %+v", got)
+	}
+	if got := config.Fallbacks["summarize"]; len(got) != 1 || got[0] != "Ollama|llama3" {
+		t.Errorf("unexpected fallbacks %v", got)
+	}
+}
+
+func TestLoadRetryConfig_MissingFile(t *testing.T) {
+	config, err := LoadRetryConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadRetryConfig() error = %v", err)
+	}
+	if got := config.Rule("OpenAI", ErrorKindRateLimit); got != DefaultRetryRule {
+		t.Errorf("expected the default rule, got %+v", got)
+	}
+}
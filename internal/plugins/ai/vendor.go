@@ -11,8 +11,15 @@ import (
 
 type Vendor interface {
 	plugins.Plugin
-	ListModels() ([]string, error)
-	SendStream([]*chat.ChatCompletionMessage, *domain.ChatOptions, chan string) error
-	Send(context.Context, []*chat.ChatCompletionMessage, *domain.ChatOptions) (string, error)
+	ListModels(context.Context) ([]string, error)
+	SendStream(context.Context, []*chat.ChatCompletionMessage, *domain.ChatOptions, chan string) (*domain.ChatResult, error)
+	Send(context.Context, []*chat.ChatCompletionMessage, *domain.ChatOptions) (*domain.ChatResult, error)
 	NeedsRawMode(modelName string) bool
 }
+
+// ToolCaller is implemented by vendors that support native tool/function calling.
+// The tools to offer are taken from ChatOptions.Tools; the returned result
+// carries either the final answer or the tool calls requested by the model.
+type ToolCaller interface {
+	SendWithTools(context.Context, []*chat.ChatCompletionMessage, *domain.ChatOptions) (*domain.ChatResult, error)
+}
@@ -44,9 +44,9 @@ func (o *VendorsManager) SetupFillEnvFileContent(envFileContent *bytes.Buffer) {
 	}
 }
 
-func (o *VendorsManager) GetModels() (ret *VendorsModels, err error) {
+func (o *VendorsManager) GetModels(ctx context.Context) (ret *VendorsModels, err error) {
 	if o.Models == nil {
-		err = o.readModels()
+		err = o.readModels(ctx)
 	}
 	ret = o.Models
 	return
@@ -67,7 +67,7 @@ func (o *VendorsManager) FindByName(name string) Vendor {
 	return o.VendorsByName[name]
 }
 
-func (o *VendorsManager) readModels() (err error) {
+func (o *VendorsManager) readModels(ctx context.Context) (err error) {
 	if len(o.Vendors) == 0 {
 
 		err = fmt.Errorf("no AI vendors configured to read models from. Please configure at least one AI vendor")
@@ -78,7 +78,7 @@ func (o *VendorsManager) readModels() (err error) {
 
 	var wg sync.WaitGroup
 	resultsChan := make(chan modelResult, len(o.Vendors))
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	for _, vendor := range o.Vendors {
@@ -111,7 +111,7 @@ func (o *VendorsManager) fetchVendorModels(
 
 	defer wg.Done()
 
-	models, err := vendor.ListModels()
+	models, err := vendor.ListModels(ctx)
 	select {
 	case <-ctx.Done():
 		// Context canceled, don't send the result
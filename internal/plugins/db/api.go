@@ -1,13 +1,19 @@
 package db
 
 type Storage[T any] interface {
+	Items
 	Configure() (err error)
 	Get(name string) (ret *T, err error)
+	ListNames(shellCompleteList bool) (err error)
+}
+
+// Items stores the content of items by their name, the part of Storage that does not
+// depend on the type of the items. The files of fsdb and the tables of sqlitedb implement it.
+type Items interface {
 	GetNames() (ret []string, err error)
 	Delete(name string) (err error)
 	Exists(name string) (ret bool)
 	Rename(oldName, newName string) (err error)
 	Save(name string, content []byte) (err error)
 	Load(name string) (ret []byte, err error)
-	ListNames(shellCompleteList bool) (err error)
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/danielmiessler/fabric/internal/plugins/db/sqlitedb"
 	"github.com/joho/godotenv"
 )
 
@@ -21,6 +22,8 @@ func NewDb(dir string) (db *Db) {
 		SystemPatternFile:      "system.md",
 		UniquePatternsFilePath: db.FilePath("unique_patterns.txt"),
 		CustomPatternsDir:      "", // Will be set after loading .env file
+		History: &StorageEntity{
+			Label: "Pattern versions", Dir: db.FilePath("pattern_versions"), FileExtension: ".json"},
 	}
 
 	db.Sessions = &SessionsEntity{
@@ -29,6 +32,8 @@ func NewDb(dir string) (db *Db) {
 	db.Contexts = &ContextsEntity{
 		&StorageEntity{Label: "Contexts", Dir: db.FilePath("contexts")}}
 
+	db.Search = NewSearchIndex(db)
+
 	return
 }
 
@@ -40,6 +45,11 @@ type Db struct {
 	Contexts *ContextsEntity
 
 	EnvFilePath string
+
+	Search *SearchIndex
+
+	// SQLite holds the patterns, sessions and contexts when the SQLite storage is chosen in the setup
+	SQLite *sqlitedb.Db
 }
 
 func (o *Db) Configure() (err error) {
@@ -63,10 +73,18 @@ func (o *Db) Configure() (err error) {
 		o.Patterns.CustomPatternsDir = customPatternsDir
 	}
 
+	if err = o.configureStorage(); err != nil {
+		return
+	}
+
 	if err = o.Patterns.Configure(); err != nil {
 		return
 	}
 
+	if err = o.Patterns.History.Configure(); err != nil {
+		return
+	}
+
 	if err = o.Sessions.Configure(); err != nil {
 		return
 	}
@@ -75,6 +93,55 @@ func (o *Db) Configure() (err error) {
 		return
 	}
 
+	o.Search.watch()
+
+	return
+}
+
+// configureStorage opens the SQLite database if the setup chose it over the files in Dir
+func (o *Db) configureStorage() (err error) {
+	switch backend := os.Getenv(StorageBackendEnv); backend {
+	case "", StorageFiles:
+	case StorageSQLite:
+		var sqlite *sqlitedb.Db
+		if sqlite, err = sqlitedb.Open(o.SQLitePath()); err != nil {
+			err = fmt.Errorf("%w, set %s=%s in %s to use the files again", err, StorageBackendEnv, StorageFiles, o.EnvFilePath)
+			return
+		}
+		o.useSQLite(sqlite)
+	default:
+		err = fmt.Errorf("unknown storage %q in %s, use %s or %s", backend, StorageBackendEnv, StorageFiles, StorageSQLite)
+	}
+	return
+}
+
+// useSQLite stores the patterns, sessions and contexts in the SQLite database
+func (o *Db) useSQLite(sqlite *sqlitedb.Db) {
+	o.SQLite = sqlite
+	o.Patterns.Items = sqlite.Table("patterns", o.Patterns.SystemPatternFile)
+	o.Patterns.History.Items = sqlite.Table("pattern_versions", "")
+	o.Sessions.Items = sqlite.Table("sessions", "")
+	o.Contexts.Items = sqlite.Table("contexts", "")
+}
+
+// SQLitePath is the path of the SQLite database, in Dir unless another path is configured
+func (o *Db) SQLitePath() (ret string) {
+	if ret = os.Getenv(StorageSQLitePathEnv); ret == "" {
+		ret = o.FilePath(sqlitedb.FileName)
+	} else if strings.HasPrefix(ret, "~/") {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			ret = filepath.Join(homeDir, ret[2:])
+		}
+	}
+	return
+}
+
+// Close closes the SQLite database if it is used
+func (o *Db) Close() (err error) {
+	if o.SQLite != nil {
+		err = o.SQLite.Close()
+		o.SQLite = nil
+	}
 	return
 }
 
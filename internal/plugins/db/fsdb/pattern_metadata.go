@@ -0,0 +1,202 @@
+package fsdb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PatternMetadataFile is the optional metadata of a pattern, next to its system.md. The same
+// fields can be given as YAML front-matter at the top of the system.md, which wins over the file.
+const PatternMetadataFile = "pattern.yaml"
+
+const frontMatterDelimiter = "---"
+
+// PatternMetadata describes a pattern and the defaults it is used with. Flags given by the
+// user override the defaults.
+type PatternMetadata struct {
+	Description string                      `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string                    `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Variables   map[string]*PatternVariable `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Model       string                      `yaml:"model,omitempty" json:"model,omitempty"`
+	Temperature *float64                    `yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	Strategy    string                      `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+	// Format is the output format of the answers, like markdown or json
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+}
+
+// PatternVariable is a variable of a pattern, named as in the pattern, like role for {{role}}
+type PatternVariable struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     string `yaml:"default,omitempty" json:"default,omitempty"`
+	Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// PatternInfo is a pattern with its description, tags and output format, as listed by ListPatterns
+type PatternInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Format      string   `json:"format,omitempty"`
+}
+
+func newPatternInfo(name string, metadata *PatternMetadata) *PatternInfo {
+	return &PatternInfo{Name: name, Description: metadata.Description, Tags: metadata.Tags, Format: metadata.Format}
+}
+
+// String returns the pattern as one line, like "translate - Translate a text [language] (format: markdown)"
+func (o *PatternInfo) String() string {
+	line := o.Name
+	if o.Description != "" {
+		line += " - " + o.Description
+	}
+	if len(o.Tags) > 0 {
+		line += " [" + strings.Join(o.Tags, ", ") + "]"
+	}
+	if o.Format != "" {
+		line += " (format: " + o.Format + ")"
+	}
+	return line
+}
+
+// HasTags tells whether the metadata has all the tags, ignoring case
+func (o *PatternMetadata) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		if !slices.ContainsFunc(o.Tags, func(own string) bool { return strings.EqualFold(own, tag) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitFrontMatter separates the YAML front-matter from the prompt of a system.md. Content
+// without front-matter, or whose front-matter is not a YAML mapping, is all prompt.
+func splitFrontMatter(content string) (frontMatter string, prompt string) {
+	prompt = content
+	firstLine, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimRight(firstLine, "\r") != frontMatterDelimiter {
+		return
+	}
+	offset := 0
+	for _, line := range strings.SplitAfter(rest, "\n") {
+		if strings.TrimRight(line, "\r\n") == frontMatterDelimiter {
+			// A markdown rule with comments or text in between is not front-matter
+			var mapping map[string]any
+			if yaml.Unmarshal([]byte(rest[:offset]), &mapping) != nil ||
+				(len(mapping) == 0 && strings.TrimSpace(rest[:offset]) != "") {
+				return
+			}
+			return rest[:offset], rest[offset+len(line):]
+		}
+		offset += len(line)
+	}
+	return
+}
+
+// parsePattern reads the front-matter of a system.md into the metadata, which may already hold
+// the metadata file, and returns the prompt
+func parsePattern(name string, content string, metadata *PatternMetadata) (prompt string, err error) {
+	var frontMatter string
+	if frontMatter, prompt = splitFrontMatter(content); frontMatter != "" {
+		if err = yaml.Unmarshal([]byte(frontMatter), metadata); err != nil {
+			err = fmt.Errorf("invalid front-matter in pattern %s: %v", name, err)
+		}
+	}
+	return
+}
+
+// loadMetadataFile reads the pattern.yaml of a pattern, a pattern without one has empty metadata
+func (o *PatternsEntity) loadMetadataFile(name string) (ret *PatternMetadata, err error) {
+	ret = &PatternMetadata{}
+	var content []byte
+	if content, err = o.LoadFile(name, PatternMetadataFile); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	if err = yaml.Unmarshal(content, ret); err != nil {
+		err = fmt.Errorf("invalid %s of pattern %s: %v", PatternMetadataFile, name, err)
+	}
+	return
+}
+
+// GetMetadata returns the metadata of a pattern, given by name or as a file path
+func (o *PatternsEntity) GetMetadata(source string) (ret *PatternMetadata, err error) {
+	var pattern *Pattern
+	if isPatternFilePath(source) {
+		pattern, err = o.getFromFile(source)
+	} else {
+		pattern, err = o.getFromDB(source)
+	}
+	if err != nil {
+		return
+	}
+	ret = pattern.Metadata
+	return
+}
+
+// applyDefaults adds the defaults of the pattern variables that are not set, a required
+// variable must be set by the user
+func (o *PatternMetadata) applyDefaults(variables map[string]string) (ret map[string]string) {
+	ret = make(map[string]string, len(variables)+len(o.Variables))
+	for key, value := range variables {
+		ret[key] = value
+	}
+	for key, variable := range o.Variables {
+		if _, ok := ret[key]; !ok && variable != nil && !variable.Required && variable.Default != "" {
+			ret[key] = variable.Default
+		}
+	}
+	return
+}
+
+// GetInfos returns the patterns that have all the tags with their descriptions
+func (o *PatternsEntity) GetInfos(tags []string) (ret []*PatternInfo, err error) {
+	var names []string
+	if names, err = o.GetNames(); err != nil {
+		return
+	}
+	for _, name := range names {
+		var metadata *PatternMetadata
+		if metadata, err = o.GetMetadata(name); err != nil {
+			return
+		}
+		if !metadata.HasTags(tags) {
+			continue
+		}
+		ret = append(ret, newPatternInfo(name, metadata))
+	}
+	return
+}
+
+// ListPatterns prints the patterns that have all the tags, with their descriptions if details is set
+func (o *PatternsEntity) ListPatterns(tags []string, details bool, shellCompleteList bool) (err error) {
+	if len(tags) == 0 && !details {
+		return o.ListNames(shellCompleteList)
+	}
+
+	var infos []*PatternInfo
+	if infos, err = o.GetInfos(tags); err != nil {
+		return
+	}
+	if len(infos) == 0 {
+		if !shellCompleteList {
+			fmt.Printf("\nNo %v\n", o.StorageEntity.Label)
+		}
+		return
+	}
+
+	for _, info := range infos {
+		if !details || shellCompleteList {
+			fmt.Println(info.Name)
+			continue
+		}
+		fmt.Println(info)
+	}
+	return
+}
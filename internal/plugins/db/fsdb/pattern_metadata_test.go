@@ -0,0 +1,141 @@
+package fsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		wantFrontMatter string
+		wantPrompt      string
+	}{
+		{
+			name:            "front-matter",
+			content:         "---\ndescription: Summarize\ntags: [writing]\n---\nYou summarize {{input}}",
+			wantFrontMatter: "description: Summarize\ntags: [writing]\n",
+			wantPrompt:      "You summarize {{input}}",
+		},
+		{
+			name:            "windows line endings",
+			content:         "---\r\nmodel: gpt-4o\r\n---\r\nPrompt",
+			wantFrontMatter: "model: gpt-4o\r\n",
+			wantPrompt:      "Prompt",
+		},
+		{
+			name:       "no front-matter",
+			content:    "# IDENTITY\n---\nkey: value\n---\n",
+			wantPrompt: "# IDENTITY\n---\nkey: value\n---\n",
+		},
+		{
+			name:       "markdown rule around a heading",
+			content:    "---\n# IDENTITY\n---\nYou are an expert",
+			wantPrompt: "---\n# IDENTITY\n---\nYou are an expert",
+		},
+		{
+			name:       "text between rules",
+			content:    "---\nJust some text\n---\nPrompt",
+			wantPrompt: "---\nJust some text\n---\nPrompt",
+		},
+		{
+			name:       "unclosed",
+			content:    "---\nmodel: gpt-4o\nPrompt",
+			wantPrompt: "---\nmodel: gpt-4o\nPrompt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontMatter, prompt := splitFrontMatter(tt.content)
+			assert.Equal(t, tt.wantFrontMatter, frontMatter)
+			assert.Equal(t, tt.wantPrompt, prompt)
+		})
+	}
+}
+
+func TestPatternsEntity_Metadata(t *testing.T) {
+	entity, cleanup := setupTestPatternsEntity(t)
+	defer cleanup()
+
+	createTestPattern(t, entity, "translate", `---
+description: Translate a text
+tags: [Language]
+model: gpt-4o
+temperature: 0.2
+variables:
+  lang:
+    default: French
+  tone:
+    required: true
+---
+Translate into {{lang}} with a {{tone}} tone.
+{{input}}`)
+	require.NoError(t, os.WriteFile(filepath.Join(entity.Dir, "translate", PatternMetadataFile),
+		[]byte("description: Overridden by the front-matter\nstrategy: cot\nformat: markdown\n"), 0644))
+
+	metadata, err := entity.GetMetadata("translate")
+	require.NoError(t, err)
+	assert.Equal(t, "Translate a text", metadata.Description)
+	assert.Equal(t, "gpt-4o", metadata.Model)
+	assert.Equal(t, "cot", metadata.Strategy)
+	assert.Equal(t, "markdown", metadata.Format)
+	require.NotNil(t, metadata.Temperature)
+	assert.Equal(t, 0.2, *metadata.Temperature)
+
+	pattern, err := entity.GetApplyVariables("translate", map[string]string{"tone": "formal"}, "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, "Translate into French with a formal tone.\nHello", pattern.Pattern)
+	assert.Equal(t, "Translate a text", pattern.Description)
+
+	pattern, err = entity.GetApplyVariables("translate", map[string]string{"tone": "casual", "lang": "German"}, "Hi")
+	require.NoError(t, err)
+	assert.Equal(t, "Translate into German with a casual tone.\nHi", pattern.Pattern)
+
+	_, err = entity.GetApplyVariables("translate", nil, "Hello")
+	assert.ErrorContains(t, err, "pattern translate needs the variables tone")
+
+	// The raw pattern keeps its front-matter
+	content, err := entity.Load("translate")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "description: Translate a text")
+}
+
+func TestPatternsEntity_InvalidFrontMatter(t *testing.T) {
+	entity, cleanup := setupTestPatternsEntity(t)
+	defer cleanup()
+
+	createTestPattern(t, entity, "broken", "---\ntemperature: warm\n---\nPrompt")
+	_, err := entity.GetApplyVariables("broken", nil, "input")
+	assert.ErrorContains(t, err, "invalid front-matter in pattern broken")
+}
+
+func TestPatternsEntity_GetInfos(t *testing.T) {
+	entity, cleanup := setupTestPatternsEntity(t)
+	defer cleanup()
+
+	createTestPattern(t, entity, "summarize", "---\ndescription: Summarize a text\ntags: [writing, summary]\nformat: markdown\n---\nSummarize")
+	createTestPattern(t, entity, "improve_writing", "---\ntags: [Writing]\n---\nImprove")
+	createTestPattern(t, entity, "plain", "No metadata")
+
+	infos, err := entity.GetInfos(nil)
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+	assert.Equal(t, &PatternInfo{Name: "summarize", Description: "Summarize a text", Tags: []string{"writing", "summary"}, Format: "markdown"}, infos[2])
+	assert.Equal(t, "summarize - Summarize a text [writing, summary] (format: markdown)", infos[2].String())
+	assert.Equal(t, "plain", infos[1].String())
+
+	infos, err = entity.GetInfos([]string{"writing"})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "improve_writing", infos[0].Name)
+
+	infos, err = entity.GetInfos([]string{"writing", "summary"})
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "summarize", infos[0].Name)
+}
@@ -0,0 +1,179 @@
+package fsdb
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/danielmiessler/fabric/internal/plugins/template"
+)
+
+// PatternVariableInfo describes a variable of a pattern, from its template and its metadata
+type PatternVariableInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	// Required is set for variables the user must set, as they are used without a default or
+	// declared required
+	Required bool `json:"required"`
+}
+
+// PatternVariables lists what a pattern refers to, so that it can be checked and UIs can
+// build forms before the pattern is sent
+type PatternVariables struct {
+	Variables  []*PatternVariableInfo `json:"variables"`
+	UsesInput  bool                   `json:"usesInput"`
+	Plugins    []*template.Call       `json:"plugins,omitempty"`
+	Extensions []*template.Call       `json:"extensions,omitempty"`
+}
+
+// newPatternVariables analyzes the template of a pattern. The variables it uses come first, in
+// their order, then the variables only declared in its metadata, by name.
+func newPatternVariables(pattern *Pattern) (ret *PatternVariables) {
+	analysis := template.Analyze(pattern.Pattern)
+	ret = &PatternVariables{
+		Variables:  []*PatternVariableInfo{},
+		UsesInput:  analysis.UsesInput,
+		Plugins:    analysis.Plugins,
+		Extensions: analysis.Extensions,
+	}
+
+	var declared map[string]*PatternVariable
+	if pattern.Metadata != nil {
+		declared = pattern.Metadata.Variables
+	}
+	used := map[string]bool{}
+	for _, name := range analysis.Variables {
+		used[name] = true
+		// Variables only used in conditions, loops or with a default in the template are optional
+		optional := slices.Contains(analysis.Optional, name)
+		info := &PatternVariableInfo{Name: name, Required: !optional}
+		if variable := declared[name]; variable != nil {
+			info.Description, info.Default = variable.Description, variable.Default
+			info.Required = variable.Required || (variable.Default == "" && !optional)
+		}
+		ret.Variables = append(ret.Variables, info)
+	}
+
+	var names []string
+	for name := range declared {
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		info := &PatternVariableInfo{Name: name}
+		if variable := declared[name]; variable != nil {
+			info.Description, info.Default, info.Required = variable.Description, variable.Default, variable.Required
+		}
+		ret.Variables = append(ret.Variables, info)
+	}
+	return
+}
+
+// Missing returns the required variables without a value, sorted by name
+func (o *PatternVariables) Missing(variables map[string]string) (ret []string) {
+	for _, variable := range o.Variables {
+		if _, ok := variables[variable.Name]; !ok && variable.Required {
+			ret = append(ret, variable.Name)
+		}
+	}
+	sort.Strings(ret)
+	return
+}
+
+// check reports all required variables of the pattern that have no value at once
+func (o *PatternVariables) check(name string, variables map[string]string) (err error) {
+	if missing := o.Missing(variables); len(missing) > 0 {
+		err = fmt.Errorf("pattern %s needs the variables %s, set them with -v=%s:value",
+			name, strings.Join(missing, ", "), missing[0])
+	}
+	return
+}
+
+// GetVariables returns the variables, plugin calls and extension calls of a pattern, given by
+// name or as a file path
+func (o *PatternsEntity) GetVariables(source string) (ret *PatternVariables, err error) {
+	var pattern *Pattern
+	if isPatternFilePath(source) {
+		pattern, err = o.getFromFile(source)
+	} else {
+		pattern, err = o.getFromDB(source)
+	}
+	if err != nil {
+		return
+	}
+	ret = newPatternVariables(pattern)
+	return
+}
+
+// CheckVariables reports all required variables of a pattern that have no value at once, so that
+// they can be set before any input is fetched or sent
+func (o *PatternsEntity) CheckVariables(source string, variables map[string]string) (err error) {
+	var patternVariables *PatternVariables
+	if patternVariables, err = o.GetVariables(source); err != nil {
+		return
+	}
+	err = patternVariables.check(source, variables)
+	return
+}
+
+// PrintInfo prints the description and tags of a pattern and what it refers to
+func (o *PatternsEntity) PrintInfo(source string) (err error) {
+	var metadata *PatternMetadata
+	if metadata, err = o.GetMetadata(source); err != nil {
+		return
+	}
+	var variables *PatternVariables
+	if variables, err = o.GetVariables(source); err != nil {
+		return
+	}
+
+	fmt.Printf("%s\n\n", newPatternInfo(source, metadata))
+
+	if len(variables.Variables) == 0 {
+		fmt.Println("No variables")
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VARIABLE\tREQUIRED\tDEFAULT\tDESCRIPTION")
+		for _, variable := range variables.Variables {
+			required := "no"
+			if variable.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", variable.Name, required, variable.Default, variable.Description)
+		}
+		if err = writer.Flush(); err != nil {
+			return
+		}
+	}
+
+	if variables.UsesInput {
+		fmt.Println("\nThe input is placed at {{input}}")
+	} else {
+		fmt.Println("\nThe input is appended to the pattern")
+	}
+	if len(variables.Plugins) > 0 {
+		fmt.Printf("Plugins: %s\n", formatCalls(variables.Plugins))
+	}
+	if len(variables.Extensions) > 0 {
+		fmt.Printf("Extensions: %s\n", formatCalls(variables.Extensions))
+	}
+	return
+}
+
+func formatCalls(calls []*template.Call) string {
+	formatted := make([]string, 0, len(calls))
+	for _, call := range calls {
+		text := call.Name + ":" + call.Operation
+		if call.Value != "" {
+			text += ":" + call.Value
+		}
+		formatted = append(formatted, text)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,77 @@
+package fsdb
+
+import (
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/plugins/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternsEntity_GetVariables(t *testing.T) {
+	entity, cleanup := setupTestPatternsEntity(t)
+	defer cleanup()
+
+	createTestPattern(t, entity, "translate", `---
+variables:
+  lang:
+    description: Language to translate into
+    default: French
+  tone:
+    required: true
+    default: formal
+  style:
+    description: Declared but not used
+---
+Translate into {{lang}} with a {{tone}} tone for {{audience}} on {{plugin:datetime:today}}.`)
+
+	variables, err := entity.GetVariables("translate")
+	require.NoError(t, err)
+	assert.Equal(t, []*PatternVariableInfo{
+		{Name: "lang", Description: "Language to translate into", Default: "French"},
+		{Name: "tone", Default: "formal", Required: true},
+		{Name: "audience", Required: true},
+		{Name: "style", Description: "Declared but not used"},
+	}, variables.Variables)
+	assert.False(t, variables.UsesInput)
+	assert.Equal(t, []*template.Call{{Name: "datetime", Operation: "today"}}, variables.Plugins)
+
+	err = entity.CheckVariables("translate", nil)
+	assert.EqualError(t, err, "pattern translate needs the variables audience, tone, set them with -v=audience:value")
+	assert.NoError(t, entity.CheckVariables("translate", map[string]string{"tone": "casual", "audience": "kids"}))
+
+	// Applying the pattern reports the same variables, before any plugin runs
+	_, err = entity.GetApplyVariables("translate", map[string]string{"tone": "casual"}, "Hello")
+	assert.ErrorContains(t, err, "pattern translate needs the variables audience,")
+}
+
+func TestPatternsEntity_GetVariables_Logic(t *testing.T) {
+	entity, cleanup := setupTestPatternsEntity(t)
+	defer cleanup()
+
+	createTestPattern(t, entity, "review", `---
+variables:
+  focus:
+    required: true
+---
+Review the code{{#if audience}} for {{audience}}{{/if}} in {{lang|default:"English"}}.
+{{#each focus}}
+- {{this}}
+{{/each}}
+{{input}}`)
+
+	variables, err := entity.GetVariables("review")
+	require.NoError(t, err)
+	assert.Equal(t, []*PatternVariableInfo{
+		{Name: "audience"},
+		{Name: "lang"},
+		{Name: "focus", Required: true},
+	}, variables.Variables)
+
+	err = entity.CheckVariables("review", nil)
+	assert.EqualError(t, err, "pattern review needs the variables focus, set them with -v=focus:value")
+
+	pattern, err := entity.GetApplyVariables("review", map[string]string{"focus": "naming, errors"}, "code")
+	require.NoError(t, err)
+	assert.Equal(t, "Review the code in English.\n- naming\n- errors\ncode", pattern.Pattern)
+}
@@ -0,0 +1,297 @@
+package fsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/util"
+)
+
+// The sources of the versions of a pattern
+const (
+	PatternVersionSave     = "save"
+	PatternVersionUpdate   = "update"
+	PatternVersionLocal    = "local"
+	PatternVersionRollback = "rollback"
+)
+
+// PatternHistory holds the versions of the system.md of a pattern, numbered from 1
+type PatternHistory struct {
+	Name     string            `json:"name"`
+	Versions []*PatternVersion `json:"versions"`
+	// Pinned is the version the pattern is held at, --updatepatterns leaves pinned patterns as they are
+	Pinned int `json:"pinned,omitempty"`
+}
+
+type PatternVersion struct {
+	Number  int       `json:"number"`
+	Created time.Time `json:"created"`
+	// Source tells how the version came about: save, update, local for changes found before
+	// an update, or rollback
+	Source  string `json:"source"`
+	Content string `json:"content"`
+}
+
+// GetHistory returns the versions of a pattern, a pattern without history has no versions
+func (o *PatternsEntity) GetHistory(name string) (ret *PatternHistory, err error) {
+	ret = &PatternHistory{Name: name}
+	if o.History == nil || !o.History.Exists(name) {
+		return
+	}
+	var content []byte
+	if content, err = o.History.Load(name); err != nil {
+		return
+	}
+	if err = json.Unmarshal(content, ret); err != nil {
+		err = fmt.Errorf("could not read the versions of pattern %s: %v", name, err)
+	}
+	return
+}
+
+func (o *PatternsEntity) saveHistory(history *PatternHistory) (err error) {
+	if o.History == nil {
+		return fmt.Errorf("the versions of pattern %s are not kept", history.Name)
+	}
+	if err = o.History.checkName(history.Name); err != nil {
+		return
+	}
+	var content []byte
+	if content, err = json.MarshalIndent(history, "", "  "); err != nil {
+		return
+	}
+	err = o.History.Save(history.Name, content)
+	return
+}
+
+// add appends content as a new version, unless it is the latest or the pinned version already.
+// The returned version is nil then.
+func (o *PatternHistory) add(content string, source string) (ret *PatternVersion) {
+	if latest := o.Latest(); latest != nil && latest.Content == content {
+		return
+	}
+	if pinned, _ := o.Get(o.Pinned); pinned != nil && pinned.Content == content {
+		return
+	}
+	ret = &PatternVersion{Number: len(o.Versions) + 1, Created: time.Now().UTC(), Source: source, Content: content}
+	o.Versions = append(o.Versions, ret)
+	return
+}
+
+// Latest returns the newest version, or nil without versions
+func (o *PatternHistory) Latest() *PatternVersion {
+	if len(o.Versions) == 0 {
+		return nil
+	}
+	return o.Versions[len(o.Versions)-1]
+}
+
+// Get returns a version by its number
+func (o *PatternHistory) Get(number int) (ret *PatternVersion, err error) {
+	if number < 1 || number > len(o.Versions) {
+		err = fmt.Errorf("pattern %s has no version %d, it has %d versions", o.Name, number, len(o.Versions))
+		return
+	}
+	ret = o.Versions[number-1]
+	return
+}
+
+// find returns the version with the content, the newest one if several have it
+func (o *PatternHistory) find(content string) *PatternVersion {
+	for i := len(o.Versions) - 1; i >= 0; i-- {
+		if o.Versions[i].Content == content {
+			return o.Versions[i]
+		}
+	}
+	return nil
+}
+
+// recordCurrent adds the current system.md of a pattern as a version if it is not one already
+// and returns the history with the version of the current content
+func (o *PatternsEntity) recordCurrent(name string) (history *PatternHistory, current *PatternVersion, err error) {
+	var content []byte
+	if content, err = o.loadFile(name, o.SystemPatternFile); err != nil {
+		err = fmt.Errorf("could not load pattern %s: %v", name, err)
+		return
+	}
+	if history, err = o.GetHistory(name); err != nil {
+		return
+	}
+	if current = history.find(string(content)); current == nil {
+		current = history.add(string(content), PatternVersionLocal)
+		err = o.saveHistory(history)
+	}
+	return
+}
+
+// recordSave adds the content a pattern is saved with as a version, and its previous content
+// if that was never recorded. A pinned pattern stays pinned, at its new content.
+func (o *PatternsEntity) recordSave(name string, previous []byte, content string) (err error) {
+	if o.History == nil {
+		return
+	}
+	var history *PatternHistory
+	if history, err = o.GetHistory(name); err != nil {
+		return
+	}
+	if previous != nil && history.find(string(previous)) == nil {
+		history.add(string(previous), PatternVersionLocal)
+	}
+	history.add(content, PatternVersionSave)
+	if history.Pinned != 0 {
+		history.Pinned = history.find(content).Number
+	}
+	err = o.saveHistory(history)
+	return
+}
+
+// RecordUpdate adds the content that --updatepatterns downloaded for a pattern as a version,
+// after its current content if that was never recorded. It tells whether the pattern is pinned,
+// then it must not be replaced.
+func (o *PatternsEntity) RecordUpdate(name string, content string) (pinned bool, err error) {
+	var history *PatternHistory
+	if history, err = o.GetHistory(name); err != nil {
+		return
+	}
+	if current, loadErr := o.loadFile(name, o.SystemPatternFile); loadErr == nil && history.find(string(current)) == nil {
+		history.add(string(current), PatternVersionLocal)
+	}
+	history.add(content, PatternVersionUpdate)
+	if err = o.saveHistory(history); err != nil {
+		return
+	}
+	pinned = history.Pinned != 0
+	return
+}
+
+// Pin holds a pattern at a version, or at its current content with version 0, so that
+// --updatepatterns leaves it as it is
+func (o *PatternsEntity) Pin(name string, number int) (ret *PatternVersion, err error) {
+	var history *PatternHistory
+	if history, ret, err = o.recordCurrent(name); err != nil {
+		return
+	}
+	if number != 0 && number != ret.Number {
+		if ret, err = history.Get(number); err != nil {
+			return
+		}
+		if err = o.saveFile(name, o.SystemPatternFile, []byte(ret.Content)); err != nil {
+			return
+		}
+	}
+	history.Pinned = ret.Number
+	err = o.saveHistory(history)
+	return
+}
+
+// Unpin lets --updatepatterns update a pattern again, it keeps its current content until then
+func (o *PatternsEntity) Unpin(name string) (err error) {
+	var history *PatternHistory
+	if history, err = o.GetHistory(name); err != nil {
+		return
+	}
+	if history.Pinned == 0 {
+		return fmt.Errorf("pattern %s is not pinned", name)
+	}
+	history.Pinned = 0
+	err = o.saveHistory(history)
+	return
+}
+
+// IsPinned tells whether a pattern is held at a version
+func (o *PatternsEntity) IsPinned(name string) bool {
+	history, err := o.GetHistory(name)
+	return err == nil && history.Pinned != 0
+}
+
+// Rollback makes a version the content of a pattern again, as a new version. Without a
+// number it goes back to the version before the current one.
+func (o *PatternsEntity) Rollback(name string, number int) (ret *PatternVersion, err error) {
+	var history *PatternHistory
+	var current *PatternVersion
+	if history, current, err = o.recordCurrent(name); err != nil {
+		return
+	}
+	if number == 0 {
+		if number = current.Number - 1; number == 0 {
+			err = fmt.Errorf("pattern %s has no version before version %d", name, current.Number)
+			return
+		}
+	}
+	var target *PatternVersion
+	if target, err = history.Get(number); err != nil {
+		return
+	}
+	if target.Content == current.Content {
+		return nil, fmt.Errorf("pattern %s is at the content of version %d already", name, number)
+	}
+
+	if err = o.saveFile(name, o.SystemPatternFile, []byte(target.Content)); err != nil {
+		return
+	}
+	if ret = history.add(target.Content, fmt.Sprintf("%s of %d", PatternVersionRollback, number)); ret == nil {
+		ret = target
+	}
+	if history.Pinned != 0 {
+		// A pinned pattern stays pinned, at its new content
+		history.Pinned = ret.Number
+	}
+	err = o.saveHistory(history)
+	return
+}
+
+// Diff compares two versions of a pattern, version 0 stands for its current content
+func (o *PatternsEntity) Diff(name string, from int, to int) (ret string, err error) {
+	var history *PatternHistory
+	var current *PatternVersion
+	if history, current, err = o.recordCurrent(name); err != nil {
+		return
+	}
+	if from == 0 {
+		if from = current.Number - 1; from == 0 {
+			err = fmt.Errorf("pattern %s has no version before version %d", name, current.Number)
+			return
+		}
+	}
+	if to == 0 {
+		to = current.Number
+	}
+	var fromVersion, toVersion *PatternVersion
+	if fromVersion, err = history.Get(from); err != nil {
+		return
+	}
+	if toVersion, err = history.Get(to); err != nil {
+		return
+	}
+	ret = util.UnifiedDiff(fromVersion.Content, toVersion.Content,
+		fmt.Sprintf("%s version %d", name, from), fmt.Sprintf("%s version %d", name, to))
+	return
+}
+
+// PrintHistory prints the versions of a pattern, marking the current and the pinned one
+func (o *PatternsEntity) PrintHistory(name string) (err error) {
+	var history *PatternHistory
+	var current *PatternVersion
+	if history, current, err = o.recordCurrent(name); err != nil {
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tCREATED\tSOURCE\tLINES\t")
+	for _, version := range history.Versions {
+		var marks []string
+		if version == current {
+			marks = append(marks, "current")
+		}
+		if version.Number == history.Pinned {
+			marks = append(marks, "pinned")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%d\t%s\n", version.Number, formatSessionTime(version.Created),
+			version.Source, strings.Count(strings.TrimSuffix(version.Content, "\n"), "\n")+1, strings.Join(marks, ", "))
+	}
+	err = writer.Flush()
+	return
+}
@@ -0,0 +1,118 @@
+package fsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVersionsTestPatterns(t *testing.T) *PatternsEntity {
+	t.Helper()
+	db := NewDb(t.TempDir())
+	require.NoError(t, db.Patterns.Configure())
+	require.NoError(t, db.Patterns.History.Configure())
+	return db.Patterns
+}
+
+func loadTestPattern(t *testing.T, patterns *PatternsEntity, name string) string {
+	t.Helper()
+	content, err := patterns.Load(name)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestPatternsEntity_SaveRecordsVersions(t *testing.T) {
+	patterns := newVersionsTestPatterns(t)
+	createTestPattern(t, patterns, "summarize", "Summarize the text")
+
+	require.NoError(t, patterns.Save("summarize", []byte("Summarize the text in 3 bullets")))
+	require.NoError(t, patterns.Save("summarize", []byte("Summarize the text in 3 bullets")))
+	require.NoError(t, patterns.Save("summarize", []byte("Summarize the text in 5 bullets")))
+
+	history, err := patterns.GetHistory("summarize")
+	require.NoError(t, err)
+	require.Len(t, history.Versions, 3, "the content before the first save and every new content")
+	assert.Equal(t, PatternVersionLocal, history.Versions[0].Source)
+	assert.Equal(t, "Summarize the text", history.Versions[0].Content)
+	assert.Equal(t, PatternVersionSave, history.Versions[2].Source)
+	assert.Equal(t, 3, history.Versions[2].Number)
+
+	diff, err := patterns.Diff("summarize", 1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "--- summarize version 1\n+++ summarize version 3\n@@ -1 +1 @@\n"+
+		"-Summarize the text\n+Summarize the text in 5 bullets\n", diff)
+	_, err = patterns.Diff("summarize", 7, 0)
+	assert.ErrorContains(t, err, "has no version 7")
+}
+
+func TestPatternsEntity_Rollback(t *testing.T) {
+	patterns := newVersionsTestPatterns(t)
+	require.NoError(t, patterns.Save("summarize", []byte("first")))
+	require.NoError(t, patterns.Save("summarize", []byte("second")))
+
+	version, err := patterns.Rollback("summarize", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version.Number)
+	assert.Equal(t, "rollback of 1", version.Source)
+	assert.Equal(t, "first", loadTestPattern(t, patterns, "summarize"))
+
+	version, err = patterns.Rollback("summarize", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "second", version.Content)
+	assert.Equal(t, "second", loadTestPattern(t, patterns, "summarize"))
+
+	_, err = patterns.Rollback("summarize", 4)
+	assert.ErrorContains(t, err, "already")
+}
+
+func TestPatternsEntity_PinSurvivesUpdates(t *testing.T) {
+	patterns := newVersionsTestPatterns(t)
+	require.NoError(t, patterns.Save("summarize", []byte("first")))
+	require.NoError(t, patterns.Save("summarize", []byte("second")))
+
+	version, err := patterns.Pin("summarize", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version.Number)
+	assert.Equal(t, "first", loadTestPattern(t, patterns, "summarize"))
+	assert.True(t, patterns.IsPinned("summarize"))
+
+	pinned, err := patterns.RecordUpdate("summarize", "downloaded")
+	require.NoError(t, err)
+	assert.True(t, pinned, "the loader must leave pinned patterns out of the update")
+	history, err := patterns.GetHistory("summarize")
+	require.NoError(t, err)
+	require.Len(t, history.Versions, 3, "the pinned content is not recorded again")
+	assert.Equal(t, PatternVersionUpdate, history.Latest().Source)
+	assert.Equal(t, 1, history.Pinned)
+
+	// Saving a pinned pattern moves the pin to the new content
+	require.NoError(t, patterns.Save("summarize", []byte("edited")))
+	history, _ = patterns.GetHistory("summarize")
+	assert.Equal(t, 4, history.Pinned)
+
+	require.NoError(t, patterns.Unpin("summarize"))
+	assert.False(t, patterns.IsPinned("summarize"))
+	assert.Error(t, patterns.Unpin("summarize"))
+	pinned, err = patterns.RecordUpdate("summarize", "downloaded")
+	require.NoError(t, err)
+	assert.False(t, pinned)
+}
+
+func TestPatternsEntity_RecordUpdateKeepsLocalChanges(t *testing.T) {
+	patterns := newVersionsTestPatterns(t)
+	createTestPattern(t, patterns, "summarize", "edited by hand")
+
+	_, err := patterns.RecordUpdate("summarize", "downloaded")
+	require.NoError(t, err)
+
+	history, err := patterns.GetHistory("summarize")
+	require.NoError(t, err)
+	require.Len(t, history.Versions, 2)
+	assert.Equal(t, "edited by hand", history.Versions[0].Content)
+	assert.Equal(t, "downloaded", history.Versions[1].Content)
+	_, err = os.Stat(filepath.Join(patterns.History.Dir, "summarize.json"))
+	assert.NoError(t, err)
+}
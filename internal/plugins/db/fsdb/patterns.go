@@ -1,11 +1,15 @@
 package fsdb
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/danielmiessler/fabric/internal/plugins/template"
 	"github.com/danielmiessler/fabric/internal/util"
@@ -13,11 +17,19 @@ import (
 
 const inputSentinel = "__FABRIC_INPUT_SENTINEL_TOKEN__"
 
+// PatternSchemaFile is the optional JSON schema of the answers of a pattern, next to its system.md
+const PatternSchemaFile = "schema.json"
+
+// PatternTestsFile holds the regression tests of a pattern, next to its system.md
+const PatternTestsFile = "tests.yaml"
+
 type PatternsEntity struct {
 	*StorageEntity
 	SystemPatternFile      string
 	UniquePatternsFilePath string
 	CustomPatternsDir      string
+	// History keeps the versions of the patterns
+	History *StorageEntity
 }
 
 // Pattern represents a single pattern with its metadata
@@ -25,19 +37,15 @@ type Pattern struct {
 	Name        string
 	Description string
 	Pattern     string
+	// Metadata comes from the pattern.yaml and the front-matter of the pattern, which is not part of Pattern
+	Metadata *PatternMetadata `json:",omitempty"`
 }
 
 // GetApplyVariables main entry point for getting patterns from any source
 func (o *PatternsEntity) GetApplyVariables(
 	source string, variables map[string]string, input string) (pattern *Pattern, err error) {
 
-	// Determine if this is a file path
-	isFilePath := strings.HasPrefix(source, "\\") ||
-		strings.HasPrefix(source, "/") ||
-		strings.HasPrefix(source, "~") ||
-		strings.HasPrefix(source, ".")
-
-	if isFilePath {
+	if isPatternFilePath(source) {
 		// Resolve the file path using GetAbsolutePath
 		absPath, err := util.GetAbsolutePath(source)
 		if err != nil {
@@ -45,7 +53,9 @@ func (o *PatternsEntity) GetApplyVariables(
 		}
 
 		// Use the resolved absolute path to get the pattern
-		pattern, _ = o.getFromFile(absPath)
+		if pattern, err = o.getFromFile(absPath); err != nil {
+			return nil, err
+		}
 	} else {
 		// Otherwise, get the pattern from the database
 		pattern, err = o.getFromDB(source)
@@ -60,9 +70,70 @@ func (o *PatternsEntity) GetApplyVariables(
 	return
 }
 
+// isPatternFilePath tells whether a pattern source is a file path instead of a pattern name
+func isPatternFilePath(source string) bool {
+	return strings.HasPrefix(source, "\\") ||
+		strings.HasPrefix(source, "/") ||
+		strings.HasPrefix(source, "~") ||
+		strings.HasPrefix(source, ".")
+}
+
+// GetSchema returns the JSON schema of the answers of a pattern, or nil if the pattern has none
+func (o *PatternsEntity) GetSchema(name string) (ret json.RawMessage, err error) {
+	if isPatternFilePath(name) {
+		return
+	}
+
+	var data []byte
+	if data, err = o.LoadFile(name, PatternSchemaFile); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+	if !json.Valid(data) {
+		err = fmt.Errorf("the JSON schema of pattern %s is not valid JSON", name)
+		return
+	}
+	ret = data
+	return
+}
+
+// LoadFile reads a file next to the system.md of a pattern. The file belongs to the pattern that
+// is used, a custom pattern overrides the main one.
+func (o *PatternsEntity) LoadFile(name string, file string) (ret []byte, err error) {
+	if !isFileName(name) || !isFileName(file) {
+		err = fmt.Errorf("invalid pattern file %s/%s", name, file)
+		return
+	}
+	if o.isCustomPattern(name) {
+		return os.ReadFile(filepath.Join(o.CustomPatternsDir, name, file))
+	}
+	return o.loadFile(name, file)
+}
+
+// isCustomPattern tells whether a pattern is in the custom patterns directory
+func (o *PatternsEntity) isCustomPattern(name string) bool {
+	if o.CustomPatternsDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(o.CustomPatternsDir, name, o.SystemPatternFile))
+	return err == nil
+}
+
 func (o *PatternsEntity) applyVariables(
 	pattern *Pattern, variables map[string]string, input string) (err error) {
 
+	// Variables the user did not set get the defaults of the pattern
+	if pattern.Metadata != nil {
+		variables = pattern.Metadata.applyDefaults(variables)
+	}
+
+	// All missing variables are reported at once, before any plugin of the pattern runs
+	if err = newPatternVariables(pattern).check(pattern.Name, variables); err != nil {
+		return
+	}
+
 	// Ensure pattern has an {{input}} placeholder
 	// If not present, append it on a new line
 	if !strings.Contains(pattern.Pattern, "{{input}}") {
@@ -95,27 +166,29 @@ func (o *PatternsEntity) getFromDB(name string) (ret *Pattern, err error) {
 	if o.CustomPatternsDir != "" {
 		customPatternPath := filepath.Join(o.CustomPatternsDir, name, o.SystemPatternFile)
 		if pattern, customErr := os.ReadFile(customPatternPath); customErr == nil {
-			ret = &Pattern{
-				Name:    name,
-				Pattern: string(pattern),
-			}
-			return ret, nil
+			return o.newPattern(name, string(pattern))
 		}
 	}
 
 	// Fallback to main patterns directory
-	patternPath := filepath.Join(o.Dir, name, o.SystemPatternFile)
-
 	var pattern []byte
-	if pattern, err = os.ReadFile(patternPath); err != nil {
+	if pattern, err = o.loadFile(name, o.SystemPatternFile); err != nil {
 		return
 	}
+	return o.newPattern(name, string(pattern))
+}
 
-	patternStr := string(pattern)
-	ret = &Pattern{
-		Name:    name,
-		Pattern: patternStr,
+// newPattern separates the prompt of a system.md from its front-matter and adds the metadata file
+func (o *PatternsEntity) newPattern(name string, content string) (ret *Pattern, err error) {
+	var metadata *PatternMetadata
+	if metadata, err = o.loadMetadataFile(name); err != nil {
+		return
+	}
+	ret = &Pattern{Name: name, Metadata: metadata}
+	if ret.Pattern, err = parsePattern(name, content, metadata); err != nil {
+		return nil, err
 	}
+	ret.Description = metadata.Description
 	return
 }
 
@@ -153,10 +226,11 @@ func (o *PatternsEntity) getFromFile(pathStr string) (pattern *Pattern, err erro
 		err = fmt.Errorf("could not read pattern file %s: %v", pathStr, err)
 		return
 	}
-	pattern = &Pattern{
-		Name:    pathStr,
-		Pattern: string(content),
+	pattern = &Pattern{Name: pathStr, Metadata: &PatternMetadata{}}
+	if pattern.Pattern, err = parsePattern(pathStr, string(content), pattern.Metadata); err != nil {
+		return nil, err
 	}
+	pattern.Description = pattern.Metadata.Description
 	return
 }
 
@@ -231,13 +305,95 @@ func (o *PatternsEntity) Get(name string) (*Pattern, error) {
 	return o.GetApplyVariables(name, nil, "")
 }
 func (o *PatternsEntity) Save(name string, content []byte) (err error) {
+	previous, loadErr := o.loadFile(name, o.SystemPatternFile)
+	if loadErr != nil {
+		previous = nil
+	}
+	if err = o.saveFile(name, o.SystemPatternFile, content); err != nil {
+		return fmt.Errorf("could not save pattern: %v", err)
+	}
+	if err = o.recordSave(name, previous, string(content)); err != nil {
+		return fmt.Errorf("could not record the version of pattern %s: %v", name, err)
+	}
+	return nil
+}
+
+// Load returns the system.md of a pattern as it is, without applying variables
+func (o *PatternsEntity) Load(name string) (ret []byte, err error) {
+	if ret, err = o.loadFile(name, o.SystemPatternFile); err != nil {
+		err = fmt.Errorf("could not load %s: %v", name, err)
+	}
+	return
+}
+
+// patternFiles is implemented by item storages that keep all files of a pattern, like sqlitedb
+type patternFiles interface {
+	LoadFile(name string, file string) ([]byte, error)
+	SaveFile(name string, file string, content []byte) error
+	GetFileNames(name string) ([]string, error)
+}
+
+// loadFile reads a file of a pattern, like its system.md, from the item storage or the patterns directory
+func (o *PatternsEntity) loadFile(name string, file string) (ret []byte, err error) {
+	if files, ok := o.Items.(patternFiles); ok {
+		return files.LoadFile(name, file)
+	}
+	return os.ReadFile(filepath.Join(o.Dir, name, file))
+}
+
+// saveFile writes a file of a pattern to the item storage or the patterns directory
+func (o *PatternsEntity) saveFile(name string, file string, content []byte) (err error) {
+	if files, ok := o.Items.(patternFiles); ok {
+		err = files.SaveFile(name, file, content)
+	} else {
+		err = o.writeFile(name, file, content)
+	}
+	o.changed(err, name)
+	return
+}
+
+func (o *PatternsEntity) writeFile(name string, file string, content []byte) (err error) {
+	// Names from other storages end up in paths, they must not point outside the pattern directory
+	if !isFileName(name) || !isFileName(file) {
+		return fmt.Errorf("invalid pattern file %s/%s", name, file)
+	}
 	patternDir := filepath.Join(o.Dir, name)
 	if err = os.MkdirAll(patternDir, os.ModePerm); err != nil {
 		return fmt.Errorf("could not create pattern directory: %v", err)
 	}
-	patternPath := filepath.Join(patternDir, o.SystemPatternFile)
-	if err = os.WriteFile(patternPath, content, 0644); err != nil {
-		return fmt.Errorf("could not save pattern: %v", err)
+	return os.WriteFile(filepath.Join(patternDir, file), content, 0644)
+}
+
+// modTime returns when the system.md of a pattern was last changed
+func (o *PatternsEntity) modTime(name string) (ret time.Time) {
+	if o.isCustomPattern(name) {
+		if info, err := os.Stat(filepath.Join(o.CustomPatternsDir, name, o.SystemPatternFile)); err == nil {
+			ret = info.ModTime()
+		}
+		return
 	}
-	return nil
+	if o.Items != nil {
+		return o.StorageEntity.modTime(name)
+	}
+	if info, err := os.Stat(filepath.Join(o.Dir, name, o.SystemPatternFile)); err == nil {
+		ret = info.ModTime()
+	}
+	return
+}
+
+// getFileNames lists the files of a pattern, in the patterns directory only the files at its top level
+func (o *PatternsEntity) getFileNames(name string) (ret []string, err error) {
+	if files, ok := o.Items.(patternFiles); ok {
+		return files.GetFileNames(name)
+	}
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(filepath.Join(o.Dir, name)); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			ret = append(ret, entry.Name())
+		}
+	}
+	return
 }
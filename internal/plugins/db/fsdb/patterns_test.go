@@ -282,3 +282,39 @@ func TestPatternsEntity_CustomPatternsEmpty(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "Main pattern content", pattern.Pattern)
 }
+
+func TestPatternsEntity_GetSchema(t *testing.T) {
+	entity, cleanup := setupTestPatternsEntity(t)
+	defer cleanup()
+
+	createTestPattern(t, entity, "plain", "Summarize {{input}}")
+	createTestPattern(t, entity, "structured", "Rate {{input}}")
+	schema := `{"type": "object", "required": ["score"]}`
+	require.NoError(t, os.WriteFile(filepath.Join(entity.Dir, "structured", PatternSchemaFile), []byte(schema), 0644))
+	createTestPattern(t, entity, "broken", "Rate {{input}}")
+	require.NoError(t, os.WriteFile(filepath.Join(entity.Dir, "broken", PatternSchemaFile), []byte("{"), 0644))
+
+	got, err := entity.GetSchema("structured")
+	require.NoError(t, err)
+	assert.JSONEq(t, schema, string(got))
+
+	got, err = entity.GetSchema("plain")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = entity.GetSchema("./some/file.md")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = entity.GetSchema("broken")
+	assert.Error(t, err)
+
+	// A custom pattern without a schema overrides the schema of the main pattern
+	entity.CustomPatternsDir = t.TempDir()
+	customDir := filepath.Join(entity.CustomPatternsDir, "structured")
+	require.NoError(t, os.MkdirAll(customDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(customDir, entity.SystemPatternFile), []byte("Custom {{input}}"), 0644))
+	got, err = entity.GetSchema("structured")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
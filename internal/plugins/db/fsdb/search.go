@@ -0,0 +1,585 @@
+package fsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// SearchIndexFile is the file of the search index in the fabric directory
+const SearchIndexFile = "search_index.json"
+
+const searchIndexVersion = 1
+
+// searchChangesSuffix names the file next to the index that lists the items saved since the
+// index was, one key per line
+const searchChangesSuffix = ".changes"
+
+// The kinds of items that are searched
+const (
+	SearchKindSession = "session"
+	SearchKindContext = "context"
+	SearchKindPattern = "pattern"
+)
+
+var SearchKinds = []string{SearchKindSession, SearchKindContext, SearchKindPattern}
+
+// ErrEmptySearchQuery is returned for a query without any words to search for
+var ErrEmptySearchQuery = errors.New("the search query has no words")
+
+// Words in the name of an item count as often as this, so that matching names rank higher
+const searchNameWeight = 3
+
+// Parameters of the BM25 ranking
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// SearchIndex is an inverted index of the words in sessions, contexts and patterns for ranked
+// full-text search. It is saved in a file, and the items saved since then are noted next to it
+// and indexed again by the next search. Every search also picks up the items that were changed
+// outside of fabric.
+type SearchIndex struct {
+	Path string
+
+	db        *Db
+	mu        sync.Mutex
+	documents map[string]*indexedDocument
+	// postings lists for every word the documents that contain it, with how often they do
+	postings map[string]map[string]int
+}
+
+type searchIndexFile struct {
+	Version   int                `json:"version"`
+	Documents []*indexedDocument `json:"documents"`
+}
+
+// indexedDocument counts the words of an item
+type indexedDocument struct {
+	Kind     string         `json:"kind"`
+	Name     string         `json:"name"`
+	Modified time.Time      `json:"modified"`
+	Length   int            `json:"length"`
+	Terms    map[string]int `json:"terms"`
+}
+
+// SearchOptions filter the results of a search
+type SearchOptions struct {
+	Kinds []string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// SearchResult is an item that matches a search, with the text around the first match
+type SearchResult struct {
+	Kind     string    `json:"kind"`
+	Name     string    `json:"name"`
+	Score    float64   `json:"score"`
+	Modified time.Time `json:"modified"`
+	Message  int       `json:"message,omitempty"` // Number of the first matching message of a session
+	Snippet  string    `json:"snippet"`
+}
+
+// queryTerm is a word of a query, a prefix if the word ended with *
+type queryTerm struct {
+	text   string
+	prefix bool
+}
+
+func NewSearchIndex(db *Db) *SearchIndex {
+	return &SearchIndex{Path: db.FilePath(SearchIndexFile), db: db}
+}
+
+// NewSearchOptions checks the kinds of items, which may also be given comma separated, and parses
+// the dates, like 2025-01-31 or RFC 3339 times. A date for until includes the whole day.
+func NewSearchOptions(kinds []string, since string, until string, limit int) (ret *SearchOptions, err error) {
+	ret = &SearchOptions{Limit: limit}
+	for _, value := range kinds {
+		for _, kind := range strings.Split(value, ",") {
+			kind = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(kind)), "s")
+			if !slices.Contains(SearchKinds, kind) {
+				err = fmt.Errorf("invalid search type %q, use %s", kind, strings.Join(SearchKinds, ", "))
+				return
+			}
+			ret.Kinds = append(ret.Kinds, kind)
+		}
+	}
+	if since != "" {
+		if ret.Since, err = parseFilterTime(since); err != nil {
+			return
+		}
+	}
+	if until != "" {
+		if ret.Until, err = parseFilterTime(until); err != nil {
+			return
+		}
+		if len(until) == len(time.DateOnly) {
+			ret.Until = ret.Until.AddDate(0, 0, 1)
+		}
+	}
+	return
+}
+
+// watch updates the index whenever a session, context or pattern is saved, deleted or renamed
+func (o *SearchIndex) watch() {
+	o.db.Sessions.onChange = func(name string) { o.changed(SearchKindSession, name) }
+	o.db.Contexts.onChange = func(name string) { o.changed(SearchKindContext, name) }
+	o.db.Patterns.onChange = func(name string) { o.changed(SearchKindPattern, name) }
+}
+
+// changed notes that an item has to be indexed again, so that saving an item does not rewrite
+// the whole index. Only an existing index is updated, the first search creates it. Errors are
+// left to the next search, which also checks the modification times of all items.
+func (o *SearchIndex) changed(kind string, name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, err := os.Stat(o.Path); err != nil {
+		return
+	}
+	file, err := os.OpenFile(o.Path+searchChangesSuffix, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	_, _ = file.WriteString(searchKey(kind, name) + "\n")
+}
+
+// Search finds the items that contain all words of the query, ranked by BM25. A word ending
+// with * matches all words that start with it.
+func (o *SearchIndex) Search(query string, options *SearchOptions) (ret []*SearchResult, err error) {
+	terms := parseQuery(query)
+	if len(terms) == 0 {
+		err = ErrEmptySearchQuery
+		return
+	}
+	if options == nil {
+		options = &SearchOptions{}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err = o.load(); err != nil {
+		return
+	}
+	var changed bool
+	if changed, err = o.refresh(); err != nil {
+		return
+	}
+	// The noted items are indexed again even if their modification time looks the same
+	changes, _ := os.ReadFile(o.Path + searchChangesSuffix)
+	for _, key := range strings.Split(string(changes), "\n") {
+		if kind, name, found := strings.Cut(key, "/"); found && slices.Contains(SearchKinds, kind) {
+			o.update(kind, name)
+			changed = true
+		}
+	}
+	if changed {
+		if err = o.save(); err != nil {
+			return
+		}
+	}
+	if len(changes) > 0 {
+		_ = os.Remove(o.Path + searchChangesSuffix)
+	}
+
+	ret = o.rank(terms, options)
+	for _, result := range ret {
+		result.Message, result.Snippet = o.snippet(result.Kind, result.Name, terms)
+	}
+	return
+}
+
+// PrintSearchResults prints the results of a search with their snippets
+func PrintSearchResults(results []*SearchResult) {
+	if len(results) == 0 {
+		fmt.Println("\nNo results")
+		return
+	}
+	for _, result := range results {
+		fmt.Printf("[%s] %s", result.Kind, result.Name)
+		if result.Message > 0 {
+			fmt.Printf(", message %d", result.Message)
+		}
+		fmt.Printf(", updated %s\n", formatSessionTime(result.Modified))
+		if result.Snippet != "" {
+			fmt.Printf("    %s\n", result.Snippet)
+		}
+	}
+}
+
+// rank scores the documents that match all terms and the options
+func (o *SearchIndex) rank(terms []queryTerm, options *SearchOptions) (ret []*SearchResult) {
+	if len(o.documents) == 0 {
+		return
+	}
+	totalLength := 0
+	for _, document := range o.documents {
+		totalLength += document.Length
+	}
+	averageLength := math.Max(float64(totalLength)/float64(len(o.documents)), 1)
+
+	matches := make([]map[string]int, len(terms))
+	for i, term := range terms {
+		matches[i] = o.matching(term)
+	}
+
+	count := float64(len(o.documents))
+	for key := range matches[0] {
+		document := o.documents[key]
+		if !options.matches(document) {
+			continue
+		}
+		score := 0.0
+		for _, documents := range matches {
+			frequency := float64(documents[key])
+			if frequency == 0 {
+				score = 0
+				break
+			}
+			// The fewer documents contain a term, the more it counts
+			idf := math.Log(1 + (count-float64(len(documents))+0.5)/(float64(len(documents))+0.5))
+			score += idf * frequency * (bm25K1 + 1) /
+				(frequency + bm25K1*(1-bm25B+bm25B*float64(document.Length)/averageLength))
+		}
+		if score > 0 {
+			ret = append(ret, &SearchResult{Kind: document.Kind, Name: document.Name, Score: score, Modified: document.Modified})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Score != ret[j].Score {
+			return ret[i].Score > ret[j].Score
+		}
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	if options.Limit > 0 && len(ret) > options.Limit {
+		ret = ret[:options.Limit]
+	}
+	return
+}
+
+// matching returns the documents that contain a term with how often they do, for a prefix
+// the documents that contain any word that starts with it
+func (o *SearchIndex) matching(term queryTerm) (ret map[string]int) {
+	if !term.prefix {
+		return o.postings[term.text]
+	}
+	ret = map[string]int{}
+	for text, documents := range o.postings {
+		if strings.HasPrefix(text, term.text) {
+			for key, count := range documents {
+				ret[key] += count
+			}
+		}
+	}
+	return
+}
+
+func (o *SearchOptions) matches(document *indexedDocument) bool {
+	if len(o.Kinds) > 0 && !slices.Contains(o.Kinds, document.Kind) {
+		return false
+	}
+	if !o.Since.IsZero() && document.Modified.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !document.Modified.Before(o.Until) {
+		return false
+	}
+	return true
+}
+
+// refresh indexes the items that are new or were changed since they were indexed and removes
+// the deleted ones
+func (o *SearchIndex) refresh() (changed bool, err error) {
+	seen := map[string]bool{}
+	for _, kind := range SearchKinds {
+		var names []string
+		if names, err = o.names(kind); err != nil {
+			return
+		}
+		for _, name := range names {
+			key := searchKey(kind, name)
+			seen[key] = true
+			if document := o.documents[key]; document != nil && !document.Modified.IsZero() &&
+				document.Modified.Equal(o.modTime(kind, name)) {
+				continue
+			}
+			o.update(kind, name)
+			changed = true
+		}
+	}
+	for key := range o.documents {
+		if !seen[key] {
+			o.remove(key)
+			changed = true
+		}
+	}
+	return
+}
+
+// update indexes an item again, or removes it if it cannot be read
+func (o *SearchIndex) update(kind string, name string) {
+	key := searchKey(kind, name)
+	o.remove(key)
+	texts, err := o.read(kind, name)
+	if err != nil {
+		return
+	}
+
+	document := &indexedDocument{Kind: kind, Name: name, Modified: o.modTime(kind, name), Terms: map[string]int{}}
+	for _, text := range texts {
+		for _, term := range searchTerms(text) {
+			document.Terms[term]++
+			document.Length++
+		}
+	}
+	for _, term := range searchTerms(name) {
+		document.Terms[term] += searchNameWeight
+		document.Length += searchNameWeight
+	}
+	o.add(document)
+}
+
+func (o *SearchIndex) add(document *indexedDocument) {
+	key := searchKey(document.Kind, document.Name)
+	o.documents[key] = document
+	for term, count := range document.Terms {
+		if o.postings[term] == nil {
+			o.postings[term] = map[string]int{}
+		}
+		o.postings[term][key] = count
+	}
+}
+
+func (o *SearchIndex) remove(key string) {
+	if document := o.documents[key]; document != nil {
+		for term := range document.Terms {
+			if delete(o.postings[term], key); len(o.postings[term]) == 0 {
+				delete(o.postings, term)
+			}
+		}
+		delete(o.documents, key)
+	}
+}
+
+func (o *SearchIndex) names(kind string) (ret []string, err error) {
+	switch kind {
+	case SearchKindSession:
+		ret, err = o.db.Sessions.GetNames()
+	case SearchKindContext:
+		ret, err = o.db.Contexts.GetNames()
+	case SearchKindPattern:
+		ret, err = o.db.Patterns.GetNames()
+	}
+	return
+}
+
+func (o *SearchIndex) modTime(kind string, name string) (ret time.Time) {
+	switch kind {
+	case SearchKindSession:
+		ret = o.db.Sessions.modTime(name)
+	case SearchKindContext:
+		ret = o.db.Contexts.modTime(name)
+	case SearchKindPattern:
+		ret = o.db.Patterns.modTime(name)
+	}
+	return
+}
+
+// read returns the searched texts of an item: the messages of a session, the content of
+// a context or the system prompt of a pattern
+func (o *SearchIndex) read(kind string, name string) (ret []string, err error) {
+	switch kind {
+	case SearchKindSession:
+		var session *Session
+		if session, err = o.db.Sessions.load(name); err != nil {
+			return
+		}
+		for _, message := range session.Messages {
+			ret = append(ret, messageText(message))
+		}
+	case SearchKindContext:
+		var content []byte
+		if content, err = o.db.Contexts.Load(name); err == nil {
+			ret = []string{string(content)}
+		}
+	case SearchKindPattern:
+		var pattern *Pattern
+		if pattern, err = o.db.Patterns.getFromDB(name); err == nil {
+			ret = []string{pattern.Description, pattern.Pattern}
+		}
+	}
+	return
+}
+
+// messageText returns the text of a message and its text parts, nothing for fabric's meta messages
+func messageText(message *chat.ChatCompletionMessage) string {
+	if message.Role == domain.ChatMessageRoleMeta {
+		return ""
+	}
+	texts := []string{message.Content}
+	for _, part := range message.MultiContent {
+		if part.Type == chat.ChatMessagePartTypeText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// snippet returns the number of the first matching message of a session and the text around the match
+func (o *SearchIndex) snippet(kind string, name string, terms []queryTerm) (message int, ret string) {
+	texts, err := o.read(kind, name)
+	if err != nil {
+		return
+	}
+	for i, text := range texts {
+		if ret = snippetOf(text, terms); ret != "" {
+			if kind == SearchKindSession {
+				message = i + 1
+			}
+			return
+		}
+	}
+	return
+}
+
+// snippetRadius is the number of characters shown before and after a match
+const snippetRadius = 80
+
+// snippetOf returns the text around the first word that matches a term, or nothing without a match
+func snippetOf(text string, terms []queryTerm) string {
+	runes := []rune(text)
+	start, end := -1, -1
+	for i := 0; i < len(runes) && start < 0; {
+		if !isSearchRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isSearchRune(runes[j]) {
+			j++
+		}
+		word := strings.ToLower(string(runes[i:j]))
+		for _, term := range terms {
+			if word == term.text || (term.prefix && strings.HasPrefix(word, term.text)) {
+				start, end = i, j
+				break
+			}
+		}
+		i = j
+	}
+	if start < 0 {
+		return ""
+	}
+
+	from, to := max(start-snippetRadius, 0), min(end+snippetRadius, len(runes))
+	// Start and end at word boundaries
+	for from > 0 && isSearchRune(runes[from-1]) && from < start {
+		from++
+	}
+	for to < len(runes) && isSearchRune(runes[to]) && to > end {
+		to--
+	}
+	ret := strings.Join(strings.Fields(string(runes[from:to])), " ")
+	if from > 0 {
+		ret = "…" + ret
+	}
+	if to < len(runes) {
+		ret += "…"
+	}
+	return ret
+}
+
+func isSearchRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// searchTerms splits a text into lower case words
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isSearchRune(r) })
+}
+
+func parseQuery(query string) (ret []queryTerm) {
+	for _, word := range strings.Fields(query) {
+		terms := searchTerms(word)
+		for i, term := range terms {
+			ret = append(ret, queryTerm{text: term, prefix: i == len(terms)-1 && strings.HasSuffix(word, "*")})
+		}
+	}
+	return
+}
+
+func searchKey(kind string, name string) string {
+	return kind + "/" + name
+}
+
+// load reads the index file, a missing or outdated index starts empty
+func (o *SearchIndex) load() (err error) {
+	o.documents = map[string]*indexedDocument{}
+	o.postings = map[string]map[string]int{}
+	var content []byte
+	if content, err = os.ReadFile(o.Path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	var file searchIndexFile
+	if json.Unmarshal(content, &file) != nil || file.Version != searchIndexVersion {
+		return
+	}
+	for _, document := range file.Documents {
+		o.add(document)
+	}
+	return
+}
+
+// save writes the index to a temporary file first, so that it is never read half written
+func (o *SearchIndex) save() (err error) {
+	file := searchIndexFile{Version: searchIndexVersion}
+	for _, document := range o.documents {
+		file.Documents = append(file.Documents, document)
+	}
+	sort.Slice(file.Documents, func(i, j int) bool {
+		return searchKey(file.Documents[i].Kind, file.Documents[i].Name) < searchKey(file.Documents[j].Kind, file.Documents[j].Name)
+	})
+
+	var content []byte
+	if content, err = json.Marshal(&file); err != nil {
+		return
+	}
+	var temp *os.File
+	if temp, err = os.CreateTemp(filepath.Dir(o.Path), SearchIndexFile+".*"); err != nil {
+		return fmt.Errorf("could not save the search index: %v", err)
+	}
+	defer os.Remove(temp.Name())
+	if _, err = temp.Write(content); err != nil {
+		temp.Close()
+		return fmt.Errorf("could not save the search index: %v", err)
+	}
+	if err = temp.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(temp.Name(), o.Path); err != nil {
+		err = fmt.Errorf("could not save the search index: %v", err)
+	}
+	return
+}
@@ -0,0 +1,221 @@
+package fsdb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+)
+
+func newSearchTestDb(t *testing.T) *Db {
+	t.Helper()
+	db := NewDb(t.TempDir())
+	for _, entity := range []*StorageEntity{db.Patterns.StorageEntity, db.Sessions.StorageEntity, db.Contexts.StorageEntity} {
+		if err := entity.Configure(); err != nil {
+			t.Fatalf("failed to configure %s: %v", entity.Label, err)
+		}
+	}
+	db.Search.watch()
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "extract_wisdom", "system.md"),
+		"You extract surprising insights and wisdom from the input.")
+	writeTestFile(t, filepath.Join(db.Contexts.Dir, "kubernetes"),
+		"We run our services on Kubernetes clusters in three regions.")
+	saveSearchTestSession(t, db, "research", "What is the capital of France?", "Paris is the capital of France.",
+		"How do Kubernetes deployments roll out?", "Kubernetes replaces the pods of a deployment gradually.")
+	return db
+}
+
+func saveSearchTestSession(t *testing.T, db *Db, name string, contents ...string) {
+	t.Helper()
+	session := &Session{Name: name}
+	for i, content := range contents {
+		role := chat.ChatMessageRoleUser
+		if i%2 == 1 {
+			role = chat.ChatMessageRoleAssistant
+		}
+		session.Append(&chat.ChatCompletionMessage{Role: role, Content: content})
+	}
+	if err := db.Sessions.SaveSession(session); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+}
+
+func searchNames(results []*SearchResult) (ret []string) {
+	for _, result := range results {
+		ret = append(ret, result.Kind+"/"+result.Name)
+	}
+	return
+}
+
+func TestSearchIndex_Search(t *testing.T) {
+	db := newSearchTestDb(t)
+
+	results, err := db.Search.Search("kubernetes", nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	// The name of the context counts more than the mentions in the session
+	if got := searchNames(results); len(got) != 2 || got[0] != "context/kubernetes" || got[1] != "session/research" {
+		t.Fatalf("unexpected results %v", got)
+	}
+	for _, result := range results {
+		if result.Score <= 0 || !strings.Contains(strings.ToLower(result.Snippet), "kubernetes") {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result.Kind == SearchKindSession && result.Message != 3 {
+			t.Errorf("expected the third message to match first, got %d", result.Message)
+		}
+	}
+
+	if results, _ = db.Search.Search("capital paris", nil); len(results) != 1 || results[0].Name != "research" {
+		t.Errorf("expected only the session to contain both words, got %v", searchNames(results))
+	}
+	if results, _ = db.Search.Search("capital wisdom", nil); len(results) != 0 {
+		t.Errorf("expected no item to contain both words, got %v", searchNames(results))
+	}
+	if results, _ = db.Search.Search("insight*", nil); len(results) != 1 || results[0].Name != "extract_wisdom" {
+		t.Errorf("expected the prefix to match the pattern, got %v", searchNames(results))
+	}
+	if _, err = db.Search.Search(" * ", nil); err == nil {
+		t.Error("expected an error for a query without words")
+	}
+	if _, err = os.Stat(db.Search.Path); err != nil {
+		t.Errorf("expected the index to be saved: %v", err)
+	}
+}
+
+func TestSearchIndex_SearchOptions(t *testing.T) {
+	db := newSearchTestDb(t)
+
+	options, err := NewSearchOptions([]string{"contexts,pattern"}, "", "", 0)
+	if err != nil {
+		t.Fatalf("NewSearchOptions() error = %v", err)
+	}
+	results, _ := db.Search.Search("kubernetes", options)
+	if got := searchNames(results); len(got) != 1 || got[0] != "context/kubernetes" {
+		t.Errorf("expected only the context, got %v", got)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(time.DateOnly)
+	if options, err = NewSearchOptions(nil, tomorrow, "", 0); err != nil {
+		t.Fatalf("NewSearchOptions() error = %v", err)
+	}
+	if results, _ = db.Search.Search("kubernetes", options); len(results) != 0 {
+		t.Errorf("expected no item changed since tomorrow, got %v", searchNames(results))
+	}
+	if options, err = NewSearchOptions(nil, "", time.Now().Format(time.DateOnly), 1); err != nil {
+		t.Fatalf("NewSearchOptions() error = %v", err)
+	}
+	if results, _ = db.Search.Search("kubernetes", options); len(results) != 1 {
+		t.Errorf("expected the limit to apply to the items changed until today, got %v", searchNames(results))
+	}
+
+	if _, err = NewSearchOptions([]string{"messages"}, "", "", 0); err == nil {
+		t.Error("expected an error for an unknown type")
+	}
+	if _, err = NewSearchOptions(nil, "yesterday", "", 0); err == nil {
+		t.Error("expected an error for an invalid date")
+	}
+}
+
+func TestSearchIndex_Updates(t *testing.T) {
+	db := newSearchTestDb(t)
+	if _, err := db.Search.Search("kubernetes", nil); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	// Saved items are noted for the next search instead of rewriting the index
+	index, err := os.ReadFile(db.Search.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveSearchTestSession(t, db, "travel", "Plan a trip to Lisbon")
+	if saved, _ := os.ReadFile(db.Search.Path); string(saved) != string(index) {
+		t.Error("expected saving a session to leave the index file as it is")
+	}
+	if changes, _ := os.ReadFile(db.Search.Path + searchChangesSuffix); string(changes) != "session/travel\n" {
+		t.Errorf("expected the saved session to be noted, got %q", changes)
+	}
+
+	// Items changed outside of fabric are picked up by the next search
+	writeTestFile(t, filepath.Join(db.Contexts.Dir, "kubernetes"), "We moved to Lisbon.")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(db.Contexts.Dir, "kubernetes"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(db.Patterns.Dir, "extract_wisdom")); err != nil {
+		t.Fatal(err)
+	}
+	results, err := db.Search.Search("lisbon", nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := searchNames(results); len(got) != 2 {
+		t.Errorf("expected the changed context and the session, got %v", got)
+	}
+	if results, _ = db.Search.Search("wisdom", nil); len(results) != 0 {
+		t.Errorf("expected the deleted pattern to be removed, got %v", searchNames(results))
+	}
+
+	if _, err = os.Stat(db.Search.Path + searchChangesSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected the noted changes to be removed once they are indexed, got %v", err)
+	}
+
+	if err = db.Sessions.Delete("travel"); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ = db.Search.Search("lisbon", nil); len(results) != 1 || results[0].Kind != SearchKindContext {
+		t.Errorf("expected the deleted session to be removed from the index, got %v", searchNames(results))
+	}
+}
+
+func TestSearchIndex_NotedChangesWithSameModTime(t *testing.T) {
+	db := newSearchTestDb(t)
+	if _, err := db.Search.Search("kubernetes", nil); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	// A context saved again within the resolution of the modification time is still indexed again
+	path := filepath.Join(db.Contexts.Dir, "kubernetes")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Contexts.Save("kubernetes", []byte("We run our services on Nomad.")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.Search.Search("nomad", nil)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if got := searchNames(results); len(got) != 1 || got[0] != "context/kubernetes" {
+		t.Errorf("expected the noted context to be indexed again, got %v", got)
+	}
+}
+
+func TestSnippetOf(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 20) + "the needle is here " + strings.Repeat("dolor sit ", 20)
+	snippet := snippetOf(text, parseQuery("needle"))
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "the needle is here") {
+		t.Errorf("unexpected snippet %q", snippet)
+	}
+	for _, word := range strings.Fields(strings.Trim(snippet, "…")) {
+		if word != "lorem" && word != "ipsum" && word != "dolor" && word != "sit" && word != "the" &&
+			word != "needle" && word != "is" && word != "here" {
+			t.Errorf("expected the snippet to end at word boundaries, got %q", snippet)
+		}
+	}
+	if snippet = snippetOf("short text", parseQuery("short")); snippet != "short text" {
+		t.Errorf("unexpected snippet %q", snippet)
+	}
+	if snippet = snippetOf("no match", parseQuery("needle")); snippet != "" {
+		t.Errorf("expected no snippet, got %q", snippet)
+	}
+}
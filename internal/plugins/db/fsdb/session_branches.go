@@ -0,0 +1,128 @@
+package fsdb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+)
+
+// loadExisting loads a session that has to exist, unlike Get it does not create a new one
+func (o *SessionsEntity) loadExisting(name string) (ret *Session, err error) {
+	if !o.Exists(name) {
+		err = fmt.Errorf("session %s does not exist", name)
+		return
+	}
+	ret, err = o.load(name)
+	return
+}
+
+// ForkSession copies the first at messages of a session, or all with at 0, into a new session
+// that links back to it. The original session is not changed.
+func (o *SessionsEntity) ForkSession(name string, at int, newName string) (ret *Session, err error) {
+	var parent *Session
+	if parent, err = o.loadExisting(name); err != nil {
+		return
+	}
+	if newName == "" || newName == name {
+		err = errors.New("the fork needs a new session name")
+		return
+	}
+	if o.Exists(newName) {
+		err = fmt.Errorf("session %s already exists", newName)
+		return
+	}
+	if at == 0 {
+		at = len(parent.Messages)
+	}
+	if at < 0 || at > len(parent.Messages) {
+		err = fmt.Errorf("session %s has no message %d, it has %d messages", name, at, len(parent.Messages))
+		return
+	}
+
+	ret = &Session{
+		Name:   newName,
+		Tags:   slices.Clone(parent.Tags),
+		Parent: &SessionParent{Name: name, Message: at},
+	}
+	for _, message := range parent.Messages[:at] {
+		ret.Messages = append(ret.Messages, copyMessage(message))
+	}
+	err = o.SaveSession(ret)
+	return
+}
+
+// TruncateSession keeps the first length messages of a session
+func (o *SessionsEntity) TruncateSession(name string, length int) (ret *Session, err error) {
+	if ret, err = o.loadExisting(name); err != nil {
+		return
+	}
+	if length < 0 || length > len(ret.Messages) {
+		err = fmt.Errorf("session %s has no message %d, it has %d messages", name, length, len(ret.Messages))
+		return
+	}
+	ret.Truncate(length)
+	err = o.SaveSession(ret)
+	return
+}
+
+// RewindSession removes the last turns of a session, each an answer with the messages it answered
+func (o *SessionsEntity) RewindSession(name string, turns int) (ret *Session, err error) {
+	if ret, err = o.loadExisting(name); err != nil {
+		return
+	}
+	if turns < 1 {
+		err = errors.New("the number of turns to rewind must be at least 1")
+		return
+	}
+	if ret.RemoveLastTurns(turns) == 0 {
+		err = fmt.Errorf("session %s has no turns to rewind", name)
+		return
+	}
+	err = o.SaveSession(ret)
+	return
+}
+
+// EditMessage replaces the content of an assistant message of a session and marks it as edited
+func (o *SessionsEntity) EditMessage(name string, number int, content string) (ret *Session, err error) {
+	if ret, err = o.loadExisting(name); err != nil {
+		return
+	}
+	var message *chat.ChatCompletionMessage
+	if message, err = ret.GetAssistantMessage(number); err != nil {
+		return
+	}
+
+	message.Content = content
+	message.MultiContent = nil
+	if message.Meta == nil {
+		message.Meta = &chat.MessageMeta{}
+	}
+	message.Meta.Edited = true
+	ret.vendorMessages = nil
+	err = o.SaveSession(ret)
+	return
+}
+
+// GetAssistantMessage returns a message by its number like GetMessage and checks that it is an answer
+func (o *Session) GetAssistantMessage(number int) (ret *chat.ChatCompletionMessage, err error) {
+	if ret, err = o.GetMessage(number); err != nil {
+		return
+	}
+	if ret.Role != chat.ChatMessageRoleAssistant {
+		err = fmt.Errorf("message %d of session %s is a %s message, not an answer", number, o.Name, ret.Role)
+		ret = nil
+	}
+	return
+}
+
+func copyMessage(message *chat.ChatCompletionMessage) *chat.ChatCompletionMessage {
+	copied := *message
+	copied.MultiContent = append([]chat.ChatMessagePart(nil), message.MultiContent...)
+	if message.Meta != nil {
+		meta := *message.Meta
+		copied.Meta = &meta
+	}
+	return &copied
+}
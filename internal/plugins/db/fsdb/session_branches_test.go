@@ -0,0 +1,116 @@
+package fsdb
+
+import (
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+)
+
+// saveTestConversation saves a session with two turns, the second with a system prompt
+func saveTestConversation(t *testing.T, sessions *SessionsEntity, name string) {
+	t.Helper()
+	session := &Session{Name: name, Tags: []string{"work"}}
+	session.Append(
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "first question"},
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: "first answer"},
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleSystem, Content: "pattern"},
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "second question"},
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: "second answer"},
+	)
+	if err := sessions.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+}
+
+func TestSessions_ForkSession(t *testing.T) {
+	sessions := newTestSessions(t)
+	saveTestConversation(t, sessions, "main")
+
+	fork, err := sessions.ForkSession("main", 2, "branch")
+	if err != nil {
+		t.Fatalf("ForkSession() error = %v", err)
+	}
+	if len(fork.Messages) != 2 || fork.Parent == nil || fork.Parent.Name != "main" || fork.Parent.Message != 2 {
+		t.Errorf("unexpected fork %+v", fork)
+	}
+
+	loaded, err := sessions.Get("branch")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if loaded.Parent == nil || loaded.Parent.Name != "main" || len(loaded.Tags) != 1 {
+		t.Errorf("expected the link to the parent and the tags to be saved, got %+v", loaded)
+	}
+
+	if _, err = sessions.EditMessage("branch", 2, "changed"); err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	if parent, _ := sessions.Get("main"); len(parent.Messages) != 5 || parent.Messages[1].Content != "first answer" {
+		t.Error("expected the parent session to be left unchanged")
+	}
+
+	if _, err = sessions.ForkSession("main", 0, "branch"); err == nil {
+		t.Error("expected an error for an existing session name")
+	}
+	if _, err = sessions.ForkSession("main", 6, "other"); err == nil {
+		t.Error("expected an error for a message number out of range")
+	}
+	if _, err = sessions.ForkSession("missing", 0, "other"); err == nil {
+		t.Error("expected an error for a missing session")
+	}
+}
+
+func TestSessions_RewindSession(t *testing.T) {
+	sessions := newTestSessions(t)
+	saveTestConversation(t, sessions, "main")
+
+	session, err := sessions.RewindSession("main", 1)
+	if err != nil {
+		t.Fatalf("RewindSession() error = %v", err)
+	}
+	if len(session.Messages) != 2 || session.GetLastMessage().Content != "first answer" {
+		t.Errorf("expected the second turn with its system prompt to be removed, got %d messages", len(session.Messages))
+	}
+
+	if session, err = sessions.RewindSession("main", 5); err != nil || len(session.Messages) != 0 {
+		t.Errorf("expected all turns to be removed, got %d messages and %v", len(session.Messages), err)
+	}
+	if _, err = sessions.RewindSession("main", 1); err == nil {
+		t.Error("expected an error when there is nothing to rewind")
+	}
+}
+
+func TestSessions_TruncateSession(t *testing.T) {
+	sessions := newTestSessions(t)
+	saveTestConversation(t, sessions, "main")
+
+	if _, err := sessions.TruncateSession("main", 3); err != nil {
+		t.Fatalf("TruncateSession() error = %v", err)
+	}
+	if session, _ := sessions.Get("main"); len(session.Messages) != 3 {
+		t.Errorf("expected 3 messages to be kept, got %d", len(session.Messages))
+	}
+	if _, err := sessions.TruncateSession("main", 9); err == nil {
+		t.Error("expected an error for a length out of range")
+	}
+}
+
+func TestSessions_EditMessage(t *testing.T) {
+	sessions := newTestSessions(t)
+	saveTestConversation(t, sessions, "main")
+
+	if _, err := sessions.EditMessage("main", -1, "better answer"); err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+	session, _ := sessions.Get("main")
+	if last := session.GetLastMessage(); last.Content != "better answer" || !last.Meta.Edited {
+		t.Errorf("expected the last answer to be edited, got %+v", last)
+	}
+
+	if _, err := sessions.EditMessage("main", 1, "question"); err == nil {
+		t.Error("expected an error for a message that is not an answer")
+	}
+	if _, err := sessions.EditMessage("main", 0, "answer"); err == nil {
+		t.Error("expected an error for message number 0")
+	}
+}
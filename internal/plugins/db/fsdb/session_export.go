@@ -0,0 +1,165 @@
+package fsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// SessionExportFormats are the formats that sessions can be exported to
+var SessionExportFormats = []string{"markdown", "html", "jsonl", "openai"}
+
+// ExportSession converts a session to markdown, standalone html, jsonl with one fabric message
+// per line including its metadata, or the message array of the OpenAI chat API
+func ExportSession(session *Session, format string) (ret []byte, err error) {
+	switch format {
+	case "markdown", "md":
+		ret = []byte(exportMarkdown(session))
+	case "html":
+		ret = []byte(exportHTML(session))
+	case "jsonl":
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		for _, message := range session.Messages {
+			if err = encoder.Encode(message); err != nil {
+				return
+			}
+		}
+		ret = buffer.Bytes()
+	case "openai":
+		messages := []*chat.ChatCompletionMessage{}
+		for _, message := range session.Messages {
+			if message.Role == domain.ChatMessageRoleMeta {
+				continue
+			}
+			copied := *message
+			copied.Meta = nil
+			messages = append(messages, &copied)
+		}
+		if ret, err = json.MarshalIndent(messages, "", "  "); err == nil {
+			ret = append(ret, '\n')
+		}
+	default:
+		err = fmt.Errorf("unknown export format %q, use one of %s", format, strings.Join(SessionExportFormats, ", "))
+	}
+	return
+}
+
+// exportedMessages returns the messages of the conversation, without fabric's own meta messages
+func exportedMessages(session *Session) (ret []*chat.ChatCompletionMessage) {
+	for _, message := range session.Messages {
+		if message.Role != domain.ChatMessageRoleMeta {
+			ret = append(ret, message)
+		}
+	}
+	return
+}
+
+// sessionDetails describes when the session was created and updated, its tags and its parent
+func sessionDetails(session *Session) (ret []string) {
+	if !session.CreatedAt.IsZero() {
+		ret = append(ret, "Created "+formatSessionTime(session.CreatedAt))
+	}
+	if !session.UpdatedAt.IsZero() {
+		ret = append(ret, "updated "+formatSessionTime(session.UpdatedAt))
+	}
+	if len(session.Tags) > 0 {
+		ret = append(ret, "tags: "+strings.Join(session.Tags, ", "))
+	}
+	if session.Parent != nil {
+		ret = append(ret, fmt.Sprintf("forked from %s at message %d", session.Parent.Name, session.Parent.Message))
+	}
+	return
+}
+
+// messageHeading names the author of a message, with the model for answers
+func messageHeading(message *chat.ChatCompletionMessage) (ret string) {
+	ret = "Message"
+	if message.Role != "" {
+		ret = strings.ToUpper(message.Role[:1]) + message.Role[1:]
+	}
+	if message.Meta != nil && message.Meta.Model != "" {
+		ret += " (" + message.Meta.Model + ")"
+	}
+	return
+}
+
+func exportMarkdown(session *Session) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n", session.Name)
+	if details := sessionDetails(session); len(details) > 0 {
+		fmt.Fprintf(&builder, "\n_%s_\n", strings.Join(details, ", "))
+	}
+
+	for _, message := range exportedMessages(session) {
+		fmt.Fprintf(&builder, "\n## %s\n\n", messageHeading(message))
+		if len(message.MultiContent) == 0 {
+			builder.WriteString(strings.TrimSpace(message.Content) + "\n")
+			continue
+		}
+		for _, part := range message.MultiContent {
+			switch part.Type {
+			case chat.ChatMessagePartTypeText:
+				builder.WriteString(strings.TrimSpace(part.Text) + "\n\n")
+			case chat.ChatMessagePartTypeImageURL:
+				if part.ImageURL != nil {
+					fmt.Fprintf(&builder, "![image](%s)\n\n", part.ImageURL.URL)
+				}
+			}
+		}
+	}
+	return builder.String()
+}
+
+const sessionHTMLStyle = `body { font-family: system-ui, sans-serif; max-width: 50rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.details { color: #666; }
+.message { border-radius: 0.5rem; padding: 0.5rem 1rem; margin: 1rem 0; }
+.message h2 { font-size: 0.9rem; margin: 0.25rem 0; color: #555; }
+.message .content { white-space: pre-wrap; }
+.message img { max-width: 100%; }
+.user { background: #eef4ff; }
+.assistant { background: #f4f4f4; }
+.system { background: #fff8e6; }`
+
+func exportHTML(session *Session) string {
+	var builder strings.Builder
+	title := html.EscapeString(session.Name)
+	fmt.Fprintf(&builder, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<h1>%s</h1>\n",
+		title, sessionHTMLStyle, title)
+	if details := sessionDetails(session); len(details) > 0 {
+		fmt.Fprintf(&builder, "<p class=\"details\">%s</p>\n", html.EscapeString(strings.Join(details, ", ")))
+	}
+
+	for _, message := range exportedMessages(session) {
+		fmt.Fprintf(&builder, "<div class=\"message %s\">\n<h2>%s</h2>\n", html.EscapeString(message.Role),
+			html.EscapeString(messageHeading(message)))
+		if len(message.MultiContent) == 0 {
+			fmt.Fprintf(&builder, "<div class=\"content\">%s</div>\n", html.EscapeString(strings.TrimSpace(message.Content)))
+		}
+		for _, part := range message.MultiContent {
+			switch part.Type {
+			case chat.ChatMessagePartTypeText:
+				fmt.Fprintf(&builder, "<div class=\"content\">%s</div>\n", html.EscapeString(strings.TrimSpace(part.Text)))
+			case chat.ChatMessagePartTypeImageURL:
+				if part.ImageURL == nil {
+					continue
+				}
+				// Only web and inline images are shown, other URLs like javascript: could run code
+				if url := part.ImageURL.URL; strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") ||
+					strings.HasPrefix(url, "data:image/") {
+					fmt.Fprintf(&builder, "<img src=\"%s\" alt=\"image\">\n", html.EscapeString(url))
+				} else {
+					fmt.Fprintf(&builder, "<div class=\"content\">[image: %s]</div>\n", html.EscapeString(url))
+				}
+			}
+		}
+		builder.WriteString("</div>\n")
+	}
+	builder.WriteString("</body>\n</html>\n")
+	return builder.String()
+}
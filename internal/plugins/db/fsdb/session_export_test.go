@@ -0,0 +1,112 @@
+package fsdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+func newTestExportSession() *Session {
+	return &Session{Name: "research", Tags: []string{"work"}, Messages: []*chat.ChatCompletionMessage{
+		{Role: chat.ChatMessageRoleSystem, Content: "You are <helpful>."},
+		{Role: chat.ChatMessageRoleUser, MultiContent: []chat.ChatMessagePart{
+			{Type: chat.ChatMessagePartTypeText, Text: "What is in the picture?"},
+			{Type: chat.ChatMessagePartTypeImageURL, ImageURL: &chat.ChatMessageImageURL{URL: "data:image/png;base64,iVBORw0KGgo="}},
+		}},
+		{Role: chat.ChatMessageRoleAssistant, Content: "A cat & a dog.",
+			Meta: &chat.MessageMeta{Vendor: "OpenAI", Model: "gpt-4o"}},
+		{Role: domain.ChatMessageRoleMeta, Content: "internal"},
+	}}
+}
+
+func TestExportSession_Markdown(t *testing.T) {
+	exported, err := ExportSession(newTestExportSession(), "markdown")
+	if err != nil {
+		t.Fatalf("ExportSession() error = %v", err)
+	}
+	markdown := string(exported)
+	for _, expected := range []string{"# research", "tags: work", "## User", "![image](data:image/png;base64,iVBORw0KGgo=)",
+		"## Assistant (gpt-4o)\n\nA cat & a dog."} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected %q in the export:\n%s", expected, markdown)
+		}
+	}
+	if strings.Contains(markdown, "internal") {
+		t.Error("expected meta messages to be left out")
+	}
+}
+
+func TestExportSession_HTMLEscapesContent(t *testing.T) {
+	session := newTestExportSession()
+	session.Messages = append(session.Messages, &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser,
+		MultiContent: []chat.ChatMessagePart{{Type: chat.ChatMessagePartTypeImageURL,
+			ImageURL: &chat.ChatMessageImageURL{URL: "javascript:alert(1)"}}}})
+
+	exported, err := ExportSession(session, "html")
+	if err != nil {
+		t.Fatalf("ExportSession() error = %v", err)
+	}
+	page := string(exported)
+	if !strings.HasPrefix(page, "<!DOCTYPE html>") || !strings.Contains(page, "<style>") {
+		t.Error("expected a standalone html page")
+	}
+	if !strings.Contains(page, "You are &lt;helpful&gt;.") || !strings.Contains(page, "A cat &amp; a dog.") {
+		t.Errorf("expected the content to be escaped:\n%s", page)
+	}
+	if !strings.Contains(page, `<img src="data:image/png;base64,iVBORw0KGgo="`) {
+		t.Error("expected the inline image to be shown")
+	}
+	if strings.Contains(page, `src="javascript:`) {
+		t.Error("expected other image urls not to be used as image sources")
+	}
+}
+
+func TestExportSession_JSONLKeepsMetadata(t *testing.T) {
+	exported, err := ExportSession(newTestExportSession(), "jsonl")
+	if err != nil {
+		t.Fatalf("ExportSession() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(exported)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a line per message, got %d", len(lines))
+	}
+	if !strings.Contains(lines[2], `"model":"gpt-4o"`) || !strings.Contains(lines[3], `"role":"meta"`) {
+		t.Errorf("expected the messages with their metadata, got %s", exported)
+	}
+}
+
+func TestExportSession_OpenAIRoundTrip(t *testing.T) {
+	exported, err := ExportSession(newTestExportSession(), "openai")
+	if err != nil {
+		t.Fatalf("ExportSession() error = %v", err)
+	}
+	if strings.Contains(string(exported), "meta") {
+		t.Errorf("expected no fabric metadata in the OpenAI format:\n%s", exported)
+	}
+
+	sessions := newTestSessions(t)
+	imported, err := sessions.ImportSessions(exported, "copy")
+	if err != nil {
+		t.Fatalf("ImportSessions() error = %v", err)
+	}
+	messages := imported[0].Messages
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	parts := messages[1].MultiContent
+	if len(parts) != 2 || parts[0].Text != "What is in the picture?" || parts[1].ImageURL == nil ||
+		parts[1].ImageURL.URL != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("expected the image content to round-trip, got %+v", parts)
+	}
+	if messages[2].Content != "A cat & a dog." {
+		t.Errorf("unexpected answer %q", messages[2].Content)
+	}
+}
+
+func TestExportSession_UnknownFormat(t *testing.T) {
+	if _, err := ExportSession(newTestExportSession(), "pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,449 @@
+package fsdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/domain"
+)
+
+// ImportSessions saves the conversations of an export of another tool as sessions. It reads
+// ChatGPT conversations.json exports, OpenAI or Anthropic message arrays, Anthropic requests
+// with a system prompt, fabric session files and jsonl with one message per line.
+// A name is used for the first conversation and with a number for the next ones,
+// otherwise the sessions are named after the conversation titles.
+func (o *SessionsEntity) ImportSessions(content []byte, name string) (ret []*Session, err error) {
+	var imported []*Session
+	if imported, err = parseImport(content); err != nil {
+		return
+	}
+	if name != "" && len(imported) == 1 && o.Exists(name) {
+		err = fmt.Errorf("session %s already exists", name)
+		return
+	}
+
+	taken := map[string]bool{}
+	for _, session := range imported {
+		base := name
+		if base == "" {
+			base = sessionNameFromTitle(session.Name)
+		}
+		session.Name = o.uniqueSessionName(base, taken)
+		taken[session.Name] = true
+		if err = o.SaveSession(session); err != nil {
+			return
+		}
+		ret = append(ret, session)
+	}
+	return
+}
+
+// uniqueSessionName appends a number to a name that is taken by a saved or just imported session
+func (o *SessionsEntity) uniqueSessionName(base string, taken map[string]bool) (ret string) {
+	ret = base
+	for i := 2; taken[ret] || o.Exists(ret); i++ {
+		ret = fmt.Sprintf("%s-%d", base, i)
+	}
+	return
+}
+
+var sessionNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// sessionNameFromTitle turns a conversation title into a name that is safe as a file name
+func sessionNameFromTitle(title string) (ret string) {
+	ret = sessionNameInvalidChars.ReplaceAllString(strings.TrimSpace(title), "-")
+	ret = strings.Trim(ret, "-.")
+	if len(ret) > 64 {
+		ret = strings.TrimRight(ret[:64], "-.")
+	}
+	if ret == "" {
+		ret = "imported"
+	}
+	return
+}
+
+// parseImport detects the format of an export and returns its conversations
+func parseImport(content []byte) (ret []*Session, err error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		err = errors.New("nothing to import")
+		return
+	}
+	if !json.Valid(content) {
+		var session *Session
+		if session, err = parseJSONLines(content); err == nil {
+			ret = []*Session{session}
+		}
+		return
+	}
+
+	if content[0] == '[' {
+		var items []json.RawMessage
+		if err = json.Unmarshal(content, &items); err != nil {
+			return
+		}
+		if len(items) == 0 {
+			err = errors.New("nothing to import")
+			return
+		}
+		if hasKey(items[0], "mapping") {
+			return parseChatGPTConversations(items)
+		}
+		var session *Session
+		if session, err = parseMessageArray(items); err == nil {
+			ret = []*Session{session}
+		}
+		return
+	}
+
+	var object map[string]json.RawMessage
+	if err = json.Unmarshal(content, &object); err != nil {
+		err = fmt.Errorf("unknown import format: %w", err)
+		return
+	}
+	var session *Session
+	switch {
+	case object["mapping"] != nil:
+		return parseChatGPTConversations([]json.RawMessage{content})
+	case object["version"] != nil:
+		session, err = parseSession(content)
+	case object["messages"] != nil:
+		session, err = parseMessagesObject(object)
+	case object["role"] != nil:
+		session, err = parseJSONLines(content)
+	default:
+		err = errors.New("unknown import format, expected a ChatGPT export, an array of messages or jsonl messages")
+	}
+	if err == nil {
+		ret = []*Session{session}
+	}
+	return
+}
+
+func hasKey(item json.RawMessage, key string) bool {
+	var object map[string]json.RawMessage
+	return json.Unmarshal(item, &object) == nil && object[key] != nil
+}
+
+// parseMessagesObject reads an object with a messages array, like an Anthropic request whose
+// system prompt is a separate field
+func parseMessagesObject(object map[string]json.RawMessage) (ret *Session, err error) {
+	var items []json.RawMessage
+	if err = json.Unmarshal(object["messages"], &items); err != nil {
+		return
+	}
+	if ret, err = parseMessageArray(items); err != nil {
+		return
+	}
+	if system := object["system"]; system != nil {
+		var message *chat.ChatCompletionMessage
+		if message, err = parseMessageContent(chat.ChatMessageRoleSystem, system); err != nil {
+			return
+		}
+		if message != nil {
+			ret.Messages = append([]*chat.ChatCompletionMessage{message}, ret.Messages...)
+		}
+	}
+	var title string
+	if json.Unmarshal(object["name"], &title) == nil || json.Unmarshal(object["title"], &title) == nil {
+		ret.Name = title
+	}
+	return
+}
+
+// parseJSONLines reads one message per line
+func parseJSONLines(content []byte) (ret *Session, err error) {
+	var items []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if !json.Valid(text) {
+			err = fmt.Errorf("line %d is not a json message", line)
+			return
+		}
+		items = append(items, slices.Clone(text))
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	ret, err = parseMessageArray(items)
+	return
+}
+
+// importedMessage is a message of the OpenAI or Anthropic chat APIs, or of a fabric session
+type importedMessage struct {
+	Role    string            `json:"role"`
+	Content json.RawMessage   `json:"content"`
+	Meta    *chat.MessageMeta `json:"meta"`
+}
+
+// importedPart is a content part of OpenAI or a content block of Anthropic
+type importedPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text"`
+	ImageURL json.RawMessage `json:"image_url"`
+	Source   *struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+		URL       string `json:"url"`
+	} `json:"source"`
+}
+
+// parseMessageArray reads OpenAI, Anthropic or fabric messages. Tool calls and their results
+// are left out, since sessions only hold the conversation.
+func parseMessageArray(items []json.RawMessage) (ret *Session, err error) {
+	ret = &Session{}
+	for i, item := range items {
+		var imported importedMessage
+		if err = json.Unmarshal(item, &imported); err != nil {
+			err = fmt.Errorf("message %d: %w", i+1, err)
+			return
+		}
+		role := imported.Role
+		switch role {
+		case chat.ChatMessageRoleUser, chat.ChatMessageRoleAssistant, chat.ChatMessageRoleSystem, domain.ChatMessageRoleMeta:
+		case chat.ChatMessageRoleDeveloper:
+			role = chat.ChatMessageRoleSystem
+		case chat.ChatMessageRoleTool, chat.ChatMessageRoleFunction:
+			continue
+		default:
+			err = fmt.Errorf("message %d has the unknown role %q", i+1, imported.Role)
+			return
+		}
+
+		var message *chat.ChatCompletionMessage
+		if message, err = parseMessageContent(role, imported.Content); err != nil {
+			err = fmt.Errorf("message %d: %w", i+1, err)
+			return
+		}
+		if message != nil {
+			message.Meta = imported.Meta
+			ret.Messages = append(ret.Messages, message)
+		}
+	}
+	if len(ret.Messages) == 0 {
+		err = errors.New("the import has no messages")
+	}
+	return
+}
+
+// parseMessageContent reads content that is either a string or an array of parts.
+// Content without images becomes plain text. Returns nil for empty content.
+func parseMessageContent(role string, content json.RawMessage) (ret *chat.ChatCompletionMessage, err error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || string(content) == "null" {
+		return
+	}
+	if content[0] == '"' {
+		var text string
+		if err = json.Unmarshal(content, &text); err != nil || text == "" {
+			return
+		}
+		ret = &chat.ChatCompletionMessage{Role: role, Content: text}
+		return
+	}
+
+	var parts []importedPart
+	if err = json.Unmarshal(content, &parts); err != nil {
+		return
+	}
+	var multiContent []chat.ChatMessagePart
+	var texts []string
+	hasImage := false
+	for _, part := range parts {
+		switch part.Type {
+		case "text", "input_text", "output_text":
+			if part.Text != "" {
+				multiContent = append(multiContent, chat.ChatMessagePart{Type: chat.ChatMessagePartTypeText, Text: part.Text})
+				texts = append(texts, part.Text)
+			}
+		case "image_url":
+			// OpenAI allows the image url as an object with the url or as the url itself
+			var image chat.ChatMessageImageURL
+			if err = json.Unmarshal(part.ImageURL, &image); err != nil {
+				if err = json.Unmarshal(part.ImageURL, &image.URL); err != nil {
+					return
+				}
+			}
+			multiContent = append(multiContent, chat.ChatMessagePart{Type: chat.ChatMessagePartTypeImageURL, ImageURL: &image})
+			hasImage = true
+		case "image":
+			if part.Source == nil {
+				continue
+			}
+			url := part.Source.URL
+			if part.Source.Type == "base64" {
+				url = "data:" + part.Source.MediaType + ";base64," + part.Source.Data
+			}
+			multiContent = append(multiContent, chat.ChatMessagePart{
+				Type: chat.ChatMessagePartTypeImageURL, ImageURL: &chat.ChatMessageImageURL{URL: url}})
+			hasImage = true
+		}
+	}
+
+	if hasImage {
+		ret = &chat.ChatCompletionMessage{Role: role, MultiContent: multiContent}
+	} else if len(texts) > 0 {
+		ret = &chat.ChatCompletionMessage{Role: role, Content: strings.Join(texts, "\n\n")}
+	}
+	return
+}
+
+// chatGPTConversation is a conversation of a ChatGPT conversations.json export. The messages form
+// a tree, with a branch for every edit or regenerated answer, and current_node is the shown leaf.
+type chatGPTConversation struct {
+	Title       string                  `json:"title"`
+	CreateTime  float64                 `json:"create_time"`
+	CurrentNode string                  `json:"current_node"`
+	Mapping     map[string]*chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Message  *chatGPTMessage `json:"message"`
+	Parent   string          `json:"parent"`
+	Children []string        `json:"children"`
+}
+
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug string `json:"model_slug"`
+		Hidden    bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+func parseChatGPTConversations(items []json.RawMessage) (ret []*Session, err error) {
+	for i, item := range items {
+		var conversation chatGPTConversation
+		if err = json.Unmarshal(item, &conversation); err != nil {
+			err = fmt.Errorf("conversation %d: %w", i+1, err)
+			return
+		}
+		session := conversation.session()
+		if len(session.Messages) > 0 {
+			ret = append(ret, session)
+		}
+	}
+	if len(ret) == 0 {
+		err = errors.New("the export has no conversations with messages")
+	}
+	return
+}
+
+// session follows the branch from the current node up to the root
+func (o *chatGPTConversation) session() (ret *Session) {
+	ret = &Session{Name: o.Title, CreatedAt: unixTime(o.CreateTime)}
+
+	visited := map[string]bool{}
+	for id := o.leaf(); id != "" && !visited[id]; {
+		visited[id] = true
+		node := o.Mapping[id]
+		if node == nil {
+			break
+		}
+		if message := node.Message.chatMessage(); message != nil {
+			ret.Messages = append(ret.Messages, message)
+		}
+		id = node.Parent
+	}
+	slices.Reverse(ret.Messages)
+	return
+}
+
+// leaf returns the current node, or for older exports without it the end of the latest branch
+func (o *chatGPTConversation) leaf() (ret string) {
+	if o.Mapping[o.CurrentNode] != nil {
+		return o.CurrentNode
+	}
+	for id, node := range o.Mapping {
+		if node.Parent == "" || o.Mapping[node.Parent] == nil {
+			ret = id
+			break
+		}
+	}
+	for node := o.Mapping[ret]; node != nil && len(node.Children) > 0; node = o.Mapping[ret] {
+		ret = node.Children[len(node.Children)-1]
+	}
+	return
+}
+
+// chatMessage returns the message without tool calls and hidden messages. Images are not part of
+// ChatGPT exports, they become a placeholder with the asset pointer.
+func (o *chatGPTMessage) chatMessage() (ret *chat.ChatCompletionMessage) {
+	if o == nil || o.Metadata.Hidden {
+		return
+	}
+	role := o.Author.Role
+	if role != chat.ChatMessageRoleUser && role != chat.ChatMessageRoleAssistant && role != chat.ChatMessageRoleSystem {
+		return
+	}
+
+	var texts []string
+	switch o.Content.ContentType {
+	case "text", "multimodal_text":
+		for _, part := range o.Content.Parts {
+			var text string
+			if json.Unmarshal(part, &text) == nil {
+				if text != "" {
+					texts = append(texts, text)
+				}
+				continue
+			}
+			var asset struct {
+				ContentType  string `json:"content_type"`
+				AssetPointer string `json:"asset_pointer"`
+			}
+			if json.Unmarshal(part, &asset) == nil && asset.AssetPointer != "" {
+				texts = append(texts, "[image: "+asset.AssetPointer+"]")
+			}
+		}
+	case "code":
+		if o.Content.Text != "" {
+			texts = append(texts, "```\n"+o.Content.Text+"\n```")
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+
+	ret = &chat.ChatCompletionMessage{Role: role, Content: strings.Join(texts, "\n\n")}
+	if o.CreateTime > 0 || o.Metadata.ModelSlug != "" {
+		ret.Meta = &chat.MessageMeta{Timestamp: unixTime(o.CreateTime)}
+		if role == chat.ChatMessageRoleAssistant {
+			ret.Meta.Model = o.Metadata.ModelSlug
+		}
+	}
+	return
+}
+
+// unixTime converts the fractional seconds of ChatGPT exports, zero stays the zero time
+func unixTime(seconds float64) (ret time.Time) {
+	if seconds <= 0 {
+		return
+	}
+	whole, fraction := math.Modf(seconds)
+	ret = time.Unix(int64(whole), int64(fraction*1e9))
+	return
+}
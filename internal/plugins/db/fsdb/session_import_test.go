@@ -0,0 +1,115 @@
+package fsdb
+
+import (
+	"testing"
+	"time"
+)
+
+const testChatGPTExport = `[{
+  "title": "Cats / dogs?",
+  "create_time": 1717405200.5,
+  "current_node": "answer-2",
+  "mapping": {
+    "root": {"id": "root", "message": null, "parent": null, "children": ["system"]},
+    "system": {"id": "system", "parent": "root", "children": ["question"], "message": {
+      "author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]},
+      "metadata": {"is_visually_hidden_from_conversation": true}}},
+    "question": {"id": "question", "parent": "system", "children": ["answer-1", "answer-2"], "message": {
+      "author": {"role": "user"}, "create_time": 1717405201,
+      "content": {"content_type": "multimodal_text", "parts": [
+        {"content_type": "image_asset_pointer", "asset_pointer": "file-service://file-abc"}, "Which is better?"]}}},
+    "answer-1": {"id": "answer-1", "parent": "question", "children": [], "message": {
+      "author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["Discarded answer"]}}},
+    "answer-2": {"id": "answer-2", "parent": "question", "children": [], "message": {
+      "author": {"role": "assistant"}, "create_time": 1717405210,
+      "content": {"content_type": "text", "parts": ["Both are great."]},
+      "metadata": {"model_slug": "gpt-4o"}}}
+  }
+}, {
+  "title": "Empty",
+  "mapping": {"root": {"id": "root", "message": null, "parent": null, "children": []}}
+}]`
+
+func TestSessions_ImportChatGPTExport(t *testing.T) {
+	sessions := newTestSessions(t)
+	imported, err := sessions.ImportSessions([]byte(testChatGPTExport), "")
+	if err != nil {
+		t.Fatalf("ImportSessions() error = %v", err)
+	}
+	if len(imported) != 1 {
+		t.Fatalf("expected the empty conversation to be skipped, got %d sessions", len(imported))
+	}
+
+	session, err := sessions.Get("Cats-dogs")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(session.Messages) != 2 {
+		t.Fatalf("expected the hidden system message and the other branch to be skipped, got %d messages",
+			len(session.Messages))
+	}
+	if session.Messages[0].Content != "[image: file-service://file-abc]\n\nWhich is better?" {
+		t.Errorf("unexpected question %q", session.Messages[0].Content)
+	}
+	answer := session.Messages[1]
+	if answer.Content != "Both are great." || answer.Meta == nil || answer.Meta.Model != "gpt-4o" ||
+		!answer.Meta.Timestamp.Equal(time.Unix(1717405210, 0)) {
+		t.Errorf("unexpected answer %+v", answer)
+	}
+	if !session.CreatedAt.Equal(time.Unix(1717405200, 5e8)) {
+		t.Errorf("expected the creation time of the conversation, got %v", session.CreatedAt)
+	}
+
+	if imported, err = sessions.ImportSessions([]byte(testChatGPTExport), ""); err != nil || imported[0].Name != "Cats-dogs-2" {
+		t.Errorf("expected a second import to get a new name, got %v", err)
+	}
+}
+
+func TestSessions_ImportAnthropicMessages(t *testing.T) {
+	sessions := newTestSessions(t)
+	anthropic := `{"system": "Be brief.", "messages": [
+		{"role": "user", "content": [
+			{"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}},
+			{"type": "text", "text": "What is this?"}]},
+		{"role": "assistant", "content": [{"type": "text", "text": "A logo."}]},
+		{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "t1", "content": "ignored"}]}
+	]}`
+
+	imported, err := sessions.ImportSessions([]byte(anthropic), "logo")
+	if err != nil {
+		t.Fatalf("ImportSessions() error = %v", err)
+	}
+	messages := imported[0].Messages
+	if len(messages) != 3 || messages[0].Role != "system" || messages[0].Content != "Be brief." {
+		t.Fatalf("expected the system prompt first and the tool result skipped, got %+v", messages)
+	}
+	parts := messages[1].MultiContent
+	if len(parts) != 2 || parts[0].ImageURL == nil || parts[0].ImageURL.URL != "data:image/png;base64,iVBORw0KGgo=" {
+		t.Errorf("expected the image as a data url, got %+v", parts)
+	}
+	if messages[2].Content != "A logo." || messages[2].MultiContent != nil {
+		t.Errorf("expected text blocks to become plain content, got %+v", messages[2])
+	}
+
+	if _, err = sessions.ImportSessions([]byte(anthropic), "logo"); err == nil {
+		t.Error("expected an error when the named session exists")
+	}
+}
+
+func TestSessions_ImportJSONLines(t *testing.T) {
+	sessions := newTestSessions(t)
+	lines := `{"role":"user","content":"question"}
+{"role":"assistant","content":"answer","meta":{"vendor":"Anthropic","model":"claude-3-opus"}}
+`
+	imported, err := sessions.ImportSessions([]byte(lines), "lines")
+	if err != nil {
+		t.Fatalf("ImportSessions() error = %v", err)
+	}
+	if messages := imported[0].Messages; len(messages) != 2 || messages[1].Meta == nil || messages[1].Meta.Model != "claude-3-opus" {
+		t.Errorf("expected the messages with their metadata, got %+v", messages)
+	}
+
+	if _, err = sessions.ImportSessions([]byte(`{"something": "else"}`), ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,223 @@
+package fsdb
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// sessionSortFields are the fields that sessions can be listed by
+var sessionSortFields = []string{"name", "created", "updated", "messages", "size", "tokens"}
+
+// sessionFilterFields are the fields that sessions can be filtered by
+var sessionFilterFields = []string{"tag", "model", "vendor", "pattern", "strategy", "since", "until"}
+
+// SessionInfo summarizes a session for listing
+type SessionInfo struct {
+	Name       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Tags       []string
+	Messages   int
+	Size       int64
+	Tokens     int
+	Vendors    []string
+	Models     []string
+	Patterns   []string
+	Strategies []string
+}
+
+// NewSessionInfo summarizes the session, size is the size of its file
+func NewSessionInfo(session *Session, size int64) (ret *SessionInfo) {
+	ret = &SessionInfo{
+		Name:      session.Name,
+		CreatedAt: session.CreatedAt,
+		UpdatedAt: session.UpdatedAt,
+		Tags:      session.Tags,
+		Messages:  len(session.Messages),
+		Size:      size,
+	}
+
+	add := func(values []string, value string) []string {
+		if value != "" && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+		return values
+	}
+	for _, message := range session.Messages {
+		if message.Meta == nil {
+			continue
+		}
+		if message.Meta.Usage != nil {
+			ret.Tokens += message.Meta.Usage.TotalTokens
+		}
+		ret.Vendors = add(ret.Vendors, message.Meta.Vendor)
+		ret.Models = add(ret.Models, message.Meta.Model)
+		ret.Patterns = add(ret.Patterns, message.Meta.Pattern)
+		ret.Strategies = add(ret.Strategies, message.Meta.Strategy)
+	}
+	return
+}
+
+// SessionFilter selects sessions by their metadata. A session is listed when it
+// matches all conditions.
+type SessionFilter struct {
+	conditions []sessionCondition
+	since      time.Time
+	until      time.Time
+}
+
+type sessionCondition struct {
+	field string
+	value string
+}
+
+// ParseSessionFilter parses filter expressions like tag=work, model=gpt-4o or since=2025-01-31
+func ParseSessionFilter(expressions []string) (ret *SessionFilter, err error) {
+	ret = &SessionFilter{}
+	for _, expression := range expressions {
+		field, value, found := strings.Cut(expression, "=")
+		field, value = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(value)
+		if !found || value == "" || !slices.Contains(sessionFilterFields, field) {
+			err = fmt.Errorf("invalid session filter %q, use field=value with one of the fields %s",
+				expression, strings.Join(sessionFilterFields, ", "))
+			return
+		}
+
+		switch field {
+		case "since":
+			if ret.since, err = parseFilterTime(value); err != nil {
+				return
+			}
+		case "until":
+			if ret.until, err = parseFilterTime(value); err != nil {
+				return
+			}
+			// A date includes the whole day
+			if len(value) == len(time.DateOnly) {
+				ret.until = ret.until.AddDate(0, 0, 1)
+			}
+		default:
+			ret.conditions = append(ret.conditions, sessionCondition{field: field, value: value})
+		}
+	}
+	return
+}
+
+func parseFilterTime(value string) (ret time.Time, err error) {
+	if ret, err = time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return
+	}
+	if ret, err = time.Parse(time.RFC3339, value); err != nil {
+		err = fmt.Errorf("invalid time %q, use a date like 2025-01-31 or a RFC 3339 time", value)
+	}
+	return
+}
+
+// Matches reports whether the session matches all conditions of the filter
+func (o *SessionFilter) Matches(info *SessionInfo) bool {
+	if !o.since.IsZero() && info.UpdatedAt.Before(o.since) {
+		return false
+	}
+	if !o.until.IsZero() && !info.CreatedAt.Before(o.until) {
+		return false
+	}
+	for _, condition := range o.conditions {
+		var values []string
+		switch condition.field {
+		case "tag":
+			values = info.Tags
+		case "model":
+			values = info.Models
+		case "vendor":
+			values = info.Vendors
+		case "pattern":
+			values = info.Patterns
+		case "strategy":
+			values = info.Strategies
+		}
+		if !slices.ContainsFunc(values, func(value string) bool { return strings.EqualFold(value, condition.value) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetInfos summarizes the sessions that match the filter, sorted by sortBy. Sessions sorted by
+// time, size or tokens are listed with the newest or largest first.
+func (o *SessionsEntity) GetInfos(filter *SessionFilter, sortBy string) (ret []*SessionInfo, err error) {
+	if sortBy == "" {
+		sortBy = "name"
+	}
+	if !slices.Contains(sessionSortFields, sortBy) {
+		err = fmt.Errorf("invalid session sort %q, use one of %s", sortBy, strings.Join(sessionSortFields, ", "))
+		return
+	}
+
+	var names []string
+	if names, err = o.GetNames(); err != nil {
+		return
+	}
+	for _, name := range names {
+		session, loadErr := o.load(name)
+		if loadErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping session %s: %v\n", name, loadErr)
+			continue
+		}
+		if info := NewSessionInfo(session, o.Size(name)); filter == nil || filter.Matches(info) {
+			ret = append(ret, info)
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, b := ret[i], ret[j]
+		switch sortBy {
+		case "created":
+			return a.CreatedAt.After(b.CreatedAt)
+		case "updated":
+			return a.UpdatedAt.After(b.UpdatedAt)
+		case "messages":
+			return a.Messages > b.Messages
+		case "size":
+			return a.Size > b.Size
+		case "tokens":
+			return a.Tokens > b.Tokens
+		}
+		return a.Name < b.Name
+	})
+	return
+}
+
+// ListSessions prints the sessions that match the filter with their metadata
+func (o *SessionsEntity) ListSessions(filter *SessionFilter, sortBy string) (err error) {
+	var infos []*SessionInfo
+	if infos, err = o.GetInfos(filter, sortBy); err != nil {
+		return
+	}
+
+	if len(infos) == 0 {
+		fmt.Printf("\nNo %v\n", o.Label)
+		return
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tCREATED\tUPDATED\tMESSAGES\tTOKENS\tMODELS\tTAGS")
+	for _, info := range infos {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n", info.Name, formatSessionTime(info.CreatedAt),
+			formatSessionTime(info.UpdatedAt), info.Messages, info.Tokens, strings.Join(info.Models, ","),
+			strings.Join(info.Tags, ","))
+	}
+	err = writer.Flush()
+	return
+}
+
+func formatSessionTime(value time.Time) string {
+	if value.IsZero() {
+		return "-"
+	}
+	return value.Local().Format("2006-01-02 15:04")
+}
@@ -1,22 +1,31 @@
 package fsdb
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/danielmiessler/fabric/internal/chat"
 	"github.com/danielmiessler/fabric/internal/domain"
 )
 
+// SessionFileVersion is the version of the session file format. Version 1 files hold only
+// the array of messages, later versions an object with the session metadata.
+const SessionFileVersion = 2
+
 type SessionsEntity struct {
 	*StorageEntity
 }
 
 func (o *SessionsEntity) Get(name string) (session *Session, err error) {
-	session = &Session{Name: name}
-
 	if o.Exists(name) {
-		err = o.LoadAsJson(name, &session.Messages)
+		session, err = o.load(name)
 	} else {
+		session = &Session{Name: name}
 		fmt.Printf("Creating new session: %s\n", name)
 	}
 	return
@@ -24,40 +33,206 @@ func (o *SessionsEntity) Get(name string) (session *Session, err error) {
 
 func (o *SessionsEntity) PrintSession(name string) (err error) {
 	if o.Exists(name) {
-		var session Session
-		if err = o.LoadAsJson(name, &session.Messages); err == nil {
-			fmt.Println(session.String())
+		var session *Session
+		if session, err = o.load(name); err == nil {
+			if session.Parent != nil {
+				fmt.Printf("Forked from session %s at message %d\n", session.Parent.Name, session.Parent.Message)
+			}
+			fmt.Println(session.format(true))
 		}
 	}
 	return
 }
 
+// SaveSession writes the session in the current file format and updates its timestamps
 func (o *SessionsEntity) SaveSession(session *Session) (err error) {
-	return o.SaveAsJson(session.Name, session.Messages)
+	now := time.Now()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	session.UpdatedAt = now
+	return o.write(session)
+}
+
+// write saves the session in the current file format as it is, with its timestamps
+func (o *SessionsEntity) write(session *Session) (err error) {
+	return o.SaveAsJson(session.Name, &sessionFile{
+		Version:   SessionFileVersion,
+		Name:      session.Name,
+		CreatedAt: session.CreatedAt,
+		UpdatedAt: session.UpdatedAt,
+		Tags:      session.Tags,
+		Parent:    session.Parent,
+		Messages:  session.Messages,
+	})
+}
+
+// load reads a session file of any version. The name of the file is the name of the session.
+func (o *SessionsEntity) load(name string) (session *Session, err error) {
+	var content []byte
+	if content, err = o.Load(name); err != nil {
+		return
+	}
+	if session, err = parseSession(content); err != nil {
+		err = fmt.Errorf("could not unmarshal %s: %s", name, err)
+		return
+	}
+	session.Name = name
+
+	// Version 1 files have no timestamps, the modification time of the file is the best guess
+	if session.UpdatedAt.IsZero() && o.Items == nil {
+		if info, statErr := os.Stat(o.BuildFilePathByName(name)); statErr == nil {
+			session.UpdatedAt = info.ModTime()
+		}
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = session.UpdatedAt
+	}
+	return
+}
+
+// parseSession parses the content of a session file, either the current format or
+// the array of messages of version 1
+func parseSession(content []byte) (session *Session, err error) {
+	session = &Session{}
+	content = bytes.TrimSpace(content)
+	if len(content) > 0 && content[0] == '[' {
+		err = json.Unmarshal(content, &session.Messages)
+		return
+	}
+
+	var file sessionFile
+	if err = json.Unmarshal(content, &file); err != nil {
+		return
+	}
+	if file.Version > SessionFileVersion {
+		err = fmt.Errorf("session file version %d is newer than the supported version %d, please update fabric",
+			file.Version, SessionFileVersion)
+		return
+	}
+	session.Name = file.Name
+	session.CreatedAt = file.CreatedAt
+	session.UpdatedAt = file.UpdatedAt
+	session.Tags = file.Tags
+	session.Parent = file.Parent
+	session.Messages = file.Messages
+	return
+}
+
+// sessionFile is how a session is saved
+type sessionFile struct {
+	Version   int                           `json:"version"`
+	Name      string                        `json:"name"`
+	CreatedAt time.Time                     `json:"created_at"`
+	UpdatedAt time.Time                     `json:"updated_at"`
+	Tags      []string                      `json:"tags,omitempty"`
+	Parent    *SessionParent                `json:"parent,omitempty"`
+	Messages  []*chat.ChatCompletionMessage `json:"messages"`
+}
+
+// SessionParent links a forked session to the session and the message it was forked at
+type SessionParent struct {
+	Name    string `json:"name"`
+	Message int    `json:"message"`
 }
 
 type Session struct {
-	Name     string
-	Messages []*chat.ChatCompletionMessage
+	Name      string
+	Messages  []*chat.ChatCompletionMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Tags      []string
+	// Parent is set for a session that was forked from another one
+	Parent *SessionParent
 
 	vendorMessages []*chat.ChatCompletionMessage
 }
 
+// AddTags adds the tags that the session does not have yet
+func (o *Session) AddTags(tags ...string) {
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(o.Tags, tag) {
+			o.Tags = append(o.Tags, tag)
+		}
+	}
+}
+
 func (o *Session) IsEmpty() bool {
 	return len(o.Messages) == 0
 }
 
+// Append adds the messages to the session and records when they were added
 func (o *Session) Append(messages ...*chat.ChatCompletionMessage) {
-	if o.vendorMessages != nil {
-		for _, message := range messages {
-			o.Messages = append(o.Messages, message)
+	for _, message := range messages {
+		stampMessage(message)
+		o.Messages = append(o.Messages, message)
+		if o.vendorMessages != nil {
 			o.appendVendorMessage(message)
 		}
-	} else {
-		o.Messages = append(o.Messages, messages...)
 	}
 }
 
+// stampMessage sets the timestamp of a message that does not have one yet
+func stampMessage(message *chat.ChatCompletionMessage) {
+	if message.Meta == nil {
+		message.Meta = &chat.MessageMeta{}
+	}
+	if message.Meta.Timestamp.IsZero() {
+		message.Meta.Timestamp = time.Now()
+	}
+}
+
+// InsertBefore inserts message in front of target, or appends it when target is not part of the session
+func (o *Session) InsertBefore(target *chat.ChatCompletionMessage, message *chat.ChatCompletionMessage) {
+	for i, existing := range o.Messages {
+		if existing == target {
+			stampMessage(message)
+			o.Messages = append(o.Messages[:i], append([]*chat.ChatCompletionMessage{message}, o.Messages[i:]...)...)
+			o.vendorMessages = nil
+			return
+		}
+	}
+	o.Append(message)
+}
+
+// Truncate keeps the first length messages of the session
+func (o *Session) Truncate(length int) {
+	if length < len(o.Messages) {
+		o.Messages = o.Messages[:max(length, 0)]
+		o.vendorMessages = nil
+	}
+}
+
+// RemoveLastTurns removes the last answers with everything that was sent since the answer
+// before them, like the user message and the system prompt, and returns how many turns were removed
+func (o *Session) RemoveLastTurns(turns int) (removed int) {
+	length := len(o.Messages)
+	for ; removed < turns && length > 0; removed++ {
+		for length > 0 && o.Messages[length-1].Role == chat.ChatMessageRoleAssistant {
+			length--
+		}
+		for length > 0 && o.Messages[length-1].Role != chat.ChatMessageRoleAssistant {
+			length--
+		}
+	}
+	o.Truncate(length)
+	return
+}
+
+// GetMessage returns a message by its number, counted from 1, or from the end for negative numbers
+func (o *Session) GetMessage(number int) (ret *chat.ChatCompletionMessage, err error) {
+	index := number - 1
+	if number < 0 {
+		index = len(o.Messages) + number
+	}
+	if number == 0 || index < 0 || index >= len(o.Messages) {
+		err = fmt.Errorf("session %s has no message %d, it has %d messages", o.Name, number, len(o.Messages))
+		return
+	}
+	ret = o.Messages[index]
+	return
+}
+
 func (o *Session) GetVendorMessages() (ret []*chat.ChatCompletionMessage) {
 	if len(o.vendorMessages) == 0 {
 		for _, message := range o.Messages {
@@ -82,8 +257,17 @@ func (o *Session) GetLastMessage() (ret *chat.ChatCompletionMessage) {
 }
 
 func (o *Session) String() (ret string) {
-	for _, message := range o.Messages {
-		ret += fmt.Sprintf("\n--- \n[%v]\n%v", message.Role, message.Content)
+	return o.format(false)
+}
+
+// format prints the messages of the session, optionally with their numbers
+func (o *Session) format(numbered bool) (ret string) {
+	for i, message := range o.Messages {
+		if numbered {
+			ret += fmt.Sprintf("\n--- #%d\n[%v]\n%v", i+1, message.Role, message.Content)
+		} else {
+			ret += fmt.Sprintf("\n--- \n[%v]\n%v", message.Role, message.Content)
+		}
 		if message.MultiContent != nil {
 			for _, part := range message.MultiContent {
 				switch part.Type {
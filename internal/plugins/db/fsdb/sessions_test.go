@@ -1,7 +1,11 @@
 package fsdb
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/danielmiessler/fabric/internal/chat"
 )
@@ -36,3 +40,176 @@ func TestSessions_SaveSession(t *testing.T) {
 		t.Errorf("expected session to be saved")
 	}
 }
+
+func TestSession_InsertBefore(t *testing.T) {
+	first := &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "first"}
+	second := &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "second"}
+	session := &Session{Messages: []*chat.ChatCompletionMessage{first, second}}
+	if got := len(session.GetVendorMessages()); got != 2 {
+		t.Fatalf("expected 2 vendor messages, got %d", got)
+	}
+
+	inserted := &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleSystem, Content: "inserted"}
+	session.InsertBefore(second, inserted)
+
+	if len(session.Messages) != 3 || session.Messages[1] != inserted {
+		t.Fatalf("expected the message to be inserted before the target, got %v", session.Messages)
+	}
+	if vendorMessages := session.GetVendorMessages(); len(vendorMessages) != 3 || vendorMessages[1] != inserted {
+		t.Errorf("expected the vendor messages to include the inserted message, got %v", vendorMessages)
+	}
+}
+
+func TestSession_Truncate(t *testing.T) {
+	first := &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "first"}
+	second := &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: "second"}
+	session := &Session{Messages: []*chat.ChatCompletionMessage{first, second}}
+	if got := len(session.GetVendorMessages()); got != 2 {
+		t.Fatalf("expected 2 vendor messages, got %d", got)
+	}
+
+	session.Truncate(1)
+	if len(session.Messages) != 1 || session.Messages[0] != first {
+		t.Fatalf("expected only the first message to be kept, got %v", session.Messages)
+	}
+	if got := len(session.GetVendorMessages()); got != 1 {
+		t.Errorf("expected 1 vendor message after truncating, got %d", got)
+	}
+
+	session.Truncate(5)
+	if len(session.Messages) != 1 {
+		t.Errorf("expected truncating to a larger length to keep the messages, got %d", len(session.Messages))
+	}
+}
+
+func newTestSessions(t *testing.T) *SessionsEntity {
+	return &SessionsEntity{StorageEntity: &StorageEntity{Label: "Sessions", Dir: t.TempDir(), FileExtension: ".json"}}
+}
+
+func TestSessions_LoadsVersion1Files(t *testing.T) {
+	sessions := newTestSessions(t)
+	legacy := `[{"role":"user","content":"question"},{"role":"assistant","content":"answer"}]`
+	if err := os.WriteFile(filepath.Join(sessions.Dir, "legacy.json"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write session: %v", err)
+	}
+
+	session, err := sessions.Get("legacy")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(session.Messages) != 2 || session.Messages[1].Content != "answer" {
+		t.Errorf("unexpected messages %v", session.Messages)
+	}
+	if session.CreatedAt.IsZero() || session.UpdatedAt.IsZero() {
+		t.Error("expected the times of the file for a version 1 session")
+	}
+}
+
+func TestSessions_SaveAndLoadMetadata(t *testing.T) {
+	sessions := newTestSessions(t)
+	session := &Session{Name: "research"}
+	session.AddTags("work", " work ", "ai")
+	session.Append(
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleUser, Content: "question"},
+		&chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: "answer", Meta: &chat.MessageMeta{
+			Vendor: "OpenAI", Model: "gpt-4o", Pattern: "summarize", Usage: &chat.Usage{TotalTokens: 42}}},
+	)
+	if err := sessions.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+
+	content, _ := os.ReadFile(sessions.BuildFilePathByName("research"))
+	if !strings.Contains(string(content), `"version":2`) {
+		t.Errorf("expected a versioned session file, got %s", content)
+	}
+
+	loaded, err := sessions.Get("research")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if strings.Join(loaded.Tags, ",") != "work,ai" {
+		t.Errorf("unexpected tags %v", loaded.Tags)
+	}
+	if !loaded.CreatedAt.Equal(session.CreatedAt) || loaded.CreatedAt.IsZero() {
+		t.Errorf("expected the creation time to be kept, got %v", loaded.CreatedAt)
+	}
+	for _, message := range loaded.Messages {
+		if message.Meta == nil || message.Meta.Timestamp.IsZero() {
+			t.Errorf("expected a timestamp on the %s message", message.Role)
+		}
+	}
+	if meta := loaded.Messages[1].Meta; meta.Model != "gpt-4o" || meta.Pattern != "summarize" {
+		t.Errorf("unexpected meta %+v", meta)
+	}
+}
+
+func TestSessions_RejectsNewerVersions(t *testing.T) {
+	sessions := newTestSessions(t)
+	if err := os.WriteFile(filepath.Join(sessions.Dir, "future.json"), []byte(`{"version": 99, "messages": []}`), 0644); err != nil {
+		t.Fatalf("failed to write session: %v", err)
+	}
+	if _, err := sessions.Get("future"); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected an error for a newer version, got %v", err)
+	}
+}
+
+func TestSessions_GetInfos(t *testing.T) {
+	sessions := newTestSessions(t)
+	answer := func(model string, tokens int) *chat.ChatCompletionMessage {
+		return &chat.ChatCompletionMessage{Role: chat.ChatMessageRoleAssistant, Content: "answer",
+			Meta: &chat.MessageMeta{Vendor: "OpenAI", Model: model, Usage: &chat.Usage{TotalTokens: tokens}}}
+	}
+
+	old := &Session{Name: "old", CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local), Tags: []string{"work"}}
+	old.Append(answer("gpt-4o", 500))
+	recent := &Session{Name: "recent"}
+	recent.Append(answer("gpt-4o-mini", 10), answer("gpt-4o", 20))
+	for _, session := range []*Session{old, recent} {
+		if err := sessions.SaveSession(session); err != nil {
+			t.Fatalf("SaveSession() error = %v", err)
+		}
+	}
+
+	names := func(filterExpressions []string, sortBy string) string {
+		t.Helper()
+		filter, err := ParseSessionFilter(filterExpressions)
+		if err != nil {
+			t.Fatalf("ParseSessionFilter() error = %v", err)
+		}
+		infos, err := sessions.GetInfos(filter, sortBy)
+		if err != nil {
+			t.Fatalf("GetInfos() error = %v", err)
+		}
+		var ret []string
+		for _, info := range infos {
+			ret = append(ret, info.Name)
+		}
+		return strings.Join(ret, ",")
+	}
+
+	if got := names(nil, ""); got != "old,recent" {
+		t.Errorf("expected the sessions sorted by name, got %s", got)
+	}
+	if got := names(nil, "created"); got != "recent,old" {
+		t.Errorf("expected the newest session first, got %s", got)
+	}
+	if got := names(nil, "tokens"); got != "old,recent" {
+		t.Errorf("expected the session with the most tokens first, got %s", got)
+	}
+	if got := names([]string{"model=GPT-4O-MINI"}, ""); got != "recent" {
+		t.Errorf("expected the session with the model, got %s", got)
+	}
+	if got := names([]string{"tag=work", "model=gpt-4o"}, ""); got != "old" {
+		t.Errorf("expected the session with the tag and the model, got %s", got)
+	}
+	if got := names([]string{"until=2024-05-01"}, ""); got != "old" {
+		t.Errorf("expected the session created on that day, got %s", got)
+	}
+
+	if _, err := ParseSessionFilter([]string{"color=blue"}); err == nil {
+		t.Error("expected an error for an unknown filter field")
+	}
+	if _, err := sessions.GetInfos(nil, "color"); err == nil {
+		t.Error("expected an error for an unknown sort field")
+	}
+}
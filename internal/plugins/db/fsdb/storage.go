@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/danielmiessler/fabric/internal/plugins/db"
 	"github.com/danielmiessler/fabric/internal/util"
 )
 
@@ -15,6 +17,12 @@ type StorageEntity struct {
 	Dir           string
 	ItemIsDir     bool
 	FileExtension string
+
+	// Items stores the items instead of the files in Dir when set, like the tables of sqlitedb
+	Items db.Items
+
+	// onChange is called with the name of every item that is saved, deleted or renamed
+	onChange func(name string)
 }
 
 func (o *StorageEntity) Configure() (err error) {
@@ -26,6 +34,10 @@ func (o *StorageEntity) Configure() (err error) {
 
 // GetNames finds all patterns in the patterns directory and enters the id, name, and pattern into a slice of Entry structs. it returns these entries or an error
 func (o *StorageEntity) GetNames() (ret []string, err error) {
+	if o.Items != nil {
+		return o.Items.GetNames()
+	}
+
 	// Resolve the directory path to an absolute path
 	absDir, err := util.GetAbsolutePath(o.Dir)
 	if err != nil {
@@ -67,39 +79,90 @@ func (o *StorageEntity) GetNames() (ret []string, err error) {
 }
 
 func (o *StorageEntity) Delete(name string) (err error) {
-	if err = os.RemoveAll(o.BuildFilePathByName(name)); err != nil {
+	if o.Items != nil {
+		err = o.Items.Delete(name)
+	} else if err = os.RemoveAll(o.BuildFilePathByName(name)); err != nil {
 		err = fmt.Errorf("could not delete %s: %v", name, err)
 	}
+	o.changed(err, name)
 	return
 }
 
 func (o *StorageEntity) Exists(name string) (ret bool) {
+	if o.Items != nil {
+		return o.Items.Exists(name)
+	}
 	_, err := os.Stat(o.BuildFilePathByName(name))
 	ret = !os.IsNotExist(err)
 	return
 }
 
 func (o *StorageEntity) Rename(oldName, newName string) (err error) {
-	if err = os.Rename(o.BuildFilePathByName(oldName), o.BuildFilePathByName(newName)); err != nil {
+	if o.Items != nil {
+		err = o.Items.Rename(oldName, newName)
+	} else if err = os.Rename(o.BuildFilePathByName(oldName), o.BuildFilePathByName(newName)); err != nil {
 		err = fmt.Errorf("could not rename %s to %s: %v", oldName, newName, err)
 	}
+	o.changed(err, oldName, newName)
 	return
 }
 
 func (o *StorageEntity) Save(name string, content []byte) (err error) {
-	if err = os.WriteFile(o.BuildFilePathByName(name), content, 0644); err != nil {
+	if o.Items != nil {
+		err = o.Items.Save(name, content)
+	} else if err = os.WriteFile(o.BuildFilePathByName(name), content, 0644); err != nil {
 		err = fmt.Errorf("could not save %s: %v", name, err)
 	}
+	o.changed(err, name)
 	return
 }
 
+// changed tells onChange about the items that were written without an error
+func (o *StorageEntity) changed(err error, names ...string) {
+	if err != nil || o.onChange == nil {
+		return
+	}
+	for _, name := range names {
+		o.onChange(name)
+	}
+}
+
 func (o *StorageEntity) Load(name string) (ret []byte, err error) {
+	if o.Items != nil {
+		return o.Items.Load(name)
+	}
 	if ret, err = os.ReadFile(o.BuildFilePathByName(name)); err != nil {
 		err = fmt.Errorf("could not load %s: %v", name, err)
 	}
 	return
 }
 
+// Size returns the size of an item in bytes, or 0 if it is not known
+func (o *StorageEntity) Size(name string) (ret int64) {
+	if sizer, ok := o.Items.(interface{ Size(string) (int64, error) }); ok {
+		ret, _ = sizer.Size(name)
+	} else if o.Items == nil {
+		if fileInfo, err := os.Stat(o.BuildFilePathByName(name)); err == nil {
+			ret = fileInfo.Size()
+		}
+	}
+	return
+}
+
+// modTime returns when an item was last changed, or the zero time if it is not known
+func (o *StorageEntity) modTime(name string) (ret time.Time) {
+	if timer, ok := o.Items.(interface {
+		ModTime(string) (time.Time, error)
+	}); ok {
+		ret, _ = timer.ModTime(name)
+	} else if o.Items == nil {
+		if fileInfo, err := os.Stat(o.BuildFilePathByName(name)); err == nil {
+			ret = fileInfo.ModTime()
+		}
+	}
+	return
+}
+
 func (o *StorageEntity) ListNames(shellCompleteList bool) (err error) {
 	var names []string
 	if names, err = o.GetNames(); err != nil {
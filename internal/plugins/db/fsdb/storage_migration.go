@@ -0,0 +1,170 @@
+package fsdb
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/danielmiessler/fabric/internal/plugins/db/sqlitedb"
+)
+
+// The storages of patterns, sessions and contexts, chosen with the Storage plugin in the setup
+const (
+	StorageFiles  = "files"
+	StorageSQLite = "sqlite"
+
+	StorageBackendEnv    = "STORAGE_BACKEND"
+	StorageSQLitePathEnv = "STORAGE_SQLITE_PATH"
+)
+
+// StorageMigration counts the items that MigrateStorage copied
+type StorageMigration struct {
+	Patterns int
+	Contexts int
+	Sessions int
+}
+
+// MigrateStorage copies the patterns, contexts and sessions from the files in Dir to the SQLite
+// database, or back with to set to files, the patterns with their versions. Items of the same
+// name are replaced and nothing is deleted. Custom patterns stay in their directory.
+func (o *Db) MigrateStorage(to string) (ret *StorageMigration, err error) {
+	var sqlite *sqlitedb.Db
+	if sqlite, err = sqlitedb.Open(o.SQLitePath()); err != nil {
+		return
+	}
+	defer sqlite.Close()
+
+	files := NewDb(o.Dir)
+	database := NewDb(o.Dir)
+	database.useSQLite(sqlite)
+
+	var from, target *Db
+	switch to {
+	case StorageSQLite:
+		from, target = files, database
+	case StorageFiles:
+		from, target = database, files
+	default:
+		err = fmt.Errorf("unknown storage %q, use %s or %s", to, StorageFiles, StorageSQLite)
+		return
+	}
+	for _, entity := range []*StorageEntity{files.Patterns.StorageEntity, files.Patterns.History,
+		files.Sessions.StorageEntity, files.Contexts.StorageEntity} {
+		if err = entity.Configure(); err != nil {
+			return
+		}
+	}
+
+	ret = &StorageMigration{}
+	if ret.Patterns, err = copyPatterns(from.Patterns, target.Patterns, nil); err != nil {
+		return
+	}
+	if _, err = copyItems(from.Patterns.History, target.Patterns.History); err != nil {
+		return
+	}
+	if ret.Contexts, err = copyItems(from.Contexts.StorageEntity, target.Contexts.StorageEntity); err != nil {
+		return
+	}
+	ret.Sessions, err = copySessions(from.Sessions, target.Sessions)
+	return
+}
+
+// copyPatterns copies every pattern with all its files, except those that skip is true for
+func copyPatterns(from *PatternsEntity, to *PatternsEntity, skip func(name string) bool) (ret int, err error) {
+	var names []string
+	if names, err = from.StorageEntity.GetNames(); err != nil {
+		return
+	}
+	for _, name := range names {
+		if skip != nil && skip(name) {
+			continue
+		}
+		var files []string
+		if files, err = from.getFileNames(name); err != nil {
+			return
+		}
+		for _, file := range files {
+			var content []byte
+			if content, err = from.loadFile(name, file); err != nil {
+				return
+			}
+			if err = to.saveFile(name, file, content); err != nil {
+				err = fmt.Errorf("could not copy pattern %s: %v", name, err)
+				return
+			}
+		}
+		ret++
+	}
+	return
+}
+
+func copyItems(from *StorageEntity, to *StorageEntity) (ret int, err error) {
+	var names []string
+	if names, err = from.GetNames(); err != nil {
+		return
+	}
+	for _, name := range names {
+		if err = to.checkName(name); err != nil {
+			return
+		}
+		var content []byte
+		if content, err = from.Load(name); err != nil {
+			return
+		}
+		if err = to.Save(name, content); err != nil {
+			return
+		}
+		ret++
+	}
+	return
+}
+
+// copySessions converts the sessions to the current format on the way, sessions of version 1
+// keep the modification time of their file as their creation and update time
+func copySessions(from *SessionsEntity, to *SessionsEntity) (ret int, err error) {
+	var names []string
+	if names, err = from.GetNames(); err != nil {
+		return
+	}
+	for _, name := range names {
+		if err = to.checkName(name); err != nil {
+			return
+		}
+		var session *Session
+		if session, err = from.load(name); err != nil {
+			return
+		}
+		if err = to.write(session); err != nil {
+			return
+		}
+		ret++
+	}
+	return
+}
+
+// checkName makes sure that a name from another storage does not point outside Dir when
+// it becomes a file name
+func (o *StorageEntity) checkName(name string) (err error) {
+	if o.Items == nil && !isFileName(name) {
+		err = fmt.Errorf("%s cannot be saved as a file of %s", name, o.Label)
+	}
+	return
+}
+
+func isFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && name == filepath.Base(name)
+}
+
+// StorePatternsDir copies the patterns directory into the item storage, so that downloaded
+// patterns reach the SQLite database. Pinned patterns keep their content in the item storage.
+// Without an item storage there is nothing to do.
+func (o *PatternsEntity) StorePatternsDir() (ret int, err error) {
+	if o.Items == nil {
+		return
+	}
+	files := &PatternsEntity{
+		StorageEntity:     &StorageEntity{Label: o.Label, Dir: o.Dir, ItemIsDir: true},
+		SystemPatternFile: o.SystemPatternFile,
+	}
+	ret, err = copyPatterns(files, o, o.IsPinned)
+	return
+}
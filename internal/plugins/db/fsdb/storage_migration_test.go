@@ -0,0 +1,115 @@
+package fsdb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestDb_MigrateStorage(t *testing.T) {
+	t.Setenv(StorageSQLitePathEnv, "")
+	dir := t.TempDir()
+	db := NewDb(dir)
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "summarize", "system.md"), "Summarize {{input}}")
+	writeTestFile(t, filepath.Join(db.Patterns.Dir, "summarize", PatternSchemaFile), `{"type":"object"}`)
+	writeTestFile(t, filepath.Join(db.Contexts.Dir, "work"), "I am a developer")
+	writeTestFile(t, filepath.Join(db.Sessions.Dir, "legacy.json"), `[{"role":"user","content":"question"}]`)
+	modified := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(db.Sessions.Dir, "legacy.json"), modified, modified); err != nil {
+		t.Fatalf("failed to set the modification time: %v", err)
+	}
+
+	migration, err := db.MigrateStorage(StorageSQLite)
+	if err != nil {
+		t.Fatalf("MigrateStorage() error = %v", err)
+	}
+	if *migration != (StorageMigration{Patterns: 1, Contexts: 1, Sessions: 1}) {
+		t.Errorf("unexpected migration %+v", migration)
+	}
+
+	t.Setenv(StorageBackendEnv, StorageSQLite)
+	sqliteDb := NewDb(dir)
+	if err = sqliteDb.configureStorage(); err != nil {
+		t.Fatalf("configureStorage() error = %v", err)
+	}
+	defer sqliteDb.Close()
+	// The files are not needed anymore
+	if err = os.RemoveAll(db.Patterns.Dir); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern, err := sqliteDb.Patterns.GetApplyVariables("summarize", nil, "text")
+	if err != nil || pattern.Pattern != "Summarize text" {
+		t.Errorf("expected the pattern from the database, got %+v, %v", pattern, err)
+	}
+	if schema, _ := sqliteDb.Patterns.GetSchema("summarize"); string(schema) != `{"type":"object"}` {
+		t.Errorf("expected the schema of the pattern, got %s", schema)
+	}
+	if context, _ := sqliteDb.Contexts.Get("work"); context == nil || context.Content != "I am a developer" {
+		t.Errorf("unexpected context %+v", context)
+	}
+	session, err := sqliteDb.Sessions.Get("legacy")
+	if err != nil || len(session.Messages) != 1 || !session.UpdatedAt.Equal(modified) {
+		t.Errorf("expected the session with the time of its file, got %+v, %v", session, err)
+	}
+	infos, err := sqliteDb.Sessions.GetInfos(nil, "name")
+	if err != nil || len(infos) != 1 || infos[0].Size == 0 {
+		t.Errorf("expected the session to be listed with its size, got %+v, %v", infos, err)
+	}
+
+	if err = sqliteDb.Patterns.Save("new", []byte("New pattern")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if migration, err = db.MigrateStorage(StorageFiles); err != nil {
+		t.Fatalf("MigrateStorage() error = %v", err)
+	}
+	if migration.Patterns != 2 {
+		t.Errorf("expected both patterns to be copied back, got %+v", migration)
+	}
+	if content, _ := os.ReadFile(filepath.Join(db.Patterns.Dir, "summarize", PatternSchemaFile)); string(content) != `{"type":"object"}` {
+		t.Error("expected the files of the patterns to be written back")
+	}
+	if names, _ := db.Patterns.GetNames(); len(names) != 2 {
+		t.Errorf("expected the patterns in the directory, got %v", names)
+	}
+	if history, _ := db.Patterns.GetHistory("new"); len(history.Versions) != 1 {
+		t.Errorf("expected the versions of the pattern to be copied back, got %+v", history)
+	}
+
+	if _, err = db.MigrateStorage("postgres"); err == nil {
+		t.Error("expected an error for an unknown storage")
+	}
+}
+
+func TestDb_MigrateStorageRejectsUnsafeNames(t *testing.T) {
+	t.Setenv(StorageSQLitePathEnv, "")
+	dir := t.TempDir()
+	db := NewDb(dir)
+	sqliteDb := NewDb(dir)
+	t.Setenv(StorageBackendEnv, StorageSQLite)
+	if err := sqliteDb.configureStorage(); err != nil {
+		t.Fatalf("configureStorage() error = %v", err)
+	}
+	defer sqliteDb.Close()
+	if err := sqliteDb.Contexts.Save("../outside", []byte("content")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if _, err := db.MigrateStorage(StorageFiles); err == nil {
+		t.Error("expected an error for a name that is not a file name")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Error("expected nothing to be written outside the contexts directory")
+	}
+}
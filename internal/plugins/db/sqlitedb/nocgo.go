@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package sqlitedb
+
+// cgoEnabled tells whether fabric was built with cgo, which the SQLite driver needs
+const cgoEnabled = false
@@ -0,0 +1,105 @@
+package sqlitedb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrNoCgo is returned by Open when fabric was built without cgo
+var ErrNoCgo = errors.New("this fabric binary was built without cgo (CGO_ENABLED=0), which the SQLite storage needs; build it with CGO_ENABLED=1")
+
+// FileName is the name of the database in the fabric directory, unless another path is configured
+const FileName = "fabric.db"
+
+// migrations create and update the tables, the version of a database is the number of
+// migrations applied to it. New migrations are only ever appended.
+var migrations = []string{
+	`CREATE TABLE items (
+		kind TEXT NOT NULL,
+		name TEXT NOT NULL,
+		file TEXT NOT NULL DEFAULT '',
+		content BLOB NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (kind, name, file)
+	)`,
+}
+
+// Db is a SQLite database that stores the items of fabric, like patterns, contexts and sessions.
+// It is safe to use from several processes at once, like the REST server and the CLI.
+type Db struct {
+	Path string
+	db   *sql.DB
+}
+
+// Open opens the database at path, creating it and its directory if needed, and brings its
+// tables up to date
+func Open(path string) (ret *Db, err error) {
+	if !cgoEnabled {
+		err = ErrNoCgo
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return
+	}
+	// Writers wait for each other instead of failing, and transactions take the write lock
+	// when they start so that they cannot deadlock
+	dsn := "file:" + path + "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	var db *sql.DB
+	if db, err = sql.Open("sqlite3", dsn); err != nil {
+		err = fmt.Errorf("could not open SQLite database %s: %w", path, err)
+		return
+	}
+
+	ret = &Db{Path: path, db: db}
+	if err = ret.migrate(); err != nil {
+		db.Close()
+		ret = nil
+		err = fmt.Errorf("could not open SQLite database %s: %w", path, err)
+	}
+	return
+}
+
+func (o *Db) Close() error {
+	return o.db.Close()
+}
+
+// Table returns the items of a kind, whose content is saved as file
+func (o *Db) Table(kind string, file string) *Table {
+	return &Table{db: o.db, Kind: kind, File: file}
+}
+
+func (o *Db) migrate() (err error) {
+	var tx *sql.Tx
+	if tx, err = o.db.Begin(); err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	var version int
+	if err = tx.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("the database version %d is newer than the supported version %d, please update fabric",
+			version, len(migrations))
+	}
+	if version == len(migrations) {
+		return
+	}
+
+	for _, migration := range migrations[version:] {
+		if _, err = tx.Exec(migration); err != nil {
+			return
+		}
+	}
+	if _, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", len(migrations))); err != nil {
+		return
+	}
+	err = tx.Commit()
+	return
+}
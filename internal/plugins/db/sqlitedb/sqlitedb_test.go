@@ -0,0 +1,135 @@
+package sqlitedb
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func openTestDb(t *testing.T) *Db {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "data", FileName))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestTable_Items(t *testing.T) {
+	db := openTestDb(t)
+	sessions := db.Table("sessions", "")
+	contexts := db.Table("contexts", "")
+
+	if err := sessions.Save("b", []byte("second")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := sessions.Save("a", []byte("first")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := sessions.Save("a", []byte("replaced")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	names, err := sessions.GetNames()
+	if err != nil || len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("GetNames() = %v, %v", names, err)
+	}
+	if content, _ := sessions.Load("a"); string(content) != "replaced" {
+		t.Errorf("Load() = %q, expected the saved content to be replaced", content)
+	}
+	if size, _ := sessions.Size("a"); size != int64(len("replaced")) {
+		t.Errorf("Size() = %d", size)
+	}
+	if contexts.Exists("a") {
+		t.Error("expected the kinds of items to be separate")
+	}
+
+	if err = sessions.Rename("a", "b"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if sessions.Exists("a") || !sessions.Exists("b") {
+		t.Error("expected the item to have the new name")
+	}
+	if content, _ := sessions.Load("b"); string(content) != "replaced" {
+		t.Errorf("expected the renamed item to replace the other, got %q", content)
+	}
+	if err = sessions.Rename("missing", "c"); err == nil {
+		t.Error("expected an error when renaming a missing item")
+	}
+
+	if err = sessions.Delete("b"); err != nil || sessions.Exists("b") {
+		t.Errorf("expected the item to be deleted, got %v", err)
+	}
+	if _, err = sessions.Load("b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing item, got %v", err)
+	}
+}
+
+func TestTable_Files(t *testing.T) {
+	db := openTestDb(t)
+	patterns := db.Table("patterns", "system.md")
+
+	if err := patterns.SaveFile("summarize", "schema.json", []byte("{}")); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	if patterns.Exists("summarize") {
+		t.Error("expected a pattern to exist only with its system.md")
+	}
+	if err := patterns.Save("summarize", []byte("# IDENTITY")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	files, err := patterns.GetFileNames("summarize")
+	if err != nil || len(files) != 2 || files[0] != "schema.json" || files[1] != "system.md" {
+		t.Errorf("GetFileNames() = %v, %v", files, err)
+	}
+	if err = patterns.Rename("summarize", "summary"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if schema, _ := patterns.LoadFile("summary", "schema.json"); string(schema) != "{}" {
+		t.Error("expected all files of the pattern to be renamed")
+	}
+	if err = patterns.Delete("summary"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if files, _ = patterns.GetFileNames("summary"); len(files) != 0 {
+		t.Errorf("expected all files of the pattern to be deleted, got %v", files)
+	}
+}
+
+func TestOpen_ReopensAndSharesTheDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	first, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer first.Close()
+	second, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer second.Close()
+
+	// Two connections write at the same time, like the REST server and the CLI
+	var wg sync.WaitGroup
+	for i, db := range []*Db{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := db.Table("sessions", "").Save(string(rune('a'+i)), []byte{byte(j)}); err != nil {
+					t.Errorf("Save() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if names, _ := first.Table("sessions", "").GetNames(); len(names) != 2 {
+		t.Errorf("expected the items of both connections, got %v", names)
+	}
+}
@@ -0,0 +1,148 @@
+package sqlitedb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// Table stores the items of one kind, like the sessions, and implements db.Items. An item can
+// have several files, like a pattern with its system.md and schema.json. Save and Load use
+// the file of the table, LoadFile and SaveFile any file of an item.
+type Table struct {
+	db   *sql.DB
+	Kind string
+	File string
+}
+
+func (o *Table) GetNames() (ret []string, err error) {
+	var rows *sql.Rows
+	if rows, err = o.db.Query("SELECT name FROM items WHERE kind = ? AND file = ? ORDER BY name",
+		o.Kind, o.File); err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", o.Kind, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return
+		}
+		ret = append(ret, name)
+	}
+	err = rows.Err()
+	return
+}
+
+// Delete removes an item with all its files
+func (o *Table) Delete(name string) (err error) {
+	if _, err = o.db.Exec("DELETE FROM items WHERE kind = ? AND name = ?", o.Kind, name); err != nil {
+		err = fmt.Errorf("could not delete %s: %v", name, err)
+	}
+	return
+}
+
+func (o *Table) Exists(name string) (ret bool) {
+	err := o.db.QueryRow("SELECT 1 FROM items WHERE kind = ? AND name = ? AND file = ?",
+		o.Kind, name, o.File).Scan(new(int))
+	ret = err == nil
+	return
+}
+
+// Rename renames an item with all its files, replacing an item that has the new name
+func (o *Table) Rename(oldName, newName string) (err error) {
+	if err = o.rename(oldName, newName); err != nil {
+		err = fmt.Errorf("could not rename %s to %s: %v", oldName, newName, err)
+	}
+	return
+}
+
+func (o *Table) rename(oldName, newName string) (err error) {
+	var tx *sql.Tx
+	if tx, err = o.db.Begin(); err != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	var result sql.Result
+	if _, err = tx.Exec("DELETE FROM items WHERE kind = ? AND name = ?", o.Kind, newName); err != nil {
+		return
+	}
+	if result, err = tx.Exec("UPDATE items SET name = ? WHERE kind = ? AND name = ?", newName, o.Kind, oldName); err != nil {
+		return
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return fmt.Errorf("%s does not exist", oldName)
+	}
+	err = tx.Commit()
+	return
+}
+
+func (o *Table) Save(name string, content []byte) (err error) {
+	return o.SaveFile(name, o.File, content)
+}
+
+func (o *Table) Load(name string) (ret []byte, err error) {
+	return o.LoadFile(name, o.File)
+}
+
+// SaveFile saves a file of an item, creating or replacing it
+func (o *Table) SaveFile(name string, file string, content []byte) (err error) {
+	if content == nil {
+		content = []byte{}
+	}
+	if _, err = o.db.Exec(`INSERT INTO items (kind, name, file, content, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (kind, name, file) DO UPDATE SET content = excluded.content, updated_at = excluded.updated_at`,
+		o.Kind, name, file, content, time.Now().UTC()); err != nil {
+		err = fmt.Errorf("could not save %s: %v", name, err)
+	}
+	return
+}
+
+// LoadFile loads a file of an item. The error wraps fs.ErrNotExist if there is no such file.
+func (o *Table) LoadFile(name string, file string) (ret []byte, err error) {
+	err = o.db.QueryRow("SELECT content FROM items WHERE kind = ? AND name = ? AND file = ?",
+		o.Kind, name, file).Scan(&ret)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("could not load %s: %w", name, fs.ErrNotExist)
+	} else if err != nil {
+		err = fmt.Errorf("could not load %s: %v", name, err)
+	}
+	return
+}
+
+// GetFileNames returns the files of an item
+func (o *Table) GetFileNames(name string) (ret []string, err error) {
+	var rows *sql.Rows
+	if rows, err = o.db.Query("SELECT file FROM items WHERE kind = ? AND name = ? ORDER BY file",
+		o.Kind, name); err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var file string
+		if err = rows.Scan(&file); err != nil {
+			return
+		}
+		ret = append(ret, file)
+	}
+	err = rows.Err()
+	return
+}
+
+// Size returns the size of the content of an item in bytes
+func (o *Table) Size(name string) (ret int64, err error) {
+	err = o.db.QueryRow("SELECT length(content) FROM items WHERE kind = ? AND name = ? AND file = ?",
+		o.Kind, name, o.File).Scan(&ret)
+	return
+}
+
+// ModTime returns when the content of an item was last saved
+func (o *Table) ModTime(name string) (ret time.Time, err error) {
+	err = o.db.QueryRow("SELECT updated_at FROM items WHERE kind = ? AND name = ? AND file = ?",
+		o.Kind, name, o.File).Scan(&ret)
+	return
+}
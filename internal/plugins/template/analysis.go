@@ -0,0 +1,166 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tokenPattern matches the innermost {{...}} tokens of a template
+var tokenPattern = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// analysisMarker stands for a reference that Analyze has seen, with the index of its text
+var analysisMarker = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// Call is a {{plugin:namespace:operation:value}} or {{ext:name:operation:value}} reference
+type Call struct {
+	// Name is the namespace of a plugin or the name of an extension
+	Name      string `json:"name"`
+	Operation string `json:"operation"`
+	Value     string `json:"value,omitempty"`
+}
+
+// Analysis lists what a template refers to, found without applying it
+type Analysis struct {
+	// Variables are the names of the variables, in the order they first appear
+	Variables []string `json:"variables"`
+	// Optional are the variables that are only used in conditions, loops, their branches or
+	// with a default, so that the template can be applied without them
+	Optional   []string `json:"optional,omitempty"`
+	UsesInput  bool     `json:"usesInput"`
+	Plugins    []*Call  `json:"plugins,omitempty"`
+	Extensions []*Call  `json:"extensions,omitempty"`
+}
+
+// Analyze finds the variables, plugin calls and extension calls of a template. References
+// inside other references are found as well, like {{name}} in {{plugin:text:upper:{{name}}}}.
+// A variable whose name is built from other variables is only known once they are set, it
+// is left out.
+func Analyze(content string) (ret *Analysis) {
+	ret = &Analysis{Variables: []string{}}
+	analyzer := &analyzer{analysis: ret, seen: map[string]bool{}, required: map[string]bool{}}
+
+	nodes, err := parseBlocks(content)
+	if err != nil {
+		// ApplyTemplate reports the broken block, its references are still listed
+		nodes = []*templateNode{{text: content}}
+	}
+	analyzer.nodes(nodes, false, false)
+
+	for _, name := range ret.Variables {
+		if !analyzer.required[name] {
+			ret.Optional = append(ret.Optional, name)
+		}
+	}
+	return
+}
+
+type analyzer struct {
+	analysis *Analysis
+	seen     map[string]bool
+	required map[string]bool
+}
+
+func (o *analyzer) addVariable(name string, required bool) {
+	if !o.seen[name] {
+		o.seen[name] = true
+		o.analysis.Variables = append(o.analysis.Variables, name)
+	}
+	if required {
+		o.required[name] = true
+	}
+}
+
+// nodes analyzes the texts and blocks of a template. The variables of blocks and their branches
+// are optional, inLoop tells whether {{this}} and {{@index}} are the item of a loop.
+func (o *analyzer) nodes(nodes []*templateNode, optional bool, inLoop bool) {
+	for _, node := range nodes {
+		if node.kind == "" {
+			o.text(node.text, optional, inLoop)
+			continue
+		}
+		if name, _ := parseExpression(node.expression); !(inLoop && isItemVariable(name)) {
+			o.addVariable(name, false)
+		}
+		o.nodes(node.body, true, inLoop || node.kind == blockEach)
+		o.nodes(node.alt, true, inLoop)
+	}
+}
+
+func (o *analyzer) text(content string, optional bool, inLoop bool) {
+	// Inner references are replaced by markers, so that the references around them are found
+	// in the next pass. The markers hold the index of the original text.
+	var originals []string
+	expand := func(text string) string {
+		return analysisMarker.ReplaceAllStringFunc(text, func(found string) string {
+			index, _ := strconv.Atoi(strings.Trim(found, "\x00"))
+			return originals[index]
+		})
+	}
+
+	for {
+		matches := tokenPattern.FindAllStringSubmatch(content, -1)
+		if len(matches) == 0 {
+			return
+		}
+		for _, match := range matches {
+			if call, kind := parseCall(match[1], expand); call != nil {
+				if kind == "plugin" {
+					o.analysis.Plugins = append(o.analysis.Plugins, call)
+				} else {
+					o.analysis.Extensions = append(o.analysis.Extensions, call)
+				}
+			} else if name, filters := parseExpression(match[1]); name == "input" {
+				o.analysis.UsesInput = true
+			} else if !analysisMarker.MatchString(name) && !(inLoop && isItemVariable(name)) {
+				o.addVariable(name, !optional && !hasDefault(filters))
+			}
+			content = strings.Replace(content, match[0], fmt.Sprintf("\x00%d\x00", len(originals)), 1)
+			originals = append(originals, expand(match[0]))
+		}
+	}
+}
+
+func isItemVariable(name string) bool {
+	return name == itemVariable || name == indexVariable
+}
+
+// parseCall parses a plugin or extension reference the way ApplyTemplate recognizes them
+func parseCall(name string, expand func(string) string) (ret *Call, kind string) {
+	parts := strings.SplitN(name, ":", 4)
+	if len(parts) < 3 || (parts[0] != "plugin" && parts[0] != "ext") || parts[1] == "" || parts[2] == "" {
+		return
+	}
+	ret = &Call{Name: expand(parts[1]), Operation: expand(parts[2])}
+	if len(parts) == 4 {
+		ret.Value = expand(parts[3])
+	}
+	kind = parts[0]
+	return
+}
+
+// Missing returns the required variables that have no value, sorted by name
+func (o *Analysis) Missing(variables map[string]string) (ret []string) {
+	for _, name := range o.Variables {
+		if _, ok := variables[name]; !ok && !slices.Contains(o.Optional, name) {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return
+}
+
+// MissingVariablesError reports all variables of a template without a value at once
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (o *MissingVariablesError) Error() string {
+	if len(o.Names) == 1 {
+		return fmt.Sprintf("missing required variable: %s", o.Names[0])
+	}
+	return fmt.Sprintf("missing required variables: %s", strings.Join(o.Names, ", "))
+}
@@ -0,0 +1,52 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	analysis := Analyze("Hello {{name}}, {{role}} and {{name}} again.\n" +
+		"{{plugin:datetime:now}} {{plugin:text:upper:{{name}}}} {{ext:word-generator:generate:3}}\n" +
+		"{{{{inner}}}} {{plugin:broken}}\n{{input}}")
+
+	if want := []string{"name", "role", "inner", "plugin:broken"}; !reflect.DeepEqual(analysis.Variables, want) {
+		t.Errorf("expected the variables %v, got %v", want, analysis.Variables)
+	}
+	if !analysis.UsesInput {
+		t.Error("expected the input to be found")
+	}
+	wantPlugins := []*Call{{Name: "datetime", Operation: "now"}, {Name: "text", Operation: "upper", Value: "{{name}}"}}
+	if !reflect.DeepEqual(analysis.Plugins, wantPlugins) {
+		t.Errorf("expected the plugins %+v, got %+v", wantPlugins, analysis.Plugins)
+	}
+	wantExtensions := []*Call{{Name: "word-generator", Operation: "generate", Value: "3"}}
+	if !reflect.DeepEqual(analysis.Extensions, wantExtensions) {
+		t.Errorf("expected the extensions %+v, got %+v", wantExtensions, analysis.Extensions)
+	}
+}
+
+func TestAnalysis_Missing(t *testing.T) {
+	analysis := Analyze("{{role}} {{name}} {{input}} {{topic}}")
+	if got := analysis.Missing(map[string]string{"name": "World"}); !reflect.DeepEqual(got, []string{"role", "topic"}) {
+		t.Errorf("expected the sorted missing variables, got %v", got)
+	}
+	if got := analysis.Missing(map[string]string{"name": "", "role": "", "topic": ""}); len(got) != 0 {
+		t.Errorf("expected empty values to count as set, got %v", got)
+	}
+}
+
+func TestApplyTemplate_ReportsAllMissingVariables(t *testing.T) {
+	_, err := ApplyTemplate("{{b}} {{plugin:text:upper:{{a}}}} {{c}}", map[string]string{"c": "set"}, "")
+
+	missingErr, ok := err.(*MissingVariablesError)
+	if !ok {
+		t.Fatalf("expected a MissingVariablesError, got %v", err)
+	}
+	if !reflect.DeepEqual(missingErr.Names, []string{"a", "b"}) {
+		t.Errorf("expected all missing variables, got %v", missingErr.Names)
+	}
+	if err.Error() != "missing required variables: a, b" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
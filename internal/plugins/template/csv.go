@@ -0,0 +1,84 @@
+// Package template provides CSV rendering for the template system.
+package template
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// CSVPlugin renders CSV as Markdown. Its values are the CSV itself, a file:PATH or a
+// var:NAME, see dataSource.
+type CSVPlugin struct{}
+
+func (p *CSVPlugin) Namespace() string { return "csv" }
+
+func (p *CSVPlugin) Description() string { return "CSV of values, file:PATH or var:NAME" }
+
+func (p *CSVPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "table", Args: "SOURCE", Help: "Markdown table of the CSV, the first row is the header"},
+	}
+}
+
+// Apply executes CSV operations on data written in the call
+func (p *CSVPlugin) Apply(operation string, value string) (string, error) {
+	return p.ApplyVariables(operation, value, nil)
+}
+
+// ApplyVariables executes CSV operations:
+//   - table:SOURCE - Markdown table with the first row as header
+func (p *CSVPlugin) ApplyVariables(operation string, value string, variables map[string]string) (ret string, err error) {
+	debugf("CSV: operation=%q value=%q", operation, value)
+
+	if operation != "table" {
+		return "", fmt.Errorf("csv: unknown operation %q (supported: %s)", operation, operationNames(p.Operations()))
+	}
+
+	var data string
+	if data, err = dataSource(value, variables); err != nil {
+		return "", fmt.Errorf("csv: %v", err)
+	}
+	reader := csv.NewReader(strings.NewReader(data))
+	// Rows may have fewer or more cells than the header
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var rows [][]string
+	if rows, err = reader.ReadAll(); err != nil {
+		return "", fmt.Errorf("csv: invalid CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return "", fmt.Errorf("csv: no rows")
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var builder strings.Builder
+	writeRow := func(row []string) {
+		builder.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = markdownCell(row[i])
+			}
+			builder.WriteString(" " + cell + " |")
+		}
+		builder.WriteString("\n")
+	}
+	writeRow(rows[0])
+	builder.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+
+	ret = strings.TrimSuffix(builder.String(), "\n")
+	debugf("CSV: table of %d rows and %d columns", len(rows), columns)
+	return
+}
+
+// markdownCell escapes the pipes of a cell and joins its lines
+func markdownCell(cell string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(cell, "|", `\|`)), " ")
+}
@@ -0,0 +1,45 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCSVPlugin(t *testing.T) {
+	plugin := &CSVPlugin{}
+	variables := map[string]string{"sales": "region, total\nnorth, 10\n\"south | east\", \"1\n2\"\nwest\n"}
+
+	got, err := plugin.ApplyVariables("table", "var:sales", variables)
+	if err != nil {
+		t.Fatalf("ApplyVariables returned error: %v", err)
+	}
+	want := "| region | total |\n| --- | --- |\n| north | 10 |\n| south \\| east | 1 2 |\n| west |  |"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err = ApplyTemplate("{{plugin:csv:table:a,b\n1,2}}", nil, "")
+	if err != nil || got != "| a | b |\n| --- | --- |\n| 1 | 2 |" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestCSVPlugin_Errors(t *testing.T) {
+	plugin := &CSVPlugin{}
+	tests := []struct {
+		operation string
+		value     string
+		want      string
+	}{
+		{"chart", "a,b", "unknown operation"},
+		{"table", "", "no rows"},
+		{"table", "a,\"b", "invalid CSV"},
+		{"table", "var:missing", "variable missing is not set"},
+	}
+	for _, tt := range tests {
+		_, err := plugin.Apply(tt.operation, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s:%s: expected an error with %q, got %v", tt.operation, tt.value, tt.want, err)
+		}
+	}
+}
@@ -10,6 +10,29 @@ import (
 // DateTimePlugin handles time and date operations
 type DateTimePlugin struct{}
 
+func (p *DateTimePlugin) Namespace() string { return "datetime" }
+
+func (p *DateTimePlugin) Description() string { return "Dates and times" }
+
+func (p *DateTimePlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "now", Help: "Current date and time (RFC3339)"},
+		{Name: "time", Help: "Current time (HH:MM:SS)"},
+		{Name: "unix", Help: "Current Unix timestamp"},
+		{Name: "startofhour", Help: "Start of the current hour (RFC3339)"},
+		{Name: "endofhour", Help: "End of the current hour (RFC3339)"},
+		{Name: "today", Help: "Current date (YYYY-MM-DD)"},
+		{Name: "full", Help: "Current date in full, like Monday, January 2, 2006"},
+		{Name: "month", Help: "Current month name"},
+		{Name: "year", Help: "Current year"},
+		{Name: "startofweek", Help: "Start of the current week (YYYY-MM-DD)"},
+		{Name: "endofweek", Help: "End of the current week (YYYY-MM-DD)"},
+		{Name: "startofmonth", Help: "Start of the current month (YYYY-MM-DD)"},
+		{Name: "endofmonth", Help: "End of the current month (YYYY-MM-DD)"},
+		{Name: "rel", Args: "OFFSET", Help: "Time relative to now, like -1h, 2d, -3w, 1m or -1y"},
+	}
+}
+
 // Apply executes datetime operations with the following formats:
 // Time: now (RFC3339), time (HH:MM:SS), unix (timestamp)
 // Hour: startofhour, endofhour
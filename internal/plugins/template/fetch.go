@@ -28,6 +28,16 @@ const (
 // - Null byte checking
 type FetchPlugin struct{}
 
+func (p *FetchPlugin) Namespace() string { return "fetch" }
+
+func (p *FetchPlugin) Description() string { return "Text content from URLs, at most 1MB" }
+
+func (p *FetchPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "get", Args: "URL", Help: "Text content of the URL"},
+	}
+}
+
 // Apply executes fetch operations:
 //   - get:URL: Fetches content from URL, returns text content
 func (p *FetchPlugin) Apply(operation string, value string) (string, error) {
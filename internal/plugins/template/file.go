@@ -11,17 +11,40 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/danielmiessler/fabric/internal/util"
 )
 
 // MaxFileSize defines the maximum file size that can be read (1MB)
 const MaxFileSize = 1 * 1024 * 1024
 
+// MaxGlobFiles defines the maximum number of files a glob or tree can list
+const MaxGlobFiles = 1000
+
 // FilePlugin provides filesystem operations with safety constraints:
 // - No directory traversal
-// - Size limits
+// - Size limits, also for all files of readall together
 // - Path sanitization
+// - Files ignored by .gitignore are left out of globs and trees
 type FilePlugin struct{}
 
+func (p *FilePlugin) Namespace() string { return "file" }
+
+func (p *FilePlugin) Description() string { return "Local files, at most 1MB" }
+
+func (p *FilePlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "read", Args: "PATH", Help: "Content of the file"},
+		{Name: "tail", Args: "PATH|N", Help: "Last N lines of the file"},
+		{Name: "exists", Args: "PATH", Help: "Whether the file exists, true or false"},
+		{Name: "size", Args: "PATH", Help: "Size of the file in bytes"},
+		{Name: "modified", Args: "PATH", Help: "Last modification time of the file (RFC3339)"},
+		{Name: "glob", Args: "PATTERN", Help: "Files matching the pattern, one per line, ** matches any directories"},
+		{Name: "tree", Args: "DIR|DEPTH", Help: "Files and directories of the directory as indented tree, DEPTH is optional"},
+		{Name: "readall", Args: "PATTERN", Help: "Content of the text files matching the pattern, each after a header with its path"},
+	}
+}
+
 // safePath validates and normalizes file paths
 func (p *FilePlugin) safePath(path string) (string, error) {
 	debugf("File: validating path %q", path)
@@ -52,6 +75,9 @@ func (p *FilePlugin) safePath(path string) (string, error) {
 //   - exists:PATH - Check if file exists
 //   - size:PATH - Get file size in bytes
 //   - modified:PATH - Get last modified time
+//   - glob:PATTERN - List files matching a pattern like src/**/*.go
+//   - tree:DIR|DEPTH - List a directory as tree, down to DEPTH levels
+//   - readall:PATTERN - Read all text files matching a pattern with headers
 func (p *FilePlugin) Apply(operation string, value string) (string, error) {
 	debugf("File: operation=%q value=%q", operation, value)
 
@@ -150,8 +176,32 @@ func (p *FilePlugin) Apply(operation string, value string) (string, error) {
 		debugf("File: modified=%q for path %q", mtime, path)
 		return mtime, nil
 
+	case "glob":
+		files, err := p.glob(value)
+		if err != nil {
+			return "", err
+		}
+
+		debugf("File: glob matched %d files", len(files))
+		return strings.Join(files, "\n"), nil
+
+	case "tree":
+		dir, depthValue, hasDepth := strings.Cut(value, "|")
+		depth := 0
+		if hasDepth {
+			var err error
+			if depth, err = strconv.Atoi(depthValue); err != nil || depth < 1 {
+				return "", fmt.Errorf("file: invalid tree depth %q", depthValue)
+			}
+		}
+
+		return p.tree(dir, depth)
+
+	case "readall":
+		return p.readAll(value)
+
 	default:
-		return "", fmt.Errorf("file: unknown operation %q (supported: read, tail, exists, size, modified)",
+		return "", fmt.Errorf("file: unknown operation %q (supported: read, tail, exists, size, modified, glob, tree, readall)",
 			operation)
 	}
 }
@@ -195,3 +245,106 @@ func (p *FilePlugin) lastNLines(path string, n int) ([]string, error) {
 	debugf("File: read %d lines total, returning last %d", lineCount, len(lines))
 	return lines, nil
 }
+
+// glob returns the regular files matching a pattern, where ** matches any number of
+// directories. Paths start with the directory of the pattern.
+func (p *FilePlugin) glob(pattern string) ([]string, error) {
+	cleaned, err := p.safePath(pattern)
+	if err != nil {
+		return nil, err
+	}
+	base, segments := util.SplitGlob(cleaned)
+	debugf("File: globbing %v in %q", segments, base)
+
+	var files []string
+	err = util.WalkFiles(base, func(path string, rel []string, entry os.DirEntry) error {
+		if !entry.Type().IsRegular() || !util.MatchGlob(segments, rel) {
+			return nil
+		}
+		if len(files) == MaxGlobFiles {
+			return fmt.Errorf("more than %d files match", MaxGlobFiles)
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("file: could not glob %s: %v", pattern, err)
+	}
+	return files, nil
+}
+
+// tree lists the files and directories of a directory indented by their depth, directories end
+// with a slash. A depth of 0 lists all levels.
+func (p *FilePlugin) tree(dir string, depth int) (string, error) {
+	root, err := p.safePath(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("file: could not stat directory: %v", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("file: %s is not a directory", dir)
+	}
+
+	var builder strings.Builder
+	entries := 0
+	err = util.WalkFiles(root, func(path string, rel []string, entry os.DirEntry) error {
+		if len(rel) == 0 {
+			builder.WriteString(filepath.ToSlash(root) + "/\n")
+			return nil
+		}
+		if entries++; entries > MaxGlobFiles {
+			return fmt.Errorf("it has more than %d entries", MaxGlobFiles)
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		builder.WriteString(strings.Repeat("  ", len(rel)) + name + "\n")
+		if entry.IsDir() && len(rel) == depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("file: could not list %s: %v", dir, err)
+	}
+
+	debugf("File: tree listed %d entries", entries)
+	return builder.String(), nil
+}
+
+// readAll concatenates the text files matching a pattern, each after a "=== path ===" header.
+// Binary files are left out, all files together must fit in MaxFileSize.
+func (p *FilePlugin) readAll(pattern string) (string, error) {
+	files, err := p.glob(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("file: could not stat file: %v", err)
+		}
+		if builder.Len()+int(info.Size()) > MaxFileSize {
+			return "", fmt.Errorf("file: files matching %s exceed limit of %d bytes", pattern, MaxFileSize)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("file: could not read: %v", err)
+		}
+		if isBinaryContent(content) {
+			debugf("File: readall skipping binary file %q", path)
+			continue
+		}
+		fmt.Fprintf(&builder, "=== %s ===\n%s\n", filepath.ToSlash(path), strings.TrimRight(string(content), "\n"))
+	}
+
+	debugf("File: readall read %d bytes from %d files", builder.Len(), len(files))
+	return builder.String(), nil
+}
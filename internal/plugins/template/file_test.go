@@ -150,3 +150,137 @@ func TestFilePlugin(t *testing.T) {
 		})
 	}
 }
+
+// setupFileTree creates a small Go package with a .gitignore and returns its directory
+func setupFileTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":      "*.log\nvendor/\n",
+		"main.go":         "package main\n",
+		"main_test.go":    "package main\n\n// test\n",
+		"debug.log":       "ignored\n",
+		"vendor/dep.go":   "package dep\n",
+		"pkg/lib.go":      "package pkg\n",
+		"pkg/logo.png":    "\x89PNG\x00",
+		"pkg/sub/deep.go": "package sub\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFilePlugin_Globs(t *testing.T) {
+	plugin := &FilePlugin{}
+	dir := setupFileTree(t)
+	root := filepath.ToSlash(dir)
+
+	tests := []struct {
+		name      string
+		operation string
+		value     string
+		want      string
+	}{
+		{"glob", "glob", dir + "/*.go", root + "/main.go\n" + root + "/main_test.go"},
+		{"glob recursive", "glob", dir + "/**/*.go", root + "/main.go\n" + root + "/main_test.go\n" +
+			root + "/pkg/lib.go\n" + root + "/pkg/sub/deep.go"},
+		{"glob no match", "glob", dir + "/*.rs", ""},
+		{"tree", "tree", dir, root + "/\n  .gitignore\n  main.go\n  main_test.go\n  pkg/\n    lib.go\n    logo.png\n    sub/\n      deep.go\n"},
+		{"tree depth", "tree", dir + "|1", root + "/\n  .gitignore\n  main.go\n  main_test.go\n  pkg/\n"},
+		{"readall", "readall", dir + "/pkg/**", "=== " + root + "/pkg/lib.go ===\npackage pkg\n=== " +
+			root + "/pkg/sub/deep.go ===\npackage sub\n"},
+		{"readall tests", "readall", dir + "/*_test.go", "=== " + root + "/main_test.go ===\npackage main\n\n// test\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.Apply(tt.operation, tt.value)
+			if err != nil {
+				t.Fatalf("Apply returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	got, err := ApplyTemplate("{{plugin:file:tree:"+dir+"/pkg|1}}", nil, "")
+	if err != nil || got != root+"/pkg/\n  lib.go\n  logo.png\n  sub/\n" {
+		t.Errorf("expected the tree from a template, got %q, %v", got, err)
+	}
+}
+
+func TestFilePlugin_GlobErrors(t *testing.T) {
+	plugin := &FilePlugin{}
+	dir := setupFileTree(t)
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "big.go"), []byte(strings.Repeat("x", MaxFileSize)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		operation string
+		value     string
+		want      string
+	}{
+		{"glob", "../**/*.go", "cannot contain '..'"},
+		{"readall", dir + "/../**", "cannot contain '..'"},
+		{"tree", "../", "cannot contain '..'"},
+		{"tree", dir + "|0", "invalid tree depth"},
+		{"tree", dir + "/main.go", "is not a directory"},
+		{"tree", dir + "/missing", "could not stat directory"},
+		{"glob", dir + "/missing/*.go", "could not glob"},
+		{"readall", dir + "/**/*.go", "exceed limit"},
+	}
+	for _, tt := range tests {
+		_, err := plugin.Apply(tt.operation, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s:%s: expected an error with %q, got %v", tt.operation, tt.value, tt.want, err)
+		}
+	}
+}
+
+func TestFilePlugin_RepositoryGitignore(t *testing.T) {
+	plugin := &FilePlugin{}
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		".git/HEAD":        "ref: refs/heads/main\n",
+		".gitignore":       "*.gen.go\n/src/build/\n",
+		"src/main.go":      "package main\n",
+		"src/main.gen.go":  "package generated\n",
+		"src/build/out.go": "package build\n",
+	} {
+		path := filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := filepath.Join(repo, "src")
+	root := filepath.ToSlash(src)
+
+	tests := []struct {
+		operation string
+		value     string
+		want      string
+	}{
+		{"glob", src + "/**", root + "/main.go"},
+		{"tree", src, root + "/\n  main.go\n"},
+		{"readall", src + "/**/*.go", "=== " + root + "/main.go ===\npackage main\n"},
+	}
+	for _, tt := range tests {
+		got, err := plugin.Apply(tt.operation, tt.value)
+		if err != nil {
+			t.Fatalf("%s returned error: %v", tt.operation, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected the .gitignore of the repository to apply, got %q, want %q", tt.operation, got, tt.want)
+		}
+	}
+}
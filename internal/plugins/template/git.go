@@ -0,0 +1,501 @@
+// Package template provides git repository operations for the template system.
+// Security Note: This plugin reads the git repository of the working directory,
+// including files that are not committed.
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// defaultGitLogCount is the number of commits of log without a count
+const defaultGitLogCount = 10
+
+// maxGitPatchSize is the most content of changed files, before and after, a diff reads. The
+// patch of both sides must still fit into MaxFileSize.
+const maxGitPatchSize = 2 * MaxFileSize
+
+// GitPlugin provides the state of the git repository of the working directory with the
+// same size limit as the file plugin:
+// - Results are limited to MaxFileSize
+// - Larger and binary files are shown as binary in diffs
+type GitPlugin struct {
+	// Dir is the directory the repository is searched from, the working directory when empty
+	Dir string
+}
+
+func (p *GitPlugin) Namespace() string { return "git" }
+
+func (p *GitPlugin) Description() string {
+	return "State of the git repository of the working directory"
+}
+
+func (p *GitPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "branch", Help: "Current branch, or the short commit hash when detached"},
+		{Name: "status", Help: "Changed files, one per line with their staged and unstaged status like git status --short"},
+		{Name: "diff", Help: "Unstaged changes as unified diff"},
+		{Name: "diff", Args: "staged", Help: "Staged changes as unified diff"},
+		{Name: "diff", Args: "REF", Help: "Changes of the working tree against a branch, tag or commit"},
+		{Name: "log", Args: "N", Help: "Last N commits (default 10), one per line with hash, date, author and subject"},
+		{Name: "show", Args: "REF", Help: "Message and changes of a commit"},
+		{Name: "show", Args: "REF:PATH", Help: "Content of a file at a branch, tag or commit"},
+		{Name: "blame", Args: "PATH", Help: "Last commit, author and date of every line of a committed file"},
+	}
+}
+
+// Apply executes git operations:
+//   - branch: Current branch
+//   - status: Changed files
+//   - diff, diff:staged, diff:REF: Unstaged, staged or changes against REF
+//   - log:N: Last N commits
+//   - show:REF, show:REF:PATH: Commit or file at a commit
+//   - blame:PATH: Authorship of the lines of a file
+func (p *GitPlugin) Apply(operation string, value string) (ret string, err error) {
+	debugf("Git: operation=%q value=%q", operation, value)
+
+	dir := p.Dir
+	if dir == "" {
+		dir = "."
+	}
+	var repo *git.Repository
+	if repo, err = git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true}); err != nil {
+		return "", fmt.Errorf("git: could not open the repository: %v", err)
+	}
+
+	switch operation {
+	case "branch":
+		ret, err = gitBranch(repo)
+	case "status":
+		ret, err = gitStatus(repo)
+	case "diff":
+		ret, err = gitDiff(repo, value)
+	case "log":
+		ret, err = gitLog(repo, value)
+	case "show":
+		ret, err = gitShow(repo, value)
+	case "blame":
+		ret, err = gitBlame(repo, value)
+	default:
+		return "", fmt.Errorf("git: unknown operation %q (supported: branch, status, diff, log, show, blame)", operation)
+	}
+	if err != nil {
+		return "", fmt.Errorf("git: %s: %v", operation, err)
+	}
+	if len(ret) > MaxFileSize {
+		return "", fmt.Errorf("git: %s result of %d bytes exceeds limit of %d bytes", operation, len(ret), MaxFileSize)
+	}
+	debugf("Git: %s returned %d bytes", operation, len(ret))
+	return
+}
+
+func gitBranch(repo *git.Repository) (ret string, err error) {
+	var head *plumbing.Reference
+	if head, err = repo.Head(); err != nil {
+		return
+	}
+	if head.Name().IsBranch() {
+		return head.Name().Short(), nil
+	}
+	return head.Hash().String()[:7], nil
+}
+
+func gitStatus(repo *git.Repository) (ret string, err error) {
+	var worktree *git.Worktree
+	if worktree, err = repo.Worktree(); err != nil {
+		return
+	}
+	var status git.Status
+	if status, err = worktree.Status(); err != nil {
+		return
+	}
+	paths := make([]string, 0, len(status))
+	for path, fileStatus := range status {
+		if fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var builder strings.Builder
+	for _, path := range paths {
+		fileStatus := status[path]
+		fmt.Fprintf(&builder, "%c%c %s\n", fileStatus.Staging, fileStatus.Worktree, path)
+	}
+	ret = builder.String()
+	return
+}
+
+func gitLog(repo *git.Repository, value string) (ret string, err error) {
+	count := defaultGitLogCount
+	if value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count < 1 {
+			return "", fmt.Errorf("invalid commit count %q", value)
+		}
+	}
+	var head *plumbing.Reference
+	if head, err = repo.Head(); err != nil {
+		return
+	}
+	var commits object.CommitIter
+	if commits, err = repo.Log(&git.LogOptions{From: head.Hash()}); err != nil {
+		return
+	}
+	defer commits.Close()
+
+	var builder strings.Builder
+	for i := 0; i < count; i++ {
+		var commit *object.Commit
+		if commit, err = commits.Next(); err != nil {
+			// The history has fewer commits
+			err = nil
+			break
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		fmt.Fprintf(&builder, "%s %s %s: %s\n", commit.Hash.String()[:7],
+			commit.Author.When.Format("2006-01-02"), commit.Author.Name, subject)
+	}
+	ret = builder.String()
+	return
+}
+
+func gitShow(repo *git.Repository, value string) (ret string, err error) {
+	ref, path, hasPath := strings.Cut(value, ":")
+	if ref == "" {
+		return "", fmt.Errorf("requires a branch, tag or commit, like show:HEAD or show:HEAD:README.md")
+	}
+	var commit *object.Commit
+	if commit, err = gitCommit(repo, ref); err != nil {
+		return
+	}
+
+	if hasPath {
+		var file *object.File
+		if file, err = commit.File(path); err != nil {
+			return "", fmt.Errorf("%s at %s: %v", path, ref, err)
+		}
+		if file.Size > MaxFileSize {
+			return "", fmt.Errorf("size %d of %s exceeds limit of %d bytes", file.Size, path, MaxFileSize)
+		}
+		return file.Contents()
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "commit %s\nAuthor: %s <%s>\nDate:   %s\n\n%s\n",
+		commit.Hash, commit.Author.Name, commit.Author.Email,
+		commit.Author.When.Format("Mon Jan 2 15:04:05 2006 -0700"), strings.TrimRight(commit.Message, "\n"))
+
+	from := gitSide{}
+	if commit.NumParents() > 0 {
+		var parent *object.Commit
+		if parent, err = commit.Parent(0); err != nil {
+			return
+		}
+		if from, err = gitTreeSide(parent); err != nil {
+			return
+		}
+	}
+	var to gitSide
+	if to, err = gitTreeSide(commit); err != nil {
+		return
+	}
+	var patch string
+	if patch, err = gitPatchOf(from, to); err != nil {
+		return
+	}
+	if patch != "" {
+		builder.WriteString("\n" + patch)
+	}
+	ret = builder.String()
+	return
+}
+
+func gitBlame(repo *git.Repository, path string) (ret string, err error) {
+	if path == "" {
+		return "", fmt.Errorf("requires a file path")
+	}
+	var commit *object.Commit
+	if commit, err = gitCommit(repo, "HEAD"); err != nil {
+		return
+	}
+	var file *object.File
+	if file, err = commit.File(path); err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+	if file.Size > MaxFileSize {
+		return "", fmt.Errorf("size %d of %s exceeds limit of %d bytes", file.Size, path, MaxFileSize)
+	}
+	var result *git.BlameResult
+	if result, err = git.Blame(commit, path); err != nil {
+		return
+	}
+
+	var builder strings.Builder
+	for _, line := range result.Lines {
+		fmt.Fprintf(&builder, "%s (%s %s) %s\n", line.Hash.String()[:7], line.AuthorName,
+			line.Date.Format("2006-01-02"), line.Text)
+	}
+	ret = builder.String()
+	return
+}
+
+func gitCommit(repo *git.Repository, ref string) (ret *object.Commit, err error) {
+	var hash *plumbing.Hash
+	if hash, err = repo.ResolveRevision(plumbing.Revision(ref)); err != nil {
+		return nil, fmt.Errorf("unknown revision %s: %v", ref, err)
+	}
+	return repo.CommitObject(*hash)
+}
+
+// gitDiff compares the index with the working tree, HEAD with the index for staged, or a
+// revision with the working tree
+func gitDiff(repo *git.Repository, value string) (ret string, err error) {
+	var index, worktree gitSide
+	if index, worktree, err = gitIndexAndWorktree(repo); err != nil {
+		return
+	}
+
+	switch value {
+	case "", "unstaged":
+		return gitPatchOf(index, worktree)
+	case "staged":
+		var head *object.Commit
+		if head, err = gitCommit(repo, "HEAD"); err != nil {
+			return
+		}
+		var from gitSide
+		if from, err = gitTreeSide(head); err != nil {
+			return
+		}
+		return gitPatchOf(from, index)
+	default:
+		var commit *object.Commit
+		if commit, err = gitCommit(repo, value); err != nil {
+			return
+		}
+		var from gitSide
+		if from, err = gitTreeSide(commit); err != nil {
+			return
+		}
+		return gitPatchOf(from, worktree)
+	}
+}
+
+// gitFile is a file of one side of a diff
+type gitFile struct {
+	path string
+	hash plumbing.Hash
+	mode filemode.FileMode
+	// read returns the content of the file
+	read func() ([]byte, error)
+}
+
+func (o *gitFile) Hash() plumbing.Hash     { return o.hash }
+func (o *gitFile) Mode() filemode.FileMode { return o.mode }
+func (o *gitFile) Path() string            { return o.path }
+
+// gitSide are the files of one side of a diff by path
+type gitSide map[string]*gitFile
+
+func gitTreeSide(commit *object.Commit) (ret gitSide, err error) {
+	var tree *object.Tree
+	if tree, err = commit.Tree(); err != nil {
+		return
+	}
+	ret = gitSide{}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		ret[file.Name] = &gitFile{path: file.Name, hash: file.Hash, mode: file.Mode, read: func() ([]byte, error) {
+			contents, err := file.Contents()
+			return []byte(contents), err
+		}}
+		return nil
+	})
+	return
+}
+
+// gitIndexAndWorktree returns the staged files and the tracked files of the working tree. Files
+// the status reports unchanged are not read.
+func gitIndexAndWorktree(repo *git.Repository) (index gitSide, worktree gitSide, err error) {
+	var tree *git.Worktree
+	if tree, err = repo.Worktree(); err != nil {
+		return
+	}
+	var status git.Status
+	if status, err = tree.Status(); err != nil {
+		return
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return
+	}
+
+	root := tree.Filesystem.Root()
+	index, worktree = gitSide{}, gitSide{}
+	for _, entry := range idx.Entries {
+		hash := entry.Hash
+		index[entry.Name] = &gitFile{path: entry.Name, hash: hash, mode: entry.Mode, read: func() ([]byte, error) {
+			return gitBlobContent(repo, hash)
+		}}
+
+		fileStatus, changed := status[entry.Name]
+		switch {
+		case !changed || fileStatus.Worktree == git.Unmodified:
+			worktree[entry.Name] = index[entry.Name]
+		case fileStatus.Worktree == git.Deleted:
+		default:
+			path := filepath.Join(root, filepath.FromSlash(entry.Name))
+			var content []byte
+			if content, err = os.ReadFile(path); err != nil {
+				return
+			}
+			worktree[entry.Name] = &gitFile{path: entry.Name, hash: plumbing.ComputeHash(plumbing.BlobObject, content),
+				mode: entry.Mode, read: func() ([]byte, error) { return content, nil }}
+		}
+	}
+	return
+}
+
+func gitBlobContent(repo *git.Repository, hash plumbing.Hash) (ret []byte, err error) {
+	var blob *object.Blob
+	if blob, err = repo.BlobObject(hash); err != nil {
+		return
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(reader)
+	ret = buffer.Bytes()
+	return
+}
+
+// gitPatch is the patch between two sides, encoded by the unified encoder of go-git
+type gitPatch struct {
+	files []fdiff.FilePatch
+}
+
+func (o *gitPatch) FilePatches() []fdiff.FilePatch { return o.files }
+func (o *gitPatch) Message() string                { return "" }
+
+type gitFilePatch struct {
+	from, to *gitFile
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+func (o *gitFilePatch) IsBinary() bool { return o.binary }
+func (o *gitFilePatch) Chunks() []fdiff.Chunk {
+	return o.chunks
+}
+func (o *gitFilePatch) Files() (from fdiff.File, to fdiff.File) {
+	// Nil files must be nil interfaces for the encoder
+	if o.from != nil {
+		from = o.from
+	}
+	if o.to != nil {
+		to = o.to
+	}
+	return
+}
+
+type gitChunk struct {
+	content   string
+	operation fdiff.Operation
+}
+
+func (o *gitChunk) Content() string       { return o.content }
+func (o *gitChunk) Type() fdiff.Operation { return o.operation }
+
+// gitPatchOf builds the unified diff of the files that differ between two sides, by path
+func gitPatchOf(from gitSide, to gitSide) (ret string, err error) {
+	paths := map[string]bool{}
+	for path := range from {
+		paths[path] = true
+	}
+	for path := range to {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	patch := &gitPatch{}
+	size := 0
+	for _, path := range sorted {
+		fromFile, toFile := from[path], to[path]
+		if fromFile != nil && toFile != nil && fromFile.hash == toFile.hash && fromFile.mode == toFile.mode {
+			continue
+		}
+		filePatch := &gitFilePatch{from: fromFile, to: toFile}
+		var fromContent, toContent []byte
+		if fromContent, err = readGitFile(fromFile); err != nil {
+			return
+		}
+		if toContent, err = readGitFile(toFile); err != nil {
+			return
+		}
+		if size += len(fromContent) + len(toContent); size > maxGitPatchSize {
+			return "", fmt.Errorf("the changed files exceed the limit of %d bytes", maxGitPatchSize)
+		}
+
+		if isBinaryContent(fromContent) || isBinaryContent(toContent) {
+			filePatch.binary = true
+		} else {
+			for _, change := range diff.Do(string(fromContent), string(toContent)) {
+				operation := fdiff.Equal
+				switch change.Type {
+				case diffmatchpatch.DiffInsert:
+					operation = fdiff.Add
+				case diffmatchpatch.DiffDelete:
+					operation = fdiff.Delete
+				}
+				filePatch.chunks = append(filePatch.chunks, &gitChunk{content: change.Text, operation: operation})
+			}
+		}
+		patch.files = append(patch.files, filePatch)
+	}
+
+	var buffer bytes.Buffer
+	if err = fdiff.NewUnifiedEncoder(&buffer, fdiff.DefaultContextLines).Encode(patch); err != nil {
+		return
+	}
+	ret = buffer.String()
+	return
+}
+
+// readGitFile reads a file of a side, files larger than the limit are left out like binary files
+func readGitFile(file *gitFile) (ret []byte, err error) {
+	if file == nil {
+		return
+	}
+	if ret, err = file.read(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file.path, err)
+	}
+	if len(ret) > MaxFileSize {
+		// A NUL byte makes the patch binary, without the content
+		ret = []byte{0}
+	}
+	return
+}
+
+// isBinaryContent detects binary files like git, by a NUL byte in the first 8000 bytes
+func isBinaryContent(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
@@ -0,0 +1,223 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// setupGitRepo creates a repository with two commits of notes.txt and returns its directory
+func setupGitRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(content string, message string, when time.Time) {
+		writeGitFile(t, dir, "notes.txt", content)
+		if _, err := worktree.Add("notes.txt"); err != nil {
+			t.Fatal(err)
+		}
+		signature := &object.Signature{Name: "Ada", Email: "ada@example.com", When: when}
+		if _, err := worktree.Commit(message, &git.CommitOptions{Author: signature}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("one\ntwo\n", "Add notes\n\nWith a body.", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC))
+	commit("one\ntwo\nthree\n", "Extend notes", time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC))
+	return dir
+}
+
+func writeGitFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGitPlugin(t *testing.T) {
+	dir := setupGitRepo(t)
+	plugin := &GitPlugin{Dir: dir}
+
+	tests := []struct {
+		name      string
+		operation string
+		value     string
+		want      []string
+		exact     string
+	}{
+		{name: "branch", operation: "branch", exact: "master"},
+		{name: "log", operation: "log", want: []string{" 2024-01-03 Ada: Extend notes\n", " 2024-01-02 Ada: Add notes\n"}},
+		{name: "show file", operation: "show", value: "HEAD~1:notes.txt", exact: "one\ntwo\n"},
+		{name: "show commit", operation: "show", value: "HEAD",
+			want: []string{"Author: Ada <ada@example.com>", "Extend notes", "--- a/notes.txt", "+++ b/notes.txt", "+three"}},
+		{name: "show first commit", operation: "show", value: "HEAD~1",
+			want: []string{"With a body.", "--- /dev/null", "+one"}},
+		{name: "blame", operation: "blame", value: "notes.txt", want: []string{"(Ada 2024-01-02) one\n", "(Ada 2024-01-03) three\n"}},
+		{name: "clean status", operation: "status", exact: ""},
+		{name: "clean diff", operation: "diff", exact: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.Apply(tt.operation, tt.value)
+			if err != nil {
+				t.Fatalf("Apply returned error: %v", err)
+			}
+			if tt.want == nil && got != tt.exact {
+				t.Errorf("got %q, want %q", got, tt.exact)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in %q", want, got)
+				}
+			}
+		})
+	}
+
+	if got, err := plugin.Apply("log", "1"); err != nil || strings.Count(got, "\n") != 1 {
+		t.Errorf("expected one commit, got %q, %v", got, err)
+	}
+}
+
+func TestGitPlugin_Changes(t *testing.T) {
+	dir := setupGitRepo(t)
+	plugin := &GitPlugin{Dir: dir}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A staged new file, then an unstaged change of a committed file
+	writeGitFile(t, dir, "todo.txt", "ship it\n")
+	if _, err = worktree.Add("todo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	writeGitFile(t, dir, "notes.txt", "one\n2\nthree\n")
+	writeGitFile(t, dir, "scratch.txt", "untracked\n")
+
+	status, err := plugin.Apply("status", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := " M notes.txt\n?? scratch.txt\nA  todo.txt\n"; status != want {
+		t.Errorf("got status %q, want %q", status, want)
+	}
+
+	unstaged, err := plugin.Apply("diff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--- a/notes.txt", "-two", "+2"} {
+		if !strings.Contains(unstaged, want) {
+			t.Errorf("expected %q in the unstaged diff %q", want, unstaged)
+		}
+	}
+	if strings.Contains(unstaged, "todo.txt") || strings.Contains(unstaged, "scratch.txt") {
+		t.Errorf("expected only unstaged changes of tracked files, got %q", unstaged)
+	}
+
+	staged, err := plugin.Apply("diff", "staged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(staged, "+++ b/todo.txt") || strings.Contains(staged, "notes.txt") {
+		t.Errorf("expected only the staged file, got %q", staged)
+	}
+
+	againstRef, err := plugin.Apply("diff", "HEAD~1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"-two", "+2", "+three", "+ship it"} {
+		if !strings.Contains(againstRef, want) {
+			t.Errorf("expected %q in the diff against HEAD~1 %q", want, againstRef)
+		}
+	}
+
+	// Binary and too large files are not shown
+	writeGitFile(t, dir, "notes.txt", "one\x00two\n")
+	binary, err := plugin.Apply("diff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(binary, "Binary files a/notes.txt and b/notes.txt differ") {
+		t.Errorf("expected a binary diff, got %q", binary)
+	}
+	writeGitFile(t, dir, "notes.txt", strings.Repeat("x", MaxFileSize+1))
+	large, err := plugin.Apply("diff", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(large, "Binary files") {
+		t.Errorf("expected a large file to be left out, got %q", large)
+	}
+}
+
+func TestGitPlugin_PatchSizeLimit(t *testing.T) {
+	dir := setupGitRepo(t)
+	plugin := &GitPlugin{Dir: dir}
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three new files, each below the file limit, together above the limit of a patch
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		writeGitFile(t, dir, name, strings.Repeat("x\n", maxGitPatchSize/6+1))
+		if _, err = worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = plugin.Apply("diff", "staged")
+	if want := fmt.Sprintf("exceed the limit of %d bytes", maxGitPatchSize); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected an error with %q, got %v", want, err)
+	}
+}
+
+func TestGitPlugin_Errors(t *testing.T) {
+	plugin := &GitPlugin{Dir: setupGitRepo(t)}
+	tests := []struct {
+		operation string
+		value     string
+		want      string
+	}{
+		{"merge", "", "unknown operation"},
+		{"log", "many", "invalid commit count"},
+		{"log", "0", "invalid commit count"},
+		{"show", "", "requires a branch"},
+		{"show", "nope", "unknown revision nope"},
+		{"show", "HEAD:missing.txt", "missing.txt at HEAD"},
+		{"blame", "", "requires a file path"},
+		{"diff", "nope", "unknown revision nope"},
+	}
+	for _, tt := range tests {
+		_, err := plugin.Apply(tt.operation, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s:%s: expected an error with %q, got %v", tt.operation, tt.value, tt.want, err)
+		}
+	}
+
+	if _, err := (&GitPlugin{Dir: t.TempDir()}).Apply("branch", ""); err == nil ||
+		!strings.Contains(err.Error(), "could not open the repository") {
+		t.Errorf("expected an error outside of a repository, got %v", err)
+	}
+}
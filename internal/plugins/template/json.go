@@ -0,0 +1,75 @@
+// Package template provides JSON queries and conversions for the template system.
+package template
+
+import (
+	"fmt"
+)
+
+// JSONPlugin queries, pretty-prints and converts JSON. Its values are the JSON itself, a
+// file:PATH or a var:NAME, see dataSource.
+type JSONPlugin struct{}
+
+func (p *JSONPlugin) Namespace() string { return "json" }
+
+func (p *JSONPlugin) Description() string {
+	return "JSON of values, file:PATH or var:NAME"
+}
+
+func (p *JSONPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "query", Args: "SOURCE|QUERY", Help: "Values at a path like .items[0].name or $.items[*].name, one per line"},
+		{Name: "pretty", Args: "SOURCE", Help: "JSON indented by two spaces"},
+		{Name: "toyaml", Args: "SOURCE", Help: "Converts JSON to YAML"},
+	}
+}
+
+// Apply executes JSON operations on data written in the call
+func (p *JSONPlugin) Apply(operation string, value string) (string, error) {
+	return p.ApplyVariables(operation, value, nil)
+}
+
+// ApplyVariables executes JSON operations:
+//   - query:SOURCE|QUERY - Values at a path, objects and arrays as JSON
+//   - pretty:SOURCE - Indented JSON
+//   - toyaml:SOURCE - JSON converted to YAML
+func (p *JSONPlugin) ApplyVariables(operation string, value string, variables map[string]string) (ret string, err error) {
+	debugf("JSON: operation=%q value=%q", operation, value)
+
+	source, query := value, ""
+	switch operation {
+	case "query":
+		if source, query, err = splitQuery(value); err != nil {
+			return "", fmt.Errorf("json: %v", err)
+		}
+	case "pretty", "toyaml":
+	default:
+		return "", fmt.Errorf("json: unknown operation %q (supported: %s)", operation, operationNames(p.Operations()))
+	}
+
+	var data string
+	if data, err = dataSource(source, variables); err != nil {
+		return "", fmt.Errorf("json: %v", err)
+	}
+	node, err := parseJSON(data)
+	if err != nil {
+		return "", fmt.Errorf("json: %v", err)
+	}
+
+	switch operation {
+	case "query":
+		nodes, queryErr := queryNodes(node, query)
+		if queryErr != nil {
+			return "", fmt.Errorf("json: %v", queryErr)
+		}
+		ret, err = formatResults(nodes, formatJSON)
+	case "pretty":
+		ret, err = formatJSON(node)
+	case "toyaml":
+		ret, err = formatYAML(node)
+	}
+	if err != nil {
+		return "", fmt.Errorf("json: %s: %v", operation, err)
+	}
+	debugf("JSON: %s returned %d bytes", operation, len(ret))
+	return
+}
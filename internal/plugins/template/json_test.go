@@ -0,0 +1,92 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJSON = `{"name": "fabric", "tags": ["ai", "cli"], "meta": {"stars": 12.50, "ok": true, "none": null, "html": "<b>"}}`
+
+func TestJSONPlugin(t *testing.T) {
+	plugin := &JSONPlugin{}
+	variables := map[string]string{"data": testJSON}
+
+	tests := []struct {
+		name      string
+		operation string
+		value     string
+		want      string
+	}{
+		{"field", "query", "var:data|.name", "fabric"},
+		{"jsonpath", "query", "var:data|$.meta.stars", "12.50"},
+		{"bracket field", "query", `var:data|.["meta"]['ok']`, "true"},
+		{"index", "query", "var:data|.tags[0]", "ai"},
+		{"negative index", "query", "var:data|.tags[-1]", "cli"},
+		{"all items", "query", "var:data|.tags[*]", "ai\ncli"},
+		{"all items jq", "query", "var:data|.tags[]", "ai\ncli"},
+		{"null", "query", "var:data|.meta.none", "null"},
+		{"missing", "query", "var:data|.missing.field", ""},
+		{"object", "query", "var:data|.meta", "{\n  \"stars\": 12.50,\n  \"ok\": true,\n  \"none\": null,\n  \"html\": \"<b>\"\n}"},
+		{"whole", "query", "[1,2]|.", "[\n  1,\n  2\n]"},
+		{"literal", "query", `["a","b"]|.[1]`, "b"},
+		{"pretty", "pretty", `[{"b":1,"a":2}]`, "[\n  {\n    \"b\": 1,\n    \"a\": 2\n  }\n]"},
+		{"toyaml", "toyaml", "var:data", "name: fabric\ntags:\n  - ai\n  - cli\nmeta:\n  stars: 12.50\n  ok: true\n  none: null\n  html: <b>"},
+		{"toyaml quotes strings", "toyaml", `["true","1","a\nb"]`, "- \"true\"\n- \"1\"\n- |-\n  a\n  b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.ApplyVariables(tt.operation, tt.value, variables)
+			if err != nil {
+				t.Fatalf("ApplyVariables returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPlugin_Sources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte(testJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ApplyTemplate("{{plugin:json:query:file:"+path+"|.tags[1]}} and {{plugin:json:query:var:data|.name}}",
+		map[string]string{"data": `{"name": "fabric"}`}, "")
+	if err != nil {
+		t.Fatalf("ApplyTemplate returned error: %v", err)
+	}
+	if want := "cli and fabric"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestJSONPlugin_Errors(t *testing.T) {
+	plugin := &JSONPlugin{}
+	tests := []struct {
+		operation string
+		value     string
+		want      string
+	}{
+		{"merge", "[]", "unknown operation"},
+		{"query", "[]", "requires format SOURCE|QUERY"},
+		{"query", "[1|.", "invalid JSON"},
+		{"pretty", "[1] [2]", "unexpected data after the value"},
+		{"query", "var:data|.a", "variable data is not set"},
+		{"query", "file:../secret.json|.a", "cannot contain '..'"},
+		{"query", `["a"]|.x`, `cannot get field "x" of an array`},
+		{"query", `["a"]|.[x]`, `invalid index "x"`},
+		{"query", `["a"]|.["x]`, "unclosed field name"},
+		{"query", `["a"]|..`, "missing field name"},
+		{"query", `["a"]|x`, "expected . or ["},
+	}
+	for _, tt := range tests {
+		_, err := plugin.Apply(tt.operation, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s:%s: expected an error with %q, got %v", tt.operation, tt.value, tt.want, err)
+		}
+	}
+}
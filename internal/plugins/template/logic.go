@@ -0,0 +1,301 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// blockTagPattern matches the tags of conditions and loops:
+//
+//	{{#if audience}}...{{else}}...{{/if}}
+//	{{#each points}}{{@index}}. {{this}}{{else}}no points{{/each}}
+var blockTagPattern = regexp.MustCompile(`\{\{\s*(#if|#each|else|/if|/each)(?:\s+([^{}]*?))?\s*\}\}`)
+
+const (
+	blockIf   = "if"
+	blockEach = "each"
+)
+
+// The variables of the item of an {{#each}} loop
+const (
+	itemVariable  = "this"
+	indexVariable = "@index"
+)
+
+const defaultFilter = "default"
+
+// templateNode is a text or an {{#if}} or {{#each}} block of a template
+type templateNode struct {
+	text string
+	// kind is blockIf or blockEach for blocks, empty for text
+	kind string
+	// expression is the variable of a block, with its filters
+	expression string
+	body       []*templateNode
+	// alt is the {{else}} part of a block
+	alt []*templateNode
+}
+
+// parseBlocks splits a template into texts and blocks. A block tag alone on its line takes
+// the line with it, so that blocks do not leave empty lines.
+func parseBlocks(content string) (ret []*templateNode, err error) {
+	type frame struct {
+		node  *templateNode
+		inAlt bool
+	}
+	root := &templateNode{}
+	stack := []*frame{{node: root}}
+	add := func(node *templateNode) {
+		top := stack[len(stack)-1]
+		if top.inAlt {
+			top.node.alt = append(top.node.alt, node)
+		} else {
+			top.node.body = append(top.node.body, node)
+		}
+	}
+
+	pos := 0
+	for _, loc := range blockTagPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := standaloneTag(content, loc[0], loc[1])
+		if start > pos {
+			add(&templateNode{text: content[pos:start]})
+		}
+		pos = end
+
+		tag := content[loc[2]:loc[3]]
+		var expression string
+		if loc[4] >= 0 {
+			expression = strings.TrimSpace(content[loc[4]:loc[5]])
+		}
+		top := stack[len(stack)-1]
+		switch tag {
+		case "#if", "#each":
+			if expression == "" {
+				return nil, fmt.Errorf("template: {{%s}} needs a variable", tag)
+			}
+			node := &templateNode{kind: tag[1:], expression: expression}
+			add(node)
+			stack = append(stack, &frame{node: node})
+		case "else":
+			if len(stack) == 1 || top.inAlt {
+				return nil, fmt.Errorf("template: {{else}} outside of {{#if}} or {{#each}}")
+			}
+			top.inAlt = true
+		default:
+			if len(stack) == 1 || top.node.kind != tag[1:] {
+				return nil, fmt.Errorf("template: {{%s}} without {{#%s}}", tag, tag[1:])
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) > 1 {
+		top := stack[len(stack)-1].node
+		return nil, fmt.Errorf("template: {{#%s %s}} is not closed with {{/%s}}", top.kind, top.expression, top.kind)
+	}
+	if pos < len(content) {
+		add(&templateNode{text: content[pos:]})
+	}
+	ret = root.body
+	return
+}
+
+// standaloneTag widens the tag at start:end to its whole line when nothing else is on the line
+func standaloneTag(content string, start int, end int) (int, int) {
+	lineStart := start
+	for lineStart > 0 && (content[lineStart-1] == ' ' || content[lineStart-1] == '\t') {
+		lineStart--
+	}
+	if lineStart > 0 && content[lineStart-1] != '\n' {
+		return start, end
+	}
+	lineEnd := end
+	for lineEnd < len(content) && (content[lineEnd] == ' ' || content[lineEnd] == '\t') {
+		lineEnd++
+	}
+	switch {
+	case lineEnd == len(content):
+		return lineStart, lineEnd
+	case content[lineEnd] == '\n':
+		return lineStart, lineEnd + 1
+	case strings.HasPrefix(content[lineEnd:], "\r\n"):
+		return lineStart, lineEnd + 2
+	}
+	return start, end
+}
+
+// renderBlocks renders the conditions and loops of a template. The variables of the items of
+// loops are replaced in their bodies, other references are left to ApplyTemplate.
+func renderBlocks(nodes []*templateNode, variables map[string]string, item map[string]string) (ret string, err error) {
+	lookup := func(name string) (value string, ok bool) {
+		if value, ok = item[name]; !ok {
+			value, ok = variables[name]
+		}
+		return
+	}
+
+	var builder strings.Builder
+	for _, node := range nodes {
+		var rendered string
+		switch node.kind {
+		case blockIf:
+			var value string
+			var ok bool
+			if value, ok, err = evalExpression(node.expression, lookup); err != nil {
+				return
+			}
+			if ok && isTrue(value) {
+				rendered, err = renderBlocks(node.body, variables, item)
+			} else {
+				rendered, err = renderBlocks(node.alt, variables, item)
+			}
+		case blockEach:
+			var value string
+			if value, _, err = evalExpression(node.expression, lookup); err != nil {
+				return
+			}
+			items := splitList(value)
+			if len(items) == 0 {
+				rendered, err = renderBlocks(node.alt, variables, item)
+			}
+			for i, itemValue := range items {
+				var itemRendered string
+				if itemRendered, err = renderBlocks(node.body, variables,
+					map[string]string{itemVariable: itemValue, indexVariable: strconv.Itoa(i)}); err != nil {
+					return
+				}
+				rendered += itemRendered
+			}
+		default:
+			rendered, err = replaceItemVariables(node.text, item)
+		}
+		if err != nil {
+			return
+		}
+		builder.WriteString(rendered)
+	}
+	ret = builder.String()
+	return
+}
+
+// replaceItemVariables replaces {{this}} and {{@index}} in the body of a loop
+func replaceItemVariables(text string, item map[string]string) (ret string, err error) {
+	if item == nil {
+		return text, nil
+	}
+	ret = tokenPattern.ReplaceAllStringFunc(text, func(token string) string {
+		expression := token[2 : len(token)-2]
+		if name, _ := parseExpression(expression); name != itemVariable && name != indexVariable {
+			return token
+		}
+		value, _, evalErr := evalExpression(expression, func(name string) (string, bool) {
+			value, ok := item[name]
+			return value, ok
+		})
+		if evalErr != nil && err == nil {
+			err = evalErr
+		}
+		return value
+	})
+	return
+}
+
+// isTrue tells whether the value of a variable makes an {{#if}} true: set, not empty and not "false"
+func isTrue(value string) bool {
+	value = strings.TrimSpace(value)
+	return value != "" && !strings.EqualFold(value, "false")
+}
+
+// splitList splits the value of an {{#each}} variable into its items, one per line or, in a
+// single line, separated by commas
+func splitList(value string) (ret []string) {
+	separator := ","
+	if strings.Contains(value, "\n") {
+		separator = "\n"
+	}
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return
+}
+
+// templateFilter is a filter of an expression like {{name|default:"x"|upper}}
+type templateFilter struct {
+	name   string
+	arg    string
+	hasArg bool
+}
+
+// parseExpression splits an expression into its variable and filters, separated by | outside
+// of quotes
+func parseExpression(expression string) (name string, filters []*templateFilter) {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, char := range expression {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '|':
+			parts = append(parts, expression[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, expression[start:])
+
+	name = strings.TrimSpace(parts[0])
+	for _, part := range parts[1:] {
+		filterName, arg, hasArg := strings.Cut(strings.TrimSpace(part), ":")
+		filters = append(filters, &templateFilter{name: strings.TrimSpace(filterName), arg: unquote(strings.TrimSpace(arg)), hasArg: hasArg})
+	}
+	return
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// hasDefault tells whether an expression has a default, so that its variable is optional
+func hasDefault(filters []*templateFilter) bool {
+	return slices.ContainsFunc(filters, func(filter *templateFilter) bool { return filter.name == defaultFilter })
+}
+
+// evalExpression looks up the variable of an expression and applies its filters in order.
+// default replaces a missing or empty value, the other filters are the text plugin operations
+// and leave empty values empty. ok is false when the variable is missing and has no default.
+func evalExpression(expression string, lookup func(string) (string, bool)) (ret string, ok bool, err error) {
+	name, filters := parseExpression(expression)
+	ret, ok = lookup(name)
+	for _, filter := range filters {
+		switch {
+		case filter.name == defaultFilter:
+			if !filter.hasArg {
+				return "", false, fmt.Errorf("template: the default filter of %s needs a value, like default:\"x\"", name)
+			}
+			if !ok || ret == "" {
+				ret, ok = filter.arg, true
+			}
+		case !slices.ContainsFunc(textPlugin.Operations(), func(operation *PluginOperation) bool { return operation.Name == filter.name }):
+			return "", false, fmt.Errorf("template: unknown filter %q of %s (supported: %s, %s)",
+				filter.name, name, defaultFilter, operationNames(textPlugin.Operations()))
+		case filter.hasArg:
+			return "", false, fmt.Errorf("template: the filter %s of %s takes no value", filter.name, name)
+		case ret != "":
+			if ret, err = textPlugin.Apply(filter.name, ret); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
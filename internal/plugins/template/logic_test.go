@@ -0,0 +1,158 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyTemplate_Conditions(t *testing.T) {
+	template := "Summarize the text.\n" +
+		"{{#if audience}}\n" +
+		"Write for {{audience}}.\n" +
+		"{{else}}\n" +
+		"Write for everyone.\n" +
+		"{{/if}}\n" +
+		"Done."
+
+	tests := []struct {
+		name      string
+		variables map[string]string
+		want      string
+	}{
+		{"set", map[string]string{"audience": "engineers"}, "Summarize the text.\nWrite for engineers.\nDone."},
+		{"missing", map[string]string{}, "Summarize the text.\nWrite for everyone.\nDone."},
+		{"empty", map[string]string{"audience": " "}, "Summarize the text.\nWrite for everyone.\nDone."},
+		{"false", map[string]string{"audience": "false"}, "Summarize the text.\nWrite for everyone.\nDone."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTemplate(template, tt.variables, "")
+			if err != nil {
+				t.Fatalf("ApplyTemplate returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTemplate_InlineAndNestedConditions(t *testing.T) {
+	template := "Tone: {{#if formal}}formal{{#if strict}}, strict{{/if}}{{else}}casual{{/if}}."
+
+	got, err := ApplyTemplate(template, map[string]string{"formal": "yes", "strict": "1"}, "")
+	if err != nil || got != "Tone: formal, strict." {
+		t.Errorf("got %q, %v", got, err)
+	}
+	got, err = ApplyTemplate(template, map[string]string{}, "")
+	if err != nil || got != "Tone: casual." {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestApplyTemplate_Loops(t *testing.T) {
+	template := "Cover:\n{{#each points}}\n{{@index}}. {{this|upper}} for {{role}}\n{{else}}\nnothing\n{{/each}}\nEnd"
+
+	got, err := ApplyTemplate(template, map[string]string{"points": "cost, speed,,risk", "role": "CTO"}, "")
+	if err != nil {
+		t.Fatalf("ApplyTemplate returned error: %v", err)
+	}
+	if want := "Cover:\n0. COST for CTO\n1. SPEED for CTO\n2. RISK for CTO\nEnd"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got, err = ApplyTemplate(template, map[string]string{"points": "a, b\nc"}, "")
+	if err == nil {
+		t.Errorf("expected the missing role to be reported, got %q", got)
+	}
+
+	got, err = ApplyTemplate(template, map[string]string{"role": "CTO"}, "")
+	if err != nil || got != "Cover:\nnothing\nEnd" {
+		t.Errorf("got %q, %v, want the else part for a missing list", got, err)
+	}
+}
+
+func TestApplyTemplate_LoopItemsOnLines(t *testing.T) {
+	got, err := ApplyTemplate("{{#each items}}- {{this}}{{#if this}}!{{/if}}\n{{/each}}", map[string]string{"items": "a, b\nc"}, "")
+	if err != nil {
+		t.Fatalf("ApplyTemplate returned error: %v", err)
+	}
+	if want := "- a, b!\n- c!\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestApplyTemplate_Filters(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  string
+		variables map[string]string
+		want      string
+	}{
+		{"default used", `{{lang|default:"English"}}`, map[string]string{}, "English"},
+		{"default of empty value", `{{lang|default:"English"}}`, map[string]string{"lang": ""}, "English"},
+		{"default not used", `{{lang|default:"English"}}`, map[string]string{"lang": "French"}, "French"},
+		{"chained", `{{name | trim | upper}}`, map[string]string{"name": "  ada  "}, "ADA"},
+		{"default then filter", `{{name|default:'x | y'|upper}}`, map[string]string{}, "X | Y"},
+		{"empty stays empty", `[{{name|upper}}]`, map[string]string{"name": ""}, "[]"},
+		{"input", `{{input|lower}}`, map[string]string{}, "loud"},
+		{"plugins still work", `{{plugin:text:upper:{{name|lower}}}}`, map[string]string{"name": "Ada"}, "ADA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTemplate(tt.template, tt.variables, "LOUD")
+			if err != nil {
+				t.Fatalf("ApplyTemplate returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTemplate_LogicErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{{#if a}}open", "is not closed"},
+		{"{{/if}}", "without {{#if}}"},
+		{"{{#each a}}{{/if}}", "without {{#if}}"},
+		{"{{else}}", "outside of"},
+		{"{{#if a}}{{else}}{{else}}{{/if}}", "outside of"},
+		{"{{name|shout}}", `unknown filter "shout"`},
+		{"{{name|upper:x}}", "takes no value"},
+		{"{{name|default}}", "needs a value"},
+		{"{{name|upper}}", "missing required variable: name"},
+	}
+	for _, tt := range tests {
+		_, err := ApplyTemplate(tt.template, map[string]string{"a": "x", "name": "Ada"}, "")
+		if tt.want == "missing required variable: name" {
+			_, err = ApplyTemplate(tt.template, map[string]string{}, "")
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error with %q, got %v", tt.template, tt.want, err)
+		}
+	}
+}
+
+func TestAnalyze_Logic(t *testing.T) {
+	analysis := Analyze("{{#if audience}}For {{audience}} and {{tone}}{{/if}}\n" +
+		"{{#each points}}{{this}} {{@index}}{{/each}}\n" +
+		"{{lang|default:\"en\"}} {{name|upper}} {{input|trim}}")
+
+	if want := []string{"audience", "tone", "points", "lang", "name"}; !reflect.DeepEqual(analysis.Variables, want) {
+		t.Errorf("expected the variables %v, got %v", want, analysis.Variables)
+	}
+	if want := []string{"audience", "tone", "points", "lang"}; !reflect.DeepEqual(analysis.Optional, want) {
+		t.Errorf("expected the optional variables %v, got %v", want, analysis.Optional)
+	}
+	if !analysis.UsesInput {
+		t.Error("expected the input to be found")
+	}
+	if got := analysis.Missing(map[string]string{}); !reflect.DeepEqual(got, []string{"name"}) {
+		t.Errorf("expected only name to be missing, got %v", got)
+	}
+}
@@ -0,0 +1,178 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PluginsConfigFile is the file in the config directory that defines template plugins
+const PluginsConfigFile = "template_plugins.yaml"
+
+// PluginsConfig defines template plugins without Go code:
+//
+//	plugins:
+//	  - namespace: persona
+//	    description: Reusable roles
+//	    operations:
+//	      - name: reviewer
+//	        args: LANGUAGE
+//	        help: A careful code reviewer
+//	        template: You are a careful reviewer of {{value|default:"Go"}} code.
+type PluginsConfig struct {
+	Plugins []*PluginDefinition `yaml:"plugins"`
+}
+
+// PluginDefinition defines a template plugin whose operations are templates, the value of a
+// call is their {{value}}
+type PluginDefinition struct {
+	Namespace   string                 `yaml:"namespace"`
+	Description string                 `yaml:"description"`
+	Operations  []*OperationDefinition `yaml:"operations"`
+}
+
+// OperationDefinition defines an operation of a template plugin from the config file
+type OperationDefinition struct {
+	PluginOperation `yaml:",inline"`
+	Template        string `yaml:"template"`
+}
+
+// validate checks the operations of the plugin
+func (o *PluginDefinition) validate() (err error) {
+	if !namespacePattern.MatchString(o.Namespace) {
+		return fmt.Errorf("invalid template plugin namespace %q, use letters, digits, - and _", o.Namespace)
+	}
+	if len(o.Operations) == 0 {
+		return fmt.Errorf("template plugin %s has no operations", o.Namespace)
+	}
+	names := map[string]bool{}
+	for _, operation := range o.Operations {
+		switch {
+		case operation.Name == "" || strings.Contains(operation.Name, ":"):
+			return fmt.Errorf("template plugin %s has an operation without a valid name", o.Namespace)
+		case names[operation.Name]:
+			return fmt.Errorf("template plugin %s has the operation %s twice", o.Namespace, operation.Name)
+		case operation.Template == "":
+			return fmt.Errorf("operation %s of template plugin %s has no template", operation.Name, o.Namespace)
+		}
+		names[operation.Name] = true
+	}
+	return
+}
+
+// configPlugin is the template plugin of a PluginDefinition
+type configPlugin struct {
+	definition *PluginDefinition
+}
+
+func (o *configPlugin) Namespace() string { return o.definition.Namespace }
+
+func (o *configPlugin) Description() string { return o.definition.Description }
+
+func (o *configPlugin) Operations() (ret []*PluginOperation) {
+	for _, operation := range o.definition.Operations {
+		description := operation.PluginOperation
+		ret = append(ret, &description)
+	}
+	return
+}
+
+func (o *configPlugin) Apply(operation string, value string) (ret string, err error) {
+	for _, candidate := range o.definition.Operations {
+		if candidate.Name == operation {
+			return ApplyTemplate(candidate.Template, map[string]string{"value": value}, "")
+		}
+	}
+	return "", fmt.Errorf("%s: unknown operation %q (supported: %s)", o.definition.Namespace, operation,
+		operationNames(o.Operations()))
+}
+
+// LoadConfig registers the plugins of a config file, replacing those loaded before. A missing
+// file defines no plugins.
+func (o *TemplatePluginRegistry) LoadConfig(path string) (err error) {
+	config := &PluginsConfig{}
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		if !os.IsNotExist(err) {
+			return
+		}
+		err = nil
+	} else if err = yaml.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("failed to parse template plugins config %s: %w", path, err)
+	}
+
+	definitions := map[string]*PluginDefinition{}
+	for _, definition := range config.Plugins {
+		if err = definition.validate(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if definitions[definition.Namespace] != nil {
+			return fmt.Errorf("%s: template plugin %s is defined twice", path, definition.Namespace)
+		}
+		definitions[definition.Namespace] = definition
+	}
+	if err = checkPluginCycles(config.Plugins, definitions); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, definition := range config.Plugins {
+		if o.plugins[definition.Namespace] != nil && !o.config[definition.Namespace] {
+			return fmt.Errorf("%s: template plugin namespace %s is already registered", path, definition.Namespace)
+		}
+	}
+	for namespace := range o.config {
+		delete(o.plugins, namespace)
+	}
+	o.config = map[string]bool{}
+	for _, definition := range config.Plugins {
+		if err = o.register(&configPlugin{definition: definition}); err != nil {
+			return
+		}
+		o.config[definition.Namespace] = true
+	}
+	return
+}
+
+// checkPluginCycles reports config plugins whose templates call each other, which would never end
+func checkPluginCycles(plugins []*PluginDefinition, definitions map[string]*PluginDefinition) (err error) {
+	calls := map[string][]string{}
+	for _, plugin := range plugins {
+		for _, operation := range plugin.Operations {
+			for _, call := range Analyze(operation.Template).Plugins {
+				if definitions[call.Name] != nil {
+					calls[plugin.Namespace] = append(calls[plugin.Namespace], call.Name)
+				}
+			}
+		}
+	}
+
+	done := map[string]bool{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		for i, parent := range path {
+			if parent == name {
+				return fmt.Errorf("template plugins call each other: %s -> %s", strings.Join(path[i:], " -> "), name)
+			}
+		}
+		if done[name] {
+			return nil
+		}
+		for _, callee := range calls[name] {
+			if err := visit(callee, append(path, name)); err != nil {
+				return err
+			}
+		}
+		done[name] = true
+		return nil
+	}
+	for _, plugin := range plugins {
+		if err = visit(plugin.Namespace, nil); err != nil {
+			return
+		}
+	}
+	return
+}
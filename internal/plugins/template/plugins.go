@@ -0,0 +1,180 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// TemplatePlugin handles the {{plugin:namespace:operation:value}} calls of a namespace
+type TemplatePlugin interface {
+	// Namespace is the namespace of the plugin in its calls, like text in {{plugin:text:upper:x}}
+	Namespace() string
+	// Description is the line about the plugin that --list-template-plugins shows
+	Description() string
+	// Operations are the operations of the plugin with their values and help
+	Operations() []*PluginOperation
+	Apply(operation string, value string) (string, error)
+}
+
+// VariablesPlugin is a template plugin whose values can refer to the variables of the template,
+// for values that cannot be written in a call, like JSON objects with braces
+type VariablesPlugin interface {
+	TemplatePlugin
+	ApplyVariables(operation string, value string, variables map[string]string) (string, error)
+}
+
+// PluginOperation describes an operation of a template plugin
+type PluginOperation struct {
+	Name string `json:"name" yaml:"name"`
+	// Args describes the value of the call, empty for operations without a value
+	Args string `json:"args,omitempty" yaml:"args"`
+	Help string `json:"help" yaml:"help"`
+}
+
+// PluginInfo describes a template plugin for listings and the REST API
+type PluginInfo struct {
+	Namespace   string             `json:"namespace"`
+	Description string             `json:"description"`
+	Operations  []*PluginOperation `json:"operations"`
+	// Config is set for plugins defined in the template plugins config file
+	Config bool `json:"config,omitempty"`
+}
+
+var namespacePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// TemplatePluginRegistry holds the template plugins by namespace
+type TemplatePluginRegistry struct {
+	mu      sync.RWMutex
+	plugins map[string]TemplatePlugin
+	// config are the namespaces of the plugins loaded from the config file
+	config map[string]bool
+}
+
+// NewTemplatePluginRegistry creates a registry with the built-in plugins
+func NewTemplatePluginRegistry() (ret *TemplatePluginRegistry) {
+	ret = &TemplatePluginRegistry{plugins: map[string]TemplatePlugin{}, config: map[string]bool{}}
+	for _, plugin := range []TemplatePlugin{textPlugin, datetimePlugin, filePlugin, fetchPlugin, sysPlugin, gitPlugin,
+		jsonPlugin, yamlPlugin, csvPlugin} {
+		if err := ret.Register(plugin); err != nil {
+			panic(err)
+		}
+	}
+	return
+}
+
+// Plugins is the registry ApplyTemplate calls plugins from. Go code adds namespaces with
+// Plugins.Register, the config file with Plugins.LoadConfig.
+var Plugins = NewTemplatePluginRegistry()
+
+// Register adds a plugin, its namespace must not be taken
+func (o *TemplatePluginRegistry) Register(plugin TemplatePlugin) (err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.register(plugin)
+}
+
+func (o *TemplatePluginRegistry) register(plugin TemplatePlugin) (err error) {
+	namespace := plugin.Namespace()
+	if !namespacePattern.MatchString(namespace) {
+		return fmt.Errorf("invalid template plugin namespace %q, use letters, digits, - and _", namespace)
+	}
+	if _, ok := o.plugins[namespace]; ok {
+		return fmt.Errorf("template plugin namespace %s is already registered", namespace)
+	}
+	o.plugins[namespace] = plugin
+	return
+}
+
+// Unregister removes the plugin of a namespace
+func (o *TemplatePluginRegistry) Unregister(namespace string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.plugins, namespace)
+	delete(o.config, namespace)
+}
+
+// Get returns the plugin of a namespace, nil if there is none
+func (o *TemplatePluginRegistry) Get(namespace string) TemplatePlugin {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.plugins[namespace]
+}
+
+// List describes the plugins, sorted by namespace
+func (o *TemplatePluginRegistry) List() (ret []*PluginInfo) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	ret = []*PluginInfo{}
+	for namespace, plugin := range o.plugins {
+		ret = append(ret, &PluginInfo{
+			Namespace:   namespace,
+			Description: plugin.Description(),
+			Operations:  plugin.Operations(),
+			Config:      o.config[namespace],
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Namespace < ret[j].Namespace })
+	return
+}
+
+// PrintPlugins prints the plugins with their operations
+func (o *TemplatePluginRegistry) PrintPlugins(out io.Writer) (err error) {
+	for i, plugin := range o.List() {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		source := ""
+		if plugin.Config {
+			source = " (config)"
+		}
+		fmt.Fprintf(out, "%s%s - %s\n", plugin.Namespace, source, plugin.Description)
+
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, operation := range plugin.Operations {
+			call := "{{plugin:" + plugin.Namespace + ":" + operation.Name
+			if operation.Args != "" {
+				call += ":" + operation.Args
+			}
+			fmt.Fprintf(writer, "  %s}}\t%s\n", call, operation.Help)
+		}
+		if err = writer.Flush(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// apply runs an operation of the plugin of a namespace, with the variables of the template for
+// plugins that refer to them
+func (o *TemplatePluginRegistry) apply(namespace string, operation string, value string,
+	variables map[string]string) (ret string, err error) {
+	plugin := o.Get(namespace)
+	if plugin == nil {
+		return "", fmt.Errorf("unknown plugin namespace: %s", namespace)
+	}
+	debugf("Executing %s plugin\n", namespace)
+	if variablesPlugin, ok := plugin.(VariablesPlugin); ok {
+		ret, err = variablesPlugin.ApplyVariables(operation, value, variables)
+	} else {
+		ret, err = plugin.Apply(operation, value)
+	}
+	if err != nil {
+		debugf("Plugin error: %v\n", err)
+		return "", fmt.Errorf("plugin %s error: %v", namespace, err)
+	}
+	return
+}
+
+// operationNames lists the names of operations for error messages
+func operationNames(operations []*PluginOperation) string {
+	names := make([]string, 0, len(operations))
+	for _, operation := range operations {
+		names = append(names, operation.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,161 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type reversePlugin struct{}
+
+func (p *reversePlugin) Namespace() string   { return "reverse" }
+func (p *reversePlugin) Description() string { return "Reverses text" }
+func (p *reversePlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{{Name: "text", Args: "TEXT", Help: "Reverses the text"}}
+}
+func (p *reversePlugin) Apply(operation string, value string) (string, error) {
+	if operation != "text" {
+		return "", fmt.Errorf("reverse: unknown operation %q", operation)
+	}
+	runes := []rune(value)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes), nil
+}
+
+func TestTemplatePluginRegistry_BuiltIns(t *testing.T) {
+	registry := NewTemplatePluginRegistry()
+	var namespaces []string
+	for _, plugin := range registry.List() {
+		namespaces = append(namespaces, plugin.Namespace)
+		if plugin.Description == "" || len(plugin.Operations) == 0 {
+			t.Errorf("expected plugin %s to describe itself", plugin.Namespace)
+		}
+	}
+	if got := strings.Join(namespaces, ","); got != "csv,datetime,fetch,file,git,json,sys,text,yaml" {
+		t.Errorf("unexpected built-in plugins %s", got)
+	}
+}
+
+func TestTemplatePluginRegistry_Register(t *testing.T) {
+	if err := Plugins.Register(&reversePlugin{}); err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	defer Plugins.Unregister("reverse")
+
+	if err := Plugins.Register(&reversePlugin{}); err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected a duplicate namespace to be refused, got %v", err)
+	}
+
+	got, err := ApplyTemplate("{{plugin:reverse:text:{{name}}}}", map[string]string{"name": "fabric"}, "")
+	if err != nil {
+		t.Fatalf("ApplyTemplate returned error: %v", err)
+	}
+	if got != "cirbaf" {
+		t.Errorf("got %q, want %q", got, "cirbaf")
+	}
+
+	_, err = ApplyTemplate("{{plugin:reverse:words:a b}}", nil, "")
+	if err == nil || !strings.Contains(err.Error(), "plugin reverse error") {
+		t.Errorf("expected the plugin error, got %v", err)
+	}
+}
+
+func TestTemplatePluginRegistry_PrintPlugins(t *testing.T) {
+	registry := NewTemplatePluginRegistry()
+	var out bytes.Buffer
+	if err := registry.PrintPlugins(&out); err != nil {
+		t.Fatalf("PrintPlugins returned error: %v", err)
+	}
+	for _, want := range []string{"text - String manipulation", "{{plugin:text:upper:TEXT}}", "{{plugin:datetime:now}}"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the listing:\n%s", want, out.String())
+		}
+	}
+}
+
+func writePluginsConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), PluginsConfigFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTemplatePluginRegistry_LoadConfig(t *testing.T) {
+	registry := NewTemplatePluginRegistry()
+	path := writePluginsConfig(t, `plugins:
+  - namespace: persona
+    description: Reusable roles
+    operations:
+      - name: reviewer
+        args: LANGUAGE
+        help: A careful code reviewer
+        template: You review {{value|default:"Go"}} code, {{plugin:text:lower:CAREFULLY}}.
+`)
+	if err := registry.LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	plugin := registry.Get("persona")
+	if plugin == nil {
+		t.Fatal("expected the persona plugin")
+	}
+	if got, err := plugin.Apply("reviewer", ""); err != nil || got != "You review Go code, carefully." {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if got, err := plugin.Apply("reviewer", "Rust"); err != nil || got != "You review Rust code, carefully." {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := plugin.Apply("writer", ""); err == nil || !strings.Contains(err.Error(), "supported: reviewer") {
+		t.Errorf("expected an unknown operation error, got %v", err)
+	}
+
+	infos := registry.List()
+	var found bool
+	for _, info := range infos {
+		if info.Namespace == "persona" {
+			found = info.Config && info.Operations[0].Args == "LANGUAGE"
+		}
+	}
+	if !found {
+		t.Errorf("expected persona to be listed as config plugin, got %+v", infos)
+	}
+
+	// Loading again replaces the plugins of the config
+	if err := registry.LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Fatalf("a missing config should define no plugins, got %v", err)
+	}
+	if registry.Get("persona") != nil {
+		t.Error("expected the persona plugin to be removed")
+	}
+}
+
+func TestTemplatePluginRegistry_LoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"built-in namespace", "plugins:\n  - namespace: text\n    operations:\n      - {name: x, template: y}\n", "already registered"},
+		{"invalid namespace", "plugins:\n  - namespace: a:b\n    operations:\n      - {name: x, template: y}\n", "invalid template plugin namespace"},
+		{"no operations", "plugins:\n  - namespace: a\n", "has no operations"},
+		{"no template", "plugins:\n  - namespace: a\n    operations:\n      - {name: x}\n", "has no template"},
+		{"twice", "plugins:\n  - namespace: a\n    operations:\n      - {name: x, template: y}\n  - namespace: a\n    operations:\n      - {name: x, template: y}\n", "defined twice"},
+		{"cycle", "plugins:\n  - namespace: a\n    operations:\n      - {name: x, template: '{{plugin:b:x}}'}\n  - namespace: b\n    operations:\n      - {name: x, template: '{{plugin:a:x}}'}\n", "a -> b -> a"},
+		{"invalid yaml", "plugins: [", "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewTemplatePluginRegistry().LoadConfig(writePluginsConfig(t, tt.config))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error with %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
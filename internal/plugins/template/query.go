@@ -0,0 +1,414 @@
+// Package template provides the data sources and queries of the json and yaml plugins.
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dataSource returns the data a value refers to. Values like JSON objects cannot be written
+// in a call, because of their braces, so they can be read from elsewhere:
+//   - file:PATH - A file, with the limits of the file plugin
+//   - var:NAME - A variable of the template
+//   - anything else is the data itself
+func dataSource(value string, variables map[string]string) (ret string, err error) {
+	if path, ok := strings.CutPrefix(value, "file:"); ok {
+		return filePlugin.Apply("read", path)
+	}
+	if name, ok := strings.CutPrefix(value, "var:"); ok {
+		var found bool
+		if ret, found = variables[name]; !found {
+			return "", fmt.Errorf("variable %s is not set", name)
+		}
+		if len(ret) > MaxFileSize {
+			return "", fmt.Errorf("variable %s exceeds limit of %d bytes", name, MaxFileSize)
+		}
+		return
+	}
+	return value, nil
+}
+
+// splitQuery splits a SOURCE|QUERY value at the last |, queries have no |
+func splitQuery(value string) (source string, query string, err error) {
+	index := strings.LastIndex(value, "|")
+	if index < 0 {
+		return "", "", fmt.Errorf("query requires format SOURCE|QUERY")
+	}
+	return value[:index], value[index+1:], nil
+}
+
+// parseJSON parses JSON into a YAML node, which keeps the order of the keys of objects
+func parseJSON(data string) (ret *yaml.Node, err error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if ret, err = parseJSONValue(decoder); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if _, err = decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after the value")
+	}
+	return ret, nil
+}
+
+func parseJSONValue(decoder *json.Decoder) (ret *yaml.Node, err error) {
+	var token json.Token
+	if token, err = decoder.Token(); err != nil {
+		return
+	}
+	switch value := token.(type) {
+	case json.Delim:
+		ret = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if value == '{' {
+			ret = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for decoder.More() {
+			if ret.Kind == yaml.MappingNode {
+				var key json.Token
+				if key, err = decoder.Token(); err != nil {
+					return
+				}
+				ret.Content = append(ret.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			var item *yaml.Node
+			if item, err = parseJSONValue(decoder); err != nil {
+				return
+			}
+			ret.Content = append(ret.Content, item)
+		}
+		// The closing delimiter
+		_, err = decoder.Token()
+	case string:
+		ret = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		ret = &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}
+	case bool:
+		ret = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)}
+	case nil:
+		ret = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	return
+}
+
+// parseYAML parses the first document of YAML
+func parseYAML(data string) (ret *yaml.Node, err error) {
+	document := &yaml.Node{}
+	if err = yaml.Unmarshal([]byte(data), document); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %v", err)
+	}
+	if ret = resolveNode(document); ret == nil {
+		ret = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	return
+}
+
+// resolveNode returns the content of documents and the target of aliases
+func resolveNode(node *yaml.Node) *yaml.Node {
+	for node != nil {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			if len(node.Content) == 0 {
+				return nil
+			}
+			node = node.Content[0]
+		case yaml.AliasNode:
+			node = node.Alias
+		default:
+			return node
+		}
+	}
+	return nil
+}
+
+// queryStep is a step of a query, a field, an index or all items
+type queryStep struct {
+	field string
+	index int
+	kind  queryStepKind
+}
+
+type queryStepKind int
+
+const (
+	queryField queryStepKind = iota
+	queryIndex
+	queryAll
+)
+
+// parseQuery parses a path query in the style of jq or JSONPath:
+//   - .name or ["name"] - A field of an object
+//   - [N] - An item of an array, negative indexes count from the end
+//   - [] or [*] or .* - All items of an array or values of an object
+//
+// A leading $ is optional, . alone is the whole data.
+func parseQuery(query string) (ret []queryStep, err error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(query), "$")
+	for rest != "" && rest != "." {
+		switch {
+		case strings.HasPrefix(rest, ".*"):
+			ret = append(ret, queryStep{kind: queryAll})
+			rest = rest[2:]
+		case strings.HasPrefix(rest, ".["):
+			rest = rest[1:]
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid query %q: missing field name", query)
+			}
+			ret = append(ret, queryStep{kind: queryField, field: rest[1 : end+1]})
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			var step queryStep
+			if step, rest, err = parseQueryBracket(rest); err != nil {
+				return nil, fmt.Errorf("invalid query %q: %v", query, err)
+			}
+			ret = append(ret, step)
+		default:
+			return nil, fmt.Errorf("invalid query %q: expected . or [ before %q", query, rest)
+		}
+	}
+	return
+}
+
+// parseQueryBracket parses a [...] step at the start of a query
+func parseQueryBracket(query string) (ret queryStep, rest string, err error) {
+	if len(query) > 1 && (query[1] == '"' || query[1] == '\'') {
+		end := strings.IndexByte(query[2:], query[1])
+		if end < 0 || !strings.HasPrefix(query[end+3:], "]") {
+			return ret, "", fmt.Errorf("unclosed field name")
+		}
+		return queryStep{kind: queryField, field: query[2 : end+2]}, query[end+4:], nil
+	}
+	end := strings.IndexByte(query, ']')
+	if end < 0 {
+		return ret, "", fmt.Errorf("missing ]")
+	}
+	inner := strings.TrimSpace(query[1:end])
+	rest = query[end+1:]
+	if inner == "" || inner == "*" {
+		return queryStep{kind: queryAll}, rest, nil
+	}
+	if ret.index, err = strconv.Atoi(inner); err != nil {
+		return ret, "", fmt.Errorf("invalid index %q", inner)
+	}
+	ret.kind = queryIndex
+	return
+}
+
+// queryNodes runs a query, missing fields and indexes have no results
+func queryNodes(root *yaml.Node, query string) (ret []*yaml.Node, err error) {
+	var steps []queryStep
+	if steps, err = parseQuery(query); err != nil {
+		return
+	}
+	ret = []*yaml.Node{resolveNode(root)}
+	for _, step := range steps {
+		var next []*yaml.Node
+		for _, node := range ret {
+			if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+				continue
+			}
+			switch {
+			case step.kind == queryField && node.Kind == yaml.MappingNode:
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == step.field {
+						next = append(next, resolveNode(node.Content[i+1]))
+					}
+				}
+			case step.kind == queryIndex && node.Kind == yaml.SequenceNode:
+				index := step.index
+				if index < 0 {
+					index += len(node.Content)
+				}
+				if index >= 0 && index < len(node.Content) {
+					next = append(next, resolveNode(node.Content[index]))
+				}
+			case step.kind == queryAll && node.Kind == yaml.SequenceNode:
+				for _, item := range node.Content {
+					next = append(next, resolveNode(item))
+				}
+			case step.kind == queryAll && node.Kind == yaml.MappingNode:
+				for i := 1; i < len(node.Content); i += 2 {
+					next = append(next, resolveNode(node.Content[i]))
+				}
+			default:
+				return nil, fmt.Errorf("query %q: cannot get %s of %s", query, step, nodeKindName(node))
+			}
+		}
+		ret = next
+	}
+	return
+}
+
+func (o queryStep) String() string {
+	switch o.kind {
+	case queryField:
+		return fmt.Sprintf("field %q", o.field)
+	case queryIndex:
+		return fmt.Sprintf("index %d", o.index)
+	default:
+		return "all items"
+	}
+}
+
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "an object"
+	case yaml.SequenceNode:
+		return "an array"
+	default:
+		return "a value"
+	}
+}
+
+// formatResults joins the results of a query line by line, values as they are and objects and
+// arrays in the format of the plugin
+func formatResults(nodes []*yaml.Node, format func(*yaml.Node) (string, error)) (ret string, err error) {
+	results := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		result := node.Value
+		if node.Kind != yaml.ScalarNode {
+			if result, err = format(node); err != nil {
+				return
+			}
+		} else if node.ShortTag() == "!!null" {
+			result = "null"
+		}
+		results = append(results, result)
+	}
+	ret = strings.Join(results, "\n")
+	return
+}
+
+// detachNode copies a node with its aliases replaced by what they refer to and without anchors,
+// for results that are written without the rest of the YAML
+func detachNode(node *yaml.Node) *yaml.Node {
+	node = resolveNode(node)
+	if node == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	ret := *node
+	ret.Anchor = ""
+	ret.Content = make([]*yaml.Node, 0, len(node.Content))
+	for _, child := range node.Content {
+		ret.Content = append(ret.Content, detachNode(child))
+	}
+	return &ret
+}
+
+// formatJSON writes a node as indented JSON
+func formatJSON(node *yaml.Node) (ret string, err error) {
+	var compact, indented bytes.Buffer
+	if err = writeJSON(&compact, node); err != nil {
+		return
+	}
+	if err = json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return
+	}
+	ret = indented.String()
+	return
+}
+
+func writeJSON(buffer *bytes.Buffer, node *yaml.Node) (err error) {
+	if node = resolveNode(node); node == nil {
+		buffer.WriteString("null")
+		return
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		buffer.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			writeJSONString(buffer, node.Content[i].Value)
+			buffer.WriteByte(':')
+			if err = writeJSON(buffer, node.Content[i+1]); err != nil {
+				return
+			}
+		}
+		buffer.WriteByte('}')
+	case yaml.SequenceNode:
+		buffer.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err = writeJSON(buffer, item); err != nil {
+				return
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		switch node.ShortTag() {
+		case "!!null":
+			buffer.WriteString("null")
+		case "!!bool", "!!int", "!!float":
+			// Numbers are kept as written when they are valid JSON, others like 0x1F converted
+			if json.Valid([]byte(node.Value)) {
+				buffer.WriteString(node.Value)
+				return
+			}
+			var value any
+			if err = node.Decode(&value); err != nil {
+				return
+			}
+			var data []byte
+			if data, err = json.Marshal(value); err != nil {
+				return fmt.Errorf("cannot convert %s to JSON: %v", node.Value, err)
+			}
+			buffer.Write(data)
+		default:
+			writeJSONString(buffer, node.Value)
+		}
+	}
+	return
+}
+
+func writeJSONString(buffer *bytes.Buffer, value string) {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	// Encoding a string cannot fail
+	_ = encoder.Encode(value)
+	// Encode ends with a newline
+	buffer.Truncate(buffer.Len() - 1)
+}
+
+// formatYAML writes a node as block style YAML with an indentation of two spaces
+func formatYAML(node *yaml.Node) (ret string, err error) {
+	blockStyle(node)
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err = encoder.Encode(node); err != nil {
+		return
+	}
+	if err = encoder.Close(); err != nil {
+		return
+	}
+	ret = strings.TrimSuffix(buffer.String(), "\n")
+	return
+}
+
+// blockStyle writes objects and arrays on their own lines, like {"a": [1]} as a:\n  - 1
+func blockStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style &^= yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
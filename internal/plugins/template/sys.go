@@ -13,6 +13,22 @@ import (
 // environment variables. Be cautious with exposed variables in templates.
 type SysPlugin struct{}
 
+func (p *SysPlugin) Namespace() string { return "sys" }
+
+func (p *SysPlugin) Description() string { return "System information" }
+
+func (p *SysPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "hostname", Help: "Host name"},
+		{Name: "user", Help: "Current user name"},
+		{Name: "os", Help: "Operating system, like linux, darwin or windows"},
+		{Name: "arch", Help: "Architecture, like amd64 or arm64"},
+		{Name: "env", Args: "NAME", Help: "Value of the environment variable"},
+		{Name: "pwd", Help: "Current working directory"},
+		{Name: "home", Help: "Home directory of the user"},
+	}
+}
+
 // Apply executes system operations with the following options:
 //   - hostname: System hostname
 //   - user: Current username
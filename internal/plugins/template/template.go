@@ -14,6 +14,10 @@ var (
 	filePlugin     = &FilePlugin{}
 	fetchPlugin    = &FetchPlugin{}
 	sysPlugin      = &SysPlugin{}
+	gitPlugin      = &GitPlugin{}
+	jsonPlugin     = &JSONPlugin{}
+	yamlPlugin     = &YAMLPlugin{}
+	csvPlugin      = &CSVPlugin{}
 	Debug          = false // Debug flag
 )
 
@@ -40,206 +44,124 @@ func debugf(format string, a ...interface{}) {
 
 func ApplyTemplate(content string, variables map[string]string, input string) (string, error) {
 
-	var missingVars []string
-	r := regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+	// Conditions and loops are rendered first, so that the variables of the branches that are
+	// left out are not needed
+	nodes, err := parseBlocks(content)
+	if err != nil {
+		return "", err
+	}
+	if content, err = renderBlocks(nodes, variables, nil); err != nil {
+		return "", err
+	}
+
+	// Report all missing variables at once instead of the first one that is reached
+	if missing := Analyze(content).Missing(variables); len(missing) > 0 {
+		return "", &MissingVariablesError{Names: missing}
+	}
 
 	debugf("Starting template processing\n")
+	// Innermost tokens are resolved first, so that their results become part of the tokens
+	// around them, like the variable in {{plugin:text:upper:{{name}}}}
 	for strings.Contains(content, "{{") {
-		matches := r.FindAllStringSubmatch(content, -1)
+		matches := tokenPattern.FindAllStringSubmatch(content, -1)
 		if len(matches) == 0 {
 			break
 		}
 
-		replaced := false
 		for _, match := range matches {
-			fullMatch := match[0]
-			varName := match[1]
-
-			// Check if this is a plugin call
-			if strings.HasPrefix(varName, "plugin:") {
-				pluginMatches := pluginPattern.FindStringSubmatch(fullMatch)
-				if len(pluginMatches) >= 3 {
-					namespace := pluginMatches[1]
-					operation := pluginMatches[2]
-					value := ""
-					if len(pluginMatches) == 4 {
-						value = pluginMatches[3]
-					}
-
-					debugf("\nPlugin call:\n")
-					debugf("  Namespace: %s\n", namespace)
-					debugf("  Operation: %s\n", operation)
-					debugf("  Value: %s\n", value)
-
-					var result string
-					var err error
-
-					switch namespace {
-					case "text":
-						debugf("Executing text plugin\n")
-						result, err = textPlugin.Apply(operation, value)
-					case "datetime":
-						debugf("Executing datetime plugin\n")
-						result, err = datetimePlugin.Apply(operation, value)
-					case "file":
-						debugf("Executing file plugin\n")
-						result, err = filePlugin.Apply(operation, value)
-						debugf("File plugin result: %#v\n", result)
-					case "fetch":
-						debugf("Executing fetch plugin\n")
-						result, err = fetchPlugin.Apply(operation, value)
-					case "sys":
-						debugf("Executing sys plugin\n")
-						result, err = sysPlugin.Apply(operation, value)
-					default:
-						return "", fmt.Errorf("unknown plugin namespace: %s", namespace)
-					}
-
-					if err != nil {
-						debugf("Plugin error: %v\n", err)
-						return "", fmt.Errorf("plugin %s error: %v", namespace, err)
-					}
-
-					debugf("Plugin result: %s\n", result)
-					content = strings.ReplaceAll(content, fullMatch, result)
-					debugf("Content after replacement: %s\n", content)
-					continue
-				}
+			result, err := resolveToken(match[1], variables, input)
+			if err != nil {
+				return "", err
 			}
+			content = strings.ReplaceAll(content, match[0], result)
+			debugf("Content after replacement: %s\n", content)
+		}
+	}
 
-			if pluginMatches := extensionPattern.FindStringSubmatch(fullMatch); len(pluginMatches) >= 3 {
-				name := pluginMatches[1]
-				operation := pluginMatches[2]
-				value := ""
-				if len(pluginMatches) == 4 {
-					value = pluginMatches[3]
-				}
-
-				debugf("\nExtension call:\n")
-				debugf("  Name: %s\n", name)
-				debugf("  Operation: %s\n", operation)
-				debugf("  Value: %s\n", value)
-
-				result, err := extensionManager.ProcessExtension(name, operation, value)
-				if err != nil {
-					return "", fmt.Errorf("extension %s error: %v", name, err)
-				}
-
-				content = strings.ReplaceAll(content, fullMatch, result)
-				replaced = true
-				continue
-			}
+	debugf("Template processing complete\n")
+	return content, nil
+}
 
-			// Handle regular variables and input
-			debugf("Processing variable: %s\n", varName)
-			if varName == "input" {
-				debugf("Replacing {{input}}\n")
-				replaced = true
-				content = strings.ReplaceAll(content, fullMatch, input)
-			} else {
-				if val, ok := variables[varName]; !ok {
-					debugf("Missing variable: %s\n", varName)
-					missingVars = append(missingVars, varName)
-					return "", fmt.Errorf("missing required variable: %s", varName)
-				} else {
-					debugf("Replacing variable %s with value: %s\n", varName, val)
-					content = strings.ReplaceAll(content, fullMatch, val)
-					replaced = true
-				}
+// resolveToken returns what a {{token}} without nested tokens is replaced by: the result of a
+// plugin or extension call, a variable with filters, a variable or the input
+func resolveToken(token string, variables map[string]string, input string) (string, error) {
+	fullMatch := "{{" + token + "}}"
+
+	// Check if this is a plugin call
+	if strings.HasPrefix(token, "plugin:") {
+		if pluginMatches := pluginPattern.FindStringSubmatch(fullMatch); len(pluginMatches) >= 3 {
+			namespace := pluginMatches[1]
+			operation := pluginMatches[2]
+			value := ""
+			if len(pluginMatches) == 4 {
+				value = pluginMatches[3]
 			}
-			if !replaced {
-				return "", fmt.Errorf("template processing stuck - potential infinite loop")
+
+			debugf("\nPlugin call:\n")
+			debugf("  Namespace: %s\n", namespace)
+			debugf("  Operation: %s\n", operation)
+			debugf("  Value: %s\n", value)
+
+			result, err := Plugins.apply(namespace, operation, value, variables)
+			if err != nil {
+				return "", err
 			}
+			debugf("Plugin result: %s\n", result)
+			return result, nil
 		}
 	}
 
-	debugf("Starting template processing\n")
-	for strings.Contains(content, "{{") {
-		matches := r.FindAllStringSubmatch(content, -1)
-		if len(matches) == 0 {
-			break
+	if extensionMatches := extensionPattern.FindStringSubmatch(fullMatch); len(extensionMatches) >= 3 {
+		name := extensionMatches[1]
+		operation := extensionMatches[2]
+		value := ""
+		if len(extensionMatches) == 4 {
+			value = extensionMatches[3]
 		}
 
-		replaced := false
-		for _, match := range matches {
-			fullMatch := match[0]
-			varName := match[1]
-
-			// Check if this is a plugin call
-			if strings.HasPrefix(varName, "plugin:") {
-				pluginMatches := pluginPattern.FindStringSubmatch(fullMatch)
-				if len(pluginMatches) >= 3 {
-					namespace := pluginMatches[1]
-					operation := pluginMatches[2]
-					value := ""
-					if len(pluginMatches) == 4 {
-						value = pluginMatches[3]
-					}
-
-					debugf("\nPlugin call:\n")
-					debugf("  Namespace: %s\n", namespace)
-					debugf("  Operation: %s\n", operation)
-					debugf("  Value: %s\n", value)
-
-					var result string
-					var err error
-
-					switch namespace {
-					case "text":
-						debugf("Executing text plugin\n")
-						result, err = textPlugin.Apply(operation, value)
-					case "datetime":
-						debugf("Executing datetime plugin\n")
-						result, err = datetimePlugin.Apply(operation, value)
-					case "file":
-						debugf("Executing file plugin\n")
-						result, err = filePlugin.Apply(operation, value)
-						debugf("File plugin result: %#v\n", result)
-					case "fetch":
-						debugf("Executing fetch plugin\n")
-						result, err = fetchPlugin.Apply(operation, value)
-					case "sys":
-						debugf("Executing sys plugin\n")
-						result, err = sysPlugin.Apply(operation, value)
-					default:
-						return "", fmt.Errorf("unknown plugin namespace: %s", namespace)
-					}
-
-					if err != nil {
-						debugf("Plugin error: %v\n", err)
-						return "", fmt.Errorf("plugin %s error: %v", namespace, err)
-					}
-
-					debugf("Plugin result: %s\n", result)
-					content = strings.ReplaceAll(content, fullMatch, result)
-					debugf("Content after replacement: %s\n", content)
-					continue
-				}
-			}
+		debugf("\nExtension call:\n")
+		debugf("  Name: %s\n", name)
+		debugf("  Operation: %s\n", operation)
+		debugf("  Value: %s\n", value)
 
-			// Handle regular variables and input
-			debugf("Processing variable: %s\n", varName)
-			if varName == "input" {
-				debugf("Replacing {{input}}\n")
-				replaced = true
-				content = strings.ReplaceAll(content, fullMatch, input)
-			} else {
-				if val, ok := variables[varName]; !ok {
-					debugf("Missing variable: %s\n", varName)
-					missingVars = append(missingVars, varName)
-					return "", fmt.Errorf("missing required variable: %s", varName)
-				} else {
-					debugf("Replacing variable %s with value: %s\n", varName, val)
-					content = strings.ReplaceAll(content, fullMatch, val)
-					replaced = true
-				}
-			}
-			if !replaced {
-				return "", fmt.Errorf("template processing stuck - potential infinite loop")
+		result, err := extensionManager.ProcessExtension(name, operation, value)
+		if err != nil {
+			return "", fmt.Errorf("extension %s error: %v", name, err)
+		}
+		return result, nil
+	}
+
+	// Handle variables with filters, like {{name|default:"x"|upper}}
+	if strings.Contains(token, "|") {
+		debugf("Processing expression: %s\n", token)
+		value, ok, err := evalExpression(token, func(name string) (string, bool) {
+			if name == "input" {
+				return input, true
 			}
+			value, ok := variables[name]
+			return value, ok
+		})
+		if err != nil {
+			return "", err
 		}
+		if !ok {
+			name, _ := parseExpression(token)
+			return "", &MissingVariablesError{Names: []string{name}}
+		}
+		return value, nil
 	}
 
-	debugf("Template processing complete\n")
-	return content, nil
+	// Handle regular variables and input
+	debugf("Processing variable: %s\n", token)
+	if token == "input" {
+		debugf("Replacing {{input}}\n")
+		return input, nil
+	}
+	val, ok := variables[token]
+	if !ok {
+		debugf("Missing variable: %s\n", token)
+		return "", &MissingVariablesError{Names: []string{token}}
+	}
+	debugf("Replacing variable %s with value: %s\n", token, val)
+	return val, nil
 }
@@ -10,6 +10,19 @@ import (
 // TextPlugin provides string manipulation operations
 type TextPlugin struct{}
 
+func (p *TextPlugin) Namespace() string { return "text" }
+
+func (p *TextPlugin) Description() string { return "String manipulation" }
+
+func (p *TextPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "upper", Args: "TEXT", Help: "Converts the text to upper case"},
+		{Name: "lower", Args: "TEXT", Help: "Converts the text to lower case"},
+		{Name: "title", Args: "TEXT", Help: "Capitalizes the words of the text"},
+		{Name: "trim", Args: "TEXT", Help: "Removes the whitespace around the text"},
+	}
+}
+
 // toTitle capitalizes a letter if it follows a non-letter, unless next char is space
 func toTitle(s string) string {
 	// First lowercase everything
@@ -59,6 +72,6 @@ func (p *TextPlugin) Apply(operation string, value string) (string, error) {
 		return result, nil
 
 	default:
-		return "", fmt.Errorf("text: unknown text operation %q (supported: upper, lower, title, trim)", operation)
+		return "", fmt.Errorf("text: unknown text operation %q (supported: %s)", operation, operationNames(p.Operations()))
 	}
 }
@@ -0,0 +1,79 @@
+// Package template provides YAML queries and conversions for the template system.
+package template
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLPlugin queries, pretty-prints and converts YAML. Its values are the YAML itself, a
+// file:PATH or a var:NAME, see dataSource.
+type YAMLPlugin struct{}
+
+func (p *YAMLPlugin) Namespace() string { return "yaml" }
+
+func (p *YAMLPlugin) Description() string {
+	return "YAML of values, file:PATH or var:NAME"
+}
+
+func (p *YAMLPlugin) Operations() []*PluginOperation {
+	return []*PluginOperation{
+		{Name: "query", Args: "SOURCE|QUERY", Help: "Values at a path like .items[0].name or $.items[*].name, one per line"},
+		{Name: "pretty", Args: "SOURCE", Help: "YAML in block style indented by two spaces"},
+		{Name: "tojson", Args: "SOURCE", Help: "Converts YAML to indented JSON"},
+	}
+}
+
+// Apply executes YAML operations on data written in the call
+func (p *YAMLPlugin) Apply(operation string, value string) (string, error) {
+	return p.ApplyVariables(operation, value, nil)
+}
+
+// ApplyVariables executes YAML operations on the first document of the YAML:
+//   - query:SOURCE|QUERY - Values at a path, objects and arrays as YAML
+//   - pretty:SOURCE - Block style YAML
+//   - tojson:SOURCE - YAML converted to JSON
+func (p *YAMLPlugin) ApplyVariables(operation string, value string, variables map[string]string) (ret string, err error) {
+	debugf("YAML: operation=%q value=%q", operation, value)
+
+	source, query := value, ""
+	switch operation {
+	case "query":
+		if source, query, err = splitQuery(value); err != nil {
+			return "", fmt.Errorf("yaml: %v", err)
+		}
+	case "pretty", "tojson":
+	default:
+		return "", fmt.Errorf("yaml: unknown operation %q (supported: %s)", operation, operationNames(p.Operations()))
+	}
+
+	var data string
+	if data, err = dataSource(source, variables); err != nil {
+		return "", fmt.Errorf("yaml: %v", err)
+	}
+	node, err := parseYAML(data)
+	if err != nil {
+		return "", fmt.Errorf("yaml: %v", err)
+	}
+
+	switch operation {
+	case "query":
+		nodes, queryErr := queryNodes(node, query)
+		if queryErr != nil {
+			return "", fmt.Errorf("yaml: %v", queryErr)
+		}
+		ret, err = formatResults(nodes, func(node *yaml.Node) (string, error) {
+			return formatYAML(detachNode(node))
+		})
+	case "pretty":
+		ret, err = formatYAML(node)
+	case "tojson":
+		ret, err = formatJSON(node)
+	}
+	if err != nil {
+		return "", fmt.Errorf("yaml: %s: %v", operation, err)
+	}
+	debugf("YAML: %s returned %d bytes", operation, len(ret))
+	return
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+const testYAML = `# Service
+name: api
+defaults: &defaults
+  port: 8080
+  hosts: [a, b]
+prod:
+  <<: *defaults
+  replicas: 0x3
+staging: *defaults
+`
+
+func TestYAMLPlugin(t *testing.T) {
+	plugin := &YAMLPlugin{}
+	variables := map[string]string{"config": testYAML}
+
+	tests := []struct {
+		name      string
+		operation string
+		value     string
+		want      string
+	}{
+		{"field", "query", "var:config|.name", "api"},
+		{"alias", "query", "var:config|.staging.port", "8080"},
+		{"all items", "query", "var:config|.defaults.hosts[*]", "a\nb"},
+		{"object without anchor", "query", "var:config|.staging", "port: 8080\nhosts:\n  - a\n  - b"},
+		{"literal", "query", "name: x|.name", "x"},
+		{"pretty", "pretty", "a: {b: [1, 2]}", "a:\n  b:\n    - 1\n    - 2"},
+		{"tojson", "tojson", "var:config", `{
+  "name": "api",
+  "defaults": {
+    "port": 8080,
+    "hosts": [
+      "a",
+      "b"
+    ]
+  },
+  "prod": {
+    "<<": {
+      "port": 8080,
+      "hosts": [
+        "a",
+        "b"
+      ]
+    },
+    "replicas": 3
+  },
+  "staging": {
+    "port": 8080,
+    "hosts": [
+      "a",
+      "b"
+    ]
+  }
+}`},
+		{"empty", "tojson", "", "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := plugin.ApplyVariables(tt.operation, tt.value, variables)
+			if err != nil {
+				t.Fatalf("ApplyVariables returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLPlugin_Errors(t *testing.T) {
+	plugin := &YAMLPlugin{}
+	tests := []struct {
+		operation string
+		value     string
+		want      string
+	}{
+		{"merge", "a: 1", "unknown operation"},
+		{"query", "a: 1", "requires format SOURCE|QUERY"},
+		{"pretty", "a: [1", "invalid YAML"},
+		{"tojson", "a: .inf", "cannot convert .inf to JSON"},
+		{"query", "a: 1|.a[0]", "cannot get index 0 of a value"},
+	}
+	for _, tt := range tests {
+		_, err := plugin.Apply(tt.operation, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s:%s: expected an error with %q, got %v", tt.operation, tt.value, tt.want, err)
+		}
+	}
+}
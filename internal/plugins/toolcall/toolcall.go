@@ -0,0 +1,295 @@
+package toolcall
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+	"github.com/danielmiessler/fabric/internal/plugins/template"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultTimeout = 30 * time.Second
+
+var argumentPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// Tool is a tool definition loaded from a YAML file in the tools directory.
+// A tool is backed either by a registered template extension or by a local command.
+//
+// Example:
+//
+//	name: word_count
+//	description: Count the words of a text
+//	parameters:
+//	  type: object
+//	  properties:
+//	    text: {type: string, description: The text to count}
+//	  required: [text]
+//	command: "echo {{text}} | wc -w"
+type Tool struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Parameters  map[string]any `yaml:"parameters"`
+
+	// Extension backed tools run Operation of the registered Extension with Value as input
+	Extension string `yaml:"extension"`
+	Operation string `yaml:"operation"`
+	Value     string `yaml:"value"`
+
+	// Command backed tools run Command through the shell
+	Command string `yaml:"command"`
+	Timeout string `yaml:"timeout"`
+}
+
+// ToChatTool converts the definition to the tool type sent to the vendors
+func (o *Tool) ToChatTool() chat.Tool {
+	return chat.Tool{
+		Type: chat.ToolTypeFunction,
+		Function: &chat.FunctionDefinition{
+			Name:        o.Name,
+			Description: o.Description,
+			Parameters:  o.Parameters,
+		},
+	}
+}
+
+func (o *Tool) validate() (err error) {
+	if o.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if o.Extension == "" && o.Command == "" {
+		return fmt.Errorf("tool %s needs either an extension or a command", o.Name)
+	}
+	if o.Extension != "" && o.Command != "" {
+		return fmt.Errorf("tool %s can not have both an extension and a command", o.Name)
+	}
+	if o.Timeout != "" {
+		if _, err = time.ParseDuration(o.Timeout); err != nil {
+			return fmt.Errorf("tool %s has an invalid timeout: %w", o.Name, err)
+		}
+	}
+	return
+}
+
+func NewToolsManager(toolsDir string, extensions *template.ExtensionManager) (ret *ToolsManager) {
+	ret = &ToolsManager{
+		Dir:        toolsDir,
+		Tools:      map[string]*Tool{},
+		extensions: extensions,
+	}
+	return
+}
+
+// ToolsManager loads tool definitions and executes the tool calls requested by models
+type ToolsManager struct {
+	Dir   string
+	Tools map[string]*Tool
+
+	extensions *template.ExtensionManager
+}
+
+// Load reads all *.yaml and *.yml tool definitions from the tools directory
+func (o *ToolsManager) Load() (err error) {
+	o.Tools = map[string]*Tool{}
+
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(o.Dir); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		var tool *Tool
+		if tool, err = LoadTool(filepath.Join(o.Dir, entry.Name())); err != nil {
+			return
+		}
+		o.Tools[tool.Name] = tool
+	}
+	return
+}
+
+// LoadTool reads and validates a single tool definition file
+func LoadTool(path string) (ret *Tool, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err != nil {
+		return
+	}
+	ret = &Tool{}
+	if err = yaml.Unmarshal(data, ret); err != nil {
+		err = fmt.Errorf("failed to parse tool %s: %w", path, err)
+		return
+	}
+	if ret.Name == "" {
+		ret.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	err = ret.validate()
+	return
+}
+
+// GetChatTools returns the chat tool definitions for the given tool names
+func (o *ToolsManager) GetChatTools(names []string) (ret []chat.Tool, err error) {
+	for _, name := range names {
+		tool, ok := o.Tools[name]
+		if !ok {
+			err = fmt.Errorf("tool %s not found. Please run 'fabric --listtools' for list", name)
+			return
+		}
+		ret = append(ret, tool.ToChatTool())
+	}
+	return
+}
+
+// Execute runs the tool requested by the model and returns its output
+func (o *ToolsManager) Execute(ctx context.Context, call chat.ToolCall) (ret string, err error) {
+	tool, ok := o.Tools[call.Function.Name]
+	if !ok {
+		err = fmt.Errorf("unknown tool: %s", call.Function.Name)
+		return
+	}
+
+	var args map[string]string
+	if args, err = parseArguments(tool, call.Function.Arguments); err != nil {
+		return
+	}
+
+	if tool.Extension != "" {
+		var value string
+		if value, err = render(tool.Value, args); err != nil {
+			return
+		}
+		return o.extensions.ProcessExtension(tool.Extension, tool.Operation, value)
+	}
+
+	// Arguments are quoted so the model can not inject shell syntax into the command
+	quoted := make(map[string]string, len(args))
+	for name, value := range args {
+		quoted[name] = shellQuote(value)
+	}
+	var command string
+	if command, err = render(tool.Command, quoted); err != nil {
+		return
+	}
+	return runCommand(ctx, command, tool.Timeout)
+}
+
+// ListTools prints the available tools
+func (o *ToolsManager) ListTools(shellCompleteList bool) error {
+	if len(o.Tools) == 0 {
+		return fmt.Errorf("no tools found. Please add tool definitions to %s", o.Dir)
+	}
+	if !shellCompleteList {
+		fmt.Print("Available Tools:\n\n")
+	}
+
+	names := make([]string, 0, len(o.Tools))
+	maxNameLength := 0
+	for name := range o.Tools {
+		names = append(names, name)
+		maxNameLength = max(maxNameLength, len(name))
+	}
+	sort.Strings(names)
+
+	formatString := "%-" + fmt.Sprintf("%d", maxNameLength+2) + "s %s\n"
+	for _, name := range names {
+		if shellCompleteList {
+			fmt.Printf("%s\n", name)
+		} else {
+			fmt.Printf(formatString, name, o.Tools[name].Description)
+		}
+	}
+	return nil
+}
+
+// parseArguments decodes the JSON arguments of a tool call into template variables.
+// Every declared parameter gets a variable so optional parameters can be referenced.
+func parseArguments(tool *Tool, arguments string) (ret map[string]string, err error) {
+	ret = map[string]string{}
+	if properties, ok := tool.Parameters["properties"].(map[string]any); ok {
+		for name := range properties {
+			ret[name] = ""
+		}
+	}
+
+	if strings.TrimSpace(arguments) == "" {
+		return
+	}
+
+	var decoded map[string]any
+	if err = json.Unmarshal([]byte(arguments), &decoded); err != nil {
+		err = fmt.Errorf("invalid arguments for tool %s: %w", tool.Name, err)
+		return
+	}
+	for name, value := range decoded {
+		switch v := value.(type) {
+		case string:
+			ret[name] = v
+		case nil:
+			ret[name] = ""
+		default:
+			var encoded []byte
+			if encoded, err = json.Marshal(v); err != nil {
+				return
+			}
+			ret[name] = string(encoded)
+		}
+	}
+	return
+}
+
+func runCommand(ctx context.Context, command string, timeout string) (ret string, err error) {
+	duration := defaultTimeout
+	if timeout != "" {
+		if duration, err = time.ParseDuration(timeout); err != nil {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err = cmd.Run(); err != nil {
+		err = fmt.Errorf("command failed: %w\nstderr: %s", err, stderr.String())
+		return
+	}
+	ret = stdout.String()
+	return
+}
+
+// render substitutes {{name}} placeholders in a single pass. Unlike template.ApplyTemplate
+// it never re-scans substituted values, so arguments chosen by the model can not trigger
+// template plugins or extensions.
+func render(content string, args map[string]string) (ret string, err error) {
+	ret = argumentPattern.ReplaceAllStringFunc(content, func(match string) string {
+		name := argumentPattern.FindStringSubmatch(match)[1]
+		value, ok := args[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("missing tool argument: %s", name)
+		}
+		return value
+	})
+	return
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
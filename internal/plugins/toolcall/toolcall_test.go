@@ -0,0 +1,111 @@
+package toolcall
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/chat"
+)
+
+func writeTool(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write tool: %v", err)
+	}
+}
+
+func TestToolsManager_LoadAndGetChatTools(t *testing.T) {
+	dir := t.TempDir()
+	writeTool(t, dir, "echo.yaml", `
+name: echo
+description: Echo the text
+parameters:
+  type: object
+  properties:
+    text:
+      type: string
+  required: [text]
+command: "echo {{text}}"
+`)
+	writeTool(t, dir, "notes.txt", "ignored")
+
+	manager := NewToolsManager(dir, nil)
+	if err := manager.Load(); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(manager.Tools) != 1 {
+		t.Fatalf("expected 1 tool, got %d", len(manager.Tools))
+	}
+
+	tools, err := manager.GetChatTools([]string{"echo"})
+	if err != nil {
+		t.Fatalf("GetChatTools failed: %v", err)
+	}
+	if tools[0].Function.Name != "echo" || tools[0].Function.Parameters["type"] != "object" {
+		t.Errorf("unexpected chat tool: %+v", tools[0].Function)
+	}
+
+	if _, err = manager.GetChatTools([]string{"missing"}); err == nil {
+		t.Error("expected error for unknown tool")
+	}
+}
+
+func TestToolsManager_LoadInvalidTool(t *testing.T) {
+	dir := t.TempDir()
+	writeTool(t, dir, "broken.yaml", "name: broken\ndescription: has no command\n")
+
+	manager := NewToolsManager(dir, nil)
+	if err := manager.Load(); err == nil {
+		t.Error("expected error for tool without command or extension")
+	}
+}
+
+func TestToolsManager_LoadMissingDir(t *testing.T) {
+	manager := NewToolsManager(filepath.Join(t.TempDir(), "missing"), nil)
+	if err := manager.Load(); err != nil {
+		t.Errorf("expected missing directory to be ignored, got %v", err)
+	}
+}
+
+func TestToolsManager_ExecuteCommand(t *testing.T) {
+	manager := NewToolsManager("", nil)
+	manager.Tools["echo"] = &Tool{
+		Name:       "echo",
+		Parameters: map[string]any{"properties": map[string]any{"text": map[string]any{}, "suffix": map[string]any{}}},
+		Command:    "echo {{text}}{{suffix}}",
+	}
+
+	output, err := manager.Execute(context.Background(), chat.ToolCall{
+		Function: chat.FunctionCall{Name: "echo", Arguments: `{"text":"hello; echo injected"}`},
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if strings.TrimSpace(output) != "hello; echo injected" {
+		t.Errorf("expected arguments to be passed literally, got %q", output)
+	}
+}
+
+func TestToolsManager_ExecuteUnknownTool(t *testing.T) {
+	manager := NewToolsManager("", nil)
+	if _, err := manager.Execute(context.Background(), chat.ToolCall{Function: chat.FunctionCall{Name: "nope"}}); err == nil {
+		t.Error("expected error for unknown tool")
+	}
+}
+
+func TestRender(t *testing.T) {
+	result, err := render("say {{ word }}", map[string]string{"word": "{{plugin:sys:env:HOME}}"})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if result != "say {{plugin:sys:env:HOME}}" {
+		t.Errorf("expected value to be inserted verbatim, got %q", result)
+	}
+
+	if _, err = render("{{missing}}", map[string]string{}); err == nil {
+		t.Error("expected error for missing argument")
+	}
+}
@@ -1,6 +1,7 @@
 package restapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/danielmiessler/fabric/internal/chat"
 
@@ -27,6 +29,7 @@ type PromptRequest struct {
 	Vendor       string            `json:"vendor"`
 	Model        string            `json:"model"`
 	ContextName  string            `json:"contextName"`
+	ContextNames []string          `json:"contextNames,omitempty"` // More contexts, merged after contextName
 	PatternName  string            `json:"patternName"`
 	StrategyName string            `json:"strategyName"`        // Optional strategy name
 	Variables    map[string]string `json:"variables,omitempty"` // Pattern variables
@@ -34,14 +37,19 @@ type PromptRequest struct {
 
 type ChatRequest struct {
 	Prompts            []PromptRequest `json:"prompts"`
-	Language           string          `json:"language"` // Add Language field to bind from request
+	Language           string          `json:"language"`          // Add Language field to bind from request
+	Timeout            int             `json:"timeout,omitempty"` // Optional timeout in seconds for each prompt
 	domain.ChatOptions                 // Embed the ChatOptions from common package
 }
 
 type StreamResponse struct {
-	Type    string `json:"type"`    // "content", "error", "complete"
-	Format  string `json:"format"`  // "markdown", "mermaid", "plain"
-	Content string `json:"content"` // The actual content
+	Type         string      `json:"type"`                   // "content", "error", "complete"
+	Format       string      `json:"format"`                 // "markdown", "mermaid", "plain"
+	Content      string      `json:"content"`                // The actual content
+	Usage        *chat.Usage `json:"usage,omitempty"`        // Token usage, sent with "complete"
+	FinishReason string      `json:"finishReason,omitempty"` // Why the model stopped, sent with "complete"
+	Vendor       string      `json:"vendor,omitempty"`       // Vendor that answered, sent with "complete"
+	Model        string      `json:"model,omitempty"`        // Model that answered, differs from the requested one after a fallback
 }
 
 func NewChatHandler(r *gin.Engine, registry *core.PluginRegistry, db *fsdb.Db) *ChatHandler {
@@ -75,7 +83,9 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 	c.Writer.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
 	c.Writer.Header().Set("X-Accel-Buffering", "no")
 
-	clientGone := c.Writer.CloseNotify()
+	// The request context is cancelled when the client disconnects, which stops the vendor call
+	ctx := c.Request.Context()
+	clientGone := ctx.Done()
 
 	for i, prompt := range request.Prompts {
 		select {
@@ -86,11 +96,21 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 			log.Printf("Processing prompt %d: Model=%s Pattern=%s Context=%s",
 				i+1, prompt.Model, prompt.PatternName, prompt.ContextName)
 
-			streamChan := make(chan string)
+			// Buffered so the goroutine can finish even if the client is gone
+			streamChan := make(chan string, 1)
+			// Set by the goroutine before streamChan is closed
+			var meta *chat.MessageMeta
 
 			go func(p PromptRequest) {
 				defer close(streamChan)
 
+				promptCtx := ctx
+				if request.Timeout > 0 {
+					var cancel context.CancelFunc
+					promptCtx, cancel = context.WithTimeout(ctx, time.Duration(request.Timeout)*time.Second)
+					defer cancel()
+				}
+
 				// Load and prepend strategy prompt if strategyName is set
 				if p.StrategyName != "" {
 					strategyFile := filepath.Join(os.Getenv("HOME"), ".config", "fabric", "strategies", p.StrategyName+".json")
@@ -105,13 +125,18 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 					}
 				}
 
-				chatter, err := h.registry.GetChatter(p.Model, 2048, "", false, false)
+				chatter, err := h.registry.GetChatter(promptCtx, p.Model, 2048, "", false, false)
 				if err != nil {
 					log.Printf("Error creating chatter: %v", err)
 					streamChan <- fmt.Sprintf("Error: %v", err)
 					return
 				}
 
+				contextNames := p.ContextNames
+				if p.ContextName != "" {
+					contextNames = append([]string{p.ContextName}, contextNames...)
+				}
+
 				// Pass the language received in the initial request to the domain.ChatRequest
 				chatReq := &domain.ChatRequest{
 					Message: &chat.ChatCompletionMessage{
@@ -119,7 +144,7 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 						Content: p.UserInput,
 					},
 					PatternName:      p.PatternName,
-					ContextName:      p.ContextName,
+					ContextNames:     contextNames,
 					PatternVariables: p.Variables,      // Pass pattern variables
 					Language:         request.Language, // Pass the language field
 				}
@@ -130,9 +155,11 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 					TopP:             request.TopP,
 					FrequencyPenalty: request.FrequencyPenalty,
 					PresencePenalty:  request.PresencePenalty,
+					// Patterns with a JSON schema get the same repair retries as on the command line
+					JSONSchemaRetries: core.DefaultJSONSchemaRetries,
 				}
 
-				session, err := chatter.Send(chatReq, opts)
+				session, err := chatter.Send(promptCtx, chatReq, opts)
 				if err != nil {
 					log.Printf("Error from chatter.Send: %v", err)
 					streamChan <- fmt.Sprintf("Error: %v", err)
@@ -147,6 +174,7 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 
 				lastMsg := session.GetLastMessage()
 				if lastMsg != nil {
+					meta = lastMsg.Meta
 					streamChan <- lastMsg.Content
 				} else {
 					log.Printf("No message content in session")
@@ -185,6 +213,12 @@ func (h *ChatHandler) HandleChat(c *gin.Context) {
 				Format:  "plain",
 				Content: "",
 			}
+			if meta != nil {
+				completeResponse.Usage = meta.Usage
+				completeResponse.FinishReason = meta.FinishReason
+				completeResponse.Vendor = meta.Vendor
+				completeResponse.Model = meta.Model
+			}
 			if err := writeSSEResponse(c.Writer, completeResponse); err != nil {
 				log.Printf("Error writing completion response: %v", err)
 				return
@@ -18,7 +18,7 @@ func NewModelsHandler(r *gin.Engine, vendorManager *ai.VendorsManager) {
 }
 
 func (h *ModelsHandler) GetModelNames(c *gin.Context) {
-	vendorsModels, err := h.vendorManager.GetModels()
+	vendorsModels, err := h.vendorManager.GetModels(c.Request.Context())
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Server failed to retrieve model names"})
 		return
@@ -2,7 +2,6 @@ package restapi
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -89,7 +88,8 @@ func ServeOllama(registry *core.PluginRegistry, address string, version string)
 	fabricDb := registry.Db
 	NewPatternsHandler(r, fabricDb.Patterns)
 	NewContextsHandler(r, fabricDb.Contexts)
-	NewSessionsHandler(r, fabricDb.Sessions)
+	NewSearchHandler(r, fabricDb.Search)
+	NewSessionsHandler(r, registry)
 	NewChatHandler(r, registry, fabricDb)
 	NewConfigHandler(r, fabricDb)
 	NewModelsHandler(r, registry.VendorManager)
@@ -190,7 +190,7 @@ func (f APIConvert) ollamaChat(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		return
 	}
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	var req *http.Request
 	if strings.Contains(*f.addr, "http") {
 		req, err = http.NewRequest("POST", fmt.Sprintf("%s/chat", *f.addr), bytes.NewBuffer(fabricChatReq))
@@ -21,13 +21,14 @@ func NewPatternsHandler(r *gin.Engine, patterns *fsdb.PatternsEntity) (ret *Patt
 
 	// Register routes manually - use custom Get for patterns, others from StorageHandler
 	r.GET("/patterns/:name", ret.Get)                       // Custom method with variables support
-	r.GET("/patterns/names", ret.GetNames)                  // From StorageHandler
+	r.GET("/patterns/names", ret.GetNames)                  // Custom method with tags and descriptions
 	r.DELETE("/patterns/:name", ret.Delete)                 // From StorageHandler
 	r.GET("/patterns/exists/:name", ret.Exists)             // From StorageHandler
 	r.PUT("/patterns/rename/:oldName/:newName", ret.Rename) // From StorageHandler
 	r.POST("/patterns/:name", ret.Save)                     // From StorageHandler
 	// Add POST route for patterns with variables in request body
 	r.POST("/patterns/:name/apply", ret.ApplyPattern)
+	r.GET("/patterns/:name/variables", ret.GetVariables)
 	return
 }
 
@@ -36,21 +37,67 @@ func (h *PatternsHandler) Get(c *gin.Context) {
 	name := c.Param("name")
 
 	// Get the raw pattern content without any variable processing
-	content, err := h.patterns.Load(name + "/" + h.patterns.SystemPatternFile)
+	content, err := h.patterns.Load(name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// Return raw pattern in the same format as the processed patterns
+	// Return raw pattern in the same format as the processed patterns, the metadata is left
+	// out if it is invalid so that the pattern can still be fixed
 	pattern := &fsdb.Pattern{
 		Name:        name,
 		Description: "",
 		Pattern:     string(content),
 	}
+	if metadata, metadataErr := h.patterns.GetMetadata(name); metadataErr == nil {
+		pattern.Description = metadata.Description
+		pattern.Metadata = metadata
+	}
 	c.JSON(http.StatusOK, pattern)
 }
 
+// GetNames handles the GET /patterns/names route. It returns the names of the patterns that have
+// all tags given with tag, or with details=true the patterns with their descriptions and tags.
+func (h *PatternsHandler) GetNames(c *gin.Context) {
+	tags := c.QueryArray("tag")
+	details := c.Query("details") == "true"
+	if len(tags) == 0 && !details {
+		h.StorageHandler.GetNames(c)
+		return
+	}
+
+	infos, err := h.patterns.GetInfos(tags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	if details {
+		if infos == nil {
+			infos = []*fsdb.PatternInfo{}
+		}
+		c.JSON(http.StatusOK, infos)
+		return
+	}
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	c.JSON(http.StatusOK, names)
+}
+
+// GetVariables handles the GET /patterns/:name/variables route. It returns the variables of the
+// pattern with their descriptions, defaults and whether they are required, and the plugins and
+// extensions it uses, so that UIs can build forms for it.
+func (h *PatternsHandler) GetVariables(c *gin.Context) {
+	variables, err := h.patterns.GetVariables(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, variables)
+}
+
 // PatternApplyRequest represents the request body for applying a pattern
 type PatternApplyRequest struct {
 	Input     string            `json:"input"`
@@ -0,0 +1,87 @@
+package restapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// PipelinesHandler runs pipelines in-process
+type PipelinesHandler struct {
+	registry *core.PluginRegistry
+}
+
+// PipelineRequest runs either a named pipeline from the pipelines directory or an inline pipeline
+type PipelineRequest struct {
+	Name               string         `json:"name"`
+	Pipeline           *core.Pipeline `json:"pipeline"`
+	Input              string         `json:"input"`
+	DryRun             bool           `json:"dryRun"`
+	Timeout            int            `json:"timeout,omitempty"` // Optional timeout in seconds for the whole run
+	domain.ChatOptions                // Embed the ChatOptions from common package
+}
+
+type PipelineResponse struct {
+	Output string                     `json:"output"`
+	Steps  []*core.PipelineStepResult `json:"steps"`
+}
+
+func NewPipelinesHandler(r *gin.Engine, registry *core.PluginRegistry) *PipelinesHandler {
+	handler := &PipelinesHandler{registry: registry}
+	r.POST("/pipeline/run", handler.Run)
+	return handler
+}
+
+// Run runs the pipeline and returns the final output together with the output of every step.
+// The output files of the steps are not written by the server.
+func (h *PipelinesHandler) Run(c *gin.Context) {
+	var req PipelineRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	pipeline := req.Pipeline
+	if pipeline == nil {
+		if req.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name or pipeline is required"})
+			return
+		}
+		var err error
+		if pipeline, err = h.registry.LoadNamedPipeline(req.Name); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := pipeline.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	opts := &domain.ChatOptions{
+		Model:            req.Model,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		FrequencyPenalty: req.FrequencyPenalty,
+		PresencePenalty:  req.PresencePenalty,
+	}
+
+	steps, err := h.registry.RunPipeline(ctx, pipeline, req.Input, opts, req.DryRun, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "steps": steps})
+		return
+	}
+
+	c.JSON(http.StatusOK, PipelineResponse{Output: steps[len(steps)-1].Output, Steps: steps})
+}
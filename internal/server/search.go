@@ -0,0 +1,53 @@
+package restapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler searches sessions, contexts and patterns
+type SearchHandler struct {
+	search *fsdb.SearchIndex
+}
+
+// NewSearchHandler registers the /search GET endpoint
+func NewSearchHandler(r *gin.Engine, search *fsdb.SearchIndex) (ret *SearchHandler) {
+	ret = &SearchHandler{search: search}
+	r.GET("/search", ret.Search)
+	return
+}
+
+// Search handles the GET /search route. The query is q, the results can be filtered with type,
+// which can be given several times, since and until, and limited with limit (default 20).
+func (h *SearchHandler) Search(c *gin.Context) {
+	limit := 20
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number"})
+			return
+		}
+	}
+	options, err := fsdb.NewSearchOptions(c.QueryArray("type"), c.Query("since"), c.Query("until"), limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.search.Search(c.Query("q"), options)
+	if errors.Is(err, fsdb.ErrEmptySearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if results == nil {
+		results = []*fsdb.SearchResult{}
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -24,12 +24,15 @@ func Serve(registry *core.PluginRegistry, address string, apiKey string) (err er
 	fabricDb := registry.Db
 	NewPatternsHandler(r, fabricDb.Patterns)
 	NewContextsHandler(r, fabricDb.Contexts)
-	NewSessionsHandler(r, fabricDb.Sessions)
+	NewSearchHandler(r, fabricDb.Search)
+	NewSessionsHandler(r, registry)
 	NewChatHandler(r, registry, fabricDb)
 	NewYouTubeHandler(r, registry)
 	NewConfigHandler(r, fabricDb)
 	NewModelsHandler(r, registry.VendorManager)
 	NewStrategiesHandler(r)
+	NewTemplatePluginsHandler(r, registry.TemplatePlugins)
+	NewPipelinesHandler(r, registry)
 
 	// Start server
 	err = r.Run(address)
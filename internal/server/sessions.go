@@ -1,6 +1,11 @@
 package restapi
 
 import (
+	"net/http"
+	"strconv"
+
+	"github.com/danielmiessler/fabric/internal/core"
+	"github.com/danielmiessler/fabric/internal/domain"
 	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
 	"github.com/gin-gonic/gin"
 )
@@ -9,11 +14,130 @@ import (
 type SessionsHandler struct {
 	*StorageHandler[fsdb.Session]
 	sessions *fsdb.SessionsEntity
+	registry *core.PluginRegistry
+}
+
+// SessionForkRequest represents the request body for forking a session
+type SessionForkRequest struct {
+	Name string `json:"name" binding:"required"`
+	At   int    `json:"at"` // Number of messages to copy, all if 0
+}
+
+// SessionRewindRequest represents the request body for rewinding a session
+type SessionRewindRequest struct {
+	Turns int `json:"turns" binding:"required"`
+}
+
+// SessionTruncateRequest represents the request body for truncating a session
+type SessionTruncateRequest struct {
+	Length int `json:"length"`
+}
+
+// MessageEditRequest represents the request body for editing an answer of a session
+type MessageEditRequest struct {
+	Content string `json:"content" binding:"required"`
 }
 
 // NewSessionsHandler creates a new SessionsHandler
-func NewSessionsHandler(r *gin.Engine, sessions *fsdb.SessionsEntity) (ret *SessionsHandler) {
+func NewSessionsHandler(r *gin.Engine, registry *core.PluginRegistry) (ret *SessionsHandler) {
+	sessions := registry.Db.Sessions
 	ret = &SessionsHandler{
-		StorageHandler: NewStorageHandler(r, "sessions", sessions), sessions: sessions}
+		StorageHandler: NewStorageHandler(r, "sessions", sessions), sessions: sessions, registry: registry}
+
+	r.POST("/sessions/:name/fork", ret.Fork)
+	r.POST("/sessions/:name/rewind", ret.Rewind)
+	r.POST("/sessions/:name/truncate", ret.Truncate)
+	r.PUT("/sessions/:name/messages/:number", ret.EditMessage)
+	r.POST("/sessions/:name/messages/:number/regenerate", ret.Regenerate)
 	return ret
 }
+
+// Fork handles the POST /sessions/:name/fork route and returns the new session
+func (h *SessionsHandler) Fork(c *gin.Context) {
+	var request SessionForkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, err := h.sessions.ForkSession(c.Param("name"), request.At, request.Name)
+	respondWithSession(c, session, err)
+}
+
+// Rewind handles the POST /sessions/:name/rewind route and returns the changed session
+func (h *SessionsHandler) Rewind(c *gin.Context) {
+	var request SessionRewindRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, err := h.sessions.RewindSession(c.Param("name"), request.Turns)
+	respondWithSession(c, session, err)
+}
+
+// Truncate handles the POST /sessions/:name/truncate route and returns the changed session
+func (h *SessionsHandler) Truncate(c *gin.Context) {
+	var request SessionTruncateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, err := h.sessions.TruncateSession(c.Param("name"), request.Length)
+	respondWithSession(c, session, err)
+}
+
+// EditMessage handles the PUT /sessions/:name/messages/:number route and returns the changed session
+func (h *SessionsHandler) EditMessage(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the message number must be an integer"})
+		return
+	}
+	var request MessageEditRequest
+	if err = c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	session, err := h.sessions.EditMessage(c.Param("name"), number, request.Content)
+	respondWithSession(c, session, err)
+}
+
+// Regenerate handles the POST /sessions/:name/messages/:number/regenerate route. The optional
+// body holds the chat options, like the model. It returns the changed session.
+func (h *SessionsHandler) Regenerate(c *gin.Context) {
+	name := c.Param("name")
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the message number must be an integer"})
+		return
+	}
+	opts := &domain.ChatOptions{}
+	if c.Request.ContentLength != 0 {
+		if err = c.ShouldBindJSON(opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !h.sessions.Exists(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session " + name + " does not exist"})
+		return
+	}
+
+	chatter, err := h.registry.GetChatter(c.Request.Context(), opts.Model, opts.ModelContextLength, "", false, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	session, err := h.sessions.Get(name)
+	if err == nil {
+		err = chatter.Regenerate(c.Request.Context(), session, number, opts)
+	}
+	respondWithSession(c, session, err)
+}
+
+func respondWithSession(c *gin.Context, session *fsdb.Session, err error) {
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
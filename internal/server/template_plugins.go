@@ -0,0 +1,25 @@
+package restapi
+
+import (
+	"net/http"
+
+	"github.com/danielmiessler/fabric/internal/plugins/template"
+	"github.com/gin-gonic/gin"
+)
+
+// TemplatePluginsHandler lists the template plugins
+type TemplatePluginsHandler struct {
+	plugins *template.TemplatePluginRegistry
+}
+
+// NewTemplatePluginsHandler registers the /template-plugins GET endpoint
+func NewTemplatePluginsHandler(r *gin.Engine, plugins *template.TemplatePluginRegistry) (ret *TemplatePluginsHandler) {
+	ret = &TemplatePluginsHandler{plugins: plugins}
+	r.GET("/template-plugins", ret.List)
+	return
+}
+
+// List handles the GET /template-plugins route, it returns the plugins with their operations
+func (h *TemplatePluginsHandler) List(c *gin.Context) {
+	c.JSON(http.StatusOK, h.plugins.List())
+}
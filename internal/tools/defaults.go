@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -10,7 +11,7 @@ import (
 	"github.com/danielmiessler/fabric/internal/plugins/ai"
 )
 
-func NeeDefaults(getVendorsModels func() (*ai.VendorsModels, error)) (ret *Defaults) {
+func NeeDefaults(getVendorsModels func(context.Context) (*ai.VendorsModels, error)) (ret *Defaults) {
 	vendorName := "Default"
 	ret = &Defaults{
 		PluginBase: &plugins.PluginBase{
@@ -29,6 +30,9 @@ func NeeDefaults(getVendorsModels func() (*ai.VendorsModels, error)) (ret *Defau
 	ret.ModelContextLength = ret.AddSetupQuestionCustom("Model Context Length", false,
 		"Enter model context length")
 
+	ret.FallbackModels = ret.AddSetupQuestionCustom("Fallback Models", false,
+		"Enter the models to fall back to in order, comma separated, as model or vendor|model (leave empty for none)")
+
 	return
 }
 
@@ -38,12 +42,13 @@ type Defaults struct {
 	Vendor             *plugins.Setting
 	Model              *plugins.SetupQuestion
 	ModelContextLength *plugins.SetupQuestion
-	GetVendorsModels   func() (*ai.VendorsModels, error)
+	FallbackModels     *plugins.SetupQuestion
+	GetVendorsModels   func(context.Context) (*ai.VendorsModels, error)
 }
 
 func (o *Defaults) Setup() (err error) {
 	var vendorsModels *ai.VendorsModels
-	if vendorsModels, err = o.GetVendorsModels(); err != nil {
+	if vendorsModels, err = o.GetVendorsModels(context.Background()); err != nil {
 		return
 	}
 
@@ -110,6 +110,14 @@ func (o *PatternsLoader) PopulateDB() (err error) {
 
 	fmt.Printf("✅ Successfully downloaded and installed patterns to %s\n", o.Patterns.Dir)
 
+	if o.Patterns.Items != nil {
+		var count int
+		if count, err = o.Patterns.StorePatternsDir(); err != nil {
+			return fmt.Errorf("failed to store patterns in the database: %w", err)
+		}
+		fmt.Printf("✅ Stored %d patterns in the database\n", count)
+	}
+
 	// Create the unique patterns file after patterns are successfully moved
 	if err = o.createUniquePatternsFile(); err != nil {
 		return fmt.Errorf("failed to create unique patterns file: %w", err)
@@ -165,6 +173,38 @@ func (o *PatternsLoader) PersistPatterns() (err error) {
 	return nil
 }
 
+// recordVersions adds the downloaded patterns to the versions of the patterns, after the local
+// content they replace, and returns the pinned patterns, which must stay as they are. It runs
+// before the custom patterns are copied into the download, so only downloaded patterns are recorded.
+func (o *PatternsLoader) recordVersions() (pinned []string, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(o.tempPatternsFolder); err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		var content []byte
+		if content, err = os.ReadFile(filepath.Join(o.tempPatternsFolder, name, o.Patterns.SystemPatternFile)); err != nil {
+			if os.IsNotExist(err) {
+				err = nil
+				continue
+			}
+			return
+		}
+		var isPinned bool
+		if isPinned, err = o.Patterns.RecordUpdate(name, string(content)); err != nil {
+			return nil, fmt.Errorf("failed to record the version of pattern '%s': %w", name, err)
+		}
+		if isPinned {
+			pinned = append(pinned, name)
+		}
+	}
+	return
+}
+
 // movePatterns copies the new patterns into the config directory
 func (o *PatternsLoader) movePatterns() (err error) {
 	if err = os.MkdirAll(o.Patterns.Dir, os.ModePerm); err != nil {
@@ -172,9 +212,20 @@ func (o *PatternsLoader) movePatterns() (err error) {
 	}
 
 	patternsDir := o.tempPatternsFolder
+	var pinned []string
+	if pinned, err = o.recordVersions(); err != nil {
+		return
+	}
 	if err = o.PersistPatterns(); err != nil {
 		return
 	}
+	// Pinned patterns are taken out of the download, so the local ones stay as they are
+	for _, name := range pinned {
+		if err = os.RemoveAll(filepath.Join(patternsDir, name)); err != nil {
+			return
+		}
+		fmt.Printf("Kept pinned pattern: %s\n", name)
+	}
 
 	if err = copy.Copy(patternsDir, o.Patterns.Dir); err != nil { // copies the patterns to the config directory
 		return
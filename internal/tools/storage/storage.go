@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/danielmiessler/fabric/internal/plugins"
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+)
+
+// NewStorage creates the plugin that chooses where patterns, sessions and contexts are stored.
+// The database reads the settings itself when it is configured, before the plugins are.
+func NewStorage() (ret *Storage) {
+	label := "Storage"
+	ret = &Storage{}
+
+	ret.PluginBase = &plugins.PluginBase{
+		Name:             label,
+		SetupDescription: "Storage - Keep patterns, sessions and contexts in files or a SQLite database (optional)",
+		EnvNamePrefix:    plugins.BuildEnvVariablePrefix(label),
+		ConfigureCustom:  ret.configure,
+	}
+
+	ret.Backend = ret.AddSetupQuestionCustom("Backend", false,
+		fmt.Sprintf("Enter the storage, %s or %s. Copy your data first with fabric --migrate-storage",
+			fsdb.StorageFiles, fsdb.StorageSQLite))
+	ret.SQLitePath = ret.AddSetupQuestionCustom("SQLite Path", false,
+		"Enter the path of the SQLite database (leave empty for fabric.db in the config directory)")
+
+	return
+}
+
+type Storage struct {
+	*plugins.PluginBase
+	Backend    *plugins.SetupQuestion
+	SQLitePath *plugins.SetupQuestion
+}
+
+func (o *Storage) configure() (err error) {
+	switch o.Backend.Value {
+	case "", fsdb.StorageFiles, fsdb.StorageSQLite:
+	default:
+		err = fmt.Errorf("unknown storage %q, use %s or %s", o.Backend.Value, fsdb.StorageFiles, fsdb.StorageSQLite)
+	}
+	return
+}
+
+// IsConfigured returns true if a storage has been chosen
+func (o *Storage) IsConfigured() bool {
+	o.Configure()
+	return o.Backend.Value != ""
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/danielmiessler/fabric/internal/plugins/db/fsdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStorage_UsesTheVariablesOfTheDatabase(t *testing.T) {
+	plugin := NewStorage()
+
+	assert.Equal(t, fsdb.StorageBackendEnv, plugin.Backend.EnvVariable)
+	assert.Equal(t, fsdb.StorageSQLitePathEnv, plugin.SQLitePath.EnvVariable)
+}
+
+func TestStorage_Configure(t *testing.T) {
+	plugin := NewStorage()
+
+	for _, backend := range []string{"", fsdb.StorageFiles, fsdb.StorageSQLite} {
+		plugin.Backend.Value = backend
+		assert.NoError(t, plugin.configure(), backend)
+	}
+
+	plugin.Backend.Value = "postgres"
+	assert.Error(t, plugin.configure())
+}
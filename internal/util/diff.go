@@ -0,0 +1,157 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around the changes of a diff
+const diffContext = 3
+
+type diffOp struct {
+	kind byte // ' ' for unchanged, '-' for removed and '+' for added lines
+	line string
+	// Line numbers of the line in the old and the new text, starting at 1
+	oldLine int
+	newLine int
+}
+
+// UnifiedDiff compares two texts line by line and returns the changes in the unified diff
+// format, with fromName and toName as the names of the texts. Equal texts have no diff.
+func UnifiedDiff(from string, to string, fromName string, toName string) string {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	var builder strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and the hunk around it
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		hunkStart := max(start-diffContext, 0)
+		end := start
+		for unchanged := 0; end < len(ops) && unchanged <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		// Keep only diffContext unchanged lines after the last change
+		for end > start && ops[end-1].kind == ' ' {
+			end--
+		}
+		hunkEnd := min(end+diffContext, len(ops))
+
+		if builder.Len() == 0 {
+			fmt.Fprintf(&builder, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&builder, ops[hunkStart:hunkEnd])
+		start = hunkEnd
+	}
+	return builder.String()
+}
+
+func writeHunk(builder *strings.Builder, ops []diffOp) {
+	oldStart, oldCount, newStart, newCount := 0, 0, 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			if oldCount == 0 {
+				oldStart = op.oldLine
+			}
+			oldCount++
+		}
+		if op.kind != '-' {
+			if newCount == 0 {
+				newStart = op.newLine
+			}
+			newCount++
+		}
+	}
+	// An empty side starts at the line before the hunk, as in diff -u
+	if oldCount == 0 {
+		oldStart = ops[0].oldLine - 1
+	}
+	if newCount == 0 {
+		newStart = ops[0].newLine - 1
+	}
+	fmt.Fprintf(builder, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, op := range ops {
+		builder.WriteByte(op.kind)
+		builder.WriteString(op.line)
+		builder.WriteByte('\n')
+	}
+}
+
+func hunkRange(start int, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines turns the old lines into the new ones with the longest common subsequence of both
+func diffLines(from []string, to []string) (ret []diffOp) {
+	// The common start and end are unchanged, which keeps the table below small
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix &&
+		from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	for k := 0; k < prefix; k++ {
+		ret = append(ret, diffOp{kind: ' ', line: from[k], oldLine: k + 1, newLine: k + 1})
+	}
+
+	oldLines, newLines := from[prefix:len(from)-suffix], to[prefix:len(to)-suffix]
+	// lengths[i][j] is the length of the longest common subsequence of oldLines[i:] and newLines[j:]
+	lengths := make([][]int, len(oldLines)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		op := diffOp{oldLine: prefix + i + 1, newLine: prefix + j + 1}
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			op.kind, op.line = ' ', oldLines[i]
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lengths[i+1][j] >= lengths[i][j+1]):
+			// Removed lines come before the added ones that replace them
+			op.kind, op.line = '-', oldLines[i]
+			i++
+		default:
+			op.kind, op.line = '+', newLines[j]
+			j++
+		}
+		ret = append(ret, op)
+	}
+
+	for k := suffix; k > 0; k-- {
+		ret = append(ret, diffOp{kind: ' ', line: from[len(from)-k], oldLine: len(from) - k + 1, newLine: len(to) - k + 1})
+	}
+	return
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			from: "one\ntwo\nthree\n",
+			to:   "one\n2\nthree\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "added to an empty text",
+			from: "",
+			to:   "first\nsecond",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+first\n+second\n",
+		},
+		{
+			name: "removed line at the end",
+			from: "a\nb\nc\nd\ne\n",
+			to:   "a\nb\nc\nd\n",
+			want: "--- old\n+++ new\n@@ -2,4 +2,3 @@\n b\n c\n d\n-e\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff(tt.from, tt.to, "old", "new"); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	var from, to []string
+	for i := 1; i <= 30; i++ {
+		line := strings.Repeat("x", i)
+		from = append(from, line)
+		if i == 5 || i == 25 {
+			line = "changed"
+		}
+		to = append(to, line)
+	}
+
+	diff := UnifiedDiff(strings.Join(from, "\n"), strings.Join(to, "\n"), "old", "new")
+	if hunks := strings.Count(diff, "\n@@ "); hunks != 2 {
+		t.Fatalf("expected two hunks for changes far apart, got %d:\n%s", hunks, diff)
+	}
+	if !strings.Contains(diff, "@@ -2,7 +2,7 @@\n") || !strings.Contains(diff, "@@ -22,7 +22,7 @@\n") {
+		t.Errorf("unexpected hunk ranges:\n%s", diff)
+	}
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// SplitGlob splits a path into the directory before the first segment with a glob and the
+// glob segments after it. A path without a glob matches all files of the directory.
+func SplitGlob(target string) (base string, pattern []string) {
+	segments := strings.Split(filepath.ToSlash(target), "/")
+	for i, segment := range segments {
+		if strings.ContainsAny(segment, "*?[") {
+			base, pattern = strings.Join(segments[:i], "/"), segments[i:]
+			if base == "" && i > 0 {
+				base = "/"
+			} else if base == "" {
+				base = "."
+			}
+			return
+		}
+	}
+	return target, []string{"**"}
+}
+
+// MatchGlob matches the segments of a path against the segments of a glob, ** matches any
+// number of segments
+func MatchGlob(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if MatchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return MatchGlob(pattern[1:], segments[1:])
+}
+
+// WalkFiles walks root like filepath.WalkDir, but skips .git directories and the files and
+// directories ignored by .gitignore files. Those are the .gitignore files of the walked
+// directories and, when root is inside a git repository, of the directories above it up to the
+// top of the repository. fn gets the segments of the path relative to root, none for root itself.
+func WalkFiles(root string, fn func(path string, segments []string, entry os.DirEntry) error) error {
+	// Paths are matched relative to the top of the repository, so that the patterns of the
+	// .gitignore files above root apply as they do for git
+	prefix, ignores := parentGitignores(root)
+	return filepath.WalkDir(root, func(path string, entry os.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, _ := filepath.Rel(root, path)
+		var segments []string
+		if rel != "." {
+			segments = strings.Split(filepath.ToSlash(rel), "/")
+		}
+		repoSegments := append(slices.Clip(prefix), segments...)
+
+		if entry.IsDir() {
+			if len(segments) > 0 && (entry.Name() == ".git" || gitignore.NewMatcher(ignores).Match(repoSegments, true)) {
+				return filepath.SkipDir
+			}
+			ignores = append(ignores, readGitignore(path, repoSegments)...)
+		} else if gitignore.NewMatcher(ignores).Match(repoSegments, false) {
+			return nil
+		}
+		return fn(path, segments, entry)
+	})
+}
+
+// parentGitignores finds the top of the git repository root is in, the directory with .git,
+// and reads the .gitignore files between it and root. prefix are the segments of root relative
+// to the top. Outside of a repository there are none.
+func parentGitignores(root string) (prefix []string, ignores []gitignore.Pattern) {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return
+	}
+	var parents []string
+	for {
+		if _, err = os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// Not in a repository
+			return nil, nil
+		}
+		prefix = append([]string{filepath.Base(dir)}, prefix...)
+		parents = append([]string{parent}, parents...)
+		dir = parent
+	}
+	for i, parent := range parents {
+		ignores = append(ignores, readGitignore(parent, slices.Clip(prefix[:i]))...)
+	}
+	return
+}
+
+// readGitignore reads the patterns of the .gitignore of a directory, segments is the path of
+// the directory the patterns apply to
+func readGitignore(dir string, segments []string) (ret []gitignore.Pattern) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+			ret = append(ret, gitignore.ParsePattern(line, segments))
+		}
+	}
+	return
+}
@@ -0,0 +1,122 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**", "a/b/c.go", true},
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "pkg/sub/main.go", true},
+		{"pkg/**/*_test.go", "pkg/a/b_test.go", true},
+		{"pkg/**/*_test.go", "cmd/a/b_test.go", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(strings.Split(tt.pattern, "/"), strings.Split(tt.path, "/")); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSplitGlob(t *testing.T) {
+	tests := []struct {
+		target  string
+		base    string
+		pattern []string
+	}{
+		{"src", "src", []string{"**"}},
+		{"src/**/*.go", "src", []string{"**", "*.go"}},
+		{"*.md", ".", []string{"*.md"}},
+		{"/tmp/*.txt", "/tmp", []string{"*.txt"}},
+	}
+	for _, tt := range tests {
+		base, pattern := SplitGlob(tt.target)
+		if base != tt.base || !reflect.DeepEqual(pattern, tt.pattern) {
+			t.Errorf("SplitGlob(%q) = %q, %v, want %q, %v", tt.target, base, pattern, tt.base, tt.pattern)
+		}
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":       "*.log\nbuild/\n",
+		"main.go":          "package main",
+		"debug.log":        "ignored",
+		"build/out.go":     "ignored",
+		".git/config":      "ignored",
+		"pkg/.gitignore":   "secret.go\n",
+		"pkg/lib.go":       "package pkg",
+		"pkg/secret.go":    "ignored",
+		"pkg/sub/other.go": "package sub",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var walked []string
+	err := WalkFiles(root, func(path string, segments []string, entry os.DirEntry) error {
+		walked = append(walked, strings.Join(segments, "/"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFiles returned error: %v", err)
+	}
+	want := []string{"", ".gitignore", "main.go", "pkg", "pkg/.gitignore", "pkg/lib.go", "pkg/sub", "pkg/sub/other.go"}
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("walked %v, want %v", walked, want)
+	}
+}
+
+func TestWalkFiles_RepositoryGitignore(t *testing.T) {
+	repo := t.TempDir()
+	for name, content := range map[string]string{
+		".git/HEAD":            "ref: refs/heads/main\n",
+		".gitignore":           "*.log\n/src/gen/\nsrc/pkg/secret.go\n",
+		"src/main.go":          "package main",
+		"src/debug.log":        "ignored",
+		"src/gen/out.go":       "ignored",
+		"src/pkg/.gitignore":   "*.tmp\n",
+		"src/pkg/lib.go":       "package pkg",
+		"src/pkg/secret.go":    "ignored",
+		"src/pkg/scratch.tmp":  "ignored",
+		"src/pkg/gen/other.go": "package gen",
+	} {
+		path := filepath.Join(repo, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var walked []string
+	err := WalkFiles(filepath.Join(repo, "src"), func(path string, segments []string, entry os.DirEntry) error {
+		walked = append(walked, strings.Join(segments, "/"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFiles returned error: %v", err)
+	}
+	want := []string{"", "main.go", "pkg", "pkg/.gitignore", "pkg/gen", "pkg/gen/other.go", "pkg/lib.go"}
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("walked %v, want %v", walked, want)
+	}
+}
@@ -0,0 +1,360 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaProblems limits how many problems a validation error lists
+const maxSchemaProblems = 10
+
+// JSONSchema validates JSON documents against a JSON schema. It supports the keywords used to
+// describe structured answers: type, enum, const, properties, required, additionalProperties,
+// items, prefixItems, the size, length and range limits, pattern, uniqueItems, allOf, anyOf,
+// oneOf, not and $ref to local definitions. Other keywords such as format are ignored.
+type JSONSchema struct {
+	root any
+}
+
+// ParseJSONSchema parses a JSON schema document
+func ParseJSONSchema(data []byte) (ret *JSONSchema, err error) {
+	var root any
+	if err = json.Unmarshal(data, &root); err != nil {
+		err = fmt.Errorf("invalid JSON schema: %w", err)
+		return
+	}
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		err = errors.New("invalid JSON schema: must be an object or a boolean")
+		return
+	}
+	ret = &JSONSchema{root: root}
+	return
+}
+
+// Validate parses the JSON document and checks it against the schema. The error
+// lists the problems found, each with the path of the value, e.g. $.items[2].name.
+func (o *JSONSchema) Validate(data []byte) (err error) {
+	var value any
+	if err = json.Unmarshal(data, &value); err != nil {
+		err = fmt.Errorf("invalid JSON: %w", err)
+		return
+	}
+	return o.ValidateValue(value)
+}
+
+// ValidateValue checks a decoded JSON value against the schema
+func (o *JSONSchema) ValidateValue(value any) (err error) {
+	var problems []string
+	o.validate(o.root, value, "$", &problems)
+	if len(problems) == 0 {
+		return
+	}
+	if len(problems) > maxSchemaProblems {
+		problems = append(problems[:maxSchemaProblems], fmt.Sprintf("and %d more", len(problems)-maxSchemaProblems))
+	}
+	err = fmt.Errorf("JSON does not match the schema: %s", strings.Join(problems, "; "))
+	return
+}
+
+func (o *JSONSchema) validate(schema any, value any, path string, problems *[]string) {
+	report := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			report("no value is allowed here")
+		}
+		return
+	case map[string]any:
+		if ref, ok := s["$ref"].(string); ok {
+			target, err := o.resolveRef(ref)
+			if err != nil {
+				report("%v", err)
+				return
+			}
+			o.validate(target, value, path, problems)
+		}
+
+		if types, ok := s["type"]; ok && !matchesType(types, value) {
+			report("expected %s, got %s", describeTypes(types), jsonType(value))
+			return
+		}
+		if enum, ok := s["enum"].([]any); ok && !containsValue(enum, value) {
+			report("must be one of %s", mustMarshal(enum))
+		}
+		if constant, ok := s["const"]; ok && !reflect.DeepEqual(constant, value) {
+			report("must be %s", mustMarshal(constant))
+		}
+
+		switch v := value.(type) {
+		case string:
+			o.validateString(s, v, report)
+		case float64:
+			validateNumber(s, v, report)
+		case map[string]any:
+			o.validateObject(s, v, path, problems, report)
+		case []any:
+			o.validateArray(s, v, path, problems, report)
+		}
+
+		o.validateCombinations(s, value, path, problems, report)
+	}
+}
+
+func (o *JSONSchema) validateString(schema map[string]any, value string, report func(string, ...any)) {
+	length := utf8.RuneCountInString(value)
+	if limit, ok := schemaNumber(schema, "minLength"); ok && float64(length) < limit {
+		report("must be at least %v characters long", limit)
+	}
+	if limit, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > limit {
+		report("must be at most %v characters long", limit)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			report("invalid pattern %q in the schema: %v", pattern, err)
+		} else if !re.MatchString(value) {
+			report("must match the pattern %q", pattern)
+		}
+	}
+}
+
+func validateNumber(schema map[string]any, value float64, report func(string, ...any)) {
+	if limit, ok := schemaNumber(schema, "minimum"); ok && value < limit {
+		report("must be at least %v", limit)
+	}
+	if limit, ok := schemaNumber(schema, "maximum"); ok && value > limit {
+		report("must be at most %v", limit)
+	}
+	if limit, ok := schemaNumber(schema, "exclusiveMinimum"); ok && value <= limit {
+		report("must be greater than %v", limit)
+	}
+	if limit, ok := schemaNumber(schema, "exclusiveMaximum"); ok && value >= limit {
+		report("must be less than %v", limit)
+	}
+	if divisor, ok := schemaNumber(schema, "multipleOf"); ok && divisor > 0 {
+		if quotient := value / divisor; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			report("must be a multiple of %v", divisor)
+		}
+	}
+}
+
+func (o *JSONSchema) validateObject(schema map[string]any, value map[string]any, path string, problems *[]string,
+	report func(string, ...any)) {
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, found := value[name]; !found {
+					report("missing required property %q", name)
+				}
+			}
+		}
+	}
+	if limit, ok := schemaNumber(schema, "minProperties"); ok && float64(len(value)) < limit {
+		report("must have at least %v properties", limit)
+	}
+	if limit, ok := schemaNumber(schema, "maxProperties"); ok && float64(len(value)) > limit {
+		report("must have at most %v properties", limit)
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	additional, hasAdditional := schema["additionalProperties"]
+
+	// Sorted so the problems are reported in a stable order
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertyPath := path + "." + name
+		if propertySchema, ok := properties[name]; ok {
+			o.validate(propertySchema, value[name], propertyPath, problems)
+		} else if hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				*problems = append(*problems, propertyPath+": property is not allowed")
+			} else {
+				o.validate(additional, value[name], propertyPath, problems)
+			}
+		}
+	}
+}
+
+func (o *JSONSchema) validateArray(schema map[string]any, value []any, path string, problems *[]string,
+	report func(string, ...any)) {
+
+	if limit, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < limit {
+		report("must have at least %v items", limit)
+	}
+	if limit, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > limit {
+		report("must have at most %v items", limit)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if reflect.DeepEqual(value[i], value[j]) {
+					report("items %d and %d must not be equal", i, j)
+				}
+			}
+		}
+	}
+
+	prefixItems, _ := schema["prefixItems"].([]any)
+	for i, item := range value {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		if i < len(prefixItems) {
+			o.validate(prefixItems[i], item, itemPath, problems)
+		} else if items, ok := schema["items"]; ok {
+			o.validate(items, item, itemPath, problems)
+		}
+	}
+}
+
+func (o *JSONSchema) validateCombinations(schema map[string]any, value any, path string, problems *[]string,
+	report func(string, ...any)) {
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			o.validate(sub, value, path, problems)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && o.countMatches(anyOf, value) == 0 {
+		report("must match at least one of the schemas in anyOf")
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if matches := o.countMatches(oneOf, value); matches != 1 {
+			report("must match exactly one of the schemas in oneOf, matches %d", matches)
+		}
+	}
+	if not, ok := schema["not"]; ok && o.countMatches([]any{not}, value) == 1 {
+		report("must not match the schema in not")
+	}
+}
+
+func (o *JSONSchema) countMatches(schemas []any, value any) (ret int) {
+	for _, sub := range schemas {
+		var subProblems []string
+		o.validate(sub, value, "$", &subProblems)
+		if len(subProblems) == 0 {
+			ret++
+		}
+	}
+	return
+}
+
+// resolveRef resolves a reference to a part of the schema, like #/$defs/item
+func (o *JSONSchema) resolveRef(ref string) (ret any, err error) {
+	if !strings.HasPrefix(ref, "#") {
+		err = fmt.Errorf("only local references are supported, got %q", ref)
+		return
+	}
+	ret = o.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref[1:], "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := ret.(map[string]any)
+		if !ok {
+			err = fmt.Errorf("could not resolve reference %q", ref)
+			return
+		}
+		if ret, ok = object[token]; !ok {
+			err = fmt.Errorf("could not resolve reference %q", ref)
+			return
+		}
+	}
+	return
+}
+
+func matchesType(types any, value any) bool {
+	switch t := types.(type) {
+	case string:
+		return isType(t, value)
+	case []any:
+		for _, name := range t {
+			if name, ok := name.(string); ok && isType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isType(name string, value any) bool {
+	switch name {
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == name
+	}
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func describeTypes(types any) string {
+	if list, ok := types.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+func containsValue(values []any, value any) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaNumber(schema map[string]any, keyword string) (ret float64, ok bool) {
+	ret, ok = schema[keyword].(float64)
+	return
+}
+
+func mustMarshal(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
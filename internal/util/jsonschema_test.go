@@ -0,0 +1,115 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["title", "score", "tags"],
+  "additionalProperties": false,
+  "properties": {
+    "title": {"type": "string", "minLength": 3},
+    "score": {"type": "integer", "minimum": 0, "maximum": 10},
+    "rating": {"enum": ["good", "bad"]},
+    "tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "minItems": 1},
+    "note": {"type": ["string", "null"]}
+  },
+  "$defs": {
+    "tag": {"type": "string", "pattern": "^[a-z]+$"}
+  }
+}`
+
+func TestJSONSchema_Validate(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		problems []string
+	}{
+		{
+			name:     "valid",
+			document: `{"title": "Essay", "score": 7, "rating": "good", "tags": ["prose"], "note": null}`,
+		},
+		{
+			name:     "missing required",
+			document: `{"title": "Essay", "score": 7}`,
+			problems: []string{`$: missing required property "tags"`},
+		},
+		{
+			name:     "wrong types",
+			document: `{"title": 1, "score": 7.5, "tags": ["prose"]}`,
+			problems: []string{"$.score: expected integer, got number", "$.title: expected string, got number"},
+		},
+		{
+			name:     "limits",
+			document: `{"title": "Es", "score": 11, "rating": "okay", "tags": []}`,
+			problems: []string{`$.rating: must be one of ["good","bad"]`, "$.score: must be at most 10", "$.tags: must have at least 1 items", "$.title: must be at least 3 characters long"},
+		},
+		{
+			name:     "refs and additional properties",
+			document: `{"title": "Essay", "score": 1, "tags": ["Prose"], "extra": true}`,
+			problems: []string{"$.extra: property is not allowed", `$.tags[0]: must match the pattern "^[a-z]+$"`},
+		},
+		{
+			name:     "not an object",
+			document: `["Essay"]`,
+			problems: []string{"$: expected object, got array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.document))
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Errorf("expected the document to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected a validation error")
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("expected %q in %q", problem, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestJSONSchema_Combinations(t *testing.T) {
+	schema, err := ParseJSONSchema([]byte(`{
+  "oneOf": [{"type": "string"}, {"type": "number", "minimum": 5}],
+  "not": {"const": "forbidden"}
+}`))
+	if err != nil {
+		t.Fatalf("ParseJSONSchema() error = %v", err)
+	}
+
+	for document, valid := range map[string]bool{`"text"`: true, `7`: true, `3`: false, `"forbidden"`: false, `true`: false} {
+		if err := schema.Validate([]byte(document)); (err == nil) != valid {
+			t.Errorf("Validate(%s) error = %v, want valid = %v", document, err, valid)
+		}
+	}
+}
+
+func TestJSONSchema_InvalidInput(t *testing.T) {
+	if _, err := ParseJSONSchema([]byte(`"string"`)); err == nil {
+		t.Error("expected an error for a schema that is not an object")
+	}
+	if _, err := ParseJSONSchema([]byte(`{`)); err == nil {
+		t.Error("expected an error for invalid JSON in the schema")
+	}
+
+	schema, _ := ParseJSONSchema([]byte(`{"type": "object"}`))
+	if err := schema.Validate([]byte(`{"unterminated": `)); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("expected an invalid JSON error, got %v", err)
+	}
+}